@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/periplon/bract/internal/fakeext"
+)
+
+func main() {
+	var (
+		url      = flag.String("url", "ws://localhost:8765", "WebSocket URL of the MCP browser server")
+		fixtures = flag.String("fixtures", "", "Directory of HTML fixtures served for http(s) URLs")
+		id       = flag.String("id", "", "Browser ID announced in the handshake (default: random)")
+		name     = flag.String("name", "Fake Extension", "Browser name announced in the handshake")
+		retry    = flag.Duration("retry", time.Second, "Delay before reconnecting after the connection fails or closes")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Fake Chrome extension - answers the browser server's commands against\n")
+		fmt.Fprintf(os.Stderr, "in-memory tabs loading local HTML fixtures, for testing without a browser\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -fixtures examples/fixtures\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -url ws://localhost:9000 -id ci-browser\n", os.Args[0])
+	}
+	flag.Parse()
+
+	opts := fakeext.Options{
+		BrowserID: *id,
+		Name:      *name,
+		Logger:    log.Default(),
+	}
+	if *fixtures != "" {
+		if info, err := os.Stat(*fixtures); err != nil || !info.IsDir() {
+			log.Fatalf("fixtures directory %s not found", *fixtures)
+		}
+		opts.Fixtures = os.DirFS(*fixtures)
+	}
+	ext := fakeext.New(opts)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Keep reconnecting, like the real extension, so the server can be
+	// started before or after the fake extension
+	for ctx.Err() == nil {
+		log.Printf("Connecting to %s as browser %s", *url, ext.BrowserID())
+		err := ext.Run(ctx, *url)
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("Disconnected: %v; reconnecting in %s", err, *retry)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(*retry):
+		}
+	}
+
+	fmt.Println("Fake extension stopped")
+}
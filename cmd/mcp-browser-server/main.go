@@ -2,20 +2,29 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/periplon/bract/internal/browser"
 	"github.com/periplon/bract/internal/config"
 	"github.com/periplon/bract/internal/handler"
 	"github.com/periplon/bract/internal/mcp"
+	"github.com/periplon/bract/internal/recorder"
 	"github.com/periplon/bract/internal/websocket"
 )
 
 func main() {
+	var (
+		recordFile   = flag.String("record", "", "Record tool calls to a DSL script at this path")
+		recordServer = flag.String("record-server", "./bin/mcp-browser-server", "Server command the recorded script connects to")
+	)
+	flag.Parse()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -32,6 +41,8 @@ func main() {
 
 	// Create browser client for Chrome extension communication
 	browserClient := browser.NewClient(cfg.WebSocket)
+	browserClient.SetEventBufferSize(cfg.Browser.EventBufferSize)
+	browserClient.SetConsoleBufferSize(cfg.Browser.ConsoleBufferSize)
 
 	// Start WebSocket server for Chrome extension
 	wsServer := websocket.NewServer(cfg.WebSocket.Port, browserClient, cfg.WebSocket.AllowedOrigins)
@@ -43,14 +54,26 @@ func main() {
 
 	// Create tool handler with browser client
 	toolHandler := handler.NewBrowserHandler(browserClient)
+	toolHandler.SetScreenshotOptions(cfg.Browser.ScreenshotDir, cfg.Browser.ScreenshotMaxBytes)
+	toolHandler.SetBaselineDir(cfg.Browser.BaselineDir)
+	toolHandler.SetDownloadDir(cfg.Browser.DownloadDir)
+	toolHandler.SetVideoDir(cfg.Browser.VideoDir)
+	toolHandler.SetHARDir(cfg.Browser.HARDir)
+	toolHandler.SetRoutesDir(cfg.Browser.RoutesDir)
 
 	// Create and configure MCP server
 	mcpServer := mcp.NewServer(cfg.Server.Name, cfg.Server.Version, toolHandler)
+	mcpServer.SetCloseSessionTabs(cfg.Browser.CloseSessionTabs)
+	mcpServer.SetSessionIdleTimeout(time.Duration(cfg.Server.SessionIdleTimeout) * time.Second)
+	if *recordFile != "" {
+		mcpServer.SetRecorder(recorder.New(*recordFile, *recordServer))
+		log.Printf("Recording tool calls to %s", *recordFile)
+	}
 
 	// Start MCP server in separate goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		errChan <- mcpServer.Start()
+		errChan <- mcpServer.Start(ctx, cfg.Server)
 	}()
 
 	// Wait for shutdown signal or error
@@ -5,20 +5,33 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/periplon/bract/internal/dsl"
+	"github.com/periplon/bract/internal/dsl/report"
+	"github.com/periplon/bract/internal/dsl/runner"
 )
 
 func main() {
 	var (
-		validate = flag.Bool("validate", false, "Validate script syntax without executing")
-		format   = flag.Bool("format", false, "Format the script")
-		output   = flag.String("o", "", "Output file for formatted script")
-		timeout  = flag.Duration("timeout", 0, "Execution timeout (0 for no timeout)")
+		validate  = flag.Bool("validate", false, "Validate script syntax without executing")
+		format    = flag.Bool("format", false, "Format the script")
+		output    = flag.String("o", "", "Output file for formatted script")
+		timeout   = flag.Duration("timeout", 0, "Execution timeout per script (0 for no timeout)")
+		reportTo  = flag.String("report", "", "Write a test report to this file")
+		reportAs  = flag.String("report-format", "", "Report format: junit, tap or json (default: from -report extension)")
+		parallel  = flag.Int("parallel", 1, "Number of scripts to run at once")
+		run       = flag.String("run", "", "Only run scripts whose file name matches one of these comma-separated globs")
+		skip      = flag.String("skip", "", "Skip scripts whose file name matches one of these comma-separated globs")
+		tags      = flag.String("tags", "", "Only run scripts with one of these comma-separated tags")
+		skipTags  = flag.String("skip-tags", "", "Skip scripts with one of these comma-separated tags")
+		serverURL = flag.String("server", "", "Run scripts against an MCP server listening at this URL (one session per worker) instead of their connect statement")
+		update    = flag.Bool("update-baselines", false, "Replace the baseline screenshots of assert screenshot_matches instead of comparing with them")
 	)
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] <script.dsl>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <script.dsl|dir>...\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "MCP Test DSL Runner - Execute DSL scripts to test MCP servers\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
@@ -27,6 +40,9 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -validate test.dsl        # Validate script syntax\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -format test.dsl          # Format script\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -format -o out.dsl in.dsl # Format and save to file\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -report out.xml test.dsl  # Run and write a JUnit report\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -run 'storage-*' ./tests/ # Run matching scripts in a directory\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -parallel 4 -tags smoke -server http://localhost:8766/mcp ./tests/\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -36,20 +52,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	scriptFile := flag.Arg(0)
-
-	// Validate only
-	if *validate {
-		if err := dsl.ValidateFile(scriptFile); err != nil {
-			fmt.Fprintf(os.Stderr, "Validation failed: %v\n", err)
+	// Format only
+	if *format {
+		if flag.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "-format takes exactly one script\n")
 			os.Exit(1)
 		}
-		fmt.Println("Script is valid")
-		return
-	}
+		scriptFile := flag.Arg(0)
 
-	// Format only
-	if *format {
 		content, err := os.ReadFile(scriptFile)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to read file: %v\n", err)
@@ -74,27 +84,104 @@ func main() {
 		return
 	}
 
-	// Execute script
-	interpreter := dsl.NewInterpreter()
+	scripts, err := runner.Discover(flag.Args(), splitList(*run), splitList(*skip))
+	if err == nil {
+		scripts, err = runner.FilterTags(scripts, splitList(*tags), splitList(*skipTags))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find scripts: %v\n", err)
+		os.Exit(1)
+	}
+	if len(scripts) == 0 {
+		fmt.Fprintf(os.Stderr, "No scripts to run\n")
+		os.Exit(1)
+	}
+
+	// Validate only
+	if *validate {
+		invalid := 0
+		for _, scriptFile := range scripts {
+			if err := dsl.ValidateFile(scriptFile); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: Validation failed: %v\n", scriptFile, err)
+				invalid++
+			}
+		}
+		if invalid > 0 {
+			os.Exit(1)
+		}
+		if len(scripts) == 1 {
+			fmt.Println("Script is valid")
+		} else {
+			fmt.Printf("%d scripts are valid\n", len(scripts))
+		}
+		return
+	}
+
+	// Resolve the report format up front so a bad flag fails before running
+	var reportFormat report.Format
+	if *reportTo != "" {
+		reportFormat = report.FormatFromPath(*reportTo)
+		if *reportAs != "" {
+			var err error
+			if reportFormat, err = report.ParseFormat(*reportAs); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
 
-	ctx := context.Background()
-	if *timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, *timeout)
-		defer cancel()
+	// Execute scripts
+	start := time.Now()
+	results := runner.Run(context.Background(), scripts, runner.Options{
+		Parallel:        *parallel,
+		Timeout:         *timeout,
+		ServerURL:       *serverURL,
+		UpdateBaselines: *update,
+	})
+	if len(results) > 1 {
+		runner.Summarize(os.Stdout, results, time.Since(start))
 	}
 
-	if err := interpreter.ExecuteFile(ctx, scriptFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Execution failed: %v\n", err)
+	if *reportTo != "" {
+		if err := writeReport(*reportTo, reportFormat, runner.Suites(results)...); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if failed := runner.Failed(results); failed > 0 {
+		if len(results) == 1 {
+			fmt.Fprintf(os.Stderr, "Execution failed: %v\n", results[0].Err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%d of %d scripts failed\n", failed, len(results))
+		}
 		os.Exit(1)
 	}
+}
 
-	// Clean up
-	if client := interpreter.GetClient(); client != nil {
-		if err := client.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close client: %v\n", err)
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
 	}
+	return items
+}
+
+// writeReport writes the test report for the executed suites to path
+func writeReport(path string, format report.Format, suites ...*report.Suite) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := report.Write(f, format, suites...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 func init() {
@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,10 +15,28 @@ import (
 // Client manages communication with the Chrome extension
 type Client struct {
 	config      config.WebSocketConfig
-	connection  Connection
+	connection  Connection // default connection, used when no browser is selected
 	mu          sync.RWMutex
 	pending     sync.Map // map[string]chan Response
 	activeTabID int
+	browsers    map[string]*browserConn
+	sessions    map[string]*session
+	tabOwners   map[tabKey]string // tab -> owning session ID
+	events      *EventBus
+	routes      map[string]*routeEntry // route ID -> route
+	routeSeq    int
+	captures    map[tabKey]*networkCapture  // network captures by tab
+	consoles    map[tabKey][]ConsoleMessage // console messages by tab, oldest first
+	consoleSize int                         // console messages kept per tab
+	actionables map[tabKey][]Actionable     // actionables last listed per tab, to resolve their labels
+	downloads   map[downloadKey]*trackedDownload
+}
+
+// browserConn is a registered browser together with its own active tab
+type browserConn struct {
+	info        BrowserInfo
+	conn        Connection
+	activeTabID int
 }
 
 // Connection interface for WebSocket connection
@@ -37,6 +55,16 @@ func NewClient(config config.WebSocketConfig) *Client {
 	return &Client{
 		config:      config,
 		activeTabID: -1,
+		browsers:    make(map[string]*browserConn),
+		sessions:    make(map[string]*session),
+		tabOwners:   make(map[tabKey]string),
+		events:      NewEventBus(DefaultEventBufferSize),
+		routes:      make(map[string]*routeEntry),
+		captures:    make(map[tabKey]*networkCapture),
+		consoles:    make(map[tabKey][]ConsoleMessage),
+		consoleSize: DefaultConsoleBufferSize,
+		actionables: make(map[tabKey][]Actionable),
+		downloads:   make(map[downloadKey]*trackedDownload),
 	}
 }
 
@@ -58,11 +86,7 @@ func (c *Client) WaitForConnection(ctx context.Context, timeout time.Duration) e
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			c.mu.RLock()
-			hasConnection := c.connection != nil
-			c.mu.RUnlock()
-
-			if hasConnection {
+			if _, err := c.connectionFor(ctx); err == nil {
 				return nil
 			}
 
@@ -73,12 +97,193 @@ func (c *Client) WaitForConnection(ctx context.Context, timeout time.Duration) e
 	}
 }
 
-// RemoveConnection removes the WebSocket connection
+// RemoveConnection removes the WebSocket connection and everything registered
+// on it. A removed default is replaced by the latest remaining connection.
 func (c *Client) RemoveConnection(conn Connection) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
+	for id, b := range c.browsers {
+		if b.conn == conn {
+			delete(c.browsers, id)
+		}
+	}
+	c.removeRoutesLocked(conn)
+	for key := range c.captures {
+		if key.conn == conn {
+			delete(c.captures, key)
+		}
+	}
+	for key := range c.consoles {
+		if key.conn == conn {
+			delete(c.consoles, key)
+		}
+	}
+	for key := range c.actionables {
+		if key.conn == conn {
+			delete(c.actionables, key)
+		}
+	}
+	for key := range c.downloads {
+		if key.conn == conn {
+			delete(c.downloads, key)
+		}
+	}
+	for key := range c.tabOwners {
+		if key.conn == conn {
+			delete(c.tabOwners, key)
+		}
+	}
+	for _, sess := range c.sessions {
+		delete(sess.activeTabs, conn)
+	}
+
 	if c.connection == conn {
 		c.connection = nil
+		var latest *browserConn
+		for _, b := range c.browsers {
+			if latest == nil || b.info.ConnectedAt.After(latest.info.ConnectedAt) {
+				latest = b
+			}
+		}
+		if latest != nil {
+			c.connection = latest.conn
+			c.activeTabID = latest.activeTabID
+		}
+	}
+}
+
+// RegisterBrowser records the identity a browser announced in its handshake,
+// so commands can be routed to it by ID
+func (c *Client) RegisterBrowser(info BrowserInfo, conn Connection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if info.ConnectedAt.IsZero() {
+		info.ConnectedAt = time.Now()
+	}
+
+	entry := &browserConn{
+		info:        info,
+		conn:        conn,
+		activeTabID: -1,
+	}
+	if existing, ok := c.browsers[info.ID]; ok {
+		// A reconnecting browser keeps its active tab
+		entry.activeTabID = existing.activeTabID
+	} else if conn == c.connection {
+		entry.activeTabID = c.activeTabID
+	}
+	c.browsers[info.ID] = entry
+}
+
+// ListBrowsers returns all registered browsers, oldest connection first
+func (c *Client) ListBrowsers() []BrowserInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	browsers := make([]BrowserInfo, 0, len(c.browsers))
+	for _, b := range c.browsers {
+		info := b.info
+		info.Default = b.conn == c.connection
+		browsers = append(browsers, info)
+	}
+	sort.Slice(browsers, func(i, j int) bool {
+		return browsers[i].ConnectedAt.Before(browsers[j].ConnectedAt)
+	})
+
+	return browsers
+}
+
+// connectionFor returns the connection selected by the browser ID in ctx,
+// falling back to the default connection
+func (c *Client) connectionFor(ctx context.Context) (Connection, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if id := BrowserIDFromContext(ctx); id != "" {
+		if _, ok := c.browsers[id]; !ok {
+			return nil, fmt.Errorf("browser %q is not connected", id)
+		}
+	}
+
+	conn := c.selectedConnLocked(ctx)
+	if conn == nil {
+		return nil, fmt.Errorf("no connection to Chrome extension")
+	}
+	return conn, nil
+}
+
+// selectedConnLocked returns the connection selected by ctx, or nil.
+// c.mu must be held.
+func (c *Client) selectedConnLocked(ctx context.Context) Connection {
+	if id := BrowserIDFromContext(ctx); id != "" {
+		if b, ok := c.browsers[id]; ok {
+			return b.conn
+		}
+		return nil
+	}
+	return c.connection
+}
+
+// browserActiveTabLocked returns the active tab of the browser on conn.
+// c.mu must be held.
+func (c *Client) browserActiveTabLocked(conn Connection) int {
+	if conn == c.connection {
+		return c.activeTabID
+	}
+	for _, b := range c.browsers {
+		if b.conn == conn {
+			return b.activeTabID
+		}
+	}
+	return -1
+}
+
+// resolveTabID returns tabID, or the active tab of the calling session on the
+// selected browser when tabID is 0
+func (c *Client) resolveTabID(ctx context.Context, tabID int) int {
+	if tabID != 0 {
+		return tabID
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	conn := c.selectedConnLocked(ctx)
+	sessionID := SessionIDFromContext(ctx)
+	if sess, ok := c.sessions[sessionID]; ok {
+		if active, ok := sess.activeTabs[conn]; ok {
+			return active
+		}
+	}
+
+	// Fall back to the browser's active tab unless another session owns it
+	active := c.browserActiveTabLocked(conn)
+	if owner := c.tabOwners[tabKey{conn, active}]; sessionID != "" && owner != "" && owner != sessionID {
+		return -1
+	}
+	return active
+}
+
+// setActiveTab records tabID as the active tab of the selected browser and
+// of the calling session
+func (c *Client) setActiveTab(ctx context.Context, tabID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn := c.selectedConnLocked(ctx)
+	if conn == c.connection {
+		c.activeTabID = tabID
+	}
+	for _, b := range c.browsers {
+		if b.conn == conn {
+			b.activeTabID = tabID
+		}
+	}
+
+	if sessionID := SessionIDFromContext(ctx); sessionID != "" {
+		c.sessionLocked(sessionID).activeTabs[conn] = tabID
 	}
 }
 
@@ -96,30 +301,117 @@ func (c *Client) HandleResponse(id string, data json.RawMessage, errMsg string)
 
 // HandleEvent handles events from the Chrome extension
 func (c *Client) HandleEvent(action string, data json.RawMessage) {
-	// Handle browser events (e.g., tab closed, navigation)
-	switch action {
-	case "tabClosed":
-		var tabData struct {
-			TabID int `json:"tabId"`
+	c.HandleConnectionEvent(nil, action, data)
+}
+
+// HandleConnectionEvent handles an event from the Chrome extension on conn,
+// recording it on the event bus. A nil conn stands for any connection.
+func (c *Client) HandleConnectionEvent(conn Connection, action string, data json.RawMessage) {
+	eventType := EventType(action)
+	payload, tabID := decodeEvent(eventType, data)
+	event := Event{Type: eventType, TabID: tabID, Time: time.Now(), Data: payload}
+
+	c.mu.Lock()
+	for id, b := range c.browsers {
+		if conn != nil && b.conn == conn {
+			event.BrowserID = id
 		}
-		if err := json.Unmarshal(data, &tabData); err == nil {
-			if tabData.TabID == c.activeTabID {
+	}
+	for key, owner := range c.tabOwners {
+		if key.tabID == tabID && (conn == nil || key.conn == conn) {
+			event.owner = owner
+		}
+	}
+
+	// Handle browser events (e.g., tab closed, navigation)
+	switch eventType {
+	case EventTabClosed:
+		if conn == nil || conn == c.connection {
+			if tabID == c.activeTabID {
 				c.activeTabID = -1
 			}
 		}
+		for _, b := range c.browsers {
+			if (conn == nil || b.conn == conn) && tabID == b.activeTabID {
+				b.activeTabID = -1
+			}
+		}
+		c.releaseTabLocked(conn, tabID)
+		for key := range c.captures {
+			if key.tabID == tabID && (conn == nil || key.conn == conn) {
+				delete(c.captures, key)
+			}
+		}
+		for key := range c.consoles {
+			if key.tabID == tabID && (conn == nil || key.conn == conn) {
+				delete(c.consoles, key)
+			}
+		}
+		c.forgetActionablesLocked(conn, tabID)
+	case EventNavigationCommitted:
+		// Labels listed on the previous page no longer apply
+		if nav, ok := payload.(*NavigationEvent); !ok || nav.FrameID == 0 {
+			c.forgetActionablesLocked(conn, tabID)
+		}
+	case EventConsole:
+		if conn == nil {
+			c.recordConsoleLocked(c.connection, event)
+		} else {
+			c.recordConsoleLocked(conn, event)
+		}
+	case EventDownload:
+		if conn == nil {
+			c.recordDownloadLocked(c.connection, event)
+		} else {
+			c.recordDownloadLocked(conn, event)
+		}
 	}
+	c.mu.Unlock()
+
+	event = c.events.Publish(event)
+	c.recordNetwork(conn, event)
+}
+
+// SetEventBufferSize sets the number of events kept for GetEvents
+func (c *Client) SetEventBufferSize(size int) {
+	c.events.Resize(size)
+}
+
+// GetEvents returns up to limit events after the since cursor matching the
+// filter. Events are limited to the browser selected in ctx, if any, and
+// events of tabs owned by other sessions are left out.
+func (c *Client) GetEvents(ctx context.Context, since uint64, filter EventFilter, limit int) *EventPage {
+	if filter.BrowserID == "" {
+		filter.BrowserID = BrowserIDFromContext(ctx)
+	}
+	filter.SessionID = SessionIDFromContext(ctx)
+
+	page := c.events.Since(since, filter, limit)
+	return &page
+}
+
+// SubscribeEvents calls fn with every event from the browsers until the
+// returned function is called. fn must not block.
+func (c *Client) SubscribeEvents(fn func(Event)) (unsubscribe func()) {
+	return c.events.Subscribe(fn)
 }
 
 // sendCommand sends a command to the Chrome extension and waits for response
 func (c *Client) sendCommand(ctx context.Context, action string, data interface{}) (json.RawMessage, error) {
-	c.mu.RLock()
-	conn := c.connection
-	c.mu.RUnlock()
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if conn == nil {
-		return nil, fmt.Errorf("no connection to Chrome extension")
+	if err := c.checkTabAccess(ctx, conn, data); err != nil {
+		return nil, err
 	}
 
+	return c.sendCommandTo(ctx, conn, action, data)
+}
+
+// sendCommandTo sends a command over a specific connection and waits for response
+func (c *Client) sendCommandTo(ctx context.Context, conn Connection, action string, data interface{}) (json.RawMessage, error) {
 	// Send command
 	msgID, err := conn.SendCommand(action, data)
 	if err != nil {
@@ -159,6 +451,7 @@ func (c *Client) ListTabs(ctx context.Context) ([]Tab, error) {
 		return nil, err
 	}
 
+	c.markOwners(ctx, tabs)
 	return tabs, nil
 }
 
@@ -184,8 +477,9 @@ func (c *Client) CreateTab(ctx context.Context, url string, active bool) (*Tab,
 		return nil, fmt.Errorf("failed to parse tab response: %w (response: %s)", err, string(data))
 	}
 
+	c.claimTab(ctx, tab.ID)
 	if active {
-		c.activeTabID = tab.ID
+		c.setActiveTab(ctx, tab.ID)
 	}
 
 	return &tab, nil
@@ -198,6 +492,11 @@ func (c *Client) CloseTab(ctx context.Context, tabID int) error {
 	}
 
 	_, err := c.sendCommand(ctx, "closeTab", params)
+	if err == nil {
+		c.mu.Lock()
+		c.releaseTabLocked(c.selectedConnLocked(ctx), tabID)
+		c.mu.Unlock()
+	}
 	return err
 }
 
@@ -209,7 +508,7 @@ func (c *Client) ActivateTab(ctx context.Context, tabID int) error {
 
 	_, err := c.sendCommand(ctx, "activateTab", params)
 	if err == nil {
-		c.activeTabID = tabID
+		c.setActiveTab(ctx, tabID)
 	}
 	return err
 }
@@ -218,9 +517,7 @@ func (c *Client) ActivateTab(ctx context.Context, tabID int) error {
 
 // Navigate navigates to a URL in a tab
 func (c *Client) Navigate(ctx context.Context, tabID int, url string, waitUntilLoad bool) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":         tabID,
@@ -234,9 +531,7 @@ func (c *Client) Navigate(ctx context.Context, tabID int, url string, waitUntilL
 
 // Reload reloads a tab
 func (c *Client) Reload(ctx context.Context, tabID int, hardReload bool) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":      tabID,
@@ -247,37 +542,50 @@ func (c *Client) Reload(ctx context.Context, tabID int, hardReload bool) error {
 	return err
 }
 
-// Interaction Methods
+// GoBack navigates back in the tab's history
+func (c *Client) GoBack(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	return c.goHistory(ctx, "goBack", tabID, waitUntilLoad, timeout)
+}
 
-// Click clicks on an element
-func (c *Client) Click(ctx context.Context, tabID int, selector string, timeout int) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+// GoForward navigates forward in the tab's history
+func (c *Client) GoForward(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	return c.goHistory(ctx, "goForward", tabID, waitUntilLoad, timeout)
+}
+
+// goHistory sends a history navigation command, optionally waiting up to
+// timeout milliseconds for the page to load
+func (c *Client) goHistory(ctx context.Context, action string, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
-		"tabId":    tabID,
-		"selector": selector,
-		"timeout":  timeout,
+		"tabId":         tabID,
+		"waitUntilLoad": waitUntilLoad,
+		"timeout":       timeout,
 	}
 
-	_, err := c.sendCommand(ctx, "click", params)
-	return err
+	return c.sendCommand(ctx, action, params)
+}
+
+// Interaction Methods
+
+// Click clicks on an element
+func (c *Client) Click(ctx context.Context, tabID int, selector string, timeout int) error {
+	return c.ClickWithOptions(ctx, tabID, selector, timeout, ClickOptions{})
 }
 
 // Type types text into an input field
 func (c *Client) Type(ctx context.Context, tabID int, selector, text string, clearFirst bool, delay int) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":      tabID,
-		"selector":   selector,
 		"text":       text,
 		"clearFirst": clearFirst,
 		"delay":      delay,
 	}
+	if err := setLocator(params, selector); err != nil {
+		return err
+	}
 
 	_, err := c.sendCommand(ctx, "type", params)
 	return err
@@ -285,9 +593,7 @@ func (c *Client) Type(ctx context.Context, tabID int, selector, text string, cle
 
 // Scroll scrolls the page
 func (c *Client) Scroll(ctx context.Context, tabID int, x, y *float64, selector, behavior string) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":    tabID,
@@ -301,7 +607,9 @@ func (c *Client) Scroll(ctx context.Context, tabID int, x, y *float64, selector,
 		params["y"] = *y
 	}
 	if selector != "" {
-		params["selector"] = selector
+		if err := setLocator(params, selector); err != nil {
+			return nil, err
+		}
 	}
 
 	response, err := c.sendCommand(ctx, "scroll", params)
@@ -310,15 +618,15 @@ func (c *Client) Scroll(ctx context.Context, tabID int, x, y *float64, selector,
 
 // WaitForElement waits for an element to appear
 func (c *Client) WaitForElement(ctx context.Context, tabID int, selector string, timeout int, state string) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
-		"tabId":    tabID,
-		"selector": selector,
-		"timeout":  timeout,
-		"state":    state,
+		"tabId":   tabID,
+		"timeout": timeout,
+		"state":   state,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return nil, err
 	}
 
 	response, err := c.sendCommand(ctx, "waitForElement", params)
@@ -329,9 +637,7 @@ func (c *Client) WaitForElement(ctx context.Context, tabID int, selector string,
 
 // ExecuteScript executes JavaScript in page context
 func (c *Client) ExecuteScript(ctx context.Context, tabID int, script string, args []interface{}) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":  tabID,
@@ -344,9 +650,7 @@ func (c *Client) ExecuteScript(ctx context.Context, tabID int, script string, ar
 
 // ExtractContent extracts content from the page
 func (c *Client) ExtractContent(ctx context.Context, tabID int, selector, contentType, attribute string) ([]string, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":       tabID,
@@ -357,6 +661,11 @@ func (c *Client) ExtractContent(ctx context.Context, tabID int, selector, conten
 	if attribute != "" {
 		params["attribute"] = attribute
 	}
+	if selector != "" {
+		if err := setLocator(params, selector); err != nil {
+			return nil, err
+		}
+	}
 
 	data, err := c.sendCommand(ctx, "extractContent", params)
 	if err != nil {
@@ -388,11 +697,68 @@ func (c *Client) ExtractContent(ctx context.Context, tabID int, selector, conten
 	}
 }
 
+// FindElements returns the elements matching selector, at most limit of them
+// unless limit is 0
+func (c *Client) FindElements(ctx context.Context, tabID int, selector string, limit int) ([]Element, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId": tabID,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return nil, err
+	}
+
+	if limit > 0 {
+		params["limit"] = limit
+	}
+
+	data, err := c.sendCommand(ctx, "findElements", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Browser extension returns {elements: [...]}
+	var response struct {
+		Elements []Element `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(response.Elements) > limit {
+		response.Elements = response.Elements[:limit]
+	}
+	return response.Elements, nil
+}
+
+// GetValue reads the current value of a form field
+func (c *Client) GetValue(ctx context.Context, tabID int, selector string) (*ElementValue, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId": tabID,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return nil, err
+	}
+
+	data, err := c.sendCommand(ctx, "getValue", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var value ElementValue
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	return &value, nil
+}
+
 // Screenshot takes a screenshot
 func (c *Client) Screenshot(ctx context.Context, tabID int, fullPage bool, selector, format string, quality int) (string, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":    tabID,
@@ -402,7 +768,9 @@ func (c *Client) Screenshot(ctx context.Context, tabID int, fullPage bool, selec
 	}
 
 	if selector != "" {
-		params["selector"] = selector
+		if err := setLocator(params, selector); err != nil {
+			return "", err
+		}
 	}
 
 	data, err := c.sendCommand(ctx, "screenshot", params)
@@ -499,9 +867,7 @@ func (c *Client) DeleteCookies(ctx context.Context, url, name string) error {
 
 // GetLocalStorage gets localStorage value
 func (c *Client) GetLocalStorage(ctx context.Context, tabID int, key string) (string, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -539,9 +905,7 @@ func (c *Client) GetLocalStorage(ctx context.Context, tabID int, key string) (st
 
 // SetLocalStorage sets localStorage value
 func (c *Client) SetLocalStorage(ctx context.Context, tabID int, key, value string) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -555,9 +919,7 @@ func (c *Client) SetLocalStorage(ctx context.Context, tabID int, key, value stri
 
 // ClearLocalStorage clears all localStorage
 func (c *Client) ClearLocalStorage(ctx context.Context, tabID int) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -569,9 +931,7 @@ func (c *Client) ClearLocalStorage(ctx context.Context, tabID int) error {
 
 // GetSessionStorage gets sessionStorage value
 func (c *Client) GetSessionStorage(ctx context.Context, tabID int, key string) (string, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -609,9 +969,7 @@ func (c *Client) GetSessionStorage(ctx context.Context, tabID int, key string) (
 
 // SetSessionStorage sets sessionStorage value
 func (c *Client) SetSessionStorage(ctx context.Context, tabID int, key, value string) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -625,9 +983,7 @@ func (c *Client) SetSessionStorage(ctx context.Context, tabID int, key, value st
 
 // ClearSessionStorage clears all sessionStorage
 func (c *Client) ClearSessionStorage(ctx context.Context, tabID int) error {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -639,9 +995,7 @@ func (c *Client) ClearSessionStorage(ctx context.Context, tabID int) error {
 
 // GetActionables gets all actionable elements on the page
 func (c *Client) GetActionables(ctx context.Context, tabID int) ([]Actionable, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -660,9 +1014,55 @@ func (c *Client) GetActionables(ctx context.Context, tabID int) ([]Actionable, e
 		return nil, err
 	}
 
+	if conn, err := c.connectionFor(ctx); err == nil {
+		c.mu.Lock()
+		c.actionables[tabKey{conn, tabID}] = response.Actionables
+		c.mu.Unlock()
+	}
+
 	return response.Actionables, nil
 }
 
+// ResolveActionable returns the actionable with a label, as last listed by
+// GetActionables on the tab. When the tab's actionables were not listed yet,
+// or it navigated since, they are listed again.
+func (c *Client) ResolveActionable(ctx context.Context, tabID int, label int) (*Actionable, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkTabAccess(ctx, conn, map[string]interface{}{"tabId": tabID}); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	actionables, ok := c.actionables[tabKey{conn, tabID}]
+	c.mu.RUnlock()
+	if !ok {
+		if actionables, err = c.GetActionables(ctx, tabID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range actionables {
+		if a.LabelNumber == label {
+			return &a, nil
+		}
+	}
+	return nil, fmt.Errorf("no actionable with label %d", label)
+}
+
+// forgetActionablesLocked drops the actionables listed on a tab. c.mu must be
+// held for writing.
+func (c *Client) forgetActionablesLocked(conn Connection, tabID int) {
+	for key := range c.actionables {
+		if key.tabID == tabID && (conn == nil || key.conn == conn) {
+			delete(c.actionables, key)
+		}
+	}
+}
+
 // ExtractText extracts content from the page as HTML and converts it to plain text
 func (c *Client) ExtractText(ctx context.Context, tabID int, selector string) (string, error) {
 	// Use the existing extractContent command with type "html"
@@ -671,65 +1071,22 @@ func (c *Client) ExtractText(ctx context.Context, tabID int, selector string) (s
 		return "", err
 	}
 
-	// Join all results and strip HTML tags
+	// Join all results converted to text
 	var plainText strings.Builder
 	for i, html := range results {
 		if i > 0 {
 			plainText.WriteString("\n\n")
 		}
-		plainText.WriteString(stripHTMLTags(html))
+		plainText.WriteString(htmlToText(html))
 	}
 
 	return plainText.String(), nil
 }
 
-// stripHTMLTags removes HTML tags from a string and returns plain text
-func stripHTMLTags(html string) string {
-	// Remove script and style tags and their contents
-	scriptRegex := regexp.MustCompile(`(?i)<script[^>]*>[\s\S]*?</script>`)
-	html = scriptRegex.ReplaceAllString(html, "")
-	styleRegex := regexp.MustCompile(`(?i)<style[^>]*>[\s\S]*?</style>`)
-	html = styleRegex.ReplaceAllString(html, "")
-
-	// Replace br tags with newlines
-	brRegex := regexp.MustCompile(`(?i)<br\s*/?>`)
-	html = brRegex.ReplaceAllString(html, "\n")
-
-	// Replace p, div, and other block tags with newlines
-	blockRegex := regexp.MustCompile(`(?i)</?(p|div|h[1-6]|ul|ol|li|blockquote|pre|table|tr|td|th)[^>]*>`)
-	html = blockRegex.ReplaceAllString(html, "\n")
-
-	// Remove all remaining HTML tags
-	tagRegex := regexp.MustCompile(`<[^>]+>`)
-	text := tagRegex.ReplaceAllString(html, "")
-
-	// Decode HTML entities
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&quot;", "\"")
-	text = strings.ReplaceAll(text, "&#39;", "'")
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-
-	// Clean up excessive whitespace
-	lines := strings.Split(text, "\n")
-	var cleanedLines []string
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" {
-			cleanedLines = append(cleanedLines, trimmed)
-		}
-	}
-
-	return strings.Join(cleanedLines, "\n")
-}
-
 // GetAccessibilitySnapshot gets the accessibility tree of the page
 func (c *Client) GetAccessibilitySnapshot(ctx context.Context, tabID int, interestingOnly bool, root string) (json.RawMessage, error) {
 	// Default to active tab if not specified
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":           tabID,
@@ -752,9 +1109,7 @@ func (c *Client) GetAccessibilitySnapshot(ctx context.Context, tabID int, intere
 
 // ShowHints shows interactive element hints
 func (c *Client) ShowHints(ctx context.Context, tabID int, selector, action string) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -772,9 +1127,7 @@ func (c *Client) ShowHints(ctx context.Context, tabID int, selector, action stri
 
 // ClickHint clicks on a hint element
 func (c *Client) ClickHint(ctx context.Context, tabID int, selector string, index int, text string) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -809,9 +1162,7 @@ func (c *Client) Search(ctx context.Context, query, engine string, newTab bool)
 
 // Find searches for text on the current page
 func (c *Client) Find(ctx context.Context, tabID int, text string, caseSensitive, wholeWord bool) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":         tabID,
@@ -856,9 +1207,7 @@ func (c *Client) WriteClipboard(ctx context.Context, text, format string) error
 
 // ShowOmnibar shows the omnibar
 func (c *Client) ShowOmnibar(ctx context.Context, tabID int, barType, query string) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
@@ -874,9 +1223,7 @@ func (c *Client) ShowOmnibar(ctx context.Context, tabID int, barType, query stri
 
 // StartVisualMode starts visual selection mode
 func (c *Client) StartVisualMode(ctx context.Context, tabID int, selectElement bool) (json.RawMessage, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId":         tabID,
@@ -888,9 +1235,7 @@ func (c *Client) StartVisualMode(ctx context.Context, tabID int, selectElement b
 
 // GetPageTitle gets the title of the current page
 func (c *Client) GetPageTitle(ctx context.Context, tabID int) (string, error) {
-	if tabID == 0 {
-		tabID = c.activeTabID
-	}
+	tabID = c.resolveTabID(ctx, tabID)
 
 	params := map[string]interface{}{
 		"tabId": tabID,
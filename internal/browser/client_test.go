@@ -63,6 +63,28 @@ func TestClient_RemoveConnection(t *testing.T) {
 	client.mu.RUnlock()
 }
 
+func TestClient_RemoveConnection_ForgetsTabs(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{})
+	conn1 := &MockConnection{}
+	conn2 := &MockConnection{}
+	client.SetConnection(conn1)
+
+	alice := WithSessionID(context.Background(), "alice")
+	client.claimTab(alice, 1)
+	client.setActiveTab(alice, 1)
+	client.SetConnection(conn2)
+	client.claimTab(alice, 2)
+	client.setActiveTab(alice, 2)
+
+	// The tabs of a dropped browser are forgotten, those of others are kept
+	client.RemoveConnection(conn1)
+
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	assert.Equal(t, map[tabKey]string{{conn2, 2}: "alice"}, client.tabOwners)
+	assert.Equal(t, map[Connection]int{conn2: 2}, client.sessions["alice"].activeTabs)
+}
+
 func TestClient_RemoveConnection_DifferentConnection(t *testing.T) {
 	client := NewClient(config.WebSocketConfig{})
 	mockConn1 := &MockConnection{}
@@ -657,6 +679,104 @@ func TestClient_Screenshot(t *testing.T) {
 	}
 }
 
+func TestClient_GoBack(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 100})
+	client.activeTabID = 456
+	mockConn := &MockConnection{}
+	client.SetConnection(mockConn)
+
+	params := map[string]interface{}{
+		"tabId":         456,
+		"waitUntilLoad": true,
+		"timeout":       5000,
+	}
+	mockConn.On("SendCommand", "goBack", params).Return("msg-back", nil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-back", json.RawMessage(`{"success":true,"url":"https://example.com/"}`), "")
+	}()
+
+	result, err := client.GoBack(context.Background(), 0, true, 5000)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"success":true,"url":"https://example.com/"}`, string(result))
+	mockConn.AssertExpectations(t)
+}
+
+func TestClient_FindElements(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		params   map[string]interface{}
+		response string
+		expected []Element
+	}{
+		{
+			name:   "find all elements",
+			params: map[string]interface{}{"tabId": 123, "selector": "a"},
+			response: `{"elements":[
+				{"index":0,"tag":"a","text":"Home","attributes":{"href":"/"},"selector":"#home","visible":true,
+				 "boundingBox":{"x":10,"y":20,"width":40,"height":16}}
+			]}`,
+			expected: []Element{{
+				Index:       0,
+				Tag:         "a",
+				Text:        "Home",
+				Attributes:  map[string]string{"href": "/"},
+				Selector:    "#home",
+				Visible:     true,
+				BoundingBox: &Rect{X: 10, Y: 20, Width: 40, Height: 16},
+			}},
+		},
+		{
+			name:     "limit elements",
+			limit:    1,
+			params:   map[string]interface{}{"tabId": 123, "selector": "a", "limit": 1},
+			response: `{"elements":[{"index":0,"tag":"a"},{"index":1,"tag":"a"}]}`,
+			expected: []Element{{Index: 0, Tag: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(config.WebSocketConfig{ReconnectMs: 100})
+			mockConn := &MockConnection{}
+			client.SetConnection(mockConn)
+			mockConn.On("SendCommand", "findElements", tt.params).Return("msg-find", nil)
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				client.HandleResponse("msg-find", json.RawMessage(tt.response), "")
+			}()
+
+			elements, err := client.FindElements(context.Background(), 123, "a", tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, elements)
+			mockConn.AssertExpectations(t)
+		})
+	}
+}
+
+func TestClient_GetValue(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 100})
+	mockConn := &MockConnection{}
+	client.SetConnection(mockConn)
+
+	params := map[string]interface{}{"tabId": 123, "selector": "#agree"}
+	mockConn.On("SendCommand", "getValue", params).Return("msg-value", nil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-value", json.RawMessage(`{"tag":"input","type":"checkbox","value":"on","checked":true}`), "")
+	}()
+
+	value, err := client.GetValue(context.Background(), 123, "#agree")
+	require.NoError(t, err)
+	checked := true
+	assert.Equal(t, &ElementValue{Tag: "input", Type: "checkbox", Value: "on", Checked: &checked}, value)
+	mockConn.AssertExpectations(t)
+}
+
 func TestClient_Timeout(t *testing.T) {
 	client := NewClient(config.WebSocketConfig{ReconnectMs: 50}) // Short timeout
 	mockConn := &MockConnection{}
@@ -727,3 +847,254 @@ func TestClient_ChromeExtensionError(t *testing.T) {
 
 	mockConn.AssertExpectations(t)
 }
+
+func TestClient_RegisterBrowser(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{})
+	conn1 := &MockConnection{}
+	conn2 := &MockConnection{}
+
+	client.SetConnection(conn1)
+	client.RegisterBrowser(BrowserInfo{ID: "work", ConnectedAt: time.Unix(100, 0)}, conn1)
+	client.SetConnection(conn2)
+	client.RegisterBrowser(BrowserInfo{ID: "personal", Profile: "Personal", ConnectedAt: time.Unix(200, 0)}, conn2)
+
+	browsers := client.ListBrowsers()
+	require.Len(t, browsers, 2)
+	assert.Equal(t, "work", browsers[0].ID)
+	assert.False(t, browsers[0].Default)
+	assert.Equal(t, "personal", browsers[1].ID)
+	assert.Equal(t, "Personal", browsers[1].Profile)
+	assert.True(t, browsers[1].Default)
+
+	// Removing the default connection falls back to the remaining browser
+	client.RemoveConnection(conn2)
+	browsers = client.ListBrowsers()
+	require.Len(t, browsers, 1)
+	assert.Equal(t, "work", browsers[0].ID)
+	assert.True(t, browsers[0].Default)
+}
+
+func TestClient_RoutesByBrowserID(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn1 := &MockConnection{}
+	conn2 := &MockConnection{}
+
+	client.SetConnection(conn1)
+	client.RegisterBrowser(BrowserInfo{ID: "first"}, conn1)
+	client.SetConnection(conn2)
+	client.RegisterBrowser(BrowserInfo{ID: "second"}, conn2)
+
+	conn1.On("SendCommand", "createTab", mock.Anything).Return("msg-1", nil)
+	conn1.On("SendCommand", "reload", map[string]interface{}{
+		"tabId":      7,
+		"hardReload": false,
+	}).Return("msg-2", nil)
+
+	ctx := WithBrowserID(context.Background(), "first")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"id": 7}`), "")
+	}()
+	_, err := client.CreateTab(ctx, "https://example.com", true)
+	require.NoError(t, err)
+
+	// The routed browser tracks its own active tab; the default is untouched
+	assert.Equal(t, -1, client.activeTabID)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-2", json.RawMessage(`{}`), "")
+	}()
+	require.NoError(t, client.Reload(ctx, 0, false))
+
+	conn1.AssertExpectations(t)
+	conn2.AssertNotCalled(t, "SendCommand", mock.Anything, mock.Anything)
+}
+
+func TestClient_UnknownBrowserID(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{})
+	client.SetConnection(&MockConnection{})
+
+	_, err := client.ListTabs(WithBrowserID(context.Background(), "missing"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `browser "missing" is not connected`)
+}
+
+func TestClient_SessionActiveTabs(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	conn.On("SendCommand", "createTab", mock.Anything).Return("msg-1", nil).Once()
+	conn.On("SendCommand", "createTab", mock.Anything).Return("msg-2", nil).Once()
+	conn.On("SendCommand", "reload", map[string]interface{}{
+		"tabId":      1,
+		"hardReload": false,
+	}).Return("msg-3", nil)
+
+	alice := WithSessionID(context.Background(), "alice")
+	bob := WithSessionID(context.Background(), "bob")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"id": 1}`), "")
+	}()
+	_, err := client.CreateTab(alice, "https://example.com", true)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-2", json.RawMessage(`{"id": 2}`), "")
+	}()
+	_, err = client.CreateTab(bob, "https://example.org", true)
+	require.NoError(t, err)
+
+	// Bob's new tab doesn't steal alice's default tab
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-3", json.RawMessage(`{}`), "")
+	}()
+	require.NoError(t, client.Reload(alice, 0, false))
+
+	assert.Equal(t, 2, client.resolveTabID(bob, 0))
+	conn.AssertExpectations(t)
+}
+
+func TestClient_RejectsTabsOwnedByOtherSession(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	conn.On("SendCommand", "createTab", mock.Anything).Return("msg-1", nil)
+
+	alice := WithSessionID(context.Background(), "alice")
+	bob := WithSessionID(context.Background(), "bob")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"id": 5}`), "")
+	}()
+	_, err := client.CreateTab(alice, "https://example.com", false)
+	require.NoError(t, err)
+
+	err = client.Click(bob, 5, "#submit", 1000)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tab 5 is owned by another session")
+
+	err = client.CloseTab(bob, 5)
+	require.Error(t, err)
+
+	// Bob falls back to the browser's active tab only if alice doesn't own it
+	client.setActiveTab(alice, 5)
+	assert.Equal(t, -1, client.resolveTabID(bob, 0))
+
+	conn.AssertNotCalled(t, "SendCommand", "click", mock.Anything)
+	conn.AssertNotCalled(t, "SendCommand", "closeTab", mock.Anything)
+}
+
+func TestClient_ListTabsMarksOwners(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	client.claimTab(WithSessionID(context.Background(), "alice"), 1)
+	client.claimTab(WithSessionID(context.Background(), "bob"), 2)
+
+	conn.On("SendCommand", "listTabs", nil).Return("msg-1", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`[{"id": 1}, {"id": 2}, {"id": 3}]`), "")
+	}()
+
+	tabs, err := client.ListTabs(WithSessionID(context.Background(), "alice"))
+	require.NoError(t, err)
+	require.Len(t, tabs, 3)
+	assert.Equal(t, "self", tabs[0].Owner)
+	assert.Equal(t, "other", tabs[1].Owner)
+	assert.Empty(t, tabs[2].Owner)
+}
+
+func TestClient_ReleaseSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		closeTabs bool
+	}{
+		{name: "closes owned tabs", closeTabs: true},
+		{name: "releases owned tabs", closeTabs: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+			conn := &MockConnection{}
+			client.SetConnection(conn)
+
+			alice := WithSessionID(context.Background(), "alice")
+			client.claimTab(alice, 3)
+			client.setActiveTab(alice, 3)
+
+			if tt.closeTabs {
+				conn.On("SendCommand", "closeTab", map[string]interface{}{"tabId": 3}).Return("msg-1", nil)
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					client.HandleResponse("msg-1", json.RawMessage(`{}`), "")
+				}()
+			}
+
+			require.NoError(t, client.ReleaseSession(context.Background(), "alice", tt.closeTabs))
+
+			assert.Empty(t, client.tabOwners)
+			assert.NotContains(t, client.sessions, "alice")
+			if tt.closeTabs {
+				conn.AssertExpectations(t)
+			} else {
+				conn.AssertNotCalled(t, "SendCommand", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestClient_ResolveActionable(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+	ctx := context.Background()
+
+	listed := 0
+	list := func(msgID string, actionables string) {
+		conn.On("SendCommand", "tabs.getActionables", map[string]interface{}{"tabId": 1}).Return(msgID, nil).Once()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			listed++
+			client.HandleResponse(msgID, json.RawMessage(`{"actionables":`+actionables+`}`), "")
+		}()
+	}
+
+	// Labels not listed yet are listed on demand
+	list("msg-1", `[{"labelNumber":0,"selector":"#login"},{"labelNumber":1,"selector":"#signup"}]`)
+	a, err := client.ResolveActionable(ctx, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "#signup", a.Selector)
+
+	// Then resolved from the last listing
+	a, err = client.ResolveActionable(ctx, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "#login", a.Selector)
+	_, err = client.ResolveActionable(ctx, 1, 5)
+	assert.EqualError(t, err, "no actionable with label 5")
+	assert.Equal(t, 1, listed)
+
+	// Navigating in a frame keeps the labels, navigating the tab drops them
+	client.HandleConnectionEvent(conn, "navigationCommitted", json.RawMessage(`{"tabId":1,"frameId":3,"url":"https://ads.example.com"}`))
+	_, err = client.ResolveActionable(ctx, 1, 0)
+	require.NoError(t, err)
+	client.HandleConnectionEvent(conn, "navigationCommitted", json.RawMessage(`{"tabId":1,"frameId":0,"url":"https://example.com/next"}`))
+	list("msg-2", `[{"labelNumber":0,"selector":"#next"}]`)
+	a, err = client.ResolveActionable(ctx, 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "#next", a.Selector)
+	assert.Equal(t, 2, listed)
+
+	conn.AssertExpectations(t)
+}
@@ -0,0 +1,118 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultConsoleBufferSize is the number of console messages kept per tab
+// unless configured otherwise
+const DefaultConsoleBufferSize = 500
+
+// ConsoleLevels lists the levels of console messages. Uncaught exceptions
+// have the exception level.
+var ConsoleLevels = []string{"log", "info", "warn", "error", "debug", "exception"}
+
+// ConsoleMessage is a console message or uncaught exception of a tab
+type ConsoleMessage struct {
+	Level  string    `json:"level"`
+	Text   string    `json:"text"`
+	Source string    `json:"source,omitempty"` // script URL
+	Line   int       `json:"line,omitempty"`
+	Column int       `json:"column,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// ConsoleFilter selects console messages. Zero fields match everything.
+type ConsoleFilter struct {
+	Levels []string
+	Since  time.Time // only messages logged after this time
+}
+
+// Matches reports whether the message passes the filter
+func (f ConsoleFilter) Matches(m ConsoleMessage) bool {
+	if !f.Since.IsZero() && !m.Time.After(f.Since) {
+		return false
+	}
+	if len(f.Levels) == 0 {
+		return true
+	}
+	for _, level := range f.Levels {
+		if level == m.Level {
+			return true
+		}
+	}
+	return false
+}
+
+// SetConsoleBufferSize sets the number of console messages kept per tab
+func (c *Client) SetConsoleBufferSize(size int) {
+	if size <= 0 {
+		size = DefaultConsoleBufferSize
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.consoleSize = size
+	for key, messages := range c.consoles {
+		if len(messages) > size {
+			c.consoles[key] = messages[len(messages)-size:]
+		}
+	}
+}
+
+// recordConsoleLocked buffers the message of a console event. c.mu must be
+// held for writing.
+func (c *Client) recordConsoleLocked(conn Connection, event Event) {
+	data, ok := event.Data.(*ConsoleEvent)
+	if !ok {
+		return
+	}
+
+	key := tabKey{conn, event.TabID}
+	messages := append(c.consoles[key], ConsoleMessage{
+		Level:  data.Level,
+		Text:   data.Text,
+		Source: data.Source,
+		Line:   data.Line,
+		Column: data.Column,
+		Time:   event.Time,
+	})
+	if len(messages) > c.consoleSize {
+		messages = messages[len(messages)-c.consoleSize:]
+	}
+	c.consoles[key] = messages
+}
+
+// GetConsole returns the buffered console messages of a tab matching the
+// filter, oldest first. With a limit, only the most recent limit messages are
+// returned.
+func (c *Client) GetConsole(ctx context.Context, tabID int, filter ConsoleFilter, limit int) ([]ConsoleMessage, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+	if tabID <= 0 {
+		return nil, fmt.Errorf("no active tab")
+	}
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkTabAccess(ctx, conn, map[string]interface{}{"tabId": tabID}); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	messages := []ConsoleMessage{}
+	for _, m := range c.consoles[tabKey{conn, tabID}] {
+		if filter.Matches(m) {
+			messages = append(messages, m)
+		}
+	}
+	if limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nil
+}
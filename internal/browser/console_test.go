@@ -0,0 +1,64 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Console(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+	client.SetConsoleBufferSize(3)
+	ctx := context.Background()
+
+	log := func(tabID int, level, text string) {
+		data, err := json.Marshal(ConsoleEvent{TabID: tabID, Level: level, Text: text})
+		require.NoError(t, err)
+		client.HandleConnectionEvent(conn, "console", data)
+	}
+
+	log(1, "log", "one")
+	log(1, "error", "two")
+	log(2, "error", "other tab")
+	mark := time.Now()
+	time.Sleep(time.Millisecond)
+	log(1, "warn", "three")
+	log(1, "exception", "Uncaught Error: four")
+
+	// Only the last 3 messages of a tab are kept
+	messages, err := client.GetConsole(ctx, 1, ConsoleFilter{}, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "two", messages[0].Text)
+	assert.False(t, messages[0].Time.IsZero())
+
+	messages, err = client.GetConsole(ctx, 1, ConsoleFilter{Levels: []string{"error", "exception"}}, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Uncaught Error: four", messages[1].Text)
+
+	messages, err = client.GetConsole(ctx, 1, ConsoleFilter{Since: mark}, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "exception", messages[0].Level)
+
+	// Messages go away with their tab
+	client.HandleConnectionEvent(conn, "tabClosed", json.RawMessage(`{"tabId":1}`))
+	messages, err = client.GetConsole(ctx, 1, ConsoleFilter{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	messages, err = client.GetConsole(ctx, 2, ConsoleFilter{}, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	_, err = client.GetConsole(ctx, 0, ConsoleFilter{}, 0)
+	assert.EqualError(t, err, "no active tab")
+}
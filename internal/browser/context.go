@@ -0,0 +1,36 @@
+package browser
+
+import "context"
+
+type contextKey int
+
+const (
+	browserIDKey contextKey = iota
+	sessionIDKey
+)
+
+// WithBrowserID returns a context that routes commands to the browser with the given ID
+func WithBrowserID(ctx context.Context, browserID string) context.Context {
+	return context.WithValue(ctx, browserIDKey, browserID)
+}
+
+// BrowserIDFromContext returns the browser ID selected in the context, if any
+func BrowserIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(browserIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// WithSessionID returns a context whose commands act on behalf of the given MCP session
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// SessionIDFromContext returns the MCP session ID in the context, if any
+func SessionIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(sessionIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,281 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Download states
+const (
+	DownloadInProgress  = "in_progress"
+	DownloadComplete    = "complete"
+	DownloadInterrupted = "interrupted"
+)
+
+// Download is a download of the browser, as last reported by its download
+// events
+type Download struct {
+	ID            int       `json:"id"`
+	TabID         int       `json:"tabId,omitempty"`
+	URL           string    `json:"url"`
+	Filename      string    `json:"filename,omitempty"` // path in the browser's download directory
+	MimeType      string    `json:"mimeType,omitempty"`
+	State         string    `json:"state"` // in_progress, complete or interrupted
+	BytesReceived int64     `json:"bytesReceived,omitempty"`
+	TotalBytes    int64     `json:"totalBytes,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Started       time.Time `json:"started"`
+	Updated       time.Time `json:"updated"`
+	Path          string    `json:"path,omitempty"` // where the file was copied to, if it was
+}
+
+// Finished reports whether the download completed or was interrupted
+func (d *Download) Finished() bool {
+	return d.State == DownloadComplete || d.State == DownloadInterrupted
+}
+
+// DownloadFilter selects downloads. Zero fields match everything.
+type DownloadFilter struct {
+	ID    int
+	URL   string // substring of the URL
+	State string
+}
+
+// Matches reports whether the download passes the filter
+func (f DownloadFilter) Matches(d *Download) bool {
+	return (f.ID == 0 || d.ID == f.ID) &&
+		(f.URL == "" || strings.Contains(d.URL, f.URL)) &&
+		(f.State == "" || d.State == f.State)
+}
+
+// downloadKey identifies a download of a browser
+type downloadKey struct {
+	conn Connection
+	id   int
+}
+
+// trackedDownload is a download with what the client knows of it
+type trackedDownload struct {
+	Download
+	owner  string // session owning the tab that started it
+	waited bool   // returned by WaitForDownload already
+}
+
+// recordDownloadLocked updates the download of a download event. c.mu must
+// be held for writing.
+func (c *Client) recordDownloadLocked(conn Connection, event Event) {
+	data, ok := event.Data.(*DownloadEvent)
+	if !ok || data.ID == 0 {
+		return
+	}
+
+	key := downloadKey{conn, data.ID}
+	d, ok := c.downloads[key]
+	if !ok {
+		d = &trackedDownload{owner: event.owner}
+		d.ID = data.ID
+		d.Started = event.Time
+		c.downloads[key] = d
+	}
+
+	// State changes only carry what changed
+	if data.TabID != 0 {
+		d.TabID = data.TabID
+	}
+	if data.URL != "" {
+		d.URL = data.URL
+	}
+	if data.Filename != "" {
+		d.Filename = data.Filename
+	}
+	if data.MimeType != "" {
+		d.MimeType = data.MimeType
+	}
+	if data.State != "" {
+		d.State = data.State
+	}
+	if data.BytesReceived > 0 {
+		d.BytesReceived = data.BytesReceived
+	}
+	if data.TotalBytes > 0 {
+		d.TotalBytes = data.TotalBytes
+	}
+	if data.Error != "" {
+		d.Error = data.Error
+	}
+	if d.State == "" {
+		d.State = DownloadInProgress
+	}
+	d.Updated = event.Time
+}
+
+// matchingDownloads returns the connection of the selected browser and
+// copies of its downloads matching the filter, oldest first. Downloads
+// started by tabs of other sessions are left out.
+func (c *Client) matchingDownloads(ctx context.Context, filter DownloadFilter) (Connection, []*trackedDownload, error) {
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessionID := SessionIDFromContext(ctx)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var downloads []*trackedDownload
+	for key, d := range c.downloads {
+		if key.conn != conn || !filter.Matches(&d.Download) {
+			continue
+		}
+		if sessionID != "" && d.owner != "" && d.owner != sessionID {
+			continue
+		}
+		copied := *d
+		downloads = append(downloads, &copied)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		if !downloads[i].Started.Equal(downloads[j].Started) {
+			return downloads[i].Started.Before(downloads[j].Started)
+		}
+		return downloads[i].ID < downloads[j].ID
+	})
+	return conn, downloads, nil
+}
+
+// ListDownloads returns the downloads of the browser matching the filter,
+// oldest first
+func (c *Client) ListDownloads(ctx context.Context, filter DownloadFilter) ([]Download, error) {
+	_, tracked, err := c.matchingDownloads(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	downloads := make([]Download, len(tracked))
+	for i, d := range tracked {
+		downloads[i] = d.Download
+	}
+	return downloads, nil
+}
+
+// WaitForDownload waits until a download matching the filter has finished,
+// completed or interrupted, and returns it. Without an ID, it returns the
+// oldest finished download that no earlier call returned, so that a click
+// followed by a wait gets the download of the click even when it finished
+// before the wait started.
+func (c *Client) WaitForDownload(ctx context.Context, filter DownloadFilter, timeout time.Duration) (*Download, error) {
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		conn, downloads, err := c.matchingDownloads(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range downloads {
+			if !d.Finished() || filter.ID == 0 && d.waited {
+				continue
+			}
+			if c.claimDownload(conn, d.ID, filter.ID == 0) {
+				return &d.Download, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for download")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimDownload marks a download as returned by WaitForDownload. With
+// unclaimed, it fails when another wait returned the download first.
+func (c *Client) claimDownload(conn Connection, id int, unclaimed bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.downloads[downloadKey{conn, id}]
+	if !ok || unclaimed && d.waited {
+		return false
+	}
+	d.waited = true
+	return true
+}
+
+// SaveDownload copies the file of a completed download into dir, under the
+// base name of its file, and returns its path. The extension reads the file,
+// so the browser does not need to run on the same machine.
+func (c *Client) SaveDownload(ctx context.Context, id int, dir string) (string, error) {
+	conn, downloads, err := c.matchingDownloads(ctx, DownloadFilter{ID: id})
+	if err != nil {
+		return "", err
+	}
+	if len(downloads) == 0 {
+		return "", fmt.Errorf("no download with id: %d", id)
+	}
+	d := downloads[0]
+	if d.State != DownloadComplete {
+		return "", fmt.Errorf("download %d is %s, not complete", id, d.State)
+	}
+
+	data, err := c.sendCommand(ctx, "readDownload", map[string]interface{}{"downloadId": id})
+	if err != nil {
+		return "", err
+	}
+	var response struct {
+		DataURL string `json:"dataUrl"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", err
+	}
+	_, content, err := DecodeDataURL(response.DataURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download data: %w", err)
+	}
+
+	name := downloadName(d.Filename, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return "", fmt.Errorf("failed to save download: %w", err)
+	}
+
+	c.mu.Lock()
+	if tracked, ok := c.downloads[downloadKey{conn, id}]; ok {
+		tracked.Path = path
+	}
+	c.mu.Unlock()
+	return path, nil
+}
+
+// downloadName returns the base name of a download's file
+func downloadName(filename string, id int) string {
+	if name := baseName(filename); name != "" {
+		return name
+	}
+	return fmt.Sprintf("download-%d", id)
+}
+
+// baseName returns the last element of path, whichever path separator the
+// system it comes from uses, or "" when it names no file
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		path = path[i+1:]
+	}
+	if path == "." || path == ".." {
+		return ""
+	}
+	return path
+}
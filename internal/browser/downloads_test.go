@@ -0,0 +1,115 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Downloads(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+	ctx := context.Background()
+
+	download := func(event DownloadEvent) {
+		data, err := json.Marshal(event)
+		require.NoError(t, err)
+		client.HandleConnectionEvent(conn, "download", data)
+	}
+
+	download(DownloadEvent{TabID: 1, ID: 7, URL: "https://example.com/report.csv", Filename: `C:\Users\me\Downloads\report.csv`, MimeType: "text/csv", State: "in_progress", TotalBytes: 12})
+	download(DownloadEvent{ID: 8, URL: "https://example.com/big.zip", State: "in_progress"})
+
+	// Nothing has finished yet
+	_, err := client.WaitForDownload(ctx, DownloadFilter{}, 50*time.Millisecond)
+	assert.EqualError(t, err, "timeout waiting for download")
+
+	// State changes only carry what changed
+	download(DownloadEvent{ID: 7, State: "complete", BytesReceived: 12})
+	download(DownloadEvent{ID: 8, State: "interrupted", Error: "NETWORK_FAILED"})
+
+	downloads, err := client.ListDownloads(ctx, DownloadFilter{})
+	require.NoError(t, err)
+	require.Len(t, downloads, 2)
+	assert.Equal(t, 7, downloads[0].ID)
+	assert.Equal(t, 1, downloads[0].TabID)
+	assert.Equal(t, "complete", downloads[0].State)
+	assert.Equal(t, int64(12), downloads[0].BytesReceived)
+	assert.Equal(t, "text/csv", downloads[0].MimeType)
+	assert.Equal(t, "NETWORK_FAILED", downloads[1].Error)
+
+	downloads, err = client.ListDownloads(ctx, DownloadFilter{URL: "big", State: "interrupted"})
+	require.NoError(t, err)
+	require.Len(t, downloads, 1)
+	assert.Equal(t, 8, downloads[0].ID)
+
+	// Waits return each finished download once, oldest first, unless asked
+	// for by ID
+	d, err := client.WaitForDownload(ctx, DownloadFilter{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 7, d.ID)
+	d, err = client.WaitForDownload(ctx, DownloadFilter{}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 8, d.ID)
+	_, err = client.WaitForDownload(ctx, DownloadFilter{}, 50*time.Millisecond)
+	assert.EqualError(t, err, "timeout waiting for download")
+	d, err = client.WaitForDownload(ctx, DownloadFilter{ID: 7}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "complete", d.State)
+
+	// A download finishing during the wait ends it
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		download(DownloadEvent{ID: 9, URL: "https://example.com/late.pdf", State: "complete"})
+	}()
+	d, err = client.WaitForDownload(ctx, DownloadFilter{URL: "late"}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 9, d.ID)
+
+	// Copying reads the file through the extension
+	conn.On("SendCommand", "readDownload", map[string]interface{}{"downloadId": 7}).Return("msg-1", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"dataUrl":"data:text/csv;base64,aWQsbmFtZQoxLGFkYQo="}`), "")
+	}()
+	dir := filepath.Join(t.TempDir(), "downloads")
+	path, err := client.SaveDownload(ctx, 7, dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "report.csv"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "id,name\n1,ada\n", string(content))
+
+	downloads, err = client.ListDownloads(ctx, DownloadFilter{ID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, path, downloads[0].Path)
+
+	_, err = client.SaveDownload(ctx, 8, dir)
+	assert.EqualError(t, err, "download 8 is interrupted, not complete")
+	_, err = client.SaveDownload(ctx, 99, dir)
+	assert.EqualError(t, err, "no download with id: 99")
+
+	// Downloads go away with their browser
+	client.RemoveConnection(conn)
+	client.SetConnection(conn)
+	downloads, err = client.ListDownloads(ctx, DownloadFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, downloads)
+
+	conn.AssertExpectations(t)
+}
+
+func TestDownloadName(t *testing.T) {
+	assert.Equal(t, "report.csv", downloadName("/home/me/Downloads/report.csv", 1))
+	assert.Equal(t, "report.csv", downloadName(`C:\Users\me\Downloads\report.csv`, 1))
+	assert.Equal(t, "download-3", downloadName("", 3))
+	assert.Equal(t, "download-4", downloadName("/tmp/..", 4))
+}
@@ -0,0 +1,361 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultEventBufferSize is the number of events kept for browser_get_events
+// unless configured otherwise
+const DefaultEventBufferSize = 1000
+
+// EventType identifies the kind of a browser event. The extension sends each
+// event with its type as the message action.
+type EventType string
+
+// Event types sent by the extension
+const (
+	EventTabCreated          EventType = "tabCreated"
+	EventTabUpdated          EventType = "tabUpdated"
+	EventTabClosed           EventType = "tabClosed"
+	EventNavigationCommitted EventType = "navigationCommitted"
+	EventPageLoad            EventType = "pageLoad"
+	EventConsole             EventType = "console"
+	EventDialog              EventType = "dialog"
+	EventDownload            EventType = "download"
+	EventRequest             EventType = "request"
+	EventResponse            EventType = "response"
+	EventRequestFailed       EventType = "requestFailed"
+)
+
+// EventTypes lists the event types the server understands
+var EventTypes = []EventType{
+	EventTabCreated,
+	EventTabUpdated,
+	EventTabClosed,
+	EventNavigationCommitted,
+	EventPageLoad,
+	EventConsole,
+	EventDialog,
+	EventDownload,
+	EventRequest,
+	EventResponse,
+	EventRequestFailed,
+}
+
+// ParseEventType returns the event type with the given name
+func ParseEventType(name string) (EventType, error) {
+	for _, t := range EventTypes {
+		if string(t) == name {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type: %s", name)
+}
+
+// Event is an event raised by a browser. Data holds the typed payload for
+// known event types (TabEvent, NavigationEvent, ...) and the raw JSON sent by
+// the extension for others.
+type Event struct {
+	Seq       uint64      `json:"seq"` // position in the event stream, used as cursor
+	Type      EventType   `json:"type"`
+	BrowserID string      `json:"browserId,omitempty"`
+	TabID     int         `json:"tabId,omitempty"`
+	Time      time.Time   `json:"time"`
+	Data      interface{} `json:"data,omitempty"`
+
+	owner string // session owning the tab when the event was raised
+}
+
+// TabEvent is the payload of tabCreated, tabUpdated and tabClosed events
+type TabEvent struct {
+	TabID  int    `json:"tabId"`
+	URL    string `json:"url,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status,omitempty"` // loading or complete
+	Active bool   `json:"active,omitempty"`
+}
+
+// NavigationEvent is the payload of navigationCommitted events
+type NavigationEvent struct {
+	TabID          int    `json:"tabId"`
+	URL            string `json:"url"`
+	FrameID        int    `json:"frameId,omitempty"` // 0 for the top frame
+	TransitionType string `json:"transitionType,omitempty"`
+}
+
+// PageLoadEvent is the payload of pageLoad events
+type PageLoadEvent struct {
+	TabID int    `json:"tabId"`
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// ConsoleEvent is the payload of console events, raised for console messages
+// and uncaught exceptions
+type ConsoleEvent struct {
+	TabID  int    `json:"tabId"`
+	Level  string `json:"level"` // log, info, warn, error, debug or exception
+	Text   string `json:"text"`
+	Source string `json:"source,omitempty"` // script URL
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// DialogEvent is the payload of dialog events
+type DialogEvent struct {
+	TabID         int    `json:"tabId"`
+	DialogType    string `json:"dialogType"` // alert, confirm, prompt or beforeunload
+	Message       string `json:"message"`
+	DefaultPrompt string `json:"defaultPrompt,omitempty"`
+}
+
+// DownloadEvent is the payload of download events, raised when a download
+// starts and whenever its state changes
+type DownloadEvent struct {
+	TabID         int    `json:"tabId,omitempty"`
+	ID            int    `json:"id"`
+	URL           string `json:"url"`
+	Filename      string `json:"filename,omitempty"`
+	MimeType      string `json:"mimeType,omitempty"`
+	State         string `json:"state"` // in_progress, complete or interrupted
+	BytesReceived int64  `json:"bytesReceived,omitempty"`
+	TotalBytes    int64  `json:"totalBytes,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// RequestEvent is the payload of request events, raised while a network
+// capture runs on the tab for every request it sends
+type RequestEvent struct {
+	TabID        int               `json:"tabId"`
+	RequestID    string            `json:"requestId"`
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	ResourceType string            `json:"resourceType,omitempty"` // Document, Script, XHR, ...
+	Headers      map[string]string `json:"headers,omitempty"`
+	PostData     string            `json:"postData,omitempty"`
+	Timestamp    float64           `json:"timestamp,omitempty"` // milliseconds since the epoch
+}
+
+// ResponseEvent is the payload of response events, raised while a network
+// capture runs on the tab once a response has been received in full
+type ResponseEvent struct {
+	TabID             int               `json:"tabId"`
+	RequestID         string            `json:"requestId"`
+	URL               string            `json:"url"`
+	Status            int               `json:"status"`
+	StatusText        string            `json:"statusText,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	MimeType          string            `json:"mimeType,omitempty"`
+	Protocol          string            `json:"protocol,omitempty"` // e.g. http/1.1 or h2
+	RemoteIP          string            `json:"remoteIp,omitempty"`
+	FromCache         bool              `json:"fromCache,omitempty"`
+	EncodedDataLength int64             `json:"encodedDataLength"` // bytes received over the network
+	Timestamp         float64           `json:"timestamp,omitempty"`
+}
+
+// RequestFailedEvent is the payload of requestFailed events, raised while a
+// network capture runs on the tab for requests that did not get a response
+type RequestFailedEvent struct {
+	TabID     int     `json:"tabId"`
+	RequestID string  `json:"requestId"`
+	URL       string  `json:"url,omitempty"`
+	ErrorText string  `json:"errorText"` // e.g. net::ERR_NAME_NOT_RESOLVED
+	Canceled  bool    `json:"canceled,omitempty"`
+	Timestamp float64 `json:"timestamp,omitempty"`
+}
+
+// decodeEvent decodes the payload of an event sent by the extension
+func decodeEvent(eventType EventType, data json.RawMessage) (payload interface{}, tabID int) {
+	switch eventType {
+	case EventTabCreated, EventTabUpdated, EventTabClosed:
+		payload = &TabEvent{}
+	case EventNavigationCommitted:
+		payload = &NavigationEvent{}
+	case EventPageLoad:
+		payload = &PageLoadEvent{}
+	case EventConsole:
+		payload = &ConsoleEvent{}
+	case EventDialog:
+		payload = &DialogEvent{}
+	case EventDownload:
+		payload = &DownloadEvent{}
+	case EventRequest:
+		payload = &RequestEvent{}
+	case EventResponse:
+		payload = &ResponseEvent{}
+	case EventRequestFailed:
+		payload = &RequestFailedEvent{}
+	}
+
+	var tab struct {
+		TabID int `json:"tabId"`
+	}
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &tab)
+	}
+
+	if payload == nil || json.Unmarshal(data, payload) != nil {
+		if len(data) == 0 {
+			return nil, tab.TabID
+		}
+		return data, tab.TabID
+	}
+	return payload, tab.TabID
+}
+
+// EventFilter selects events. Zero fields match everything.
+type EventFilter struct {
+	BrowserID string
+	TabID     int
+	Types     []EventType
+	SessionID string // hides events of tabs owned by other sessions
+}
+
+// Matches reports whether the event passes the filter
+func (f EventFilter) Matches(e Event) bool {
+	if f.BrowserID != "" && e.BrowserID != f.BrowserID {
+		return false
+	}
+	if f.TabID != 0 && e.TabID != f.TabID {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return f.SessionID == "" || e.owner == "" || e.owner == f.SessionID
+}
+
+// EventPage is a batch of events read from the bus
+type EventPage struct {
+	Events  []Event `json:"events"`
+	Cursor  uint64  `json:"cursor"`            // pass as since to read the following events
+	Dropped uint64  `json:"dropped,omitempty"` // events after since that were evicted before being read
+}
+
+// EventBus keeps the most recent browser events in a ring buffer and passes
+// new events to subscribers
+type EventBus struct {
+	mu          sync.Mutex
+	ring        []Event
+	seq         uint64 // sequence number of the last published event
+	oldest      uint64 // sequence number of the oldest event kept
+	subscribers map[int]func(Event)
+	nextSub     int
+}
+
+// NewEventBus creates a bus keeping the last size events
+func NewEventBus(size int) *EventBus {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	return &EventBus{
+		ring:        make([]Event, size),
+		oldest:      1,
+		subscribers: make(map[int]func(Event)),
+	}
+}
+
+// Resize changes the number of events kept, keeping the most recent ones
+func (b *EventBus) Resize(size int) {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ring := make([]Event, size)
+	if b.seq >= uint64(size) && b.oldest <= b.seq-uint64(size) {
+		b.oldest = b.seq - uint64(size) + 1
+	}
+	for seq := b.oldest; seq <= b.seq; seq++ {
+		ring[seq%uint64(size)] = b.ring[seq%uint64(len(b.ring))]
+	}
+	b.ring = ring
+}
+
+// Publish assigns the event the next sequence number, stores it and passes
+// it to the subscribers
+func (b *EventBus) Publish(e Event) Event {
+	b.mu.Lock()
+	b.seq++
+	e.Seq = b.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.ring[e.Seq%uint64(len(b.ring))] = e
+	if e.Seq-b.oldest >= uint64(len(b.ring)) {
+		b.oldest++
+	}
+
+	subscribers := make([]func(Event), 0, len(b.subscribers))
+	for _, fn := range b.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	b.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+	return e
+}
+
+// Since returns up to limit events published after the since cursor that
+// match the filter, oldest first. A limit of 0 returns all of them.
+func (b *EventBus) Since(since uint64, filter EventFilter, limit int) EventPage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A cursor past the last event, as from before a restart, starts over
+	page := EventPage{Events: []Event{}, Cursor: b.seq}
+	if since >= b.seq {
+		return page
+	}
+
+	start := since + 1
+	if start < b.oldest {
+		page.Dropped = b.oldest - start
+		start = b.oldest
+	}
+
+	for seq := start; seq <= b.seq; seq++ {
+		e := b.ring[seq%uint64(len(b.ring))]
+		if !filter.Matches(e) {
+			continue
+		}
+		page.Events = append(page.Events, e)
+		if limit > 0 && len(page.Events) == limit {
+			page.Cursor = seq
+			break
+		}
+	}
+	return page
+}
+
+// Subscribe calls fn with every event published until the returned function
+// is called. fn runs on the goroutine publishing the event and must not block.
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextSub
+	b.nextSub++
+	b.subscribers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
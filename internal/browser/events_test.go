@@ -0,0 +1,130 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publishEvents(bus *EventBus, events ...Event) {
+	for _, e := range events {
+		bus.Publish(e)
+	}
+}
+
+func seqs(events []Event) []uint64 {
+	result := make([]uint64, len(events))
+	for i, e := range events {
+		result[i] = e.Seq
+	}
+	return result
+}
+
+func TestEventBus_Since(t *testing.T) {
+	bus := NewEventBus(3)
+	publishEvents(bus,
+		Event{Type: EventTabCreated, TabID: 1},
+		Event{Type: EventConsole, TabID: 1},
+		Event{Type: EventConsole, TabID: 2},
+	)
+
+	page := bus.Since(0, EventFilter{}, 0)
+	assert.Equal(t, []uint64{1, 2, 3}, seqs(page.Events))
+	assert.Equal(t, uint64(3), page.Cursor)
+	assert.Zero(t, page.Dropped)
+
+	// Filtering by tab and type
+	page = bus.Since(0, EventFilter{TabID: 1, Types: []EventType{EventConsole}}, 0)
+	assert.Equal(t, []uint64{2}, seqs(page.Events))
+	assert.Equal(t, uint64(3), page.Cursor, "the cursor moves past filtered out events")
+
+	// A limit stops at the last returned event
+	page = bus.Since(0, EventFilter{}, 2)
+	assert.Equal(t, []uint64{1, 2}, seqs(page.Events))
+	assert.Equal(t, uint64(2), page.Cursor)
+
+	// Nothing new after the cursor
+	page = bus.Since(3, EventFilter{}, 0)
+	assert.Empty(t, page.Events)
+	assert.Equal(t, uint64(3), page.Cursor)
+
+	// Older events are evicted once the ring is full
+	publishEvents(bus, Event{Type: EventPageLoad}, Event{Type: EventPageLoad})
+	page = bus.Since(1, EventFilter{}, 0)
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(page.Events))
+	assert.Equal(t, uint64(1), page.Dropped)
+
+	// A cursor from a previous run starts over at the current position
+	page = bus.Since(42, EventFilter{}, 0)
+	assert.Empty(t, page.Events)
+	assert.Equal(t, uint64(5), page.Cursor)
+}
+
+func TestEventBus_Resize(t *testing.T) {
+	bus := NewEventBus(4)
+	publishEvents(bus, Event{}, Event{}, Event{}, Event{}, Event{})
+
+	bus.Resize(2)
+	page := bus.Since(0, EventFilter{}, 0)
+	assert.Equal(t, []uint64{4, 5}, seqs(page.Events))
+	assert.Equal(t, uint64(3), page.Dropped)
+
+	bus.Resize(10)
+	publishEvents(bus, Event{})
+	page = bus.Since(0, EventFilter{}, 0)
+	assert.Equal(t, []uint64{4, 5, 6}, seqs(page.Events))
+}
+
+func TestEventBus_Subscribe(t *testing.T) {
+	bus := NewEventBus(10)
+
+	var received []EventType
+	unsubscribe := bus.Subscribe(func(e Event) {
+		received = append(received, e.Type)
+	})
+	bus.Publish(Event{Type: EventDialog})
+	unsubscribe()
+	bus.Publish(Event{Type: EventDownload})
+
+	assert.Equal(t, []EventType{EventDialog}, received)
+}
+
+func TestClient_HandleConnectionEvent(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+	client.RegisterBrowser(BrowserInfo{ID: "work"}, conn)
+	client.tabOwners[tabKey{conn, 7}] = "session-a"
+
+	client.HandleConnectionEvent(conn, "console", json.RawMessage(`{"tabId":7,"level":"error","text":"boom","line":3}`))
+	client.HandleConnectionEvent(conn, "dialog", json.RawMessage(`{"tabId":8,"dialogType":"alert","message":"hi"}`))
+	client.HandleConnectionEvent(conn, "somethingNew", json.RawMessage(`{"tabId":8,"detail":1}`))
+
+	page := client.GetEvents(context.Background(), 0, EventFilter{}, 0)
+	require.Len(t, page.Events, 3)
+
+	console := page.Events[0]
+	assert.Equal(t, EventConsole, console.Type)
+	assert.Equal(t, "work", console.BrowserID)
+	assert.Equal(t, 7, console.TabID)
+	assert.Equal(t, &ConsoleEvent{TabID: 7, Level: "error", Text: "boom", Line: 3}, console.Data)
+	assert.Equal(t, &DialogEvent{TabID: 8, DialogType: "alert", Message: "hi"}, page.Events[1].Data)
+
+	// Unknown events are kept with their raw data
+	assert.Equal(t, EventType("somethingNew"), page.Events[2].Type)
+	assert.JSONEq(t, `{"tabId":8,"detail":1}`, string(page.Events[2].Data.(json.RawMessage)))
+
+	// Events of tabs owned by another session are hidden
+	ctx := WithSessionID(context.Background(), "session-b")
+	page = client.GetEvents(ctx, 0, EventFilter{}, 0)
+	assert.Equal(t, []uint64{2, 3}, seqs(page.Events))
+
+	// Events are limited to the browser selected in the context
+	ctx = WithBrowserID(context.Background(), "home")
+	page = client.GetEvents(ctx, 0, EventFilter{}, 0)
+	assert.Empty(t, page.Events)
+}
@@ -0,0 +1,215 @@
+package browser
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR is an HTTP Archive, the format DevTools exports network logs in. See
+// http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR file
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []HARPage  `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator identifies the application that wrote the HAR file
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HARPage is a page whose requests are in the log
+type HARPage struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	ID              string         `json:"id"`
+	Title           string         `json:"title"`
+	PageTimings     HARPageTimings `json:"pageTimings"`
+}
+
+// HARPageTimings are the load times of a page in milliseconds since it
+// started, -1 if unknown
+type HARPageTimings struct {
+	OnContentLoad float64 `json:"onContentLoad"`
+	OnLoad        float64 `json:"onLoad"`
+}
+
+// HAREntry is one request and its response
+type HAREntry struct {
+	Pageref         string      `json:"pageref,omitempty"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"` // why a request got no response
+}
+
+// HARRequest is the request of a HAR entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response of a HAR entry. Failed requests have status 0.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent describes the body of a response
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings splits the time of an entry in milliseconds. Only the total is
+// known, so it is all accounted as waiting.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harTime formats a time as HAR dates are written
+func harTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// harHeaders converts headers to HAR name/value pairs, sorted by name
+func harHeaders(headers map[string]string) []HARNameValue {
+	pairs := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		pairs = append(pairs, HARNameValue{Name: name, Value: value})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Name < pairs[j].Name
+	})
+	return pairs
+}
+
+// harQueryString returns the query parameters of a URL in order
+func harQueryString(rawURL string) []HARNameValue {
+	pairs := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return pairs
+	}
+	for _, part := range strings.Split(u.RawQuery, "&") {
+		name, value, _ := strings.Cut(part, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		pairs = append(pairs, HARNameValue{Name: name, Value: value})
+	}
+	return pairs
+}
+
+// headerValue looks a header up by name, ignoring case
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// httpVersion returns the HAR HTTP version for a protocol reported by the
+// extension
+func httpVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "":
+		return "HTTP/1.1"
+	case "h2":
+		return "HTTP/2"
+	case "h3":
+		return "HTTP/3"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
+
+// newHAREntry converts a captured request to a HAR entry
+func newHAREntry(e *NetworkEntry, pageref string) HAREntry {
+	entry := HAREntry{
+		Pageref:         pageref,
+		StartedDateTime: harTime(e.Started),
+		Time:            e.Duration,
+		Request: HARRequest{
+			Method:      e.Method,
+			URL:         e.URL,
+			HTTPVersion: httpVersion(e.Protocol),
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(e.RequestHeaders),
+			QueryString: harQueryString(e.URL),
+			HeadersSize: -1,
+			BodySize:    int64(len(e.PostData)),
+		},
+		Response: HARResponse{
+			Status:      e.Status,
+			StatusText:  e.StatusText,
+			HTTPVersion: httpVersion(e.Protocol),
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(e.ResponseHeaders),
+			Content:     HARContent{Size: e.Size, MimeType: e.MimeType},
+			RedirectURL: headerValue(e.ResponseHeaders, "Location"),
+			HeadersSize: -1,
+			BodySize:    e.Size,
+		},
+		Timings:         HARTimings{Wait: e.Duration},
+		ServerIPAddress: e.RemoteIP,
+		ResourceType:    e.ResourceType,
+		Error:           e.Error,
+	}
+	if e.PostData != "" {
+		entry.Request.PostData = &HARPostData{
+			MimeType: headerValue(e.RequestHeaders, "Content-Type"),
+			Text:     e.PostData,
+		}
+	}
+	if e.FromCache {
+		entry.Response.BodySize = 0
+	}
+	return entry
+}
@@ -0,0 +1,171 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ClickOptions are the mouse button, click count and modifier keys of a
+// click. The zero value is a plain left click.
+type ClickOptions struct {
+	Button     string   // left, middle or right; left when empty
+	ClickCount int      // 2 for a double click; 1 when zero
+	Modifiers  []string // Alt, Control, Meta or Shift, held during the click
+}
+
+// KeyPress is a key pressed with modifier keys held down, as in Control+Shift+K
+type KeyPress struct {
+	Key       string   `json:"key"`                 // DOM key value, such as a, K, Enter or ArrowDown
+	Code      string   `json:"code,omitempty"`      // physical key, such as KeyK, when known
+	Modifiers []string `json:"modifiers,omitempty"` // Alt, Control, Meta or Shift
+}
+
+// DragPoint is where a drag starts or ends: the center of the element
+// matching Selector, or the X and Y viewport coordinates
+type DragPoint struct {
+	Selector string   `json:"selector,omitempty"`
+	Hosts    []string `json:"hosts,omitempty"` // iframes and shadow hosts the selector is in, set from its locator
+	X        *float64 `json:"x,omitempty"`
+	Y        *float64 `json:"y,omitempty"`
+}
+
+// OptionSelection picks the options of a select element by value, by label
+// or by index. Only one of them is set; several options are for multiple
+// selects.
+type OptionSelection struct {
+	Values  []string `json:"values,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	Indexes []int    `json:"indexes,omitempty"`
+}
+
+// ClickWithOptions clicks an element with a mouse button, click count and
+// modifier keys. Right clicks open the context menu instead of activating the
+// element.
+func (c *Client) ClickWithOptions(ctx context.Context, tabID int, selector string, timeout int, options ClickOptions) error {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":   tabID,
+		"timeout": timeout,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return err
+	}
+	if options.Button != "" && options.Button != "left" {
+		params["button"] = options.Button
+	}
+	if options.ClickCount > 1 {
+		params["clickCount"] = options.ClickCount
+	}
+	if len(options.Modifiers) > 0 {
+		params["modifiers"] = options.Modifiers
+	}
+
+	_, err := c.sendCommand(ctx, "click", params)
+	return err
+}
+
+// PressKey presses keys one after the other, in the element matching
+// selector, or the focused element when it is empty
+func (c *Client) PressKey(ctx context.Context, tabID int, selector string, keys []KeyPress) error {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId": tabID,
+		"keys":  keys,
+	}
+	if selector != "" {
+		if err := setLocator(params, selector); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.sendCommand(ctx, "pressKey", params)
+	return err
+}
+
+// Hover moves the mouse over an element
+func (c *Client) Hover(ctx context.Context, tabID int, selector string, timeout int) error {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":   tabID,
+		"timeout": timeout,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return err
+	}
+
+	_, err := c.sendCommand(ctx, "hover", params)
+	return err
+}
+
+// Drag drags the mouse from source to target, with both HTML5 drag and drop
+// events and mouse events
+func (c *Client) Drag(ctx context.Context, tabID int, source, target DragPoint) error {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	for _, point := range []*DragPoint{&source, &target} {
+		if point.Selector == "" {
+			continue
+		}
+		locator, err := ParseLocator(point.Selector)
+		if err != nil {
+			return err
+		}
+		point.Selector, point.Hosts = locator.Selector, locator.Hosts
+	}
+
+	params := map[string]interface{}{
+		"tabId":  tabID,
+		"source": source,
+		"target": target,
+	}
+
+	_, err := c.sendCommand(ctx, "drag", params)
+	return err
+}
+
+// SelectOption selects options of a select element, replacing its selection,
+// and returns the values of the selected options
+func (c *Client) SelectOption(ctx context.Context, tabID int, selector string, selection OptionSelection) ([]string, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":     tabID,
+		"selection": selection,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return nil, err
+	}
+
+	data, err := c.sendCommand(ctx, "selectOption", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Values []string `json:"values"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	return response.Values, nil
+}
+
+// SetChecked checks or unchecks a checkbox, or checks a radio button. Fields
+// already in that state are left alone.
+func (c *Client) SetChecked(ctx context.Context, tabID int, selector string, checked bool) error {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":   tabID,
+		"checked": checked,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return err
+	}
+
+	_, err := c.sendCommand(ctx, "setChecked", params)
+	return err
+}
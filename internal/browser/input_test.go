@@ -0,0 +1,57 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ClickWithOptions(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	respond := func(msgID string) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			client.HandleResponse(msgID, json.RawMessage(`{"success":true}`), "")
+		}()
+	}
+
+	// Plain clicks send no options
+	conn.On("SendCommand", "click", map[string]interface{}{"tabId": 1, "selector": "#a", "timeout": 500}).Return("msg-1", nil).Once()
+	respond("msg-1")
+	require.NoError(t, client.Click(context.Background(), 1, "#a", 500))
+
+	conn.On("SendCommand", "click", map[string]interface{}{
+		"tabId": 1, "selector": "#a", "timeout": 500, "button": "right", "clickCount": 2, "modifiers": []string{"Shift"},
+	}).Return("msg-2", nil).Once()
+	respond("msg-2")
+	require.NoError(t, client.ClickWithOptions(context.Background(), 1, "#a", 500, ClickOptions{Button: "right", ClickCount: 2, Modifiers: []string{"Shift"}}))
+
+	conn.AssertExpectations(t)
+}
+
+func TestClient_SelectOption(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	selection := OptionSelection{Labels: []string{"Red", "Blue"}}
+	conn.On("SendCommand", "selectOption", map[string]interface{}{"tabId": 2, "selector": "#colors", "selection": selection}).Return("msg-1", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"values":["r","b"]}`), "")
+	}()
+
+	values, err := client.SelectOption(context.Background(), 2, "#colors", selection)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r", "b"}, values)
+
+	conn.AssertExpectations(t)
+}
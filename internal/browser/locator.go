@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LocatorSeparator separates the selectors of a locator
+const LocatorSeparator = ">>>"
+
+// Locator finds an element inside iframes and open shadow roots: each host
+// selector matches an iframe, whose document the next selector is matched
+// in, or a shadow host, whose shadow root it is matched in. It is written as
+// the selectors separated by >>>, outermost first, as in
+// "iframe#editor >>> rich-text >>> .toolbar button". Every method taking
+// the selector of an element takes a locator: Click, Type, PressKey, Hover,
+// Drag, SelectOption, SetChecked, Scroll, WaitForElement, FindElements,
+// GetValue, UploadFiles, ExtractContent, ExtractTable, ExtractRows,
+// ExtractStructured and Screenshot.
+type Locator struct {
+	Hosts    []string // selectors of the iframes and shadow hosts, outermost first
+	Selector string   // selector of the element, inside the last host
+}
+
+// ParseLocator parses and validates a locator. A selector without >>> is a
+// locator without hosts.
+func ParseLocator(s string) (Locator, error) {
+	parts, err := splitLocator(s)
+	if err != nil {
+		return Locator{}, err
+	}
+	for _, part := range parts {
+		if err := validateSelector(part); err != nil {
+			return Locator{}, fmt.Errorf("invalid locator %q: %w", s, err)
+		}
+	}
+	locator := Locator{Selector: parts[len(parts)-1]}
+	if len(parts) > 1 {
+		locator.Hosts = parts[:len(parts)-1]
+	}
+	return locator, nil
+}
+
+// String returns the locator as its selectors separated by >>>
+func (l Locator) String() string {
+	return strings.Join(append(append([]string{}, l.Hosts...), l.Selector), " "+LocatorSeparator+" ")
+}
+
+// setLocator sets the selector parameter of a command to the selector of a
+// locator, and the hosts parameter to its hosts, if it has any
+func setLocator(params map[string]interface{}, selector string) error {
+	locator, err := ParseLocator(selector)
+	if err != nil {
+		return err
+	}
+	params["selector"] = locator.Selector
+	if len(locator.Hosts) > 0 {
+		params["hosts"] = locator.Hosts
+	}
+	return nil
+}
+
+// splitLocator splits a locator at the >>> outside strings, brackets and
+// parentheses, trimming the selectors
+func splitLocator(s string) ([]string, error) {
+	var (
+		parts []string
+		start int
+		quote rune
+		depth []rune // closing brackets expected
+	)
+	for i := 0; i < len(s); i++ {
+		c := rune(s[i])
+		switch {
+		case c == '\\':
+			i++ // escaped character
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth = append(depth, ']')
+		case c == '(':
+			depth = append(depth, ')')
+		case c == ']' || c == ')':
+			if len(depth) == 0 || depth[len(depth)-1] != c {
+				return nil, fmt.Errorf("invalid locator %q: unbalanced %c", s, c)
+			}
+			depth = depth[:len(depth)-1]
+		case len(depth) == 0 && strings.HasPrefix(s[i:], LocatorSeparator):
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			i += len(LocatorSeparator) - 1
+			start = i + 1
+		}
+	}
+	switch {
+	case quote != 0:
+		return nil, fmt.Errorf("invalid locator %q: unterminated string", s)
+	case len(depth) > 0:
+		return nil, fmt.Errorf("invalid locator %q: missing %c", s, depth[len(depth)-1])
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+// validateSelector checks the selectors of a locator for mistakes that would
+// make them fail in the page: being empty, or starting or ending with a
+// combinator
+func validateSelector(selector string) error {
+	if selector == "" {
+		return fmt.Errorf("empty selector")
+	}
+	if strings.ContainsRune(">+~,", rune(selector[0])) {
+		return fmt.Errorf("selector %q starts with %c", selector, selector[0])
+	}
+	// The last character is escaped when an odd number of backslashes
+	// precede it: in \\>, the backslash is escaped instead
+	last := selector[len(selector)-1]
+	body := selector[:len(selector)-1]
+	escaped := (len(body)-len(strings.TrimRight(body, `\`)))%2 == 1
+	if strings.ContainsRune(">+~,", rune(last)) && !escaped {
+		return fmt.Errorf("selector %q ends with %c", selector, last)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocator(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Locator
+		err      string
+	}{
+		{name: "selector", input: "#login button.primary", expected: Locator{Selector: "#login button.primary"}},
+		{name: "child combinators", input: "ul > li + li ~ li", expected: Locator{Selector: "ul > li + li ~ li"}},
+		{name: "iframe", input: "iframe#editor >>> .body", expected: Locator{Hosts: []string{"iframe#editor"}, Selector: ".body"}},
+		{name: "nested hosts", input: "  iframe[name=app]>>>chat-window >>>  textarea ", expected: Locator{Hosts: []string{"iframe[name=app]", "chat-window"}, Selector: "textarea"}},
+		{name: "separator in strings", input: `a[title=">>>"] >>> b:has(> c)`, expected: Locator{Hosts: []string{`a[title=">>>"]`}, Selector: "b:has(> c)"}},
+		{name: "escaped comma", input: `#a\,`, expected: Locator{Selector: `#a\,`}},
+		{name: "escaped backslash before comma", input: `#a\\\,`, expected: Locator{Selector: `#a\\\,`}},
+		{name: "escaped backslash", input: `#a\\,`, err: `invalid locator "#a\\\\,": selector "#a\\\\," ends with ,`},
+		{name: "empty", input: " ", err: `invalid locator " ": empty selector`},
+		{name: "empty host", input: ">>> .body", err: `invalid locator ">>> .body": empty selector`},
+		{name: "empty selector", input: "iframe >>>", err: `invalid locator "iframe >>>": empty selector`},
+		{name: "leading combinator", input: "iframe >>>> .body", err: `invalid locator "iframe >>>> .body": selector "> .body" starts with >`},
+		{name: "trailing comma", input: "a,", err: `invalid locator "a,": selector "a," ends with ,`},
+		{name: "unterminated string", input: `a[title="x]`, err: `invalid locator "a[title=\"x]": unterminated string`},
+		{name: "missing bracket", input: "input[name=q", err: `invalid locator "input[name=q": missing ]`},
+		{name: "unbalanced parenthesis", input: "li:not(.a))", err: `invalid locator "li:not(.a))": unbalanced )`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locator, err := ParseLocator(tt.input)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, locator)
+		})
+	}
+
+	locator, err := ParseLocator("iframe#app>>>my-widget >>>button")
+	require.NoError(t, err)
+	assert.Equal(t, "iframe#app >>> my-widget >>> button", locator.String())
+}
+
+func TestClient_Locators(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	// Hosts are sent apart from the selector, and only when there are some
+	conn.On("SendCommand", "type", map[string]interface{}{
+		"tabId": 1, "selector": "textarea", "hosts": []string{"iframe#chat", "chat-box"}, "text": "hi", "clearFirst": false, "delay": 0,
+	}).Return("msg-1", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"success":true}`), "")
+	}()
+	require.NoError(t, client.Type(context.Background(), 1, "iframe#chat >>> chat-box >>> textarea", "hi", false, 0))
+
+	conn.On("SendCommand", "setChecked", map[string]interface{}{
+		"tabId": 1, "selector": "#terms", "hosts": []string{"iframe#signup"}, "checked": true,
+	}).Return("msg-2", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-2", json.RawMessage(`{"success":true}`), "")
+	}()
+	require.NoError(t, client.SetChecked(context.Background(), 1, "iframe#signup >>> #terms", true))
+
+	conn.On("SendCommand", "pressKey", map[string]interface{}{
+		"tabId": 1, "selector": "input", "hosts": []string{"search-box"}, "keys": []KeyPress{{Key: "Enter"}},
+	}).Return("msg-3", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-3", json.RawMessage(`{"success":true}`), "")
+	}()
+	require.NoError(t, client.PressKey(context.Background(), 1, "search-box >>> input", []KeyPress{{Key: "Enter"}}))
+
+	// Each end of a drag has its own hosts
+	x, y := 10.0, 20.0
+	conn.On("SendCommand", "drag", map[string]interface{}{
+		"tabId":  1,
+		"source": DragPoint{Selector: "li.card", Hosts: []string{"iframe#board"}},
+		"target": DragPoint{X: &x, Y: &y},
+	}).Return("msg-4", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-4", json.RawMessage(`{"success":true}`), "")
+	}()
+	require.NoError(t, client.Drag(context.Background(), 1, DragPoint{Selector: "iframe#board >>> li.card"}, DragPoint{X: &x, Y: &y}))
+
+	// Invalid locators are not sent
+	assert.EqualError(t, client.Click(context.Background(), 1, "iframe >>>", 0), `invalid locator "iframe >>>": empty selector`)
+	_, err := client.Screenshot(context.Background(), 1, false, "a[href", "png", 90)
+	assert.EqualError(t, err, `invalid locator "a[href": missing ]`)
+	assert.EqualError(t, client.Hover(context.Background(), 1, "iframe >>> > a", 0), `invalid locator "iframe >>> > a": selector "> a" starts with >`)
+	_, err = client.FindElements(context.Background(), 1, "ul >>> li,", 0)
+	assert.EqualError(t, err, `invalid locator "ul >>> li,": selector "li," ends with ,`)
+	_, err = client.Scroll(context.Background(), 1, nil, nil, ">>> #footer", "auto")
+	assert.EqualError(t, err, `invalid locator ">>> #footer": empty selector`)
+	_, err = client.UploadFiles(context.Background(), 1, "iframe >>>", []string{"missing.txt"})
+	assert.EqualError(t, err, `invalid locator "iframe >>>": empty selector`)
+	_, err = client.ExtractRows(context.Background(), 1, "iframe >>> tr[", nil)
+	assert.EqualError(t, err, `invalid locator "iframe >>> tr[": missing ]`)
+	_, err = client.ExtractStructured(context.Background(), 1, "iframe >>> + main", nil)
+	assert.EqualError(t, err, `invalid locator "iframe >>> + main": selector "+ main" starts with +`)
+	assert.EqualError(t, client.Drag(context.Background(), 1, DragPoint{Selector: "ul >>>"}, DragPoint{Selector: "ol"}), `invalid locator "ul >>>": empty selector`)
+
+	conn.AssertExpectations(t)
+}
@@ -0,0 +1,655 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// MarkdownOptions configures the conversion of page content to Markdown
+type MarkdownOptions struct {
+	AbsoluteLinks bool // resolve relative links against the page URL
+	MainContent   bool // keep only the main content, readability style, dropping navigation, sidebars and footers
+	MaxChars      int  // truncate the Markdown to this many characters, 0 for no limit
+}
+
+// truncatedMarker ends Markdown cut at MarkdownOptions.MaxChars
+const truncatedMarker = "[truncated]"
+
+// ExtractMarkdown extracts the elements matching selector as HTML and
+// converts them to Markdown
+func (c *Client) ExtractMarkdown(ctx context.Context, tabID int, selector string, options MarkdownOptions) (string, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	results, err := c.ExtractContent(ctx, tabID, selector, "html", "")
+	if err != nil {
+		return "", err
+	}
+
+	var base *url.URL
+	if options.AbsoluteLinks {
+		data, err := c.ExecuteScript(ctx, tabID, "document.baseURI", nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the page URL: %w", err)
+		}
+		var response struct {
+			Result string `json:"result"`
+		}
+		if err := json.Unmarshal(data, &response); err == nil {
+			base, _ = url.Parse(response.Result)
+		}
+	}
+
+	parts := make([]string, 0, len(results))
+	for _, fragment := range results {
+		if md := htmlToMarkdown(fragment, base, options.MainContent); md != "" {
+			parts = append(parts, md)
+		}
+	}
+	return truncateMarkdown(strings.Join(parts, "\n\n"), options.MaxChars), nil
+}
+
+// blockElements are the elements that start a new block of text
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Body: true, atom.Caption: true, atom.Center: true, atom.Dd: true,
+	atom.Details: true, atom.Dialog: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hgroup: true, atom.Hr: true, atom.Html: true,
+	atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Summary: true,
+	atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Tfoot: true,
+	atom.Th: true, atom.Thead: true, atom.Tr: true, atom.Ul: true,
+}
+
+// skippedElements are the elements whose content is not read
+var skippedElements = map[atom.Atom]bool{
+	atom.Canvas: true, atom.Head: true, atom.Iframe: true, atom.Noscript: true,
+	atom.Object: true, atom.Option: true, atom.Script: true, atom.Select: true,
+	atom.Style: true, atom.Svg: true, atom.Template: true, atom.Textarea: true,
+	atom.Title: true,
+}
+
+// htmlToText converts HTML to plain text, one line per block of text or table
+// row
+func htmlToText(fragment string) string {
+	var sb strings.Builder
+	skip, depth := atom.Atom(0), 0 // element whose content is skipped, and its nesting
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken:
+			switch {
+			case skip != 0:
+				if tok.DataAtom == skip {
+					depth++
+				}
+			case skippedElements[tok.DataAtom]:
+				skip, depth = tok.DataAtom, 1
+			case tok.DataAtom == atom.Td || tok.DataAtom == atom.Th:
+				// The cells of a row stay on its line
+				sb.WriteByte(' ')
+			case tok.DataAtom == atom.Br || blockElements[tok.DataAtom]:
+				sb.WriteByte('\n')
+			}
+		case html.SelfClosingTagToken:
+			if skip == 0 && (tok.DataAtom == atom.Br || blockElements[tok.DataAtom]) {
+				sb.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			switch {
+			case skip != 0:
+				if tok.DataAtom == skip {
+					if depth--; depth == 0 {
+						skip = 0
+					}
+				}
+			case tok.DataAtom == atom.Td || tok.DataAtom == atom.Th:
+				sb.WriteByte(' ')
+			case blockElements[tok.DataAtom]:
+				sb.WriteByte('\n')
+			}
+		case html.TextToken:
+			if skip == 0 {
+				sb.WriteString(tok.Data)
+			}
+		}
+	}
+
+	var lines []string
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// htmlToMarkdown converts an HTML fragment to Markdown. Links and images are
+// resolved against base when it is not nil. With mainContent, only the main
+// content of the fragment is converted.
+func htmlToMarkdown(fragment string, base *url.URL, mainContent bool) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return htmlToText(fragment)
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+
+	root := body
+	if mainContent {
+		root = findMainContent(body)
+		removeClutter(root)
+	}
+
+	m := &markdownWriter{base: base}
+	return strings.Join(m.blocks(root), "\n\n")
+}
+
+// markdownWriter renders HTML nodes as Markdown
+type markdownWriter struct {
+	base *url.URL
+}
+
+// blocks renders the children of n as Markdown blocks: paragraphs, headings,
+// lists, tables, code blocks and quotes
+func (m *markdownWriter) blocks(n *html.Node) []string {
+	var blocks []string
+	var inline strings.Builder
+
+	flush := func() {
+		if p := cleanParagraph(inline.String()); p != "" {
+			blocks = append(blocks, p)
+		}
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (blockElements[c.DataAtom] || c.DataAtom == atom.Table) {
+			if hidden(c) {
+				continue
+			}
+			flush()
+			blocks = append(blocks, m.block(c)...)
+			continue
+		}
+		inline.WriteString(m.inline(c))
+	}
+	flush()
+	return blocks
+}
+
+// block renders a block element
+func (m *markdownWriter) block(n *html.Node) []string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.Join(strings.Fields(m.inlineChildren(n)), " ")
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case atom.Ul, atom.Ol:
+		if list := m.list(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case atom.Blockquote:
+		inner := m.blocks(n)
+		if len(inner) == 0 {
+			return nil
+		}
+		return []string{prefixLines(strings.Join(inner, "\n\n"), "> ", ">")}
+	case atom.Pre:
+		return m.codeBlock(n)
+	case atom.Hr:
+		return []string{"---"}
+	case atom.Table:
+		return m.table(n)
+	case atom.Dt:
+		if text := cleanParagraph(m.inlineChildren(n)); text != "" {
+			return []string{"**" + text + "**"}
+		}
+		return nil
+	default:
+		return m.blocks(n)
+	}
+}
+
+// list renders the items of a ul or ol element, nested lists indented under
+// their item
+func (m *markdownWriter) list(n *html.Node) string {
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+
+	var items []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || hidden(c) {
+			continue
+		}
+		var content string
+		if c.DataAtom == atom.Li {
+			content = strings.Join(m.blocks(c), "\n")
+		} else {
+			// Stray content of the list, such as a nested list without an item
+			content = strings.Join(m.block(c), "\n")
+		}
+		if content == "" {
+			continue
+		}
+
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		items = append(items, marker+prefixLines(content, strings.Repeat(" ", len(marker)), "")[len(marker):])
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeBlock renders a pre element as a fenced code block, with the language
+// of its language-* or lang-* class
+func (m *markdownWriter) codeBlock(n *html.Node) []string {
+	code := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return nil
+	}
+
+	language := codeLanguage(n)
+	for c := n.FirstChild; c != nil && language == ""; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Code {
+			language = codeLanguage(c)
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return []string{fence + language + "\n" + code + "\n" + fence}
+}
+
+// codeLanguage returns the language named by the class of a code element
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// tableCell is a cell of a table row
+type tableCell struct {
+	node   *html.Node
+	header bool
+	span   int
+}
+
+// table renders a table as a Markdown table. Its header is the first row when
+// it is in thead or only has th cells, and is left empty otherwise. Tables of
+// a single column are used for layout, so their cells are rendered as blocks.
+func (m *markdownWriter) table(n *html.Node) []string {
+	var rows [][]tableCell
+	var caption string
+	headerRow := false
+
+	var walk func(*html.Node, bool)
+	walk = func(parent *html.Node, inHead bool) {
+		for c := parent.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || hidden(c) {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Caption:
+				caption = cleanParagraph(m.inlineChildren(c))
+			case atom.Thead:
+				walk(c, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(c, false)
+			case atom.Tr:
+				var row []tableCell
+				allHeaders := true
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+						continue
+					}
+					span, err := strconv.Atoi(attr(cell, "colspan"))
+					if err != nil || span < 1 {
+						span = 1
+					}
+					row = append(row, tableCell{node: cell, header: cell.DataAtom == atom.Th, span: span})
+					allHeaders = allHeaders && cell.DataAtom == atom.Th
+				}
+				if len(row) == 0 {
+					continue
+				}
+				if len(rows) == 0 {
+					headerRow = inHead || allHeaders
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n, false)
+
+	columns := 0
+	for _, row := range rows {
+		width := 0
+		for _, cell := range row {
+			width += cell.span
+		}
+		columns = max(columns, width)
+	}
+	if columns == 0 {
+		return nil
+	}
+
+	var blocks []string
+	if caption != "" {
+		blocks = append(blocks, caption)
+	}
+	if columns == 1 {
+		for _, row := range rows {
+			blocks = append(blocks, m.blocks(row[0].node)...)
+		}
+		return blocks
+	}
+
+	format := func(row []tableCell) string {
+		cells := make([]string, 0, columns)
+		for _, cell := range row {
+			text := strings.Join(strings.Fields(m.inlineChildren(cell.node)), " ")
+			cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			for i := 1; i < cell.span; i++ {
+				cells = append(cells, "")
+			}
+		}
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+
+	var lines []string
+	if headerRow {
+		lines = append(lines, format(rows[0]))
+		rows = rows[1:]
+	} else {
+		lines = append(lines, format(nil))
+	}
+	lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+	for _, row := range rows {
+		lines = append(lines, format(row))
+	}
+	return append(blocks, strings.Join(lines, "\n"))
+}
+
+// inline renders a node as inline Markdown: text, links, emphasis, code and
+// the alt text of images. Line breaks are kept as newlines.
+func (m *markdownWriter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escapeMarkdown(collapseSpaces(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if skippedElements[n.DataAtom] || hidden(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			return escapeMarkdown(collapseSpaces(alt))
+		}
+		return ""
+	case atom.A:
+		text := strings.TrimSpace(m.inlineChildren(n))
+		if text == "" {
+			text = escapeMarkdown(strings.TrimSpace(attr(n, "aria-label")))
+		}
+		href := m.resolve(attr(n, "href"))
+		if text == "" || href == "" {
+			return text
+		}
+		return "[" + strings.ReplaceAll(text, "\n", " ") + "](" + href + ")"
+	case atom.Strong, atom.B:
+		return wrapInline(m.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(m.inlineChildren(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrapInline(m.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		code := strings.Join(strings.Fields(textContent(n)), " ")
+		if code == "" {
+			return ""
+		}
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			code = " " + code + " "
+		}
+		return fence + code + fence
+	}
+
+	text := m.inlineChildren(n)
+	if blockElements[n.DataAtom] {
+		// Blocks inside inline content, such as a div in a link, are kept
+		// apart by spaces
+		return " " + text + " "
+	}
+	return text
+}
+
+// inlineChildren renders the children of n as inline Markdown
+func (m *markdownWriter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(m.inline(c))
+	}
+	return sb.String()
+}
+
+// resolve returns the URL of a link, absolute when there is a base URL.
+// Script links have none.
+func (m *markdownWriter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	if m.base != nil {
+		if u, err := m.base.Parse(href); err == nil {
+			href = u.String()
+		}
+	}
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(href)
+}
+
+// wrapInline puts markers around inline text, outside its surrounding spaces
+func wrapInline(text, marker string) string {
+	core := strings.TrimSpace(text)
+	if core == "" {
+		return text
+	}
+	start := strings.Index(text, core)
+	return text[:start] + marker + core + marker + text[start+len(core):]
+}
+
+// collapseSpaces replaces runs of whitespace with a single space
+func collapseSpaces(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// escapeMarkdown escapes the characters of text that Markdown would read as
+// formatting. Underscores inside words are left alone.
+func escapeMarkdown(text string) string {
+	var sb strings.Builder
+	runes := []rune(text)
+	for i, r := range runes {
+		switch r {
+		case '\\', '*', '`', '[', ']':
+			sb.WriteByte('\\')
+		case '_':
+			if i == 0 || i == len(runes)-1 || !isWordRune(runes[i-1]) || !isWordRune(runes[i+1]) {
+				sb.WriteByte('\\')
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// blockStart matches text Markdown would read as the start of a heading,
+// quote, list item or rule
+var blockStart = regexp.MustCompile(`^(#{1,6}(\s|$)|>|[-+](\s|$)|\d+[.)](\s|$)|={3,}|-{3,})`)
+
+// cleanParagraph trims the lines of an inline run, dropping empty ones, and
+// escapes what would start another kind of block
+func cleanParagraph(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if blockStart.MatchString(line) {
+			line = `\` + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prefixLines prefixes each line of text, empty lines with emptyPrefix
+func prefixLines(text, prefix, emptyPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateMarkdown cuts Markdown to at most maxChars characters, at the end
+// of a block or line when there is one in its second half, and marks it as
+// truncated
+func truncateMarkdown(md string, maxChars int) string {
+	if maxChars <= 0 || utf8.RuneCountInString(md) <= maxChars {
+		return md
+	}
+
+	cut := md
+	for i := range md {
+		if maxChars == 0 {
+			cut = md[:i]
+			break
+		}
+		maxChars--
+	}
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(cut, sep); i > len(cut)/2 {
+			cut = cut[:i]
+			break
+		}
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + "\n\n" + truncatedMarker
+}
+
+// attr returns the value of an attribute of n
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hidden reports whether an element is hidden from readers
+func hidden(n *html.Node) bool {
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if a.Val == "true" {
+				return true
+			}
+		case "style":
+			style := strings.ReplaceAll(strings.ToLower(a.Val), " ", "")
+			if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// textContent returns the text of n and its descendants, as is
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Br {
+			sb.WriteByte('\n')
+			continue
+		}
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
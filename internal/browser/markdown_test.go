@@ -0,0 +1,168 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLToText(t *testing.T) {
+	text := htmlToText(`<h1>Title</h1><script>var x = "<p>no</p>";</script><style>p{}</style>
+<p>Fish &amp; chips &mdash; &#8364;5&nbsp;each</p><div>Line one<br>Line   two</div>
+<svg><text>logo</text><svg></svg><text>still logo</text></svg><ul><li>a</li><li>b</li></ul>`)
+	assert.Equal(t, "Title\nFish & chips — €5 each\nLine one\nLine two\na\nb", text)
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	base, err := url.Parse("https://example.com/docs/guide.html")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		html     string
+		base     *url.URL
+		expected string
+	}{
+		{
+			name:     "headings and paragraphs",
+			html:     "<h1>Guide</h1><p>Some   <b>bold</b> and <em>soft</em>\ntext.</p><h3> Setup </h3><p>Run <code>make build</code>.</p>",
+			expected: "# Guide\n\nSome **bold** and *soft* text.\n\n### Setup\n\nRun `make build`.",
+		},
+		{
+			name:     "links",
+			html:     `<p><a href="/api">API</a>, <a href="#top">top</a>, <a href="javascript:void(0)">menu</a> and <a href="a b.html"><img alt="logo"></a></p>`,
+			expected: "[API](/api), [top](#top), menu and [logo](a%20b.html)",
+		},
+		{
+			name:     "absolute links",
+			html:     `<p><a href="/api">API</a> and <a href="intro.html#top">intro</a></p>`,
+			base:     base,
+			expected: "[API](https://example.com/api) and [intro](https://example.com/docs/intro.html#top)",
+		},
+		{
+			name:     "nested lists",
+			html:     `<ul><li>One</li><li>Two<ul><li>Nested</li></ul></li></ul><ol start="3"><li><p>Three</p></li><li>Four</li></ol>`,
+			expected: "- One\n- Two\n  - Nested\n\n3. Three\n4. Four",
+		},
+		{
+			name:     "code block",
+			html:     "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"&lt;hi&gt;\")\n}\n</code></pre>",
+			expected: "```go\nfunc main() {\n\tfmt.Println(\"<hi>\")\n}\n```",
+		},
+		{
+			name:     "quote and rule",
+			html:     "<blockquote><p>First</p><p>Second</p></blockquote><hr>",
+			expected: "> First\n>\n> Second\n\n---",
+		},
+		{
+			name: "table with header",
+			html: `<table><caption>Prices</caption><thead><tr><th>Item</th><th>Price</th></tr></thead>
+<tbody><tr><td>Tea | herbal</td><td>2</td></tr><tr><td colspan="2">Sold out</td></tr></tbody></table>`,
+			expected: "Prices\n\n| Item | Price |\n| --- | --- |\n| Tea \\| herbal | 2 |\n| Sold out |  |",
+		},
+		{
+			name:     "table without header",
+			html:     `<table><tr><td>a</td><td>b</td></tr></table>`,
+			expected: "|  |  |\n| --- | --- |\n| a | b |",
+		},
+		{
+			name:     "layout table",
+			html:     `<table><tr><td><p>Only</p></td></tr><tr><td>column</td></tr></table>`,
+			expected: "Only\n\ncolumn",
+		},
+		{
+			name:     "escaping",
+			html:     `<p>snake_case *stars* [brackets] _under_</p><p># not a heading</p><p>1. not a list</p>`,
+			expected: "snake_case \\*stars\\* \\[brackets\\] \\_under\\_\n\n\\# not a heading\n\n\\1. not a list",
+		},
+		{
+			name:     "hidden and non-content elements",
+			html:     `<p>Shown</p><p hidden>Hidden</p><div style="display: none">Gone</div><script>alert(1)</script><select><option>x</option></select><p aria-hidden="true">Icon</p>`,
+			expected: "Shown",
+		},
+		{
+			name:     "line breaks and entities",
+			html:     `<p>Fish &amp; chips<br>&euro;5&nbsp;each</p>`,
+			expected: "Fish & chips\n€5 each",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, htmlToMarkdown(tt.html, tt.base, false))
+		})
+	}
+}
+
+func TestHTMLToMarkdown_MainContent(t *testing.T) {
+	page := `<header class="masthead"><a href="/">Home</a> <a href="/blog">Blog</a></header>
+<nav><ul><li><a href="/a">A</a></li><li><a href="/b">B</a></li></ul></nav>
+<div id="layout">
+  <div class="sidebar"><p>Subscribe to our newsletter, it is great, really, we promise.</p></div>
+  <div class="post-body">
+    <h2>Why tokenizers</h2>
+    <p>Regular expressions cannot parse HTML, as everyone finds out sooner or later.</p>
+    <p>A tokenizer, on the other hand, sees tags, text and entities the way browsers do.</p>
+    <div class="share"><a href="/share/x">Share</a></div>
+    <ul class="tags"><li><a href="/t/go">go</a></li><li><a href="/t/html">html</a></li></ul>
+  </div>
+</div>
+<footer><p>Copyright 2025, Example Inc., all rights reserved worldwide.</p></footer>`
+
+	assert.Equal(t, "## Why tokenizers\n\n"+
+		"Regular expressions cannot parse HTML, as everyone finds out sooner or later.\n\n"+
+		"A tokenizer, on the other hand, sees tags, text and entities the way browsers do.",
+		htmlToMarkdown(page, nil, true))
+
+	// A main element is the main content
+	assert.Equal(t, "Main text", htmlToMarkdown(`<nav><a href="/">Home</a></nav><main><p>Main text</p><aside>Ad</aside></main>`, nil, true))
+}
+
+func TestTruncateMarkdown(t *testing.T) {
+	md := "# Title\n\nFirst paragraph.\n\nSecond paragraph is longer."
+	assert.Equal(t, md, truncateMarkdown(md, 0))
+	assert.Equal(t, md, truncateMarkdown(md, len(md)))
+	assert.Equal(t, "# Title\n\nFirst paragraph.\n\n[truncated]", truncateMarkdown(md, 40))
+	assert.Equal(t, "héhé\n\n[truncated]", truncateMarkdown("héhé héhé", 6))
+}
+
+func TestClient_ExtractMarkdown(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	respond := func(msgID, response string) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			client.HandleResponse(msgID, json.RawMessage(response), "")
+		}()
+	}
+
+	conn.On("SendCommand", "extractContent", map[string]interface{}{"tabId": 1, "selector": "article", "contentType": "html"}).Return("msg-1", nil).Once()
+	respond("msg-1", `{"text":["<h1>One</h1><p><a href=\"next\">Next</a></p>","<p>Two</p>"]}`)
+	conn.On("SendCommand", "executeScript", map[string]interface{}{"tabId": 1, "script": "document.baseURI", "args": []interface{}(nil)}).Return("msg-2", nil).Once()
+	go func() {
+		// The script runs once the content is extracted
+		time.Sleep(30 * time.Millisecond)
+		client.HandleResponse("msg-2", json.RawMessage(`{"result":"https://example.com/posts/"}`), "")
+	}()
+
+	md, err := client.ExtractMarkdown(context.Background(), 1, "article", MarkdownOptions{AbsoluteLinks: true})
+	require.NoError(t, err)
+	assert.Equal(t, "# One\n\n[Next](https://example.com/posts/next)\n\nTwo", md)
+
+	conn.On("SendCommand", "extractContent", map[string]interface{}{"tabId": 1, "selector": "body", "contentType": "html"}).Return("msg-3", nil).Once()
+	respond("msg-3", `{"text":"<p>`+strings.Repeat("word ", 20)+`</p>"}`)
+	md, err = client.ExtractMarkdown(context.Background(), 1, "body", MarkdownOptions{MaxChars: 12})
+	require.NoError(t, err)
+	assert.Equal(t, "word word\n\n[truncated]", md)
+
+	conn.AssertExpectations(t)
+}
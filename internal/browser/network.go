@@ -0,0 +1,357 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultSlowRequestMs is the duration from which NetworkSummary reports a
+// request as slow unless told otherwise
+const DefaultSlowRequestMs = 1000
+
+// NetworkEntry is a request captured on a tab, with its response once
+// received
+type NetworkEntry struct {
+	RequestID       string            `json:"requestId"`
+	URL             string            `json:"url"`
+	Method          string            `json:"method"`
+	ResourceType    string            `json:"resourceType,omitempty"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
+	PostData        string            `json:"postData,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	StatusText      string            `json:"statusText,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	MimeType        string            `json:"mimeType,omitempty"`
+	Protocol        string            `json:"protocol,omitempty"`
+	RemoteIP        string            `json:"remoteIp,omitempty"`
+	FromCache       bool              `json:"fromCache,omitempty"`
+	Size            int64             `json:"size"`            // bytes received
+	Error           string            `json:"error,omitempty"` // why the request got no response
+	Started         time.Time         `json:"started"`
+	Duration        float64           `json:"duration"` // milliseconds until the response was received or the request failed
+	Done            bool              `json:"done"`     // whether the response was received or the request failed
+}
+
+// Failed reports whether the request got no response or an error status
+func (e *NetworkEntry) Failed() bool {
+	return e.Error != "" || e.Status >= 400
+}
+
+// RequestSummary is a request listed in a NetworkSummary
+type RequestSummary struct {
+	URL      string  `json:"url"`
+	Method   string  `json:"method"`
+	Status   int     `json:"status,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration"`
+	Size     int64   `json:"size"`
+}
+
+// NetworkSummary sums up the requests captured on a tab
+type NetworkSummary struct {
+	TabID      int              `json:"tabId"`
+	Capturing  bool             `json:"capturing"`
+	Requests   int              `json:"requests"`
+	Pending    int              `json:"pending"` // requests still waiting for their response
+	TotalBytes int64            `json:"totalBytes"`
+	SlowMs     int              `json:"slowMs"` // threshold of slow requests
+	Failed     []RequestSummary `json:"failed"`
+	Slow       []RequestSummary `json:"slow"` // slowest first
+}
+
+// NetworkRecording is a finished network capture saved as a HAR file
+type NetworkRecording struct {
+	TabID   int             `json:"tabId"`
+	Path    string          `json:"path"`
+	Entries int             `json:"entries"`
+	Summary *NetworkSummary `json:"summary"`
+}
+
+// networkCapture collects the network events of a tab
+type networkCapture struct {
+	tabID     int
+	started   time.Time
+	stopped   bool
+	entries   []*NetworkEntry
+	byID      map[string]*NetworkEntry
+	pageURL   string
+	pageTitle string
+	onLoad    float64 // milliseconds from the start to the first page load, -1 if none
+}
+
+// record updates the capture with a network or page load event
+func (nc *networkCapture) record(event Event) {
+	switch data := event.Data.(type) {
+	case *RequestEvent:
+		entry := &NetworkEntry{
+			RequestID:      data.RequestID,
+			URL:            data.URL,
+			Method:         data.Method,
+			ResourceType:   data.ResourceType,
+			RequestHeaders: data.Headers,
+			PostData:       data.PostData,
+			Started:        eventTime(data.Timestamp, event.Time),
+		}
+		// Redirects reuse the request ID; the earlier hop stays in the log
+		nc.byID[data.RequestID] = entry
+		nc.entries = append(nc.entries, entry)
+		if nc.pageURL == "" && data.ResourceType == "Document" {
+			nc.pageURL = data.URL
+		}
+
+	case *ResponseEvent:
+		entry, ok := nc.byID[data.RequestID]
+		if !ok {
+			return
+		}
+		entry.Status = data.Status
+		entry.StatusText = data.StatusText
+		entry.ResponseHeaders = data.Headers
+		entry.MimeType = data.MimeType
+		entry.Protocol = data.Protocol
+		entry.RemoteIP = data.RemoteIP
+		entry.FromCache = data.FromCache
+		entry.Size = data.EncodedDataLength
+		entry.finish(eventTime(data.Timestamp, event.Time))
+
+	case *RequestFailedEvent:
+		entry, ok := nc.byID[data.RequestID]
+		if !ok {
+			return
+		}
+		entry.Error = data.ErrorText
+		if data.Canceled && entry.Error == "" {
+			entry.Error = "canceled"
+		}
+		entry.finish(eventTime(data.Timestamp, event.Time))
+
+	case *PageLoadEvent:
+		if nc.onLoad < 0 {
+			nc.onLoad = float64(event.Time.Sub(nc.started).Microseconds()) / 1000
+			nc.pageURL = data.URL
+			nc.pageTitle = data.Title
+		}
+	}
+}
+
+// finish records the end of a request
+func (e *NetworkEntry) finish(end time.Time) {
+	e.Done = true
+	if d := end.Sub(e.Started); d > 0 {
+		e.Duration = float64(d.Microseconds()) / 1000
+	}
+}
+
+// eventTime returns the time of a timestamp sent by the extension, in
+// milliseconds since the epoch, or fallback when it sent none
+func eventTime(timestamp float64, fallback time.Time) time.Time {
+	if timestamp <= 0 {
+		return fallback
+	}
+	return time.UnixMicro(int64(timestamp * 1000))
+}
+
+// summary sums up the capture. Requests taking at least slowMs are slow.
+func (nc *networkCapture) summary(slowMs int) *NetworkSummary {
+	summary := &NetworkSummary{
+		TabID:     nc.tabID,
+		Capturing: !nc.stopped,
+		Requests:  len(nc.entries),
+		SlowMs:    slowMs,
+		Failed:    []RequestSummary{},
+		Slow:      []RequestSummary{},
+	}
+	for _, e := range nc.entries {
+		summary.TotalBytes += e.Size
+		if !e.Done {
+			summary.Pending++
+			continue
+		}
+		request := RequestSummary{
+			URL:      e.URL,
+			Method:   e.Method,
+			Status:   e.Status,
+			Error:    e.Error,
+			Duration: e.Duration,
+			Size:     e.Size,
+		}
+		if e.Failed() {
+			summary.Failed = append(summary.Failed, request)
+		}
+		if e.Duration >= float64(slowMs) {
+			summary.Slow = append(summary.Slow, request)
+		}
+	}
+	sort.SliceStable(summary.Slow, func(i, j int) bool {
+		return summary.Slow[i].Duration > summary.Slow[j].Duration
+	})
+	return summary
+}
+
+// har exports the capture as a HAR log with a single page
+func (nc *networkCapture) har() *HAR {
+	title := nc.pageTitle
+	if title == "" {
+		title = nc.pageURL
+	}
+	page := HARPage{
+		StartedDateTime: harTime(nc.started),
+		ID:              "page_1",
+		Title:           title,
+		PageTimings:     HARPageTimings{OnContentLoad: -1, OnLoad: nc.onLoad},
+	}
+
+	entries := make([]HAREntry, len(nc.entries))
+	for i, e := range nc.entries {
+		entries[i] = newHAREntry(e, page.ID)
+	}
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "bract", Version: "1.0.0"},
+		Pages:   []HARPage{page},
+		Entries: entries,
+	}}
+}
+
+// recordNetwork passes an event to the network capture of its tab, if any
+func (c *Client) recordNetwork(conn Connection, event Event) {
+	switch event.Type {
+	case EventRequest, EventResponse, EventRequestFailed, EventPageLoad:
+	default:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, nc := range c.captures {
+		if key.tabID == event.TabID && (conn == nil || key.conn == conn) && !nc.stopped {
+			nc.record(event)
+		}
+	}
+}
+
+// StartNetworkCapture starts recording the requests of a tab. The extension
+// then raises request, response and requestFailed events for the tab.
+func (c *Client) StartNetworkCapture(ctx context.Context, tabID int) error {
+	tabID = c.resolveTabID(ctx, tabID)
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	nc, ok := c.captures[tabKey{conn, tabID}]
+	c.mu.RUnlock()
+	if ok && !nc.stopped {
+		return fmt.Errorf("network capture already running for tab %d", tabID)
+	}
+
+	params := map[string]interface{}{
+		"tabId": tabID,
+	}
+
+	if _, err := c.sendCommand(ctx, "startNetworkCapture", params); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.captures[tabKey{conn, tabID}] = &networkCapture{
+		tabID:   tabID,
+		started: time.Now(),
+		byID:    make(map[string]*NetworkEntry),
+		onLoad:  -1,
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// StopNetworkCapture stops recording the requests of a tab and saves them as
+// a HAR file in dir, or in the temporary directory when dir is empty. The file
+// is named after the base name of name, or gets a timestamped name when name
+// is empty. The capture stays available to NetworkSummary until the next one
+// starts on the tab.
+func (c *Client) StopNetworkCapture(ctx context.Context, tabID int, dir, name string) (*NetworkRecording, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	nc, ok := c.captures[tabKey{conn, tabID}]
+	c.mu.RUnlock()
+	if !ok || nc.stopped {
+		return nil, fmt.Errorf("no network capture running for tab %d", tabID)
+	}
+
+	params := map[string]interface{}{
+		"tabId": tabID,
+	}
+
+	if _, err := c.sendCommand(ctx, "stopNetworkCapture", params); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	nc.stopped = true
+	data, err := json.MarshalIndent(nc.har(), "", "  ")
+	summary := nc.summary(DefaultSlowRequestMs)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	name = baseName(name)
+	if name == "" {
+		name = fmt.Sprintf("bract-network-%d-%s.har", tabID, time.Now().Format("20060102-150405"))
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create HAR directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save HAR file: %w", err)
+	}
+
+	return &NetworkRecording{
+		TabID:   tabID,
+		Path:    path,
+		Entries: summary.Requests,
+		Summary: summary,
+	}, nil
+}
+
+// NetworkSummary sums up the running or last network capture of a tab.
+// Requests taking at least slowMs milliseconds are reported as slow, or
+// DefaultSlowRequestMs when slowMs is 0.
+func (c *Client) NetworkSummary(ctx context.Context, tabID int, slowMs int) (*NetworkSummary, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkTabAccess(ctx, conn, map[string]interface{}{"tabId": tabID}); err != nil {
+		return nil, err
+	}
+	if slowMs <= 0 {
+		slowMs = DefaultSlowRequestMs
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nc, ok := c.captures[tabKey{conn, tabID}]
+	if !ok {
+		return nil, fmt.Errorf("no network capture for tab %d", tabID)
+	}
+	return nc.summary(slowMs), nil
+}
@@ -0,0 +1,118 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_NetworkCapture(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+	ctx := context.Background()
+
+	respond := func(msgID string) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			client.HandleResponse(msgID, json.RawMessage(`{"success":true}`), "")
+		}()
+	}
+
+	conn.On("SendCommand", "startNetworkCapture", map[string]interface{}{"tabId": 5}).Return("msg-start", nil)
+	respond("msg-start")
+	require.NoError(t, client.StartNetworkCapture(ctx, 5))
+	assert.EqualError(t, client.StartNetworkCapture(ctx, 5), "network capture already running for tab 5")
+
+	start := time.Now().UnixMilli()
+	events := []struct {
+		action string
+		data   string
+	}{
+		{"request", `{"tabId":5,"requestId":"1","url":"https://example.com/?q=go","method":"GET","resourceType":"Document","timestamp":%d}`},
+		{"request", `{"tabId":5,"requestId":"2","url":"https://example.com/app.js","method":"GET","resourceType":"Script","timestamp":%d}`},
+		{"request", `{"tabId":5,"requestId":"3","url":"https://api.example.com/users","method":"POST","postData":"{}","headers":{"Content-Type":"application/json"},"timestamp":%d}`},
+		{"request", `{"tabId":5,"requestId":"4","url":"https://cdn.example.com/hero.png","method":"GET","resourceType":"Image","timestamp":%d}`},
+		{"request", `{"tabId":6,"requestId":"9","url":"https://other.example.com/","method":"GET","timestamp":%d}`},
+		{"response", `{"tabId":5,"requestId":"1","url":"https://example.com/?q=go","status":200,"statusText":"OK","mimeType":"text/html","protocol":"h2","encodedDataLength":1000,"timestamp":%d}`},
+		{"response", `{"tabId":5,"requestId":"2","url":"https://example.com/app.js","status":404,"encodedDataLength":100,"timestamp":%d}`},
+		{"requestFailed", `{"tabId":5,"requestId":"3","url":"https://api.example.com/users","errorText":"net::ERR_CONNECTION_REFUSED","timestamp":%d}`},
+	}
+	offsets := []int64{0, 10, 20, 30, 30, 50, 60, 1520}
+	for i, e := range events {
+		client.HandleConnectionEvent(conn, e.action, json.RawMessage(fmt.Sprintf(e.data, start+offsets[i])))
+	}
+
+	summary, err := client.NetworkSummary(ctx, 5, 0)
+	require.NoError(t, err)
+	assert.True(t, summary.Capturing)
+	assert.Equal(t, 4, summary.Requests)
+	assert.Equal(t, 1, summary.Pending)
+	assert.Equal(t, int64(1100), summary.TotalBytes)
+	assert.Equal(t, DefaultSlowRequestMs, summary.SlowMs)
+	require.Len(t, summary.Failed, 2)
+	assert.Equal(t, 404, summary.Failed[0].Status)
+	assert.Equal(t, "net::ERR_CONNECTION_REFUSED", summary.Failed[1].Error)
+	require.Len(t, summary.Slow, 1)
+	assert.Equal(t, "https://api.example.com/users", summary.Slow[0].URL)
+	assert.InDelta(t, 1500, summary.Slow[0].Duration, 1)
+
+	summary, err = client.NetworkSummary(ctx, 5, 40)
+	require.NoError(t, err)
+	require.Len(t, summary.Slow, 3)
+	assert.Equal(t, "https://example.com/app.js", summary.Slow[2].URL, "slowest first")
+
+	conn.On("SendCommand", "stopNetworkCapture", map[string]interface{}{"tabId": 5}).Return("msg-stop", nil)
+	respond("msg-stop")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "network.har")
+	recording, err := client.StopNetworkCapture(ctx, 5, dir, "../network.har")
+	require.NoError(t, err)
+	assert.Equal(t, path, recording.Path)
+	assert.Equal(t, 4, recording.Entries)
+	assert.False(t, recording.Summary.Capturing)
+
+	// Events after the capture stopped are left out
+	client.HandleConnectionEvent(conn, "response", json.RawMessage(`{"tabId":5,"requestId":"4","status":200,"encodedDataLength":5000}`))
+	summary, err = client.NetworkSummary(ctx, 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Pending)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var har HAR
+	require.NoError(t, json.Unmarshal(data, &har))
+	assert.Equal(t, "1.2", har.Log.Version)
+	assert.Equal(t, "bract", har.Log.Creator.Name)
+	require.Len(t, har.Log.Pages, 1)
+	assert.Equal(t, "https://example.com/?q=go", har.Log.Pages[0].Title)
+	require.Len(t, har.Log.Entries, 4)
+
+	document := har.Log.Entries[0]
+	assert.Equal(t, "page_1", document.Pageref)
+	assert.Equal(t, "HTTP/2", document.Request.HTTPVersion)
+	assert.Equal(t, []HARNameValue{{Name: "q", Value: "go"}}, document.Request.QueryString)
+	assert.Equal(t, 200, document.Response.Status)
+	assert.Equal(t, HARContent{Size: 1000, MimeType: "text/html"}, document.Response.Content)
+	assert.InDelta(t, 50, document.Time, 1)
+
+	post := har.Log.Entries[2]
+	assert.Equal(t, &HARPostData{MimeType: "application/json", Text: "{}"}, post.Request.PostData)
+	assert.Equal(t, 0, post.Response.Status)
+	assert.Equal(t, "net::ERR_CONNECTION_REFUSED", post.Error)
+
+	_, err = client.StopNetworkCapture(ctx, 5, dir, "network.har")
+	assert.EqualError(t, err, "no network capture running for tab 5")
+	_, err = client.NetworkSummary(ctx, 6, 0)
+	assert.EqualError(t, err, "no network capture for tab 6")
+
+	conn.AssertExpectations(t)
+}
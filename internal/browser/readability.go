@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Class and id names telling content from clutter, as Readability does
+var (
+	unlikelyNames = regexp.MustCompile(`(?i)(^|[^a-z])(ads?|advert\w*|banner|breadcrumbs?|comments?|cookie\w*|footer|footnotes?|masthead|menu|modal|nav|navbar|newsletter|pagination|popup|promo\w*|related|share|sharing|sidebar|social|sponsor\w*|subscribe|widget)([^a-z]|$)`)
+	likelyNames   = regexp.MustCompile(`(?i)(^|[^a-z])(article|body|blog|content|entry|main|page|post|story|text)([^a-z]|$)`)
+)
+
+// clutterElements are left out of the main content
+var clutterElements = map[atom.Atom]bool{
+	atom.Aside: true, atom.Button: true, atom.Dialog: true, atom.Footer: true,
+	atom.Form: true, atom.Nav: true,
+}
+
+// findMainContent returns the element of a document holding its main
+// content: the main element or the only article when there is one, or else
+// the element whose paragraphs score best, scored by length and commas,
+// weighted by class names and lowered by the share of link text
+func findMainContent(root *html.Node) *html.Node {
+	var mains, articles []*html.Node
+	walkElements(root, func(n *html.Node) bool {
+		switch {
+		case n.DataAtom == atom.Main || attr(n, "role") == "main":
+			mains = append(mains, n)
+		case n.DataAtom == atom.Article:
+			articles = append(articles, n)
+		}
+		return true
+	})
+	if len(mains) == 1 {
+		return mains[0]
+	}
+	if len(articles) == 1 {
+		return articles[0]
+	}
+
+	scores := map[*html.Node]float64{}
+	walkElements(root, func(n *html.Node) bool {
+		if skippedElements[n.DataAtom] || clutterElements[n.DataAtom] || hidden(n) {
+			return false
+		}
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return true
+		}
+		text := strings.Join(strings.Fields(textContent(n)), " ")
+		if len(text) < 25 {
+			return true
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		if parent := n.Parent; parent != nil && parent.Type == html.ElementNode {
+			scores[parent] += score
+			if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+				scores[grandparent] += score / 2
+			}
+		}
+		return true
+	})
+
+	best, bestScore := root, 0.0
+	for n, score := range scores {
+		score = (score + nameWeight(n)) * (1 - linkDensity(n))
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// nameWeight scores the class and id of an element by how likely they name
+// content
+func nameWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if unlikelyNames.MatchString(name) {
+			weight -= 25
+		}
+		if likelyNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity returns the share of the text of an element inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(strings.Join(strings.Fields(textContent(n)), " "))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	walkElements(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			links += len(strings.Join(strings.Fields(textContent(c)), " "))
+			return false
+		}
+		return true
+	})
+	return min(float64(links)/float64(total), 1)
+}
+
+// removeClutter removes navigation, sidebars, footers, forms and link lists
+// from the main content
+func removeClutter(root *html.Node) {
+	var clutter []*html.Node
+	walkElements(root, func(n *html.Node) bool {
+		if n == root {
+			return true
+		}
+		if clutterElements[n.DataAtom] || isUnlikely(n) || isLinkList(n) {
+			clutter = append(clutter, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range clutter {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// isUnlikely reports whether the class or id of an element names clutter
+// and not content
+func isUnlikely(n *html.Node) bool {
+	if !blockElements[n.DataAtom] || n.DataAtom == atom.Body {
+		return false
+	}
+	name := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyNames.MatchString(name) && !likelyNames.MatchString(name)
+}
+
+// isLinkList reports whether a short block is mostly links, such as a list of
+// related pages or tags
+func isLinkList(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Ul, atom.Ol:
+	default:
+		return false
+	}
+	text := strings.Join(strings.Fields(textContent(n)), " ")
+	return len(text) < 200 && linkDensity(n) > 0.5
+}
+
+// walkElements calls fn on n and its descendant elements in document order,
+// not descending into an element when fn returns false
+func walkElements(n *html.Node, fn func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, fn)
+	}
+}
@@ -0,0 +1,293 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RouteAction is what a route does with the requests it matches
+type RouteAction string
+
+// Route actions
+const (
+	RouteBlock         RouteAction = "block"         // fail the request
+	RouteDelay         RouteAction = "delay"         // hold the request for DelayMs, then let it through
+	RouteModifyHeaders RouteAction = "modifyHeaders" // change request and response headers
+	RouteFulfill       RouteAction = "fulfill"       // answer with Status, ResponseHeaders and Body without hitting the network
+)
+
+// RouteActions lists the supported route actions
+var RouteActions = []RouteAction{RouteBlock, RouteDelay, RouteModifyHeaders, RouteFulfill}
+
+// Route is a rule the extension applies to the network requests of a
+// browser. Pattern is a glob matched against the whole request URL: * matches
+// any characters but /, ** matches any characters. When several routes match
+// a request, the most recently added one applies.
+type Route struct {
+	ID              string            `json:"id"`
+	Pattern         string            `json:"pattern"`
+	Methods         []string          `json:"methods,omitempty"` // HTTP methods to match; all methods if empty
+	Action          RouteAction       `json:"action"`
+	TabID           int               `json:"tabId,omitempty"` // only requests of this tab; all tabs if 0
+	DelayMs         int               `json:"delayMs,omitempty"`
+	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`  // headers to set on the request; an empty value removes the header
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"` // headers to set on the response; an empty value removes the header
+	Status          int               `json:"status,omitempty"`
+	ContentType     string            `json:"contentType,omitempty"`
+	Body            []byte            `json:"body,omitempty"` // base64 in JSON
+	BodySize        int               `json:"bodySize,omitempty"`
+	BrowserID       string            `json:"browserId,omitempty"`
+}
+
+// Validate checks the route and fills in defaults: methods are upper-cased
+// and fulfill routes answer 200 unless given a status
+func (r *Route) Validate() error {
+	if r.Pattern == "" {
+		return errors.New("pattern is required")
+	}
+	if _, err := compileRoutePattern(r.Pattern); err != nil {
+		return err
+	}
+	if r.DelayMs < 0 {
+		return errors.New("delayMs must not be negative")
+	}
+	for i, method := range r.Methods {
+		r.Methods[i] = strings.ToUpper(method)
+	}
+
+	switch r.Action {
+	case RouteBlock:
+	case RouteDelay:
+		if r.DelayMs == 0 {
+			return errors.New("delay routes need a delayMs greater than 0")
+		}
+	case RouteModifyHeaders:
+		if len(r.RequestHeaders) == 0 && len(r.ResponseHeaders) == 0 {
+			return errors.New("modifyHeaders routes need requestHeaders or responseHeaders")
+		}
+	case RouteFulfill:
+		if r.Status == 0 {
+			r.Status = http.StatusOK
+		}
+		if r.Status < 100 || r.Status > 599 {
+			return fmt.Errorf("invalid status: %d", r.Status)
+		}
+	case "":
+		return errors.New("action is required")
+	default:
+		return fmt.Errorf("unknown route action: %s", r.Action)
+	}
+
+	if r.Action != RouteFulfill && (len(r.Body) > 0 || r.Status != 0 || r.ContentType != "") {
+		return errors.New("status, contentType and body are only used by fulfill routes")
+	}
+	r.BodySize = len(r.Body)
+	return nil
+}
+
+// Matches reports whether the route applies to a request
+func (r *Route) Matches(method, url string) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	re, err := compileRoutePattern(r.Pattern)
+	return err == nil && re.MatchString(url)
+}
+
+// compileRoutePattern translates a route's URL glob to a regular expression
+func compileRoutePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// routeEntry is a route in the client's registry
+type routeEntry struct {
+	route Route
+	seq   int
+	conn  Connection
+	owner string // session that added the route
+}
+
+// AddRoute validates the route, pushes it to the selected browser and
+// records it in the registry. The route is given a new ID, which is returned
+// along with the defaults filled in.
+func (c *Client) AddRoute(ctx context.Context, route Route) (*Route, error) {
+	if err := route.Validate(); err != nil {
+		return nil, err
+	}
+	re, _ := compileRoutePattern(route.Pattern)
+
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.routeSeq++
+	seq := c.routeSeq
+	route.ID = fmt.Sprintf("route-%d", seq)
+	route.BrowserID = c.browserIDLocked(conn)
+	c.mu.Unlock()
+
+	params := map[string]interface{}{
+		"route":    route,
+		"urlRegex": re.String(),
+	}
+	if route.TabID > 0 {
+		params["tabId"] = route.TabID
+	}
+	if err := c.checkTabAccess(ctx, conn, params); err != nil {
+		return nil, err
+	}
+	if _, err := c.sendCommandTo(ctx, conn, "addRoute", params); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.routes[route.ID] = &routeEntry{route: route, seq: seq, conn: conn, owner: SessionIDFromContext(ctx)}
+	c.mu.Unlock()
+
+	route.Body = nil
+	return &route, nil
+}
+
+// RemoveRoute removes a route from the registry and from the browser it was
+// pushed to
+func (c *Client) RemoveRoute(ctx context.Context, id string) error {
+	c.mu.RLock()
+	entry, ok := c.routes[id]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no route with id: %s", id)
+	}
+	if sessionID := SessionIDFromContext(ctx); sessionID != "" && entry.owner != "" && entry.owner != sessionID {
+		return fmt.Errorf("route %s is owned by another session", id)
+	}
+
+	params := map[string]interface{}{
+		"id": id,
+	}
+	if _, err := c.sendCommandTo(ctx, entry.conn, "removeRoute", params); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.routes, id)
+	c.mu.Unlock()
+	return nil
+}
+
+// ListRoutes returns the routes of the selected browser visible to the
+// calling session, oldest first. Bodies are left out; BodySize gives their
+// length.
+func (c *Client) ListRoutes(ctx context.Context) ([]Route, error) {
+	conn, err := c.connectionFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionID := SessionIDFromContext(ctx)
+
+	c.mu.RLock()
+	entries := make([]*routeEntry, 0, len(c.routes))
+	for _, entry := range c.routes {
+		if entry.conn != conn {
+			continue
+		}
+		if sessionID != "" && entry.owner != "" && entry.owner != sessionID {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+	routes := make([]Route, len(entries))
+	for i, entry := range entries {
+		routes[i] = entry.route
+		routes[i].Body = nil
+	}
+	return routes, nil
+}
+
+// browserIDLocked returns the ID of the browser registered on conn, or "".
+// c.mu must be held.
+func (c *Client) browserIDLocked(conn Connection) string {
+	for id, b := range c.browsers {
+		if b.conn == conn {
+			return id
+		}
+	}
+	return ""
+}
+
+// removeRoutesLocked forgets the routes pushed over conn. c.mu must be held
+// for writing.
+func (c *Client) removeRoutesLocked(conn Connection) {
+	for id, entry := range c.routes {
+		if entry.conn == conn {
+			delete(c.routes, id)
+		}
+	}
+}
+
+// releaseRoutes removes the routes added by a session, returning errors from
+// browsers that are still connected
+func (c *Client) releaseRoutes(ctx context.Context, sessionID string, connected map[Connection]bool) []error {
+	c.mu.Lock()
+	var owned []*routeEntry
+	for id, entry := range c.routes {
+		if entry.owner == sessionID {
+			owned = append(owned, entry)
+			delete(c.routes, id)
+		}
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, entry := range owned {
+		if !connected[entry.conn] {
+			continue
+		}
+		params := map[string]interface{}{
+			"id": entry.route.ID,
+		}
+		if _, err := c.sendCommandTo(ctx, entry.conn, "removeRoute", params); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove route %s: %w", entry.route.ID, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,173 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    Route
+		expected Route
+		err      string
+	}{
+		{
+			name:     "fulfill defaults to 200",
+			route:    Route{Pattern: "**/api/*", Action: RouteFulfill, Body: []byte("{}"), Methods: []string{"get"}},
+			expected: Route{Pattern: "**/api/*", Action: RouteFulfill, Body: []byte("{}"), Methods: []string{"GET"}, Status: 200, BodySize: 2},
+		},
+		{
+			name:     "block",
+			route:    Route{Pattern: "https://ads.example.com/**", Action: RouteBlock},
+			expected: Route{Pattern: "https://ads.example.com/**", Action: RouteBlock},
+		},
+		{
+			name:  "missing pattern",
+			route: Route{Action: RouteBlock},
+			err:   "pattern is required",
+		},
+		{
+			name:  "missing action",
+			route: Route{Pattern: "**"},
+			err:   "action is required",
+		},
+		{
+			name:  "unknown action",
+			route: Route{Pattern: "**", Action: "rewrite"},
+			err:   "unknown route action: rewrite",
+		},
+		{
+			name:  "delay without duration",
+			route: Route{Pattern: "**", Action: RouteDelay},
+			err:   "delay routes need a delayMs greater than 0",
+		},
+		{
+			name:  "modifyHeaders without headers",
+			route: Route{Pattern: "**", Action: RouteModifyHeaders},
+			err:   "modifyHeaders routes need requestHeaders or responseHeaders",
+		},
+		{
+			name:  "invalid status",
+			route: Route{Pattern: "**", Action: RouteFulfill, Status: 42},
+			err:   "invalid status: 42",
+		},
+		{
+			name:  "body on a block route",
+			route: Route{Pattern: "**", Action: RouteBlock, Body: []byte("x")},
+			err:   "status, contentType and body are only used by fulfill routes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := tt.route
+			err := route.Validate()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, route)
+		})
+	}
+}
+
+func TestRoute_Matches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		methods []string
+		method  string
+		url     string
+		matches bool
+	}{
+		{pattern: "**/api/*", method: "GET", url: "https://example.com/api/users", matches: true},
+		{pattern: "**/api/*", method: "GET", url: "https://example.com/api/users/1", matches: false},
+		{pattern: "**/api/**", method: "GET", url: "https://example.com/api/users/1", matches: true},
+		{pattern: "https://example.com/*.png", method: "GET", url: "https://example.com/logo.png", matches: true},
+		{pattern: "https://example.com/*.png", method: "GET", url: "https://example.com/logo.jpg", matches: false},
+		{pattern: "https://example.com/?q=1", method: "GET", url: "https://example.com/?q=1", matches: true},
+		{pattern: "**", methods: []string{"POST"}, method: "post", url: "https://example.com/", matches: true},
+		{pattern: "**", methods: []string{"POST"}, method: "GET", url: "https://example.com/", matches: false},
+	}
+
+	for _, tt := range tests {
+		route := Route{Pattern: tt.pattern, Methods: tt.methods}
+		assert.Equal(t, tt.matches, route.Matches(tt.method, tt.url), "%s %s %s", tt.pattern, tt.method, tt.url)
+	}
+}
+
+func TestClient_Routes(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+	client.RegisterBrowser(BrowserInfo{ID: "work"}, conn)
+
+	respond := func(msgID string) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			client.HandleResponse(msgID, json.RawMessage(`{"success":true}`), "")
+		}()
+	}
+
+	alice := WithSessionID(context.Background(), "alice")
+	bob := WithSessionID(context.Background(), "bob")
+
+	conn.On("SendCommand", "addRoute", map[string]interface{}{
+		"route":    Route{ID: "route-1", Pattern: "**/ads/**", Action: RouteBlock, BrowserID: "work"},
+		"urlRegex": "^.*/ads/.*$",
+	}).Return("msg-1", nil)
+	respond("msg-1")
+	route, err := client.AddRoute(alice, Route{Pattern: "**/ads/**", Action: RouteBlock})
+	require.NoError(t, err)
+	assert.Equal(t, "route-1", route.ID)
+
+	conn.On("SendCommand", "addRoute", map[string]interface{}{
+		"route": Route{ID: "route-2", Pattern: "**/api/user", Action: RouteFulfill, Status: 200,
+			Body: []byte(`{"name":"Ada"}`), BodySize: 14, TabID: 4, BrowserID: "work"},
+		"urlRegex": `^.*/api/user$`,
+		"tabId":    4,
+	}).Return("msg-2", nil)
+	respond("msg-2")
+	route, err = client.AddRoute(bob, Route{Pattern: "**/api/user", Action: RouteFulfill, Body: []byte(`{"name":"Ada"}`), TabID: 4})
+	require.NoError(t, err)
+	assert.Nil(t, route.Body, "the body is not echoed back")
+	assert.Equal(t, 14, route.BodySize)
+
+	// Sessions only see their own routes
+	routes, err := client.ListRoutes(alice)
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	assert.Equal(t, "route-1", routes[0].ID)
+
+	routes, err = client.ListRoutes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.Equal(t, "route-2", routes[1].ID)
+	assert.Nil(t, routes[1].Body)
+
+	assert.EqualError(t, client.RemoveRoute(bob, "route-1"), "route route-1 is owned by another session")
+	assert.EqualError(t, client.RemoveRoute(bob, "route-9"), "no route with id: route-9")
+
+	// A released session's routes are removed from the browser
+	conn.On("SendCommand", "removeRoute", map[string]interface{}{"id": "route-1"}).Return("msg-3", nil)
+	respond("msg-3")
+	require.NoError(t, client.ReleaseSession(context.Background(), "alice", false))
+
+	routes, err = client.ListRoutes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	assert.Equal(t, "route-2", routes[0].ID)
+
+	// Routes go away with their browser
+	client.RemoveConnection(conn)
+	assert.Empty(t, client.routes)
+
+	conn.AssertExpectations(t)
+}
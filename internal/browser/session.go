@@ -0,0 +1,147 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// tabKey identifies a tab on a specific browser connection
+type tabKey struct {
+	conn  Connection
+	tabID int
+}
+
+// session tracks the tabs used by one MCP session
+type session struct {
+	activeTabs map[Connection]int // active tab per browser connection
+}
+
+// sessionLocked returns the state for sessionID, creating it if needed.
+// c.mu must be held for writing.
+func (c *Client) sessionLocked(sessionID string) *session {
+	sess, ok := c.sessions[sessionID]
+	if !ok {
+		sess = &session{activeTabs: make(map[Connection]int)}
+		c.sessions[sessionID] = sess
+	}
+	return sess
+}
+
+// claimTab marks tabID as owned by the calling session
+func (c *Client) claimTab(ctx context.Context, tabID int) {
+	sessionID := SessionIDFromContext(ctx)
+	if sessionID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sessionLocked(sessionID)
+	c.tabOwners[tabKey{c.selectedConnLocked(ctx), tabID}] = sessionID
+}
+
+// releaseTabLocked forgets ownership of tabID on conn, or on every connection
+// when conn is nil. c.mu must be held for writing.
+func (c *Client) releaseTabLocked(conn Connection, tabID int) {
+	for key := range c.tabOwners {
+		if key.tabID == tabID && (conn == nil || key.conn == conn) {
+			delete(c.tabOwners, key)
+		}
+	}
+	for _, sess := range c.sessions {
+		for tabConn, active := range sess.activeTabs {
+			if active == tabID && (conn == nil || tabConn == conn) {
+				delete(sess.activeTabs, tabConn)
+			}
+		}
+	}
+}
+
+// checkTabAccess rejects commands from one session against a tab owned by another
+func (c *Client) checkTabAccess(ctx context.Context, conn Connection, data interface{}) error {
+	sessionID := SessionIDFromContext(ctx)
+	if sessionID == "" {
+		return nil
+	}
+
+	params, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	tabID, ok := params["tabId"].(int)
+	if !ok || tabID <= 0 {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if owner := c.tabOwners[tabKey{conn, tabID}]; owner != "" && owner != sessionID {
+		return fmt.Errorf("tab %d is owned by another session", tabID)
+	}
+	return nil
+}
+
+// markOwners annotates tabs with their ownership relative to the calling session
+func (c *Client) markOwners(ctx context.Context, tabs []Tab) {
+	sessionID := SessionIDFromContext(ctx)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	conn := c.selectedConnLocked(ctx)
+	for i := range tabs {
+		switch owner := c.tabOwners[tabKey{conn, tabs[i].ID}]; {
+		case owner == "":
+		case owner == sessionID:
+			tabs[i].Owner = "self"
+		default:
+			tabs[i].Owner = "other"
+		}
+	}
+}
+
+// ReleaseSession forgets a disconnected session and removes its routes. Its
+// tabs are closed when closeTabs is set, otherwise they are released for other
+// sessions to use.
+func (c *Client) ReleaseSession(ctx context.Context, sessionID string, closeTabs bool) error {
+	c.mu.Lock()
+	var owned []tabKey
+	for key, owner := range c.tabOwners {
+		if owner == sessionID {
+			owned = append(owned, key)
+			delete(c.tabOwners, key)
+		}
+	}
+	delete(c.sessions, sessionID)
+
+	// Only close tabs on browsers that are still connected
+	connected := make(map[Connection]bool)
+	if c.connection != nil {
+		connected[c.connection] = true
+	}
+	for _, b := range c.browsers {
+		connected[b.conn] = true
+	}
+	c.mu.Unlock()
+
+	errs := c.releaseRoutes(ctx, sessionID, connected)
+	if !closeTabs {
+		return errors.Join(errs...)
+	}
+
+	for _, key := range owned {
+		if !connected[key.conn] {
+			continue
+		}
+		params := map[string]interface{}{
+			"tabId": key.tabID,
+		}
+		if _, err := c.sendCommandTo(ctx, key.conn, "closeTab", params); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close tab %d: %w", key.tabID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// StructuredField describes a field of a structured extraction: where its
+// value is read and what it holds. A field with Fields is an object whose
+// fields are read inside the element matching its selector.
+type StructuredField struct {
+	Selector  string                      `json:"selector,omitempty"`  // CSS selector relative to the enclosing element, itself when empty
+	Attribute string                      `json:"attribute,omitempty"` // attribute to read instead of the text
+	Type      string                      `json:"type,omitempty"`      // string, number, integer, boolean, date or object
+	Multiple  bool                        `json:"multiple,omitempty"`  // read every match as a list instead of the first
+	Optional  bool                        `json:"optional,omitempty"`  // matching nothing is not an error
+	Fields    map[string]*StructuredField `json:"fields,omitempty"`
+}
+
+// ExtractStructured reads the fields of a schema inside the element matching
+// selector, or the document when it is empty. The values are returned as
+// read from the page: a string for each field, or null for an element
+// without the attribute read, lists for multiple fields and objects for
+// nested ones. Fields matching nothing are left out.
+func (c *Client) ExtractStructured(ctx context.Context, tabID int, selector string, fields map[string]*StructuredField) (map[string]interface{}, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":  tabID,
+		"fields": fields,
+	}
+	if selector != "" {
+		if err := setLocator(params, selector); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := c.sendCommand(ctx, "extractStructured", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	if response.Data == nil {
+		response.Data = map[string]interface{}{}
+	}
+	return response.Data, nil
+}
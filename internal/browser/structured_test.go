@@ -0,0 +1,45 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_ExtractStructured(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	fields := map[string]*StructuredField{
+		"title": {Selector: "h1", Type: "string"},
+		"tags":  {Selector: ".tag", Type: "string", Multiple: true},
+	}
+	conn.On("SendCommand", "extractStructured", map[string]interface{}{"tabId": 3, "selector": "article", "fields": fields}).Return("msg-1", nil).Once()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"data":{"title":"Hello","tags":["go","html"]}}`), "")
+	}()
+
+	data, err := client.ExtractStructured(context.Background(), 3, "article", fields)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"title": "Hello", "tags": []interface{}{"go", "html"}}, data)
+
+	// Without a selector the schema is read in the document
+	conn.On("SendCommand", "extractStructured", map[string]interface{}{"tabId": 3, "fields": fields}).Return("msg-2", nil).Once()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-2", json.RawMessage(`{}`), "")
+	}()
+
+	data, err = client.ExtractStructured(context.Background(), 3, "", fields)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, data)
+
+	conn.AssertExpectations(t)
+}
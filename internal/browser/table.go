@@ -0,0 +1,261 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Spans larger than browsers allow are clamped to their limits
+const (
+	maxColspan = 1000
+	maxRowspan = 65534
+)
+
+// Table is tabular data extracted from a page, with a value for each column
+// in every row
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// TableColumn selects the value of a column in each row of a repeated element
+// pattern, such as the cards of a product list
+type TableColumn struct {
+	Name      string `json:"name"`
+	Selector  string `json:"selector,omitempty"`  // CSS selector relative to the row, the row itself when empty
+	Attribute string `json:"attribute,omitempty"` // attribute to read instead of the text
+}
+
+// ExtractTable extracts the HTML table matching selector, or the first table
+// inside the element matching it. Index picks one of several matches.
+func (c *Client) ExtractTable(ctx context.Context, tabID int, selector string, index int) (*Table, error) {
+	results, err := c.ExtractContent(ctx, tabID, selector, "html", "")
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(results) {
+		return nil, fmt.Errorf("%d element(s) match %s, no element at index %d", len(results), selector, index)
+	}
+	return parseTable(results[index])
+}
+
+// ExtractRows extracts the value of each column in the elements matching
+// rowSelector. Columns that match nothing in a row are empty.
+func (c *Client) ExtractRows(ctx context.Context, tabID int, rowSelector string, columns []TableColumn) (*Table, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":   tabID,
+		"columns": columns,
+	}
+	if err := setLocator(params, rowSelector); err != nil {
+		return nil, err
+	}
+
+	data, err := c.sendCommand(ctx, "extractRows", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Values are null for columns without a match
+	var response struct {
+		Rows [][]*string `json:"rows"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	table := &Table{Columns: make([]string, len(columns)), Rows: make([][]string, 0, len(response.Rows))}
+	for i, column := range columns {
+		table.Columns[i] = column.Name
+	}
+	for _, values := range response.Rows {
+		row := make([]string, len(columns))
+		for i := range row {
+			if i < len(values) && values[i] != nil {
+				row[i] = strings.TrimSpace(*values[i])
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// tableRow is a row of an HTML table before its spans are laid out
+type tableRow struct {
+	cells  []*html.Node
+	header bool // in thead, or made of th cells only
+	inHead bool
+}
+
+// parseTable lays out the rows of an HTML table, given as the table's inner
+// HTML or as HTML containing a table. Cells spanning several columns or rows
+// repeat their value in each. The header rows are those in thead or, without
+// one, the leading rows made of th cells only; their values name the columns,
+// joined with " / " for grouped headers.
+func parseTable(fragment string) (*Table, error) {
+	table := findTable(fragment)
+	if table == nil {
+		return nil, fmt.Errorf("no table found")
+	}
+
+	var rows []tableRow
+	hasHead := false
+	var collect func(*html.Node, bool)
+	collect = func(parent *html.Node, inHead bool) {
+		for n := parent.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type != html.ElementNode {
+				continue
+			}
+			switch n.DataAtom {
+			case atom.Thead:
+				hasHead = true
+				collect(n, true)
+			case atom.Tbody, atom.Tfoot:
+				collect(n, false)
+			case atom.Tr:
+				row := tableRow{inHead: inHead, header: true}
+				for cell := n.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						row.cells = append(row.cells, cell)
+						row.header = row.header && cell.DataAtom == atom.Th
+					}
+				}
+				if len(row.cells) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	collect(table, false)
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("the table has no rows")
+	}
+
+	// Lay the cells out on a grid, filling the slots their spans cover
+	grid := make([][]string, len(rows))
+	filled := make([][]bool, len(rows))
+	set := func(r, c int, value string) {
+		for len(grid[r]) <= c {
+			grid[r] = append(grid[r], "")
+			filled[r] = append(filled[r], false)
+		}
+		grid[r][c], filled[r][c] = value, true
+	}
+	for r, row := range rows {
+		c := 0
+		for _, cell := range row.cells {
+			for c < len(filled[r]) && filled[r][c] {
+				c++
+			}
+			colspan := cellSpan(cell, "colspan", 1, maxColspan)
+			rowspan := cellSpan(cell, "rowspan", 1, maxRowspan)
+			if rowspan == 0 || r+rowspan > len(rows) {
+				// A rowspan of 0 spans the rest of the table
+				rowspan = len(rows) - r
+			}
+			value := strings.Join(strings.Fields(textContent(cell)), " ")
+			for dr := 0; dr < rowspan; dr++ {
+				for dc := 0; dc < colspan; dc++ {
+					set(r+dr, c+dc, value)
+				}
+			}
+			c += colspan
+		}
+	}
+
+	width := 0
+	for _, values := range grid {
+		width = max(width, len(values))
+	}
+
+	headers := 0
+	for headers < len(rows) && ((hasHead && rows[headers].inHead) || (!hasHead && rows[headers].header)) {
+		headers++
+	}
+	if headers == len(rows) && !hasHead {
+		// A table of th cells only is data with row headers
+		headers = 0
+	}
+
+	result := &Table{Columns: tableColumns(grid[:headers], width)}
+	for _, values := range grid[headers:] {
+		if strings.Join(values, "") == "" {
+			continue
+		}
+		row := make([]string, width)
+		copy(row, values)
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// findTable returns the table an HTML fragment holds, or the table the
+// fragment is the inner HTML of
+func findTable(fragment string) *html.Node {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	if nodes, err := html.ParseFragment(strings.NewReader(fragment), body); err == nil {
+		for _, n := range nodes {
+			var table *html.Node
+			walkElements(n, func(e *html.Node) bool {
+				if table == nil && e.DataAtom == atom.Table {
+					table = e
+				}
+				return table == nil
+			})
+			if table != nil {
+				return table
+			}
+		}
+	}
+
+	table := &html.Node{Type: html.ElementNode, Data: "table", DataAtom: atom.Table}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), table)
+	if err != nil {
+		return nil
+	}
+	for _, n := range nodes {
+		table.AppendChild(n)
+	}
+	return table
+}
+
+// cellSpan returns the colspan or rowspan of a cell, clamped to limit
+func cellSpan(cell *html.Node, name string, fallback, limit int) int {
+	span, err := strconv.Atoi(strings.TrimSpace(attr(cell, name)))
+	if err != nil || span < 0 || (span == 0 && name == "colspan") {
+		return fallback
+	}
+	return min(span, limit)
+}
+
+// tableColumns names the columns after the header rows. Columns without a
+// header are named column1, column2 and so on, and repeated names get a
+// numbered suffix.
+func tableColumns(headers [][]string, width int) []string {
+	columns := make([]string, width)
+	seen := map[string]int{}
+	for c := range columns {
+		var parts []string
+		for _, row := range headers {
+			if c < len(row) && row[c] != "" && (len(parts) == 0 || parts[len(parts)-1] != row[c]) {
+				parts = append(parts, row[c])
+			}
+		}
+		name := strings.Join(parts, " / ")
+		if name == "" {
+			name = "column" + strconv.Itoa(c+1)
+		}
+		if seen[name]++; seen[name] > 1 {
+			name += "_" + strconv.Itoa(seen[name])
+		}
+		columns[c] = name
+	}
+	return columns
+}
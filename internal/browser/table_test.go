@@ -0,0 +1,105 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected *Table
+		err      string
+	}{
+		{
+			name: "header in thead",
+			html: `<thead><tr><th>Name</th><th>Price</th></tr></thead>
+<tbody><tr><td>Tea</td><td> $2.50 </td></tr><tr><td>Coffee</td><td>$3</td></tr></tbody>`,
+			expected: &Table{Columns: []string{"Name", "Price"}, Rows: [][]string{{"Tea", "$2.50"}, {"Coffee", "$3"}}},
+		},
+		{
+			name:     "header of th cells",
+			html:     `<tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr>`,
+			expected: &Table{Columns: []string{"A", "B"}, Rows: [][]string{{"1", "2"}}},
+		},
+		{
+			name:     "no header",
+			html:     `<tr><td>1</td><td>2</td></tr><tr><td>3</td></tr>`,
+			expected: &Table{Columns: []string{"column1", "column2"}, Rows: [][]string{{"1", "2"}, {"3", ""}}},
+		},
+		{
+			name: "grouped headers",
+			html: `<thead><tr><th rowspan="2">Region</th><th colspan="2">Q1</th></tr><tr><th>Sales</th><th>Costs</th></tr></thead>
+<tr><td>North</td><td>10</td><td>4</td></tr>`,
+			expected: &Table{Columns: []string{"Region", "Q1 / Sales", "Q1 / Costs"}, Rows: [][]string{{"North", "10", "4"}}},
+		},
+		{
+			name: "spans repeat their value",
+			html: `<tr><th>Day</th><th>Slot</th><th>Talk</th></tr>
+<tr><td rowspan="2">Mon</td><td>9:00</td><td>Keynote</td></tr>
+<tr><td>10:00</td><td>Go</td></tr>
+<tr><td>Tue</td><td colspan="2">Closed</td></tr>`,
+			expected: &Table{Columns: []string{"Day", "Slot", "Talk"}, Rows: [][]string{
+				{"Mon", "9:00", "Keynote"}, {"Mon", "10:00", "Go"}, {"Tue", "Closed", "Closed"},
+			}},
+		},
+		{
+			name:     "duplicate and empty header names",
+			html:     `<tr><th>Name</th><th></th><th>Name</th></tr><tr><td>a</td><td>b</td><td>c</td></tr>`,
+			expected: &Table{Columns: []string{"Name", "column2", "Name_2"}, Rows: [][]string{{"a", "b", "c"}}},
+		},
+		{
+			name:     "table inside the element",
+			html:     `<h2>Scores</h2><table><tr><th>Team</th></tr><tr><td>Blue <b>team</b></td></tr></table>`,
+			expected: &Table{Columns: []string{"Team"}, Rows: [][]string{{"Blue team"}}},
+		},
+		{
+			name:     "row headers only",
+			html:     `<tr><th>Total</th><th>12</th></tr>`,
+			expected: &Table{Columns: []string{"column1", "column2"}, Rows: [][]string{{"Total", "12"}}},
+		},
+		{
+			name: "empty table",
+			html: `<caption>Nothing</caption>`,
+			err:  "the table has no rows",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := parseTable(tt.html)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, table)
+		})
+	}
+}
+
+func TestClient_ExtractRows(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	columns := []TableColumn{{Name: "title", Selector: "h3"}, {Name: "link", Selector: "a", Attribute: "href"}}
+	conn.On("SendCommand", "extractRows", map[string]interface{}{"tabId": 2, "selector": ".card", "columns": columns}).Return("msg-1", nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-1", json.RawMessage(`{"rows":[[" Tea ","/tea"],["Coffee",null]]}`), "")
+	}()
+
+	table, err := client.ExtractRows(context.Background(), 2, ".card", columns)
+	require.NoError(t, err)
+	assert.Equal(t, &Table{Columns: []string{"title", "link"}, Rows: [][]string{{"Tea", "/tea"}, {"Coffee", ""}}}, table)
+
+	conn.AssertExpectations(t)
+}
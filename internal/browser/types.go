@@ -1,5 +1,7 @@
 package browser
 
+import "time"
+
 // Tab represents a browser tab
 type Tab struct {
 	ID      int    `json:"id"`
@@ -8,6 +10,7 @@ type Tab struct {
 	Active  bool   `json:"active"`
 	Index   int    `json:"index"`
 	Favicon string `json:"favicon,omitempty"`
+	Owner   string `json:"owner,omitempty"` // "self" or "other" when owned by an MCP session
 }
 
 // Cookie represents a browser cookie
@@ -28,4 +31,53 @@ type Actionable struct {
 	Description string `json:"description"`
 	Type        string `json:"type"`
 	Selector    string `json:"selector"`
+	BoundingBox *Rect  `json:"boundingBox,omitempty"` // if reported by the extension
+}
+
+// Element is an element found on a page
+type Element struct {
+	Index       int               `json:"index"`
+	Tag         string            `json:"tag"`
+	Text        string            `json:"text"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Selector    string            `json:"selector,omitempty"` // selector matching only this element
+	Visible     bool              `json:"visible"`
+	BoundingBox *Rect             `json:"boundingBox,omitempty"`
+}
+
+// Rect is a bounding box in CSS pixels, relative to the viewport
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ElementValue is the current value of a form field
+type ElementValue struct {
+	Tag     string   `json:"tag,omitempty"`
+	Type    string   `json:"type,omitempty"`    // input type
+	Value   string   `json:"value"`             // value of the field, or of the first selected option
+	Values  []string `json:"values,omitempty"`  // selected options of a multiple select
+	Checked *bool    `json:"checked,omitempty"` // checkboxes and radio buttons
+	Files   []string `json:"files,omitempty"`   // names of the files chosen in a file input
+}
+
+// VideoRecording is a finished video recording saved to disk
+type VideoRecording struct {
+	TabID    int     `json:"tabId"`
+	Path     string  `json:"path"`
+	MimeType string  `json:"mimeType"`
+	Size     int64   `json:"size"`
+	Duration float64 `json:"duration,omitempty"` // seconds
+}
+
+// BrowserInfo describes a connected browser extension instance
+type BrowserInfo struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Profile     string    `json:"profile,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	Default     bool      `json:"default"`
 }
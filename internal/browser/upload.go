@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// UploadChunkSize is the number of bytes of a file sent to the extension in
+// each uploadChunk command, keeping WebSocket messages small whatever the
+// size of the file
+const UploadChunkSize = 256 << 10
+
+// UploadedFile is a file set on a file input
+type UploadedFile struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+}
+
+// UploadFiles sets the files of the file input matching selector to local
+// files. The files are read by the server and streamed to the extension in
+// chunks, then the extension sets them on the input, firing its input and
+// change events as a file picker would.
+func (c *Client) UploadFiles(ctx context.Context, tabID int, selector string, paths []string) ([]UploadedFile, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+	uploadID := uuid.New().String()
+
+	params := map[string]interface{}{
+		"tabId":    tabID,
+		"uploadId": uploadID,
+	}
+	if err := setLocator(params, selector); err != nil {
+		return nil, err
+	}
+
+	files := make([]UploadedFile, len(paths))
+	for i, path := range paths {
+		file, err := c.uploadFile(ctx, tabID, uploadID, i, path)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = *file
+	}
+	params["files"] = files
+
+	if _, err := c.sendCommand(ctx, "uploadFile", params); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// uploadFile streams the file at path to the extension as the index-th file
+// of an upload
+func (c *Client) uploadFile(ctx context.Context, tabID int, uploadID string, index int, path string) (*UploadedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a file: %s", path)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	file := &UploadedFile{Name: filepath.Base(path), MimeType: mimeType}
+
+	buf := make([]byte, UploadChunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			params := map[string]interface{}{
+				"tabId":    tabID,
+				"uploadId": uploadID,
+				"index":    index,
+				"data":     base64.StdEncoding.EncodeToString(buf[:n]),
+			}
+			if _, err := c.sendCommand(ctx, "uploadChunk", params); err != nil {
+				return nil, err
+			}
+			file.Size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return file, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+}
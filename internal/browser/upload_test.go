@@ -0,0 +1,73 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_UploadFiles(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 1000})
+	conn := &MockConnection{}
+	client.SetConnection(conn)
+
+	dir := t.TempDir()
+	big := bytes.Repeat([]byte("x"), UploadChunkSize+10)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.bin"), big, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.json"), []byte(`{"id":1}`), 0o644))
+
+	// The files are streamed in chunks, then set on the input
+	var uploadID interface{}
+	received := map[int][]byte{}
+	expect := func(action string, msgID string, check func(params map[string]interface{})) {
+		conn.On("SendCommand", action, mock.Anything).Return(msgID, nil).Once().Run(func(args mock.Arguments) {
+			params := args.Get(1).(map[string]interface{})
+			assert.Equal(t, 3, params["tabId"])
+			check(params)
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				client.HandleResponse(msgID, json.RawMessage(`{"success":true}`), "")
+			}()
+		})
+	}
+	chunk := func(params map[string]interface{}) {
+		data, err := base64.StdEncoding.DecodeString(params["data"].(string))
+		require.NoError(t, err)
+		index := params["index"].(int)
+		received[index] = append(received[index], data...)
+		uploadID = params["uploadId"]
+	}
+	expect("uploadChunk", "msg-1", chunk)
+	expect("uploadChunk", "msg-2", chunk)
+	expect("uploadChunk", "msg-3", chunk)
+	expect("uploadFile", "msg-4", func(params map[string]interface{}) {
+		assert.Equal(t, uploadID, params["uploadId"])
+		assert.Equal(t, "#import", params["selector"])
+	})
+
+	files, err := client.UploadFiles(context.Background(), 3, "#import", []string{filepath.Join(dir, "big.bin"), filepath.Join(dir, "users.json")})
+	require.NoError(t, err)
+	assert.Equal(t, []UploadedFile{
+		{Name: "big.bin", MimeType: "application/octet-stream", Size: int64(len(big))},
+		{Name: "users.json", MimeType: "application/json", Size: 8},
+	}, files)
+	assert.Equal(t, big, received[0])
+	assert.Equal(t, `{"id":1}`, string(received[1]))
+
+	_, err = client.UploadFiles(context.Background(), 3, "#import", []string{filepath.Join(dir, "missing.csv")})
+	assert.ErrorContains(t, err, "failed to open")
+	_, err = client.UploadFiles(context.Background(), 3, "#import", []string{dir})
+	assert.EqualError(t, err, "not a file: "+dir)
+
+	conn.AssertExpectations(t)
+}
@@ -0,0 +1,119 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// videoExtensions maps recording MIME types to file extensions
+var videoExtensions = map[string]string{
+	"video/webm": ".webm",
+	"video/mp4":  ".mp4",
+}
+
+// StartVideo starts recording a video of the tab. The extension stops the
+// recording by itself after maxDuration seconds, unless maxDuration is 0.
+func (c *Client) StartVideo(ctx context.Context, tabID int, maxDuration int) (json.RawMessage, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":  tabID,
+		"action": "start",
+	}
+
+	if maxDuration > 0 {
+		params["maxDuration"] = maxDuration
+	}
+
+	return c.sendCommand(ctx, "captureVideo", params)
+}
+
+// StopVideo stops recording the tab and saves the video in dir, or in the
+// temporary directory when dir is empty. The file is named after the base
+// name of name, or gets a timestamped name when name is empty.
+func (c *Client) StopVideo(ctx context.Context, tabID int, dir, name string) (*VideoRecording, error) {
+	tabID = c.resolveTabID(ctx, tabID)
+
+	params := map[string]interface{}{
+		"tabId":  tabID,
+		"action": "stop",
+	}
+
+	data, err := c.sendCommand(ctx, "captureVideo", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Browser extension returns {dataUrl, duration}
+	var response struct {
+		DataURL  string  `json:"dataUrl"`
+		Duration float64 `json:"duration"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	mimeType, video, err := DecodeDataURL(response.DataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid video data: %w", err)
+	}
+
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create video directory: %w", err)
+	}
+	path := filepath.Join(dir, videoName(name, tabID, mimeType))
+	if err := os.WriteFile(path, video, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save video: %w", err)
+	}
+
+	return &VideoRecording{
+		TabID:    tabID,
+		Path:     path,
+		MimeType: mimeType,
+		Size:     int64(len(video)),
+		Duration: response.Duration,
+	}, nil
+}
+
+// videoName returns the base name of a recording's file, or a timestamped
+// name with the extension of its MIME type when name has no base name
+func videoName(name string, tabID int, mimeType string) string {
+	if name := baseName(name); name != "" {
+		return name
+	}
+
+	ext, ok := videoExtensions[mimeType]
+	if !ok {
+		ext = ".webm"
+	}
+	return fmt.Sprintf("bract-video-%d-%s%s", tabID, time.Now().Format("20060102-150405"), ext)
+}
+
+// DecodeDataURL returns the MIME type and content of a base64 data URL, as
+// the extension returns screenshots, recordings and downloaded files
+func DecodeDataURL(dataURL string) (string, []byte, error) {
+	header, content, ok := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !ok || !strings.HasPrefix(dataURL, "data:") {
+		return "", nil, fmt.Errorf("not a data URL")
+	}
+
+	if !strings.HasSuffix(header, ";base64") {
+		return "", nil, fmt.Errorf("data URL is not base64 encoded")
+	}
+	mimeType, _, _ := strings.Cut(header, ";")
+
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	return mimeType, data, nil
+}
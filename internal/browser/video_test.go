@@ -0,0 +1,151 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_StartVideo(t *testing.T) {
+	client := NewClient(config.WebSocketConfig{ReconnectMs: 100})
+	mockConn := &MockConnection{}
+	client.SetConnection(mockConn)
+
+	params := map[string]interface{}{
+		"tabId":       123,
+		"action":      "start",
+		"maxDuration": 30,
+	}
+	mockConn.On("SendCommand", "captureVideo", params).Return("msg-start", nil)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		client.HandleResponse("msg-start", json.RawMessage(`{"success":true}`), "")
+	}()
+
+	_, err := client.StartVideo(context.Background(), 123, 30)
+	require.NoError(t, err)
+	mockConn.AssertExpectations(t)
+}
+
+func TestClient_StopVideo(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		tempDir  bool
+		response string
+		wantName string
+		wantExt  string
+		content  string
+		errMsg   string
+	}{
+		{
+			name:     "save under name",
+			fileName: "run.webm",
+			response: `{"dataUrl":"data:video/webm;base64,dmlkZW8=","duration":2.5}`,
+			wantName: "run.webm",
+			wantExt:  ".webm",
+			content:  "video",
+		},
+		{
+			name:     "save under base name of path",
+			fileName: "../../etc/run.webm",
+			response: `{"dataUrl":"data:video/webm;base64,dmlkZW8=","duration":2.5}`,
+			wantName: "run.webm",
+			wantExt:  ".webm",
+			content:  "video",
+		},
+		{
+			name:     "save under timestamped name",
+			fileName: "videos/",
+			response: `{"dataUrl":"data:video/mp4;codecs=avc1;base64,dmlkZW8=","duration":1}`,
+			wantExt:  ".mp4",
+			content:  "video",
+		},
+		{
+			name:     "save to temporary directory",
+			tempDir:  true,
+			response: `{"dataUrl":"data:video/mp4;codecs=avc1;base64,dmlkZW8=","duration":1}`,
+			wantExt:  ".mp4",
+			content:  "video",
+		},
+		{
+			name:     "invalid data URL",
+			response: `{"dataUrl":"video"}`,
+			errMsg:   "invalid video data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "videos")
+			if tt.tempDir {
+				dir = ""
+			}
+
+			client := NewClient(config.WebSocketConfig{ReconnectMs: 100})
+			mockConn := &MockConnection{}
+			client.SetConnection(mockConn)
+
+			params := map[string]interface{}{"tabId": 123, "action": "stop"}
+			mockConn.On("SendCommand", "captureVideo", params).Return("msg-stop", nil)
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				client.HandleResponse("msg-stop", json.RawMessage(tt.response), "")
+			}()
+
+			recording, err := client.StopVideo(context.Background(), 123, dir, tt.fileName)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() { os.Remove(recording.Path) })
+
+			if dir == "" {
+				dir = os.TempDir()
+			}
+			assert.Equal(t, dir, filepath.Dir(recording.Path))
+			if tt.wantName != "" {
+				assert.Equal(t, tt.wantName, filepath.Base(recording.Path))
+			}
+			assert.True(t, strings.HasSuffix(recording.Path, tt.wantExt), "path %s should end in %s", recording.Path, tt.wantExt)
+			assert.Equal(t, 123, recording.TabID)
+			assert.Equal(t, int64(len(tt.content)), recording.Size)
+
+			data, err := os.ReadFile(recording.Path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.content, string(data))
+			mockConn.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDecodeDataURL(t *testing.T) {
+	mimeType, data, err := DecodeDataURL("data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("png")))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, []byte("png"), data)
+
+	mimeType, _, err = DecodeDataURL("data:video/mp4;codecs=avc1;base64,dmlkZW8=")
+	require.NoError(t, err)
+	assert.Equal(t, "video/mp4", mimeType)
+
+	_, _, err = DecodeDataURL("https://example.com/shot.png")
+	assert.EqualError(t, err, "not a data URL")
+	_, _, err = DecodeDataURL("data:text/plain,hello")
+	assert.EqualError(t, err, "data URL is not base64 encoded")
+	_, _, err = DecodeDataURL("data:image/png;base64,!!!")
+	assert.ErrorContains(t, err, "invalid base64 data")
+}
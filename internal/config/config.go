@@ -16,10 +16,22 @@ type Config struct {
 	Logging   LoggingConfig   `yaml:"logging"`
 }
 
+// MCP transports supported by the server
+const (
+	TransportStdio          = "stdio"
+	TransportSSE            = "sse"
+	TransportStreamableHTTP = "http"
+)
+
 // ServerConfig contains MCP server settings
 type ServerConfig struct {
-	Name    string `yaml:"name"`
-	Version string `yaml:"version"`
+	Name               string `yaml:"name"`
+	Version            string `yaml:"version"`
+	Transport          string `yaml:"transport"`            // stdio, sse or http
+	Host               string `yaml:"host"`                 // listen host for the sse and http transports
+	Port               int    `yaml:"port"`                 // listen port for the sse and http transports
+	PublicURL          string `yaml:"public_url"`           // URL clients reach the sse transport at, when not the listen address
+	SessionIdleTimeout int    `yaml:"session_idle_timeout"` // seconds before idle streamable HTTP sessions are released (0 never)
 }
 
 // WebSocketConfig contains WebSocket server settings
@@ -33,8 +45,18 @@ type WebSocketConfig struct {
 
 // BrowserConfig contains browser automation settings
 type BrowserConfig struct {
-	DefaultTimeout int `yaml:"default_timeout"`
-	MaxTabs        int `yaml:"max_tabs"`
+	DefaultTimeout     int    `yaml:"default_timeout"`
+	MaxTabs            int    `yaml:"max_tabs"`
+	CloseSessionTabs   bool   `yaml:"close_session_tabs"`   // close a session's tabs when it disconnects
+	EventBufferSize    int    `yaml:"event_buffer_size"`    // browser events kept for browser_get_events
+	ConsoleBufferSize  int    `yaml:"console_buffer_size"`  // console messages kept per tab for browser_get_console
+	ScreenshotDir      string `yaml:"screenshot_dir"`       // directory screenshots are saved in instead of being returned
+	ScreenshotMaxBytes int    `yaml:"screenshot_max_bytes"` // size screenshots returned inline are downscaled to fit in
+	BaselineDir        string `yaml:"baseline_dir"`         // directory of the baseline screenshots of browser_screenshot_compare
+	DownloadDir        string `yaml:"download_dir"`         // directory browser_wait_for_download copies finished downloads into
+	VideoDir           string `yaml:"video_dir"`            // directory browser_stop_video saves recordings in
+	HARDir             string `yaml:"har_dir"`              // directory browser_network_stop saves HAR files in
+	RoutesDir          string `yaml:"routes_dir"`           // directory browser_route_add reads bodyFile from
 }
 
 // LoggingConfig contains logging settings
@@ -47,8 +69,12 @@ type LoggingConfig struct {
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Name:    "Browser Automation Server",
-			Version: "1.0.0",
+			Name:               "Browser Automation Server",
+			Version:            "1.0.0",
+			Transport:          TransportStdio,
+			Host:               "localhost",
+			Port:               8766,
+			SessionIdleTimeout: 1800,
 		},
 		WebSocket: WebSocketConfig{
 			Host:         "localhost",
@@ -62,8 +88,12 @@ func DefaultConfig() *Config {
 			},
 		},
 		Browser: BrowserConfig{
-			DefaultTimeout: 30000,
-			MaxTabs:        100,
+			DefaultTimeout:     30000,
+			MaxTabs:            100,
+			EventBufferSize:    1000,
+			ConsoleBufferSize:  500,
+			ScreenshotMaxBytes: 1 << 20,
+			BaselineDir:        "baselines",
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -104,6 +134,15 @@ func Load() (*Config, error) {
 	}
 
 	// Override with environment variables if set
+	if transport := os.Getenv("MCP_BROWSER_TRANSPORT"); transport != "" {
+		cfg.Server.Transport = transport
+	}
+	if port := os.Getenv("MCP_BROWSER_HTTP_PORT"); port != "" {
+		var p int
+		if _, err := fmt.Sscanf(port, "%d", &p); err == nil {
+			cfg.Server.Port = p
+		}
+	}
 	if host := os.Getenv("MCP_BROWSER_WS_HOST"); host != "" {
 		cfg.WebSocket.Host = host
 	}
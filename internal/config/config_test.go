@@ -14,12 +14,22 @@ func TestDefaultConfig(t *testing.T) {
 
 	assert.Equal(t, "Browser Automation Server", cfg.Server.Name)
 	assert.Equal(t, "1.0.0", cfg.Server.Version)
+	assert.Equal(t, TransportStdio, cfg.Server.Transport)
+	assert.Equal(t, "localhost", cfg.Server.Host)
+	assert.Equal(t, 8766, cfg.Server.Port)
 	assert.Equal(t, "localhost", cfg.WebSocket.Host)
 	assert.Equal(t, 8765, cfg.WebSocket.Port)
 	assert.Equal(t, 5000, cfg.WebSocket.ReconnectMs)
 	assert.Equal(t, 30, cfg.WebSocket.PingInterval)
 	assert.Equal(t, 30000, cfg.Browser.DefaultTimeout)
 	assert.Equal(t, 100, cfg.Browser.MaxTabs)
+	assert.False(t, cfg.Browser.CloseSessionTabs)
+	assert.Equal(t, 1800, cfg.Server.SessionIdleTimeout)
+	assert.Equal(t, 1000, cfg.Browser.EventBufferSize)
+	assert.Equal(t, 500, cfg.Browser.ConsoleBufferSize)
+	assert.Equal(t, 1<<20, cfg.Browser.ScreenshotMaxBytes)
+	assert.Empty(t, cfg.Browser.ScreenshotDir)
+	assert.Equal(t, "baselines", cfg.Browser.BaselineDir)
 	assert.Equal(t, "info", cfg.Logging.Level)
 	assert.Equal(t, "json", cfg.Logging.Format)
 }
@@ -251,6 +261,50 @@ websocket:
 	assert.Equal(t, 9999, cfg.WebSocket.Port)
 }
 
+func TestLoad_TransportConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	configContent := `
+server:
+  transport: sse
+  host: "0.0.0.0"
+  port: 9000
+`
+
+	err := os.WriteFile(configPath, []byte(configContent), 0o644)
+	require.NoError(t, err)
+
+	oldConfig := os.Getenv("MCP_BROWSER_CONFIG")
+	os.Setenv("MCP_BROWSER_CONFIG", configPath)
+	defer func() {
+		if oldConfig != "" {
+			os.Setenv("MCP_BROWSER_CONFIG", oldConfig)
+		} else {
+			os.Unsetenv("MCP_BROWSER_CONFIG")
+		}
+		os.Unsetenv("MCP_BROWSER_TRANSPORT")
+		os.Unsetenv("MCP_BROWSER_HTTP_PORT")
+	}()
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, TransportSSE, cfg.Server.Transport)
+	assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+	assert.Equal(t, 9000, cfg.Server.Port)
+
+	// Environment variables should override file config
+	os.Setenv("MCP_BROWSER_TRANSPORT", TransportStreamableHTTP)
+	os.Setenv("MCP_BROWSER_HTTP_PORT", "9100")
+
+	cfg, err = Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, TransportStreamableHTTP, cfg.Server.Transport)
+	assert.Equal(t, 9100, cfg.Server.Port)
+}
+
 func TestLoad_InvalidPortEnvironmentVariable(t *testing.T) {
 	// Save old env vars
 	oldPort := os.Getenv("MCP_BROWSER_WS_PORT")
@@ -12,6 +12,7 @@ type Node interface {
 // Script represents the root of a DSL script
 type Script struct {
 	Statements []Statement
+	File       string // file the script was read from, if any
 }
 
 func (s *Script) String() string {
@@ -22,8 +23,21 @@ func (s *Script) String() string {
 type Statement interface {
 	Node
 	statementNode()
+	StartLine() int
+	SetLine(line int)
 }
 
+// Position records where a statement starts in the DSL source
+type Position struct {
+	Line int
+}
+
+// StartLine returns the source line the statement starts on
+func (p *Position) StartLine() int { return p.Line }
+
+// SetLine sets the source line the statement starts on
+func (p *Position) SetLine(line int) { p.Line = line }
+
 // Expression represents an expression in the DSL
 type Expression interface {
 	Node
@@ -32,6 +46,7 @@ type Expression interface {
 
 // ConnectStatement connects to an MCP server
 type ConnectStatement struct {
+	Position
 	Server  Expression
 	Args    []Expression
 	Options map[string]Expression
@@ -44,9 +59,11 @@ func (c *ConnectStatement) String() string {
 
 // CallStatement calls an MCP tool
 type CallStatement struct {
+	Position
 	Tool      string
 	Arguments Expression
 	Variable  string // Optional: store result in variable
+	Try       bool   // try call: failures are stored in Variable instead of failing
 }
 
 func (c *CallStatement) statementNode() {}
@@ -54,19 +71,27 @@ func (c *CallStatement) String() string {
 	return fmt.Sprintf("Call{Tool: %s, Args: %v, Var: %s}", c.Tool, c.Arguments, c.Variable)
 }
 
-// AssertStatement makes an assertion
+// AssertStatement makes an assertion: an expression that must be true, or a
+// built-in check such as no_console_errors
 type AssertStatement struct {
+	Position
 	Expression Expression
+	Check      string     // Optional: built-in check made in place of the expression
+	Arguments  Expression // Optional: object literal with the check's arguments
 	Message    string
 }
 
 func (a *AssertStatement) statementNode() {}
 func (a *AssertStatement) String() string {
+	if a.Check != "" {
+		return fmt.Sprintf("Assert{Check: %s, Args: %v, Msg: %s}", a.Check, a.Arguments, a.Message)
+	}
 	return fmt.Sprintf("Assert{Expr: %v, Msg: %s}", a.Expression, a.Message)
 }
 
 // WaitStatement waits for a condition
 type WaitStatement struct {
+	Position
 	Condition Expression
 	Timeout   Expression
 	Interval  Expression
@@ -79,6 +104,7 @@ func (w *WaitStatement) String() string {
 
 // LoopStatement represents a loop
 type LoopStatement struct {
+	Position
 	Iterator   string
 	Collection Expression
 	Body       []Statement
@@ -91,6 +117,7 @@ func (l *LoopStatement) String() string {
 
 // IfStatement represents a conditional
 type IfStatement struct {
+	Position
 	Condition Expression
 	Then      []Statement
 	Else      []Statement
@@ -101,19 +128,23 @@ func (i *IfStatement) String() string {
 	return fmt.Sprintf("If{Condition: %v, Then: %v, Else: %v}", i.Condition, i.Then, i.Else)
 }
 
-// SetStatement sets a variable
+// SetStatement sets a variable. Outer assigns to an existing variable of an
+// enclosing automation or the script instead of the current one.
 type SetStatement struct {
+	Position
 	Variable string
 	Value    Expression
+	Outer    bool
 }
 
 func (s *SetStatement) statementNode() {}
 func (s *SetStatement) String() string {
-	return fmt.Sprintf("Set{Var: %s, Value: %v}", s.Variable, s.Value)
+	return fmt.Sprintf("Set{Var: %s, Value: %v, Outer: %t}", s.Variable, s.Value, s.Outer)
 }
 
 // PrintStatement prints output
 type PrintStatement struct {
+	Position
 	Expression Expression
 }
 
@@ -124,6 +155,7 @@ func (p *PrintStatement) String() string {
 
 // DefineStatement defines a reusable automation
 type DefineStatement struct {
+	Position
 	Name       string
 	Parameters []string
 	Body       []Statement
@@ -136,13 +168,101 @@ func (d *DefineStatement) String() string {
 
 // RunStatement runs a defined automation
 type RunStatement struct {
+	Position
 	Name      string
 	Arguments []Expression
+	Variable  string // Optional: store the returned value in variable
 }
 
 func (r *RunStatement) statementNode() {}
 func (r *RunStatement) String() string {
-	return fmt.Sprintf("Run{Name: %s, Args: %v}", r.Name, r.Arguments)
+	return fmt.Sprintf("Run{Name: %s, Args: %v, Var: %s}", r.Name, r.Arguments, r.Variable)
+}
+
+// ReturnStatement ends the running automation, returning Value
+type ReturnStatement struct {
+	Position
+	Value Expression // Optional
+}
+
+func (r *ReturnStatement) statementNode() {}
+func (r *ReturnStatement) String() string {
+	return fmt.Sprintf("Return{Value: %v}", r.Value)
+}
+
+// TestStatement defines a named test case
+type TestStatement struct {
+	Position
+	Name string
+	Body []Statement
+}
+
+func (t *TestStatement) statementNode() {}
+func (t *TestStatement) String() string {
+	return fmt.Sprintf("Test{Name: %s, Body: %v}", t.Name, t.Body)
+}
+
+// Hook kinds
+const (
+	HookSetup      = "setup"
+	HookTeardown   = "teardown"
+	HookBeforeEach = "before_each"
+	HookAfterEach  = "after_each"
+)
+
+// HookStatement defines a block run around tests: once before the first test
+// (setup), once after all tests (teardown), or around every test
+// (before_each, after_each)
+type HookStatement struct {
+	Position
+	Kind string
+	Body []Statement
+}
+
+func (h *HookStatement) statementNode() {}
+func (h *HookStatement) String() string {
+	return fmt.Sprintf("Hook{Kind: %s, Body: %v}", h.Kind, h.Body)
+}
+
+// ImportStatement imports the automations defined in another DSL file under
+// a namespace. Library is set when the import is resolved.
+type ImportStatement struct {
+	Position
+	Path      string
+	Namespace string
+	Library   *Script
+}
+
+func (i *ImportStatement) statementNode() {}
+func (i *ImportStatement) String() string {
+	return fmt.Sprintf("Import{Path: %s, Namespace: %s}", i.Path, i.Namespace)
+}
+
+// TryStatement runs a block and, if it fails, runs the catch block with the
+// error bound to ErrorVar
+type TryStatement struct {
+	Position
+	Body     []Statement
+	ErrorVar string // Optional: variable holding the caught error
+	Catch    []Statement
+}
+
+func (t *TryStatement) statementNode() {}
+func (t *TryStatement) String() string {
+	return fmt.Sprintf("Try{Body: %v, ErrorVar: %s, Catch: %v}", t.Body, t.ErrorVar, t.Catch)
+}
+
+// ExpectErrorStatement makes a tool call that is expected to fail. The
+// call's result variable receives the error.
+type ExpectErrorStatement struct {
+	Position
+	Call    *CallStatement
+	Pattern string // Optional: regular expression the error message must match
+}
+
+func (e *ExpectErrorStatement) statementNode() {}
+func (e *ExpectErrorStatement) String() string {
+	return fmt.Sprintf("ExpectError{Call: %v, Pattern: %s}", e.Call, e.Pattern)
 }
 
 // StringLiteral represents a string value
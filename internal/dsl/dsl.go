@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/periplon/bract/internal/dsl/ast"
@@ -25,30 +29,54 @@ func NewInterpreter() *Interpreter {
 	}
 }
 
-// ExecuteString executes a DSL script from a string
+// ExecuteString executes a DSL script from a string. Imports are resolved
+// against the working directory.
 func (i *Interpreter) ExecuteString(ctx context.Context, script string) error {
 	// Parse the script
 	ast, err := ParseString(script)
+	if err == nil {
+		err = ResolveImports(ast, "")
+	}
 	if err != nil {
 		return fmt.Errorf("parse error: %w", err)
 	}
 
-	// Execute the AST
-	if err := i.runtime.Execute(ctx, ast); err != nil {
-		return fmt.Errorf("runtime error: %w", err)
-	}
-
-	return nil
+	return i.execute(ctx, ast)
 }
 
-// ExecuteFile executes a DSL script from a file
+// ExecuteFile executes a DSL script from a file. Imports and files read
+// with read_file are resolved against the script's directory.
 func (i *Interpreter) ExecuteFile(ctx context.Context, filename string) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return i.ExecuteString(ctx, string(data))
+	ast, err := ParseString(string(data))
+	if err == nil {
+		err = ResolveImports(ast, filename)
+	}
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	ast.File = filename
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	i.runtime.SetDir(dir)
+
+	return i.execute(ctx, ast)
+}
+
+// execute runs a parsed script
+func (i *Interpreter) execute(ctx context.Context, script *ast.Script) error {
+	if err := i.runtime.Execute(ctx, script); err != nil {
+		return fmt.Errorf("runtime error: %w", err)
+	}
+
+	return nil
 }
 
 // ExecuteReader executes a DSL script from a reader
@@ -66,6 +94,28 @@ func (i *Interpreter) GetOutput() string {
 	return i.runtime.GetOutput()
 }
 
+// OnStep registers fn to be called after every call and assert statement
+func (i *Interpreter) OnStep(fn func(runtime.Step)) {
+	i.runtime.OnStep(fn)
+}
+
+// SetStdout sets where print output is written, os.Stdout by default
+func (i *Interpreter) SetStdout(w io.Writer) {
+	i.runtime.SetStdout(w)
+}
+
+// SetUpdateBaselines makes assert screenshot_matches replace the baseline
+// screenshots instead of comparing with them
+func (i *Interpreter) SetUpdateBaselines(update bool) {
+	i.runtime.SetUpdateBaselines(update)
+}
+
+// UseClient runs scripts against an already connected client instead of the
+// server named by their connect statement
+func (i *Interpreter) UseClient(client *mcpclient.Client) {
+	i.runtime.UseClient(client)
+}
+
 // GetClient returns the MCP client
 func (i *Interpreter) GetClient() *mcpclient.Client {
 	return i.runtime.GetClient()
@@ -85,14 +135,21 @@ func ParseString(script string) (*ast.Script, error) {
 	return p.Parse()
 }
 
-// ParseFile parses a DSL script from a file
+// ParseFile parses a DSL script from a file and resolves its imports
 func ParseFile(filename string) (*ast.Script, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return ParseString(string(data))
+	script, err := ParseString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := ResolveImports(script, filename); err != nil {
+		return nil, err
+	}
+	return script, nil
 }
 
 // ValidateString validates a DSL script syntax without executing it
@@ -101,7 +158,8 @@ func ValidateString(script string) error {
 	return err
 }
 
-// ValidateFile validates a DSL script file syntax without executing it
+// ValidateFile validates a DSL script file syntax, including the files it
+// imports, without executing it
 func ValidateFile(filename string) error {
 	_, err := ParseFile(filename)
 	return err
@@ -162,7 +220,38 @@ func (f *astFormatter) formatStatement(sb *strings.Builder, stmt ast.Statement)
 		f.formatDefine(sb, s)
 	case *ast.RunStatement:
 		f.formatRun(sb, s)
+	case *ast.TestStatement:
+		sb.WriteString(fmt.Sprintf("test %q", s.Name))
+		f.formatBody(sb, s.Body)
+	case *ast.HookStatement:
+		sb.WriteString(s.Kind)
+		f.formatBody(sb, s.Body)
+	case *ast.ReturnStatement:
+		sb.WriteString("return")
+		if s.Value != nil {
+			sb.WriteString(" ")
+			f.formatExpression(sb, s.Value)
+		}
+		sb.WriteString("\n")
+	case *ast.ImportStatement:
+		sb.WriteString(fmt.Sprintf("import %q\n", s.Path))
+	case *ast.TryStatement:
+		f.formatTry(sb, s)
+	case *ast.ExpectErrorStatement:
+		f.formatExpectError(sb, s)
+	}
+}
+
+// formatBody formats a braced block of statements
+func (f *astFormatter) formatBody(sb *strings.Builder, body []ast.Statement) {
+	sb.WriteString(" {\n")
+	f.indent++
+	for _, s := range body {
+		f.formatStatement(sb, s)
 	}
+	f.indent--
+	f.writeIndent(sb)
+	sb.WriteString("}\n")
 }
 
 func (f *astFormatter) formatConnect(sb *strings.Builder, stmt *ast.ConnectStatement) {
@@ -177,11 +266,11 @@ func (f *astFormatter) formatConnect(sb *strings.Builder, stmt *ast.ConnectState
 	if len(stmt.Options) > 0 {
 		sb.WriteString(" {\n")
 		f.indent++
-		for name, value := range stmt.Options {
+		for _, name := range sortedKeys(stmt.Options) {
 			f.writeIndent(sb)
 			sb.WriteString(name)
 			sb.WriteString(": ")
-			f.formatExpression(sb, value)
+			f.formatExpression(sb, stmt.Options[name])
 			sb.WriteString("\n")
 		}
 		f.indent--
@@ -192,6 +281,9 @@ func (f *astFormatter) formatConnect(sb *strings.Builder, stmt *ast.ConnectState
 }
 
 func (f *astFormatter) formatCall(sb *strings.Builder, stmt *ast.CallStatement) {
+	if stmt.Try {
+		sb.WriteString("try ")
+	}
 	sb.WriteString("call ")
 	sb.WriteString(stmt.Tool)
 
@@ -207,9 +299,37 @@ func (f *astFormatter) formatCall(sb *strings.Builder, stmt *ast.CallStatement)
 	sb.WriteString("\n")
 }
 
+func (f *astFormatter) formatExpectError(sb *strings.Builder, stmt *ast.ExpectErrorStatement) {
+	sb.WriteString("expect_error call ")
+	sb.WriteString(stmt.Call.Tool)
+
+	if stmt.Call.Arguments != nil {
+		sb.WriteString(" ")
+		f.formatExpression(sb, stmt.Call.Arguments)
+	}
+
+	if stmt.Pattern != "" {
+		sb.WriteString(fmt.Sprintf(" matches %q", stmt.Pattern))
+	}
+
+	if stmt.Call.Variable != "" {
+		sb.WriteString(" -> ")
+		sb.WriteString(stmt.Call.Variable)
+	}
+	sb.WriteString("\n")
+}
+
 func (f *astFormatter) formatAssert(sb *strings.Builder, stmt *ast.AssertStatement) {
 	sb.WriteString("assert ")
-	f.formatExpression(sb, stmt.Expression)
+	if stmt.Check != "" {
+		sb.WriteString(stmt.Check)
+		if stmt.Arguments != nil {
+			sb.WriteString(" ")
+			f.formatExpression(sb, stmt.Arguments)
+		}
+	} else {
+		f.formatExpression(sb, stmt.Expression)
+	}
 
 	if stmt.Message != "" {
 		sb.WriteString(", ")
@@ -251,6 +371,23 @@ func (f *astFormatter) formatLoop(sb *strings.Builder, stmt *ast.LoopStatement)
 	sb.WriteString("}\n")
 }
 
+func (f *astFormatter) formatTry(sb *strings.Builder, stmt *ast.TryStatement) {
+	sb.WriteString("try {\n")
+	f.indent++
+	for _, s := range stmt.Body {
+		f.formatStatement(sb, s)
+	}
+	f.indent--
+
+	f.writeIndent(sb)
+	sb.WriteString("} catch")
+	if stmt.ErrorVar != "" {
+		sb.WriteString(" ")
+		sb.WriteString(stmt.ErrorVar)
+	}
+	f.formatBody(sb, stmt.Catch)
+}
+
 func (f *astFormatter) formatIf(sb *strings.Builder, stmt *ast.IfStatement) {
 	sb.WriteString("if ")
 	f.formatExpression(sb, stmt.Condition)
@@ -289,6 +426,9 @@ func (f *astFormatter) formatIf(sb *strings.Builder, stmt *ast.IfStatement) {
 
 func (f *astFormatter) formatSet(sb *strings.Builder, stmt *ast.SetStatement) {
 	sb.WriteString("set ")
+	if stmt.Outer {
+		sb.WriteString("outer ")
+	}
 	sb.WriteString(stmt.Variable)
 	sb.WriteString(" = ")
 	f.formatExpression(sb, stmt.Value)
@@ -340,6 +480,11 @@ func (f *astFormatter) formatRun(sb *strings.Builder, stmt *ast.RunStatement) {
 		}
 		sb.WriteString(")")
 	}
+
+	if stmt.Variable != "" {
+		sb.WriteString(" -> ")
+		sb.WriteString(stmt.Variable)
+	}
 	sb.WriteString("\n")
 }
 
@@ -348,22 +493,20 @@ func (f *astFormatter) formatExpression(sb *strings.Builder, expr ast.Expression
 	case *ast.StringLiteral:
 		sb.WriteString(fmt.Sprintf("%q", e.Value))
 	case *ast.NumberLiteral:
-		sb.WriteString(fmt.Sprintf("%v", e.Value))
+		sb.WriteString(strconv.FormatFloat(e.Value, 'f', -1, 64))
 	case *ast.BooleanLiteral:
 		sb.WriteString(fmt.Sprintf("%v", e.Value))
 	case *ast.Variable:
 		sb.WriteString(e.Name)
 	case *ast.ObjectLiteral:
 		sb.WriteString("{")
-		first := true
-		for k, v := range e.Fields {
-			if !first {
+		for i, k := range sortedKeys(e.Fields) {
+			if i > 0 {
 				sb.WriteString(", ")
 			}
-			first = false
-			sb.WriteString(k)
+			f.formatKey(sb, k)
 			sb.WriteString(": ")
-			f.formatExpression(sb, v)
+			f.formatExpression(sb, e.Fields[k])
 		}
 		sb.WriteString("}")
 	case *ast.ArrayLiteral:
@@ -408,6 +551,28 @@ func (f *astFormatter) formatExpression(sb *strings.Builder, expr ast.Expression
 	}
 }
 
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// formatKey writes an object key, quoting it unless it lexes as a plain identifier
+func (f *astFormatter) formatKey(sb *strings.Builder, key string) {
+	tokens, err := parser.NewLexer(key).Tokenize()
+	if identifierPattern.MatchString(key) && err == nil && len(tokens) > 0 && tokens[0].Type == parser.TokenIdentifier {
+		sb.WriteString(key)
+		return
+	}
+	sb.WriteString(fmt.Sprintf("%q", key))
+}
+
+// sortedKeys returns the keys of m in sorted order, so formatting is deterministic
+func sortedKeys(m map[string]ast.Expression) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (f *astFormatter) writeIndent(sb *strings.Builder) {
 	for i := 0; i < f.indent; i++ {
 		sb.WriteString("  ")
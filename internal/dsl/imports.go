@@ -0,0 +1,129 @@
+package dsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/periplon/bract/internal/dsl/ast"
+)
+
+// IsLibrary reports whether a script only defines automations, which makes
+// it importable
+func IsLibrary(script *ast.Script) bool {
+	for _, stmt := range script.Statements {
+		switch stmt.(type) {
+		case *ast.DefineStatement, *ast.ImportStatement:
+		default:
+			return false
+		}
+	}
+	return len(script.Statements) > 0
+}
+
+// ResolveImports loads the libraries imported by script and by those
+// libraries in turn. Relative paths are resolved against the directory of
+// the importing file; file is the script's own path, or "" if it was not read
+// from a file, in which case imports are resolved against the working
+// directory.
+func ResolveImports(script *ast.Script, file string) error {
+	r := &importResolver{libraries: make(map[string]*ast.Script)}
+
+	dir := "."
+	var stack []string
+	if file != "" {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		dir = filepath.Dir(abs)
+		stack = []string{abs}
+	}
+	return r.resolve(script, dir, stack)
+}
+
+// importResolver loads each imported library once
+type importResolver struct {
+	libraries map[string]*ast.Script // absolute path -> resolved library
+}
+
+// resolve resolves the imports of script, which lives in dir. stack holds the
+// files currently being resolved, outermost first.
+func (r *importResolver) resolve(script *ast.Script, dir string, stack []string) error {
+	namespaces := make(map[string]string)
+
+	for _, stmt := range script.Statements {
+		imp, ok := stmt.(*ast.ImportStatement)
+		if !ok {
+			continue
+		}
+
+		path := imp.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", imp.Line, err)
+		}
+
+		if other, ok := namespaces[imp.Namespace]; ok && other != path {
+			return fmt.Errorf("line %d: namespace %q is already imported from %s", imp.Line, imp.Namespace, other)
+		}
+		namespaces[imp.Namespace] = path
+
+		for i, file := range stack {
+			if file == path {
+				return fmt.Errorf("line %d: import cycle: %s", imp.Line, cycle(append(stack[i:], path)))
+			}
+		}
+
+		library, ok := r.libraries[path]
+		if !ok {
+			if library, err = r.load(path, stack); err != nil {
+				return fmt.Errorf("line %d: import %q: %w", imp.Line, imp.Path, err)
+			}
+			r.libraries[path] = library
+		}
+		imp.Library = library
+	}
+
+	return nil
+}
+
+// load parses the library at path and resolves its own imports
+func (r *importResolver) load(path string, stack []string) (*ast.Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	library, err := ParseString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	library.File = path
+
+	for _, stmt := range library.Statements {
+		switch stmt.(type) {
+		case *ast.DefineStatement, *ast.ImportStatement:
+		default:
+			return nil, fmt.Errorf("line %d: a library may only contain define and import statements", stmt.StartLine())
+		}
+	}
+
+	if err := r.resolve(library, filepath.Dir(path), append(stack, path)); err != nil {
+		return nil, err
+	}
+	return library, nil
+}
+
+// cycle formats an import cycle for display
+func cycle(files []string) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,209 @@
+package dsl
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/periplon/bract/internal/dsl/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the named files into dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"tests/main.dsl": `import "../lib/common.dsl"
+import "../lib/page-utils.dsl"
+
+run common.greet("world")
+run page_utils.title()
+`,
+		"lib/common.dsl": `# Shared automations
+import "strings.dsl"
+
+define greet(name) {
+  run banner()
+  run strings.hello()
+  print name
+}
+
+define banner() {
+  print "== common =="
+}
+`,
+		"lib/strings.dsl": `define hello() {
+  print "hello"
+}
+`,
+		"lib/page-utils.dsl": `define title() {
+  print "title"
+}
+`,
+	})
+
+	main := filepath.Join(dir, "tests", "main.dsl")
+	require.NoError(t, ValidateFile(main))
+
+	interpreter := NewInterpreter()
+	interpreter.SetStdout(io.Discard)
+	require.NoError(t, interpreter.ExecuteFile(context.Background(), main))
+	assert.Equal(t, "== common ==\nhello\nworld\ntitle\n", interpreter.GetOutput())
+
+	// Imported automations are only reachable through their namespace
+	interpreter = NewInterpreter()
+	interpreter.SetStdout(io.Discard)
+	writeFiles(t, dir, map[string]string{"tests/bare.dsl": "import \"../lib/common.dsl\"\nrun banner()"})
+	err := interpreter.ExecuteFile(context.Background(), filepath.Join(dir, "tests", "bare.dsl"))
+	assert.ErrorContains(t, err, "automation 'banner' not defined")
+}
+
+func TestImports_StepFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.dsl": `import "lib/checks.dsl"
+
+assert true, "main"
+run checks.one(2)
+`,
+		"lib/checks.dsl": `define one(x) {
+  assert x == 1, "x is one"
+}
+`,
+	})
+
+	interpreter := NewInterpreter()
+	interpreter.SetStdout(io.Discard)
+	var steps []runtime.Step
+	interpreter.OnStep(func(step runtime.Step) { steps = append(steps, step) })
+
+	main := filepath.Join(dir, "main.dsl")
+	err := interpreter.ExecuteFile(context.Background(), main)
+	require.Error(t, err)
+
+	// Steps and errors inside a library are located in the library
+	library := filepath.Join(dir, "lib", "checks.dsl")
+	require.Len(t, steps, 2)
+	assert.Equal(t, main, steps[0].File)
+	assert.Equal(t, 3, steps[0].Line)
+	assert.Equal(t, library, steps[1].File)
+	assert.Equal(t, 2, steps[1].Line)
+
+	var rtErr *runtime.Error
+	require.ErrorAs(t, err, &rtErr)
+	assert.Equal(t, library, rtErr.File)
+	assert.Equal(t, 2, rtErr.Line)
+}
+
+func TestImports_ReadFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"tests/main.dsl": `import "../lib/fixtures.dsl"
+
+print read_file("name.txt")
+print fixtures.user()
+`,
+		"tests/name.txt": "main",
+		"lib/fixtures.dsl": `define user() {
+  return read_file("data/user.json")
+}
+`,
+		"lib/data/user.json": `{"name":"Ada"}`,
+	})
+
+	// Relative paths are resolved against the file of the calling code
+	interpreter := NewInterpreter()
+	interpreter.SetStdout(io.Discard)
+	require.NoError(t, interpreter.ExecuteFile(context.Background(), filepath.Join(dir, "tests", "main.dsl")))
+	assert.Equal(t, "main\n{\"name\":\"Ada\"}\n", interpreter.GetOutput())
+}
+
+func TestImports_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"cycle-a.dsl":     `import "cycle_b.dsl"`,
+		"cycle_b.dsl":     `import "cycle-a.dsl"`,
+		"self.dsl":        "import \"self.dsl\"\ndefine x() { print 1 }",
+		"script.dsl":      `print "not a library"`,
+		"broken.dsl":      `define broken( {`,
+		"other/utils.dsl": `define x() { print 1 }`,
+		"utils.dsl":       `define y() { print 2 }`,
+	})
+
+	tests := []struct {
+		name   string
+		script string
+		err    string
+	}{
+		{
+			name:   "missing file",
+			script: `import "missing.dsl"`,
+			err:    `line 1: import "missing.dsl": open`,
+		},
+		{
+			name:   "cycle",
+			script: `import "cycle-a.dsl"`,
+			err:    "import cycle: cycle-a.dsl -> cycle_b.dsl -> cycle-a.dsl",
+		},
+		{
+			name:   "library importing itself",
+			script: `import "self.dsl"`,
+			err:    `import "self.dsl": line 1: import cycle: self.dsl -> self.dsl`,
+		},
+		{
+			name:   "not a library",
+			script: "print 1\nimport \"script.dsl\"",
+			err:    `line 2: import "script.dsl": line 1: a library may only contain define and import statements`,
+		},
+		{
+			name:   "syntax error in library",
+			script: `import "broken.dsl"`,
+			err:    `import "broken.dsl": `,
+		},
+		{
+			name:   "namespace clash",
+			script: "import \"utils.dsl\"\nimport \"other/utils.dsl\"",
+			err:    `line 2: namespace "utils" is already imported from`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "main-"+tt.name+".dsl")
+			writeFiles(t, dir, map[string]string{filepath.Base(path): tt.script})
+
+			err := ValidateFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	// A script importing itself is a cycle
+	err := ValidateFile(filepath.Join(dir, "self.dsl"))
+	assert.ErrorContains(t, err, "import cycle: self.dsl -> self.dsl")
+
+	// Syntax is checked without resolving imports
+	assert.NoError(t, ValidateString(`import "missing.dsl"`))
+	_, err = ParseString(`import "wait.dsl"`)
+	assert.ErrorContains(t, err, `namespace "wait" is a keyword`)
+	_, err = ParseString(`if true { import "utils.dsl" }`)
+	assert.ErrorContains(t, err, "import must be at the top level")
+}
+
+func TestFormatScript_Import(t *testing.T) {
+	formatted, err := FormatScript("import 'lib/common.dsl'\nrun common.login(\"me\")")
+	require.NoError(t, err)
+	assert.Equal(t, "import \"lib/common.dsl\"\n\nrun common.login(\"me\")\n", formatted)
+}
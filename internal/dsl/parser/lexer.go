@@ -346,25 +346,29 @@ func (l *Lexer) readIdentifier() error {
 	return nil
 }
 
-func (l *Lexer) getKeywordType(value string) TokenType {
-	keywords := map[string]TokenType{
-		"connect": TokenConnect,
-		"call":    TokenCall,
-		"assert":  TokenAssert,
-		"wait":    TokenWait,
-		"loop":    TokenLoop,
-		"in":      TokenIn,
-		"if":      TokenIf,
-		"else":    TokenElse,
-		"set":     TokenSet,
-		"print":   TokenPrint,
-		"define":  TokenDefine,
-		"run":     TokenRun,
-		"true":    TokenTrue,
-		"false":   TokenFalse,
-		"null":    TokenNull,
-	}
+// keywords are the reserved words of the language. Words such as test, try,
+// matches, import or return are contextual keywords instead: they are lexed
+// as identifiers, and the parser only treats them as keywords where their
+// statement or clause can start, so scripts can still use them as names.
+var keywords = map[string]TokenType{
+	"connect": TokenConnect,
+	"call":    TokenCall,
+	"assert":  TokenAssert,
+	"wait":    TokenWait,
+	"loop":    TokenLoop,
+	"in":      TokenIn,
+	"if":      TokenIf,
+	"else":    TokenElse,
+	"set":     TokenSet,
+	"print":   TokenPrint,
+	"define":  TokenDefine,
+	"run":     TokenRun,
+	"true":    TokenTrue,
+	"false":   TokenFalse,
+	"null":    TokenNull,
+}
 
+func (l *Lexer) getKeywordType(value string) TokenType {
 	if tokenType, ok := keywords[strings.ToLower(value)]; ok {
 		return tokenType
 	}
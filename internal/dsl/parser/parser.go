@@ -2,7 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/periplon/bract/internal/dsl/ast"
 )
@@ -11,6 +14,8 @@ import (
 type Parser struct {
 	tokens  []Token
 	current int
+	depth   int // block nesting depth
+	defines int // nesting depth of define bodies
 }
 
 // NewParser creates a new parser
@@ -49,6 +54,16 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 	// Skip newlines
 	p.consumeNewlines()
 
+	line := p.peek().Line
+	stmt, err := p.parseStatementKind()
+	if err != nil || stmt == nil {
+		return nil, err
+	}
+	stmt.SetLine(line)
+	return stmt, nil
+}
+
+func (p *Parser) parseStatementKind() (ast.Statement, error) {
 	switch {
 	case p.match(TokenConnect):
 		return p.parseConnect()
@@ -70,6 +85,18 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 		return p.parseDefine()
 	case p.match(TokenRun):
 		return p.parseRun()
+	case p.matchKeyword("test"):
+		return p.parseTest()
+	case p.matchKeyword("setup", "teardown", "before_each", "after_each"):
+		return p.parseHook()
+	case p.matchKeyword("return"):
+		return p.parseReturn()
+	case p.matchKeyword("import"):
+		return p.parseImport()
+	case p.matchKeyword("try"):
+		return p.parseTry()
+	case p.matchKeyword("expect_error"):
+		return p.parseExpectError()
 	case p.check(TokenIdentifier):
 		// Could be a variable assignment or a call
 		if p.checkAhead(TokenAssign) {
@@ -156,7 +183,9 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 	stmt.Tool = toolToken.Value
 
 	// Parse optional arguments
-	if !p.checkNewlineOrEOF() && !p.check(TokenArrow) {
+	// An argument cannot be followed by a string, so matches followed by one
+	// is the pattern of expect_error
+	if !p.checkNewlineOrEOF() && !p.check(TokenArrow) && !(p.checkKeyword("matches") && p.checkAhead(TokenString)) {
 		args, err := p.parseExpression()
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse arguments: %w", err)
@@ -176,15 +205,34 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 	return stmt, nil
 }
 
+// assertChecks are the built-in checks an assert statement can make in place
+// of evaluating an expression
+var assertChecks = map[string]bool{
+	"no_console_errors":  true,
+	"screenshot_matches": true,
+}
+
 func (p *Parser) parseAssert() (ast.Statement, error) {
 	stmt := &ast.AssertStatement{}
 
-	// Parse condition
-	expr, err := p.parseExpression()
-	if err != nil {
-		return nil, fmt.Errorf("expected expression after 'assert': %w", err)
+	if p.check(TokenIdentifier) && assertChecks[p.peek().Value] {
+		// Parse built-in check with optional arguments
+		stmt.Check = p.advance().Value
+		if p.check(TokenLeftBrace) {
+			args, err := p.parseExpression()
+			if err != nil {
+				return nil, fmt.Errorf("expected arguments after '%s': %w", stmt.Check, err)
+			}
+			stmt.Arguments = args
+		}
+	} else {
+		// Parse condition
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, fmt.Errorf("expected expression after 'assert': %w", err)
+		}
+		stmt.Expression = expr
 	}
-	stmt.Expression = expr
 
 	// Parse optional message
 	if p.match(TokenComma) {
@@ -306,6 +354,12 @@ func (p *Parser) parseSet() (ast.Statement, error) {
 	// Skip 'set' if present
 	p.match(TokenSet)
 
+	// 'set outer x = ...' assigns to a variable outside the automation
+	if p.check(TokenIdentifier) && strings.EqualFold(p.peek().Value, "outer") && p.checkAhead(TokenIdentifier) {
+		p.advance()
+		stmt.Outer = true
+	}
+
 	// Parse variable name
 	if !p.check(TokenIdentifier) {
 		return nil, fmt.Errorf("expected variable name at line %d", p.peek().Line)
@@ -374,7 +428,9 @@ func (p *Parser) parseDefine() (ast.Statement, error) {
 	}
 
 	// Parse body
+	p.defines++
 	body, err := p.parseBlock()
+	p.defines--
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse automation body: %w", err)
 	}
@@ -388,11 +444,17 @@ func (p *Parser) parseRun() (ast.Statement, error) {
 		Arguments: []ast.Expression{},
 	}
 
-	// Parse automation name
+	// Parse automation name, qualified by namespace for imported automations
 	if !p.check(TokenIdentifier) {
 		return nil, fmt.Errorf("expected automation name after 'run' at line %d", p.peek().Line)
 	}
 	stmt.Name = p.advance().Value
+	for p.match(TokenDot) {
+		if !p.check(TokenIdentifier) {
+			return nil, fmt.Errorf("expected automation name after '.' at line %d", p.peek().Line)
+		}
+		stmt.Name += "." + p.advance().Value
+	}
 
 	// Parse optional arguments
 	if p.match(TokenLeftParen) {
@@ -415,13 +477,211 @@ func (p *Parser) parseRun() (ast.Statement, error) {
 		}
 	}
 
+	// Parse optional result variable
+	if p.match(TokenArrow) {
+		if !p.check(TokenIdentifier) {
+			return nil, fmt.Errorf("expected variable name after '->' at line %d", p.peek().Line)
+		}
+		stmt.Variable = p.advance().Value
+	}
+
+	p.consumeNewlines()
+	return stmt, nil
+}
+
+func (p *Parser) parseReturn() (ast.Statement, error) {
+	line := p.previous().Line
+	if p.defines == 0 {
+		return nil, fmt.Errorf("return outside of define at line %d", line)
+	}
+
+	stmt := &ast.ReturnStatement{}
+
+	// Parse optional value
+	if !p.checkNewlineOrEOF() && !p.check(TokenRightBrace) {
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, fmt.Errorf("expected return value: %w", err)
+		}
+		stmt.Value = value
+	}
+
+	p.consumeNewlines()
+	return stmt, nil
+}
+
+func (p *Parser) parseTest() (ast.Statement, error) {
+	line := p.previous().Line
+	if p.depth > 0 {
+		return nil, fmt.Errorf("test blocks must be at the top level (line %d)", line)
+	}
+
+	stmt := &ast.TestStatement{}
+
+	// Parse test name
+	if !p.check(TokenString) {
+		return nil, fmt.Errorf("expected test name after 'test' at line %d", p.peek().Line)
+	}
+	stmt.Name = p.advance().Value
+
+	// Parse body
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse test body: %w", err)
+	}
+	stmt.Body = body
+
+	return stmt, nil
+}
+
+func (p *Parser) parseHook() (ast.Statement, error) {
+	kind := p.previous()
+	if p.depth > 0 {
+		return nil, fmt.Errorf("%s blocks must be at the top level (line %d)", kind.Value, kind.Line)
+	}
+
+	stmt := &ast.HookStatement{
+		Kind: strings.ToLower(kind.Value),
+	}
+
+	// Parse body
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s body: %w", stmt.Kind, err)
+	}
+	stmt.Body = body
+
+	return stmt, nil
+}
+
+func (p *Parser) parseImport() (ast.Statement, error) {
+	line := p.previous().Line
+	if p.depth > 0 {
+		return nil, fmt.Errorf("import must be at the top level (line %d)", line)
+	}
+
+	// Parse library path
+	if !p.check(TokenString) {
+		return nil, fmt.Errorf("expected file path after 'import' at line %d", p.peek().Line)
+	}
+	path := p.advance().Value
+
+	namespace := ImportNamespace(path)
+	if namespace == "" {
+		return nil, fmt.Errorf("cannot derive a namespace from import path %q at line %d", path, line)
+	}
+	if (&Lexer{}).getKeywordType(namespace) != TokenIdentifier {
+		return nil, fmt.Errorf("cannot import %q at line %d: namespace %q is a keyword", path, line, namespace)
+	}
+
 	p.consumeNewlines()
+	return &ast.ImportStatement{Path: path, Namespace: namespace}, nil
+}
+
+// ImportNamespace returns the namespace of an imported file: its base name
+// without extension, with characters not allowed in identifiers replaced by
+// underscores
+func ImportNamespace(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	var sb strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9'):
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	if strings.Trim(sb.String(), "_") == "" {
+		return ""
+	}
+	return sb.String()
+}
+
+func (p *Parser) parseTry() (ast.Statement, error) {
+	// try call stores the failure of a single call in its result variable
+	if p.match(TokenCall) {
+		line := p.previous().Line
+		call, err := p.parseCall()
+		if err != nil {
+			return nil, err
+		}
+		stmt := call.(*ast.CallStatement)
+		if stmt.Variable == "" {
+			return nil, fmt.Errorf("expected '->' and a result variable after 'try call' at line %d", line)
+		}
+		stmt.Try = true
+		return stmt, nil
+	}
+
+	stmt := &ast.TryStatement{}
+
+	// Parse body
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse try body: %w", err)
+	}
+	stmt.Body = body
+
+	// Parse catch with optional error variable
+	if !p.matchKeyword("catch") {
+		return nil, fmt.Errorf("expected 'catch' after try block at line %d", p.peek().Line)
+	}
+	if p.check(TokenIdentifier) {
+		stmt.ErrorVar = p.advance().Value
+	}
+
+	catch, err := p.parseBlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catch body: %w", err)
+	}
+	stmt.Catch = catch
+
+	return stmt, nil
+}
+
+func (p *Parser) parseExpectError() (ast.Statement, error) {
+	line := p.previous().Line
+	if !p.match(TokenCall) {
+		return nil, fmt.Errorf("expected 'call' after 'expect_error' at line %d", line)
+	}
+
+	call, err := p.parseCall()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ast.ExpectErrorStatement{Call: call.(*ast.CallStatement)}
+
+	// Parse optional pattern; the result variable may come before or after it
+	if p.matchKeyword("matches") {
+		if !p.check(TokenString) {
+			return nil, fmt.Errorf("expected pattern string after 'matches' at line %d", p.peek().Line)
+		}
+		stmt.Pattern = p.advance().Value
+		if _, err := regexp.Compile(stmt.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern at line %d: %w", line, err)
+		}
+
+		if p.match(TokenArrow) {
+			if stmt.Call.Variable != "" || !p.check(TokenIdentifier) {
+				return nil, fmt.Errorf("expected variable name after '->' at line %d", p.peek().Line)
+			}
+			stmt.Call.Variable = p.advance().Value
+		}
+		p.consumeNewlines()
+	}
+
 	return stmt, nil
 }
 
 func (p *Parser) parseBlock() ([]ast.Statement, error) {
 	statements := []ast.Statement{}
 
+	p.depth++
+	defer func() { p.depth-- }()
+
 	p.consumeNewlines()
 	if !p.match(TokenLeftBrace) {
 		return nil, fmt.Errorf("expected '{' at line %d", p.peek().Line)
@@ -611,7 +871,7 @@ func (p *Parser) parsePostfix() (ast.Expression, error) {
 	for {
 		if p.match(TokenDot) {
 			// Field access
-			if !p.check(TokenIdentifier) {
+			if !p.checkName() {
 				return nil, fmt.Errorf("expected field name after '.' at line %d", p.peek().Line)
 			}
 			field := p.advance().Value
@@ -633,8 +893,8 @@ func (p *Parser) parsePostfix() (ast.Expression, error) {
 				Index:  index,
 			}
 		} else if p.check(TokenLeftParen) && p.previous().Type == TokenIdentifier {
-			// Function call (only for identifiers)
-			if ident, ok := expr.(*ast.Variable); ok {
+			// Function call (only for names, which may be qualified by a namespace)
+			if name, ok := qualifiedName(expr); ok {
 				p.advance() // consume '('
 				args := []ast.Expression{}
 
@@ -657,7 +917,7 @@ func (p *Parser) parsePostfix() (ast.Expression, error) {
 				}
 
 				expr = &ast.FunctionCall{
-					Name:      ident.Name,
+					Name:      name,
 					Arguments: args,
 				}
 			} else {
@@ -671,6 +931,20 @@ func (p *Parser) parsePostfix() (ast.Expression, error) {
 	return expr, nil
 }
 
+// qualifiedName returns the dotted name of a variable or a chain of field
+// accesses on one, such as common.login
+func qualifiedName(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.Variable:
+		return e.Name, true
+	case *ast.FieldAccess:
+		if object, ok := qualifiedName(e.Object); ok {
+			return object + "." + e.Field, true
+		}
+	}
+	return "", false
+}
+
 func (p *Parser) parsePrimary() (ast.Expression, error) {
 	// String literal
 	if p.match(TokenString) {
@@ -711,7 +985,7 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 			var fieldName string
 			if p.check(TokenString) {
 				fieldName = p.advance().Value
-			} else if p.check(TokenIdentifier) {
+			} else if p.checkName() {
 				fieldName = p.advance().Value
 			} else {
 				return nil, fmt.Errorf("expected field name at line %d", p.peek().Line)
@@ -806,6 +1080,41 @@ func (p *Parser) check(t TokenType) bool {
 	return p.peek().Type == t
 }
 
+// checkKeyword reports whether the current token is the contextual keyword
+// word, which is lexed as an identifier
+func (p *Parser) checkKeyword(word string) bool {
+	return p.check(TokenIdentifier) && strings.EqualFold(p.peek().Value, word)
+}
+
+// matchKeyword consumes one of the contextual keywords words, unless it is
+// the name assigned to by an assignment
+func (p *Parser) matchKeyword(words ...string) bool {
+	if p.checkAhead(TokenAssign) {
+		return false
+	}
+	for _, word := range words {
+		if p.checkKeyword(word) {
+			p.advance()
+			return true
+		}
+	}
+	return false
+}
+
+// checkName reports whether the current token can be a field name: an
+// identifier or a keyword, as in result.wait or {if: 1}
+func (p *Parser) checkName() bool {
+	if p.isAtEnd() {
+		return false
+	}
+	token := p.peek()
+	if token.Type == TokenIdentifier {
+		return true
+	}
+	keyword, ok := keywords[strings.ToLower(token.Value)]
+	return ok && keyword == token.Type
+}
+
 func (p *Parser) checkAhead(t TokenType) bool {
 	if p.current+1 >= len(p.tokens) {
 		return false
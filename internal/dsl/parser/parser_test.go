@@ -175,6 +175,23 @@ func TestParser(t *testing.T) {
 				assert.Equal(t, "==", binOp.Operator)
 			},
 		},
+		{
+			name:  "assert check",
+			input: `assert no_console_errors {tabId: tab.id}, "No JS errors"`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 1)
+				assertStmt, ok := script.Statements[0].(*ast.AssertStatement)
+				require.True(t, ok)
+
+				assert.Equal(t, "no_console_errors", assertStmt.Check)
+				assert.Nil(t, assertStmt.Expression)
+				assert.Equal(t, "No JS errors", assertStmt.Message)
+
+				args, ok := assertStmt.Arguments.(*ast.ObjectLiteral)
+				require.True(t, ok)
+				assert.Contains(t, args.Fields, "tabId")
+			},
+		},
 		{
 			name: "if-else statement",
 			input: `if x > 0 {
@@ -277,6 +294,206 @@ func TestParser(t *testing.T) {
 				assert.Equal(t, "json", fn2.Name)
 			},
 		},
+		{
+			name: "statement line numbers",
+			input: `# comment
+set x = 1
+
+if x > 0 {
+  print x
+}`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 2)
+				assert.Equal(t, 2, script.Statements[0].StartLine())
+
+				ifStmt, ok := script.Statements[1].(*ast.IfStatement)
+				require.True(t, ok)
+				assert.Equal(t, 4, ifStmt.StartLine())
+				require.Len(t, ifStmt.Then, 1)
+				assert.Equal(t, 5, ifStmt.Then[0].StartLine())
+			},
+		},
+		{
+			name: "test and hook blocks",
+			input: `setup {
+  set base = "https://example.com"
+}
+before_each {
+  print "starting"
+}
+test "home page" {
+  assert true
+}
+teardown {
+  print "done"
+}`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 4)
+
+				setup, ok := script.Statements[0].(*ast.HookStatement)
+				require.True(t, ok)
+				assert.Equal(t, ast.HookSetup, setup.Kind)
+				require.Len(t, setup.Body, 1)
+
+				beforeEach, ok := script.Statements[1].(*ast.HookStatement)
+				require.True(t, ok)
+				assert.Equal(t, ast.HookBeforeEach, beforeEach.Kind)
+
+				test, ok := script.Statements[2].(*ast.TestStatement)
+				require.True(t, ok)
+				assert.Equal(t, "home page", test.Name)
+				assert.Equal(t, 7, test.StartLine())
+				require.Len(t, test.Body, 1)
+
+				teardown, ok := script.Statements[3].(*ast.HookStatement)
+				require.True(t, ok)
+				assert.Equal(t, ast.HookTeardown, teardown.Kind)
+			},
+		},
+		{
+			name: "try catch",
+			input: `try {
+  call browser_click {selector: "#missing"}
+}
+catch err {
+  print err.message
+}
+try { print "x" } catch { print "y" }`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 2)
+
+				try, ok := script.Statements[0].(*ast.TryStatement)
+				require.True(t, ok)
+				assert.Equal(t, "err", try.ErrorVar)
+				require.Len(t, try.Body, 1)
+				require.Len(t, try.Catch, 1)
+
+				try, ok = script.Statements[1].(*ast.TryStatement)
+				require.True(t, ok)
+				assert.Empty(t, try.ErrorVar)
+				require.Len(t, try.Catch, 1)
+			},
+		},
+		{
+			name: "expect_error",
+			input: `expect_error call browser_click {selector: "#x"} matches "tabId\\s+required" -> err
+expect_error call browser_click -> err2
+expect_error call list_tools`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 3)
+
+				expect, ok := script.Statements[0].(*ast.ExpectErrorStatement)
+				require.True(t, ok)
+				assert.Equal(t, "browser_click", expect.Call.Tool)
+				assert.NotNil(t, expect.Call.Arguments)
+				assert.Equal(t, `tabId\s+required`, expect.Pattern)
+				assert.Equal(t, "err", expect.Call.Variable)
+
+				expect, ok = script.Statements[1].(*ast.ExpectErrorStatement)
+				require.True(t, ok)
+				assert.Empty(t, expect.Pattern)
+				assert.Equal(t, "err2", expect.Call.Variable)
+				assert.Equal(t, 2, expect.StartLine())
+
+				expect, ok = script.Statements[2].(*ast.ExpectErrorStatement)
+				require.True(t, ok)
+				assert.Nil(t, expect.Call.Arguments)
+			},
+		},
+		{
+			name: "try call",
+			input: `try call browser_click {selector: "#x"} -> clicked
+call browser_click -> clicked2`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 2)
+
+				call, ok := script.Statements[0].(*ast.CallStatement)
+				require.True(t, ok)
+				assert.True(t, call.Try)
+				assert.Equal(t, "clicked", call.Variable)
+				assert.Equal(t, 1, call.StartLine())
+
+				call, ok = script.Statements[1].(*ast.CallStatement)
+				require.True(t, ok)
+				assert.False(t, call.Try)
+			},
+		},
+		{
+			name: "return values and outer assignment",
+			input: `define double(n) {
+  return n * 2
+}
+define stop() {
+  return
+}
+run double(2) -> four
+set outer total = page.count(1) + double(3)`,
+			check: func(t *testing.T, script *ast.Script) {
+				require.Len(t, script.Statements, 4)
+
+				define, ok := script.Statements[0].(*ast.DefineStatement)
+				require.True(t, ok)
+				ret, ok := define.Body[0].(*ast.ReturnStatement)
+				require.True(t, ok)
+				assert.IsType(t, &ast.BinaryOp{}, ret.Value)
+
+				define, ok = script.Statements[1].(*ast.DefineStatement)
+				require.True(t, ok)
+				ret, ok = define.Body[0].(*ast.ReturnStatement)
+				require.True(t, ok)
+				assert.Nil(t, ret.Value)
+
+				run, ok := script.Statements[2].(*ast.RunStatement)
+				require.True(t, ok)
+				assert.Equal(t, "double", run.Name)
+				assert.Equal(t, "four", run.Variable)
+
+				set, ok := script.Statements[3].(*ast.SetStatement)
+				require.True(t, ok)
+				assert.True(t, set.Outer)
+				assert.Equal(t, "total", set.Variable)
+				sum, ok := set.Value.(*ast.BinaryOp)
+				require.True(t, ok)
+				call, ok := sum.Left.(*ast.FunctionCall)
+				require.True(t, ok)
+				assert.Equal(t, "page.count", call.Name)
+			},
+		},
+		{
+			name:    "syntax error - return outside define",
+			input:   `return 1`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error - try without catch",
+			input:   `try { print "x" }`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error - try call without result variable",
+			input:   `try call browser_click {selector: "#x"}`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error - expect_error without call",
+			input:   `expect_error browser_click`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error - invalid expect_error pattern",
+			input:   `expect_error call browser_click matches "("`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error - nested test",
+			input:   `if true { test "inner" { print "x" } }`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error - test without name",
+			input:   `test { print "x" }`,
+			wantErr: true,
+		},
 		{
 			name:    "syntax error - missing brace",
 			input:   `if true { print "yes"`,
@@ -314,6 +531,55 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestParser_KeywordsAsNames(t *testing.T) {
+	parse := func(t *testing.T, input string) *ast.Script {
+		t.Helper()
+		tokens, err := NewLexer(input).Tokenize()
+		require.NoError(t, err)
+		script, err := NewParser(tokens).Parse()
+		require.NoError(t, err)
+		return script
+	}
+
+	// Keywords are field names after a dot and object keys
+	for _, input := range []string{
+		`print result.test`,
+		`print x.matches`,
+		`print step.setup.teardown`,
+		`print result.wait + result.if`,
+		`set x = {setup: 1, return: 2, import: 3, call: 4, true: 5}`,
+	} {
+		t.Run(input, func(t *testing.T) { parse(t, input) })
+	}
+
+	// Contextual keywords are names where their statement cannot start
+	script := parse(t, "set Return = 1\ntest = Return + 1\nprint test\ncall tool matches -> catch")
+	require.Len(t, script.Statements, 4)
+	assert.Equal(t, "Return", script.Statements[0].(*ast.SetStatement).Variable)
+	assert.Equal(t, "test", script.Statements[1].(*ast.SetStatement).Variable)
+	call := script.Statements[3].(*ast.CallStatement)
+	assert.Equal(t, &ast.Variable{Name: "matches"}, call.Arguments)
+	assert.Equal(t, "catch", call.Variable)
+
+	obj := parse(t, `set x = {setup: 1}`).Statements[0].(*ast.SetStatement).Value.(*ast.ObjectLiteral)
+	assert.Contains(t, obj.Fields, "setup")
+
+	// and keywords where they can
+	script = parse(t, `test "t" {
+  try {
+    expect_error call tool matches "x" -> err
+  } catch e {
+    print e
+  }
+}`)
+	test := script.Statements[0].(*ast.TestStatement)
+	try := test.Body[0].(*ast.TryStatement)
+	assert.Equal(t, "e", try.ErrorVar)
+	expect := try.Body[0].(*ast.ExpectErrorStatement)
+	assert.Equal(t, "x", expect.Pattern)
+	assert.Equal(t, "err", expect.Call.Variable)
+}
+
 func TestLexerEdgeCases(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,379 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/periplon/bract/internal/dsl/runtime"
+)
+
+// Format is a test report format
+type Format string
+
+// Supported report formats
+const (
+	FormatJUnit Format = "junit"
+	FormatTAP   Format = "tap"
+	FormatJSON  Format = "json"
+)
+
+// ParseFormat parses a report format name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatJUnit, "xml":
+		return FormatJUnit, nil
+	case FormatTAP:
+		return FormatTAP, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown report format: %s (expected junit, tap or json)", name)
+	}
+}
+
+// FormatFromPath infers the report format from a file extension, defaulting to JUnit
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tap":
+		return FormatTAP
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatJUnit
+	}
+}
+
+// Case is a single reported step of a script
+type Case struct {
+	Kind     runtime.StepKind
+	Name     string
+	File     string // source file of the case, if not the suite's
+	Line     int
+	Test     string
+	Duration time.Duration
+	Err      error
+}
+
+// Suite collects the steps of one script run
+type Suite struct {
+	Name     string
+	File     string
+	Cases    []Case
+	Duration time.Duration
+	started  time.Time
+}
+
+// NewSuite starts a suite for the script in file
+func NewSuite(file string) *Suite {
+	return &Suite{
+		Name:    strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+		File:    file,
+		started: time.Now(),
+	}
+}
+
+// AddStep records a step reported by the runtime. A test is only recorded as
+// a case of its own when it failed for a reason none of its steps reported.
+func (s *Suite) AddStep(step runtime.Step) {
+	if step.Kind == runtime.StepTest && (step.Err == nil || s.reported(step.Err)) {
+		return
+	}
+	s.Cases = append(s.Cases, Case{
+		Kind:     step.Kind,
+		Name:     step.Name,
+		File:     step.File,
+		Line:     step.Line,
+		Test:     step.Test,
+		Duration: step.Duration,
+		Err:      step.Err,
+	})
+}
+
+// reported reports whether err is a failure already recorded by a case
+func (s *Suite) reported(err error) bool {
+	for _, c := range s.Cases {
+		if c.Err != nil && errors.Is(err, c.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finish records the script's outcome. An error that did not come from a
+// reported step is added as a failed case of its own.
+func (s *Suite) Finish(err error) {
+	s.Duration = time.Since(s.started)
+	if err == nil || errors.Is(err, runtime.ErrTestsFailed) || s.reported(err) {
+		return
+	}
+
+	c := Case{Kind: "error", Name: "script error", Err: err}
+	var rtErr *runtime.Error
+	if errors.As(err, &rtErr) {
+		c.File, c.Line = rtErr.File, rtErr.Line
+	}
+	s.Cases = append(s.Cases, c)
+}
+
+// Failures returns the number of failed cases
+func (s *Suite) Failures() int {
+	failures := 0
+	for _, c := range s.Cases {
+		if c.Err != nil {
+			failures++
+		}
+	}
+	return failures
+}
+
+// file returns the source file of a case
+func (s *Suite) file(c Case) string {
+	if c.File != "" {
+		return c.File
+	}
+	return s.File
+}
+
+// location returns the file:line source location of a case
+func (s *Suite) location(c Case) string {
+	if c.Line == 0 {
+		return s.file(c)
+	}
+	return fmt.Sprintf("%s:%d", s.file(c), c.Line)
+}
+
+// title returns the display name of a case
+func (c Case) title() string {
+	title := c.Name
+	switch c.Kind {
+	case runtime.StepCall, runtime.StepAssert:
+		title = fmt.Sprintf("line %d: %s %s", c.Line, c.Kind, c.Name)
+	case runtime.StepTest:
+		return fmt.Sprintf("test %s", c.Name)
+	}
+
+	if c.Test != "" {
+		return c.Test + " > " + title
+	}
+	return title
+}
+
+// className returns the JUnit class name of a case
+func (s *Suite) className(c Case) string {
+	if c.Test != "" {
+		return s.Name + "." + c.Test
+	}
+	return s.Name
+}
+
+// Write writes the suites to w in the given format
+func Write(w io.Writer, format Format, suites ...*Suite) error {
+	switch format {
+	case FormatJUnit:
+		return writeJUnit(w, suites)
+	case FormatTAP:
+		return writeTAP(w, suites)
+	case FormatJSON:
+		return writeJSON(w, suites)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	File     string          `xml:"file,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeJUnit(w io.Writer, suites []*Suite) error {
+	doc := junitTestSuites{}
+	var total time.Duration
+
+	for _, s := range suites {
+		js := junitTestSuite{
+			Name:     s.Name,
+			File:     s.File,
+			Tests:    len(s.Cases),
+			Failures: s.Failures(),
+			Time:     seconds(s.Duration),
+		}
+		for _, c := range s.Cases {
+			jc := junitTestCase{
+				Name:      c.title(),
+				ClassName: s.className(c),
+				File:      s.file(c),
+				Line:      c.Line,
+				Time:      seconds(c.Duration),
+			}
+			if c.Err != nil {
+				jc.Failure = &junitFailure{
+					Message: c.Err.Error(),
+					Type:    string(c.Kind),
+					Text:    fmt.Sprintf("%s: %v", s.location(c), c.Err),
+				}
+			}
+			js.Cases = append(js.Cases, jc)
+		}
+
+		doc.Tests += js.Tests
+		doc.Failures += js.Failures
+		total += s.Duration
+		doc.Suites = append(doc.Suites, js)
+	}
+	doc.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeTAP(w io.Writer, suites []*Suite) error {
+	total := 0
+	for _, s := range suites {
+		total += len(s.Cases)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("TAP version 13\n")
+	sb.WriteString(fmt.Sprintf("1..%d\n", total))
+
+	n := 0
+	for _, s := range suites {
+		sb.WriteString(fmt.Sprintf("# %s\n", s.File))
+		for _, c := range s.Cases {
+			n++
+			status := "ok"
+			if c.Err != nil {
+				status = "not ok"
+			}
+			sb.WriteString(fmt.Sprintf("%s %d - %s: %s\n", status, n, s.Name, c.title()))
+			if c.Err != nil {
+				sb.WriteString("  ---\n")
+				sb.WriteString(fmt.Sprintf("  message: %q\n", c.Err.Error()))
+				sb.WriteString(fmt.Sprintf("  at: %q\n", s.location(c)))
+				sb.WriteString(fmt.Sprintf("  duration_ms: %d\n", c.Duration.Milliseconds()))
+				sb.WriteString("  ...\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type jsonReport struct {
+	Tests    int         `json:"tests"`
+	Failures int         `json:"failures"`
+	Duration float64     `json:"durationMs"`
+	Suites   []jsonSuite `json:"suites"`
+}
+
+type jsonSuite struct {
+	Name     string     `json:"name"`
+	File     string     `json:"file"`
+	Tests    int        `json:"tests"`
+	Failures int        `json:"failures"`
+	Duration float64    `json:"durationMs"`
+	Cases    []jsonCase `json:"cases"`
+}
+
+type jsonCase struct {
+	Kind     string  `json:"kind"`
+	Name     string  `json:"name"`
+	Line     int     `json:"line,omitempty"`
+	Test     string  `json:"test,omitempty"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"durationMs"`
+	Error    string  `json:"error,omitempty"`
+	Location string  `json:"location,omitempty"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func writeJSON(w io.Writer, suites []*Suite) error {
+	doc := jsonReport{Suites: []jsonSuite{}}
+	var total time.Duration
+
+	for _, s := range suites {
+		js := jsonSuite{
+			Name:     s.Name,
+			File:     s.File,
+			Tests:    len(s.Cases),
+			Failures: s.Failures(),
+			Duration: milliseconds(s.Duration),
+			Cases:    []jsonCase{},
+		}
+		for _, c := range s.Cases {
+			jc := jsonCase{
+				Kind:     string(c.Kind),
+				Name:     c.Name,
+				Line:     c.Line,
+				Test:     c.Test,
+				Status:   "passed",
+				Duration: milliseconds(c.Duration),
+			}
+			if c.Err != nil {
+				jc.Status = "failed"
+				jc.Error = c.Err.Error()
+				jc.Location = s.location(c)
+			}
+			js.Cases = append(js.Cases, jc)
+		}
+
+		doc.Tests += js.Tests
+		doc.Failures += js.Failures
+		total += s.Duration
+		doc.Suites = append(doc.Suites, js)
+	}
+	doc.Duration = milliseconds(total)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
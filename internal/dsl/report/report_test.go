@@ -0,0 +1,160 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/periplon/bract/internal/dsl/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSuite() *Suite {
+	suite := NewSuite("examples/login.dsl")
+	suite.AddStep(runtime.Step{Kind: runtime.StepCall, Name: "browser_navigate", Line: 3, Duration: 120 * time.Millisecond})
+	assertErr := errors.New("assertion error: title should match")
+	suite.AddStep(runtime.Step{Kind: runtime.StepAssert, Name: "title should match", Line: 5, Err: assertErr})
+	suite.Finish(fmt.Errorf("runtime error: %w", &runtime.Error{Line: 5, Err: assertErr}))
+	return suite
+}
+
+func TestSuite_Finish(t *testing.T) {
+	t.Run("failure from a step is not duplicated", func(t *testing.T) {
+		suite := newTestSuite()
+		assert.Len(t, suite.Cases, 2)
+		assert.Equal(t, 1, suite.Failures())
+	})
+
+	t.Run("other errors are added as a case", func(t *testing.T) {
+		suite := NewSuite("test.dsl")
+		suite.Finish(&runtime.Error{Line: 7, Err: errors.New("undefined variable: x")})
+
+		require.Len(t, suite.Cases, 1)
+		assert.Equal(t, 7, suite.Cases[0].Line)
+		assert.Equal(t, 1, suite.Failures())
+	})
+}
+
+func TestSuite_Files(t *testing.T) {
+	suite := NewSuite("tests/login.dsl")
+	assertErr := errors.New("assertion error: x is one")
+	suite.AddStep(runtime.Step{Kind: runtime.StepCall, Name: "browser_navigate", File: "tests/login.dsl", Line: 3})
+	suite.AddStep(runtime.Step{Kind: runtime.StepAssert, Name: "x is one", File: "/repo/lib/checks.dsl", Line: 2, Err: assertErr})
+	suite.Finish(&runtime.Error{File: "/repo/lib/checks.dsl", Line: 2, Err: assertErr})
+
+	require.Len(t, suite.Cases, 2)
+	assert.Equal(t, "tests/login.dsl:3", suite.location(suite.Cases[0]))
+	assert.Equal(t, "/repo/lib/checks.dsl:2", suite.location(suite.Cases[1]))
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJUnit, suite))
+	assert.Contains(t, buf.String(), `file="/repo/lib/checks.dsl" line="2"`)
+}
+
+func TestSuite_Tests(t *testing.T) {
+	suite := NewSuite("suite.dsl")
+
+	assertErr := &runtime.Error{Line: 4, Err: errors.New("assertion error: boom")}
+	suite.AddStep(runtime.Step{Kind: runtime.StepAssert, Name: "boom", Line: 4, Test: "login", Err: assertErr})
+	suite.AddStep(runtime.Step{Kind: runtime.StepTest, Name: "login", Line: 3, Test: "login", Err: assertErr})
+
+	// A test failing without a failed step is reported on its own
+	setupErr := fmt.Errorf("setup failed: %w", errors.New("no browser"))
+	suite.AddStep(runtime.Step{Kind: runtime.StepTest, Name: "search", Line: 8, Test: "search", Err: setupErr})
+
+	// Passing tests are represented by their steps
+	suite.AddStep(runtime.Step{Kind: runtime.StepTest, Name: "logout", Line: 12, Test: "logout"})
+
+	suite.Finish(fmt.Errorf("%w: 2 of 3", runtime.ErrTestsFailed))
+
+	require.Len(t, suite.Cases, 2)
+	assert.Equal(t, "login > line 4: assert boom", suite.Cases[0].title())
+	assert.Equal(t, "suite.login", suite.className(suite.Cases[0]))
+	assert.Equal(t, "test search", suite.Cases[1].title())
+	assert.Equal(t, 2, suite.Failures())
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Format
+		wantErr  bool
+	}{
+		{name: "junit", expected: FormatJUnit},
+		{name: "XML", expected: FormatJUnit},
+		{name: "tap", expected: FormatTAP},
+		{name: "json", expected: FormatJSON},
+		{name: "html", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ParseFormat(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+
+	assert.Equal(t, FormatTAP, FormatFromPath("out/report.tap"))
+	assert.Equal(t, FormatJSON, FormatFromPath("report.JSON"))
+	assert.Equal(t, FormatJUnit, FormatFromPath("report.xml"))
+}
+
+func TestWrite_JUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJUnit, newTestSuite()))
+
+	var doc junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, 2, doc.Tests)
+	assert.Equal(t, 1, doc.Failures)
+	require.Len(t, doc.Suites, 1)
+	require.Len(t, doc.Suites[0].Cases, 2)
+
+	call := doc.Suites[0].Cases[0]
+	assert.Equal(t, "line 3: call browser_navigate", call.Name)
+	assert.Equal(t, "0.120", call.Time)
+	assert.Nil(t, call.Failure)
+
+	failed := doc.Suites[0].Cases[1]
+	assert.Equal(t, 5, failed.Line)
+	require.NotNil(t, failed.Failure)
+	assert.Contains(t, failed.Failure.Text, "examples/login.dsl:5")
+}
+
+func TestWrite_TAP(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatTAP, newTestSuite()))
+
+	out := buf.String()
+	assert.Contains(t, out, "TAP version 13\n1..2\n")
+	assert.Contains(t, out, "ok 1 - login: line 3: call browser_navigate\n")
+	assert.Contains(t, out, "not ok 2 - login: line 5: assert title should match\n")
+	assert.Contains(t, out, `at: "examples/login.dsl:5"`)
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, newTestSuite()))
+
+	var doc jsonReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, 2, doc.Tests)
+	assert.Equal(t, 1, doc.Failures)
+	require.Len(t, doc.Suites[0].Cases, 2)
+	assert.Equal(t, "passed", doc.Suites[0].Cases[0].Status)
+	assert.Equal(t, 120.0, doc.Suites[0].Cases[0].Duration)
+	assert.Equal(t, "failed", doc.Suites[0].Cases[1].Status)
+	assert.Equal(t, "examples/login.dsl:5", doc.Suites[0].Cases[1].Location)
+}
@@ -0,0 +1,361 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/periplon/bract/internal/dsl"
+	"github.com/periplon/bract/internal/dsl/report"
+	"github.com/periplon/bract/internal/mcpclient"
+)
+
+// ScriptExt is the file extension of DSL scripts found in directories
+const ScriptExt = ".dsl"
+
+// Options configures a run of several scripts
+type Options struct {
+	Parallel  int           // scripts run at once, 1 if unset
+	Timeout   time.Duration // timeout per script, 0 for none
+	ServerURL string        // connect each worker to this running server instead of following connect statements
+	Stdout    io.Writer     // where script output goes, os.Stdout if nil
+
+	UpdateBaselines bool // replace baseline screenshots instead of comparing with them
+}
+
+// Result is the outcome of one script
+type Result struct {
+	File     string
+	Suite    *report.Suite
+	Duration time.Duration
+	Err      error
+}
+
+// Discover returns the scripts named by paths in sorted order. Directories
+// are searched recursively for .dsl files, skipping libraries that only
+// define automations for other scripts to import. A script is kept if its
+// base name matches any of the include globs, or include is empty, and none
+// of the exclude globs.
+func Discover(paths, include, exclude []string) ([]string, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		name := filepath.Base(path)
+		if seen[path] || (len(include) > 0 && !matchAny(include, name)) || matchAny(exclude, name) {
+			return
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(p) == ScriptExt && !isLibrary(p) {
+				add(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// isLibrary reports whether the file at path is an importable library.
+// Files that fail to parse are not, so running them reports the error.
+func isLibrary(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	script, err := dsl.ParseString(string(data))
+	return err == nil && dsl.IsLibrary(script)
+}
+
+// matchAny reports whether name matches any of the glob patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags returns the tags a script declares in "# tags:" lines of its leading
+// comment block, lowercased
+func Tags(source string) []string {
+	var tags []string
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+
+		comment := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if len(comment) < 5 || !strings.EqualFold(comment[:5], "tags:") {
+			continue
+		}
+		for _, tag := range strings.Split(comment[5:], ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// FilterTags keeps the scripts tagged with any of include, or all scripts if
+// include is empty, and with none of exclude
+func FilterTags(files, include, exclude []string) ([]string, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return files, nil
+	}
+
+	var kept []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		tags := Tags(string(data))
+		if len(include) > 0 && !hasAnyTag(tags, include) {
+			continue
+		}
+		if hasAnyTag(tags, exclude) {
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, nil
+}
+
+// hasAnyTag reports whether tags contains any of wanted
+func hasAnyTag(tags, wanted []string) bool {
+	for _, want := range wanted {
+		for _, tag := range tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Run executes the scripts with up to opts.Parallel of them at once and
+// returns their results in the order of files. Each worker has a client of
+// its own: with opts.ServerURL it is one server session per worker, otherwise
+// every script starts the server named by its connect statement. When more
+// than one script runs, the output of each is written in one piece once it
+// finishes, followed by its status.
+func Run(ctx context.Context, files []string, opts Options) []Result {
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > len(files) {
+		parallel = len(files)
+	}
+	verbose := len(files) > 1
+
+	results := make([]Result, len(files))
+	jobs := make(chan int, len(files))
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex // serializes writes to stdout
+	)
+	for n := 0; n < parallel; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			w := &worker{opts: opts}
+			defer w.close(stdout, &mu)
+
+			for i := range jobs {
+				// A single worker streams output as it is printed
+				var out io.Writer = stdout
+				var buf bytes.Buffer
+				if parallel > 1 {
+					out = &buf
+				} else if verbose {
+					fmt.Fprintf(stdout, "=== RUN %s\n", files[i])
+				}
+
+				results[i] = w.run(ctx, files[i], out)
+
+				if !verbose {
+					continue
+				}
+				mu.Lock()
+				if parallel > 1 {
+					fmt.Fprintf(stdout, "=== RUN %s\n", files[i])
+					stdout.Write(buf.Bytes())
+				}
+				writeStatus(stdout, results[i])
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// worker runs scripts one after another
+type worker struct {
+	opts   Options
+	client *mcpclient.Client // shared server session, with opts.ServerURL
+}
+
+// run executes one script, writing its output to stdout
+func (w *worker) run(ctx context.Context, file string, stdout io.Writer) Result {
+	start := time.Now()
+	suite := report.NewSuite(file)
+	result := func(err error) Result {
+		suite.Finish(err)
+		return Result{File: file, Suite: suite, Duration: time.Since(start), Err: err}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result(err)
+	}
+
+	interpreter := dsl.NewInterpreter()
+	interpreter.SetStdout(stdout)
+	interpreter.OnStep(suite.AddStep)
+	interpreter.SetUpdateBaselines(w.opts.UpdateBaselines)
+
+	if w.opts.ServerURL != "" {
+		// The session outlives the script, and the SSE stream of the client
+		// ends with the context it was connected with, so it is connected
+		// with the worker's context rather than the script's
+		if w.client == nil {
+			client := &mcpclient.Client{}
+			if err := client.ConnectURL(ctx, w.opts.ServerURL); err != nil {
+				client.Close()
+				return result(fmt.Errorf("failed to connect to %s: %w", w.opts.ServerURL, err))
+			}
+			w.client = client
+		}
+		interpreter.UseClient(w.client)
+	}
+
+	if w.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.Timeout)
+		defer cancel()
+	}
+	err := interpreter.ExecuteFile(ctx, file)
+
+	if w.client == nil {
+		if client := interpreter.GetClient(); client != nil {
+			if err := client.Close(); err != nil {
+				fmt.Fprintf(stdout, "Warning: failed to close client: %v\n", err)
+			}
+		}
+	}
+
+	return result(err)
+}
+
+// close closes the worker's shared client
+func (w *worker) close(stdout io.Writer, mu *sync.Mutex) {
+	if w.client == nil {
+		return
+	}
+	if err := w.client.Close(); err != nil {
+		mu.Lock()
+		fmt.Fprintf(stdout, "Warning: failed to close client: %v\n", err)
+		mu.Unlock()
+	}
+}
+
+// writeStatus writes the pass/fail line of a finished script
+func writeStatus(w io.Writer, result Result) {
+	if result.Err == nil {
+		fmt.Fprintf(w, "--- PASS: %s (%.2fs)\n", result.File, result.Duration.Seconds())
+		return
+	}
+	fmt.Fprintf(w, "--- FAIL: %s (%.2fs)\n", result.File, result.Duration.Seconds())
+	fmt.Fprintf(w, "    %v\n", result.Err)
+}
+
+// Summarize writes the aggregated outcome of a run to w
+func Summarize(w io.Writer, results []Result, elapsed time.Duration) {
+	passed := 0
+	fmt.Fprintln(w, "\n=== Summary ===")
+	for _, result := range results {
+		if result.Err == nil {
+			passed++
+			fmt.Fprintf(w, "✓ %s (%.2fs)\n", result.File, result.Duration.Seconds())
+			continue
+		}
+		fmt.Fprintf(w, "✗ %s (%.2fs)\n", result.File, result.Duration.Seconds())
+		fmt.Fprintf(w, "    %v\n", result.Err)
+	}
+	fmt.Fprintf(w, "%d scripts: %d passed, %d failed (%.2fs)\n",
+		len(results), passed, len(results)-passed, elapsed.Seconds())
+}
+
+// Failed returns the number of scripts that failed
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Suites returns the report suites of the results
+func Suites(results []Result) []*report.Suite {
+	suites := make([]*report.Suite, len(results))
+	for i, result := range results {
+		suites[i] = result.Suite
+	}
+	return suites
+}
@@ -0,0 +1,265 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScripts writes the named scripts into dir
+func writeScripts(t *testing.T, dir string, scripts map[string]string) {
+	t.Helper()
+	for name, source := range scripts {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(source), 0o644))
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writeScripts(t, dir, map[string]string{
+		"basic.dsl":             `print "basic"`,
+		"storage-example.dsl":   `print "storage"`,
+		"storage-test.dsl":      `print "storage test"`,
+		"nested/multi-tab.dsl":  `print "tabs"`,
+		"README.md":             "not a script",
+		"nested/notes.txt":      "not a script",
+		"nested/deeper/x.dsl":   `print "x"`,
+		"nested/deeper/y.other": `print "y"`,
+		"lib/common.dsl":        "define hello() {\n  print \"hi\"\n}",
+	})
+	rel := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(dir, name)
+		}
+		return names
+	}
+
+	files, err := Discover([]string{dir}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, rel("basic.dsl", "nested/deeper/x.dsl", "nested/multi-tab.dsl", "storage-example.dsl", "storage-test.dsl"), files)
+
+	files, err = Discover([]string{dir}, []string{"storage-*", "multi-*"}, []string{"*-test.dsl"})
+	require.NoError(t, err)
+	assert.Equal(t, rel("nested/multi-tab.dsl", "storage-example.dsl"), files)
+
+	// Explicit files are kept whatever their extension, and only once
+	files, err = Discover(rel("nested/deeper/y.other", "basic.dsl", "basic.dsl"), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, rel("basic.dsl", "nested/deeper/y.other"), files)
+
+	_, err = Discover([]string{dir}, []string{"[bad"}, nil)
+	assert.Error(t, err)
+
+	_, err = Discover([]string{filepath.Join(dir, "missing")}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestTags(t *testing.T) {
+	source := `# Storage example
+# Tags: Smoke, storage
+#
+# tags: slow
+connect "./bin/mcp-browser-server"
+# tags: ignored
+`
+	assert.Equal(t, []string{"smoke", "storage", "slow"}, Tags(source))
+	assert.Empty(t, Tags(`print "untagged"`))
+}
+
+func TestFilterTags(t *testing.T) {
+	dir := t.TempDir()
+	writeScripts(t, dir, map[string]string{
+		"a.dsl": "# tags: smoke\nprint 1",
+		"b.dsl": "# tags: smoke, slow\nprint 2",
+		"c.dsl": "print 3",
+	})
+	files, err := Discover([]string{dir}, nil, nil)
+	require.NoError(t, err)
+
+	kept, err := FilterTags(files, []string{"SMOKE"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.dsl"), filepath.Join(dir, "b.dsl")}, kept)
+
+	kept, err = FilterTags(files, nil, []string{"slow"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.dsl"), filepath.Join(dir, "c.dsl")}, kept)
+
+	kept, err = FilterTags(files, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, files, kept)
+}
+
+func TestRun_Parallel(t *testing.T) {
+	dir := t.TempDir()
+	writeScripts(t, dir, map[string]string{
+		"1-pass.dsl":  "print \"one\"\nassert 1 == 1, \"math works\"",
+		"2-fail.dsl":  "print \"two\"\nassert 1 == 2, \"math is broken\"",
+		"3-pass.dsl":  `print "three"`,
+		"4-tests.dsl": "test \"ok\" {\n  assert true\n}\ntest \"bad\" {\n  assert false, \"nope\"\n}",
+	})
+	files, err := Discover([]string{dir}, nil, nil)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	results := Run(context.Background(), files, Options{Parallel: 3, Stdout: &out})
+	require.Len(t, results, 4)
+
+	for i, result := range results {
+		assert.Equal(t, files[i], result.File)
+		assert.Equal(t, files[i], result.Suite.File)
+	}
+	assert.NoError(t, results[0].Err)
+	assert.ErrorContains(t, results[1].Err, "math is broken")
+	assert.NoError(t, results[2].Err)
+	assert.Error(t, results[3].Err)
+	assert.Equal(t, 2, Failed(results))
+	assert.Len(t, Suites(results), 4)
+	assert.Equal(t, 1, results[1].Suite.Failures())
+
+	// Output of each script is written in one piece with its status
+	output := out.String()
+	assert.Contains(t, output, "=== RUN "+files[0]+"\none\n--- PASS: "+files[0])
+	assert.Contains(t, output, "=== RUN "+files[1]+"\ntwo\n--- FAIL: "+files[1])
+
+	var summary bytes.Buffer
+	Summarize(&summary, results, time.Second)
+	assert.Contains(t, summary.String(), "✓ "+files[0])
+	assert.Contains(t, summary.String(), "✗ "+files[1])
+	assert.Contains(t, summary.String(), "4 scripts: 2 passed, 2 failed (1.00s)")
+}
+
+func TestRun_SingleScriptStreamsOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeScripts(t, dir, map[string]string{"only.dsl": `print "hello"`})
+
+	var out bytes.Buffer
+	results := Run(context.Background(), []string{filepath.Join(dir, "only.dsl")}, Options{Parallel: 4, Stdout: &out})
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "hello\n", out.String())
+}
+
+func TestRun_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	writeScripts(t, dir, map[string]string{"slow.dsl": "wait 5"})
+
+	results := Run(context.Background(), []string{filepath.Join(dir, "slow.dsl")}, Options{Timeout: 50 * time.Millisecond, Stdout: &bytes.Buffer{}})
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, context.DeadlineExceeded)
+}
+
+func TestRun_ServerURL(t *testing.T) {
+	// A server recording the session of every call
+	var (
+		mu       sync.Mutex
+		sessions = make(map[string]int)
+	)
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp.NewTool("whoami"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id := server.ClientSessionFromContext(ctx).SessionID()
+		mu.Lock()
+		sessions[id]++
+		mu.Unlock()
+		return mcp.NewToolResultText(id), nil
+	})
+	ts := httptest.NewServer(server.NewStreamableHTTPServer(s))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	scripts := make(map[string]string)
+	for _, name := range []string{"a.dsl", "b.dsl", "c.dsl", "d.dsl"} {
+		// The connect statement is ignored in favour of the server URL
+		scripts[name] = "connect \"./does-not-exist\"\ncall whoami -> me\nassert me != \"\""
+	}
+	writeScripts(t, dir, scripts)
+	files, err := Discover([]string{dir}, nil, nil)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	results := Run(context.Background(), files, Options{Parallel: 2, ServerURL: ts.URL + "/mcp", Stdout: &out})
+	for _, result := range results {
+		assert.NoError(t, result.Err, result.File)
+	}
+
+	// Every worker used a session of its own for all its scripts
+	mu.Lock()
+	defer mu.Unlock()
+	total := 0
+	for id, calls := range sessions {
+		assert.NotEmpty(t, id)
+		total += calls
+	}
+	assert.Equal(t, 4, total)
+	assert.LessOrEqual(t, len(sessions), 2)
+	assert.False(t, strings.Contains(out.String(), "Warning"), out.String())
+}
+
+func TestRun_ServerURLTimeout(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp.NewTool("ping"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("pong"), nil
+	})
+
+	tests := []struct {
+		name    string
+		handler func(baseURL string) http.Handler
+		path    string
+	}{
+		{
+			name: "sse",
+			handler: func(baseURL string) http.Handler {
+				return server.NewSSEServer(s, server.WithBaseURL(baseURL))
+			},
+			path: "/sse",
+		},
+		{
+			name: "streamable http",
+			handler: func(string) http.Handler {
+				return server.NewStreamableHTTPServer(s)
+			},
+			path: "/mcp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h http.Handler
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+			h = tt.handler(ts.URL)
+
+			dir := t.TempDir()
+			writeScripts(t, dir, map[string]string{
+				"a.dsl": "call ping -> reply\nassert reply == \"pong\"",
+				"b.dsl": "call ping -> reply\nassert reply == \"pong\"",
+				"c.dsl": "call ping -> reply\nassert reply == \"pong\"",
+			})
+			files, err := Discover([]string{dir}, nil, nil)
+			require.NoError(t, err)
+
+			// The timeout of each script does not end the session the
+			// worker shares between them
+			results := Run(context.Background(), files, Options{Timeout: 5 * time.Second, ServerURL: ts.URL + tt.path, Stdout: &bytes.Buffer{}})
+			require.Len(t, results, 3)
+			for _, result := range results {
+				assert.NoError(t, result.Err, result.File)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/periplon/bract/internal/dsl/ast"
+)
+
+// check is a built-in assertion made by `assert <name> {args}`. It returns a
+// description of the failure, or "" when the check passes.
+type check func(ctx context.Context, rt *Runtime, args map[string]interface{}) (string, error)
+
+// checks are the built-in assertions by name
+var checks = map[string]check{
+	"no_console_errors":  checkNoConsoleErrors,
+	"screenshot_matches": checkScreenshotMatches,
+}
+
+// executeCheck makes the built-in check of an assert statement
+func (rt *Runtime) executeCheck(ctx context.Context, stmt *ast.AssertStatement) error {
+	run, ok := checks[stmt.Check]
+	if !ok {
+		return fmt.Errorf("unknown assertion check: %s", stmt.Check)
+	}
+	if rt.client == nil {
+		return fmt.Errorf("not connected to any MCP server")
+	}
+
+	args := map[string]interface{}{}
+	if stmt.Arguments != nil {
+		value, err := rt.evaluateExpression(ctx, stmt.Arguments)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate arguments: %w", err)
+		}
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s arguments must be an object", stmt.Check)
+		}
+		for k, v := range obj {
+			args[k] = v
+		}
+	}
+
+	failure, err := run(ctx, rt, args)
+	if err != nil {
+		return err
+	}
+	if failure != "" {
+		msg := stmt.Message
+		if msg == "" {
+			msg = failure
+		}
+		return fmt.Errorf("assertion error: %s", msg)
+	}
+	return nil
+}
+
+// callCheckTool calls a tool for a check and decodes its JSON result into v
+func (rt *Runtime) callCheckTool(ctx context.Context, tool string, args map[string]interface{}, v interface{}) error {
+	result, err := rt.client.CallTool(ctx, tool, args)
+	if err != nil {
+		return &CallError{Tool: tool, Message: err.Error(), Err: err}
+	}
+	if result.IsError {
+		return &CallError{Tool: tool, Message: resultText(result), IsError: true}
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), v); err != nil {
+		return fmt.Errorf("unexpected %s result: %w", tool, err)
+	}
+	return nil
+}
+
+// checkNoConsoleErrors fails when console errors or uncaught exceptions were
+// logged since the script, or the running test, started. The arguments are
+// passed to browser_get_console, e.g. {tabId: 3}.
+func checkNoConsoleErrors(ctx context.Context, rt *Runtime, args map[string]interface{}) (string, error) {
+	if _, ok := args["levels"]; !ok {
+		args["levels"] = []interface{}{"error", "exception"}
+	}
+	if _, ok := args["since"]; !ok && !rt.started.IsZero() {
+		args["since"] = rt.started.Format(time.RFC3339Nano)
+	}
+
+	var messages []struct {
+		Level  string `json:"level"`
+		Text   string `json:"text"`
+		Source string `json:"source"`
+		Line   int    `json:"line"`
+	}
+	if err := rt.callCheckTool(ctx, "browser_get_console", args, &messages); err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	if len(messages) == 1 {
+		sb.WriteString("1 console error:")
+	} else {
+		fmt.Fprintf(&sb, "%d console errors:", len(messages))
+	}
+	for _, m := range messages {
+		fmt.Fprintf(&sb, " [%s] %s", m.Level, m.Text)
+		switch {
+		case m.Source != "" && m.Line > 0:
+			fmt.Fprintf(&sb, " (%s:%d)", m.Source, m.Line)
+		case m.Source != "":
+			fmt.Fprintf(&sb, " (%s)", m.Source)
+		}
+		sb.WriteString(";")
+	}
+	return strings.TrimSuffix(sb.String(), ";"), nil
+}
+
+// checkScreenshotMatches fails when a screenshot differs from its baseline by
+// more than the tolerance. The arguments are passed to
+// browser_screenshot_compare, e.g. {name: "home", tolerance: 0.5}. When
+// baselines are being updated, the screenshot is saved as the baseline.
+func checkScreenshotMatches(ctx context.Context, rt *Runtime, args map[string]interface{}) (string, error) {
+	if rt.updateBaselines {
+		args["update"] = true
+	}
+
+	var result struct {
+		Name        string  `json:"name"`
+		Mismatch    float64 `json:"mismatch"`
+		Tolerance   float64 `json:"tolerance"`
+		Passed      bool    `json:"passed"`
+		SizeChanged bool    `json:"sizeChanged"`
+		DiffPath    string  `json:"diffPath"`
+	}
+	if err := rt.callCheckTool(ctx, "browser_screenshot_compare", args, &result); err != nil {
+		return "", err
+	}
+	if result.Passed {
+		return "", nil
+	}
+
+	msg := fmt.Sprintf("screenshot differs from baseline %s by %g%% (tolerance %g%%)", result.Name, result.Mismatch, result.Tolerance)
+	if result.SizeChanged {
+		msg += ", its size changed"
+	}
+	if result.DiffPath != "" {
+		msg += ", diff saved to " + result.DiffPath
+	}
+	return msg, nil
+}
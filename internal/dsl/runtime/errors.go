@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/periplon/bract/internal/dsl/ast"
+)
+
+// CallError is returned when a tool call fails, either because the request
+// failed or because the tool reported an error result
+type CallError struct {
+	Tool    string
+	Message string // error text of the tool or the request
+	IsError bool   // the tool returned a result flagged as an error
+	Err     error  // request error, if the request failed
+}
+
+func (e *CallError) Error() string {
+	if e.IsError {
+		return fmt.Sprintf("%s returned an error: %s", e.Tool, e.Message)
+	}
+	return fmt.Sprintf("tool call failed: %v", e.Err)
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// resultText joins the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// errorValue converts an error to the object bound by catch and expect_error:
+// {message, tool, isError, line}
+func errorValue(err error) map[string]interface{} {
+	value := map[string]interface{}{
+		"message": err.Error(),
+		"tool":    "",
+		"isError": false,
+		"line":    float64(0),
+	}
+
+	var rtErr *Error
+	if errors.As(err, &rtErr) {
+		value["message"] = rtErr.Err.Error()
+		value["line"] = float64(rtErr.Line)
+	}
+
+	var callErr *CallError
+	if errors.As(err, &callErr) {
+		value["message"] = callErr.Message
+		value["tool"] = callErr.Tool
+		value["isError"] = callErr.IsError
+	}
+	return value
+}
+
+// executeTry runs the try block and, if it fails, the catch block. Errors
+// caused by the script being cancelled or timing out are not caught, and
+// neither are returns from the enclosing automation.
+func (rt *Runtime) executeTry(ctx context.Context, stmt *ast.TryStatement) error {
+	rt.tryDepth++
+	err := rt.executeBlock(ctx, stmt.Body)
+	rt.tryDepth--
+
+	var ret *returnSignal
+	if err == nil || errors.As(err, &ret) || ctx.Err() != nil {
+		return err
+	}
+
+	// The caught error is only visible in the catch block
+	catch := newScope(rt.scope, false)
+	if stmt.ErrorVar != "" {
+		catch.vars[stmt.ErrorVar] = errorValue(err)
+	}
+	return rt.withScope(catch, func() error {
+		return rt.executeBlock(ctx, stmt.Catch)
+	})
+}
+
+// executeExpectError makes a tool call that must fail, with an error message
+// matching the pattern if one is given
+func (rt *Runtime) executeExpectError(ctx context.Context, stmt *ast.ExpectErrorStatement) error {
+	call := *stmt.Call
+	call.Variable = ""
+
+	err := rt.executeCall(ctx, &call)
+	if err == nil {
+		return fmt.Errorf("expected %s to fail, but it succeeded", call.Tool)
+	}
+
+	var callErr *CallError
+	if !errors.As(err, &callErr) || ctx.Err() != nil {
+		return err
+	}
+
+	if stmt.Pattern != "" {
+		matched, err := regexp.MatchString(stmt.Pattern, callErr.Message)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", stmt.Pattern, err)
+		}
+		if !matched {
+			return fmt.Errorf("%s failed with %q, which does not match %q", call.Tool, callErr.Message, stmt.Pattern)
+		}
+	}
+
+	if stmt.Call.Variable != "" {
+		rt.setVar(stmt.Call.Variable, errorValue(callErr))
+	}
+	return nil
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
@@ -16,21 +19,80 @@ import (
 // Runtime executes DSL scripts
 type Runtime struct {
 	client      *mcpclient.Client
-	variables   map[string]interface{}
-	automations map[string]*ast.DefineStatement
+	variables   map[string]interface{} // variables of the script's top-level scope
+	automations map[string]*automation // automations of the script's top-level scope
+	globals     *scope
+	scope       *scope // scope of the running code
+	namespace   string // prefix of automations defined by the running code
+	file        string // file of the running code, if it was read from one
+	callDepth   int
 	output      strings.Builder
+	stdout      io.Writer
+	shared      bool // client was supplied with UseClient
+	onStep      func(Step)
+	currentTest string
+	tryDepth    int
+	testResults []TestResult
+	dir         string    // directory relative file paths are resolved against
+	started     time.Time // start of the script, or of the running test
+
+	updateBaselines bool // screenshot_matches replaces baselines instead of comparing
 }
 
 // NewRuntime creates a new runtime
 func NewRuntime() *Runtime {
+	globals := newScope(nil, true)
 	return &Runtime{
-		variables:   make(map[string]interface{}),
-		automations: make(map[string]*ast.DefineStatement),
+		variables:   globals.vars,
+		automations: globals.automations,
+		globals:     globals,
+		scope:       globals,
+		stdout:      os.Stdout,
 	}
 }
 
+// SetStdout sets where print output is written, os.Stdout by default
+func (rt *Runtime) SetStdout(w io.Writer) {
+	rt.stdout = w
+}
+
+// SetDir sets the directory relative paths given to read_file are resolved
+// against in scripts without a file, the working directory by default
+func (rt *Runtime) SetDir(dir string) {
+	rt.dir = dir
+}
+
+// fileDir returns the directory relative paths are resolved against: the
+// directory of the file of the running code, which is an imported library
+// inside its automations, or the configured directory without a file
+func (rt *Runtime) fileDir() string {
+	if rt.file != "" {
+		return filepath.Dir(rt.file)
+	}
+	return rt.dir
+}
+
+// SetUpdateBaselines makes assert screenshot_matches replace the baseline
+// screenshots instead of comparing with them
+func (rt *Runtime) SetUpdateBaselines(update bool) {
+	rt.updateBaselines = update
+}
+
+// UseClient runs the script against an already connected client. connect
+// statements are then skipped, and the client is left open.
+func (rt *Runtime) UseClient(client *mcpclient.Client) {
+	rt.client = client
+	rt.shared = true
+}
+
 // Execute runs a DSL script
 func (rt *Runtime) Execute(ctx context.Context, script *ast.Script) error {
+	rt.started = time.Now()
+	rt.file = script.File
+	if hasTests(script) {
+		return rt.executeTests(ctx, script)
+	}
+
 	for _, stmt := range script.Statements {
 		if err := rt.executeStatement(ctx, stmt); err != nil {
 			return err
@@ -50,6 +112,31 @@ func (rt *Runtime) GetClient() *mcpclient.Client {
 }
 
 func (rt *Runtime) executeStatement(ctx context.Context, stmt ast.Statement) error {
+	start := time.Now()
+	err := rt.dispatchStatement(ctx, stmt)
+
+	// Failures inside a try block are handled by its catch block
+	if err != nil && rt.tryDepth > 0 {
+		return withLine(err, rt.file, stmt.StartLine())
+	}
+
+	switch s := stmt.(type) {
+	case *ast.CallStatement:
+		rt.reportStep(Step{Kind: StepCall, Name: s.Tool, Line: s.Line, Test: rt.currentTest, Duration: time.Since(start), Err: err})
+	case *ast.AssertStatement:
+		name := s.Message
+		if name == "" {
+			name = "assert"
+		}
+		rt.reportStep(Step{Kind: StepAssert, Name: name, Line: s.Line, Test: rt.currentTest, Duration: time.Since(start), Err: err})
+	case *ast.ExpectErrorStatement:
+		rt.reportStep(Step{Kind: StepAssert, Name: "expect_error " + s.Call.Tool, Line: s.Line, Test: rt.currentTest, Duration: time.Since(start), Err: err})
+	}
+
+	return withLine(err, rt.file, stmt.StartLine())
+}
+
+func (rt *Runtime) dispatchStatement(ctx context.Context, stmt ast.Statement) error {
 	switch s := stmt.(type) {
 	case *ast.ConnectStatement:
 		return rt.executeConnect(ctx, s)
@@ -71,12 +158,26 @@ func (rt *Runtime) executeStatement(ctx context.Context, stmt ast.Statement) err
 		return rt.executeDefine(ctx, s)
 	case *ast.RunStatement:
 		return rt.executeRun(ctx, s)
+	case *ast.ReturnStatement:
+		return rt.executeReturn(ctx, s)
+	case *ast.ImportStatement:
+		return rt.executeImport(ctx, s)
+	case *ast.TryStatement:
+		return rt.executeTry(ctx, s)
+	case *ast.ExpectErrorStatement:
+		return rt.executeExpectError(ctx, s)
+	case *ast.TestStatement, *ast.HookStatement:
+		return fmt.Errorf("%T is only allowed at the top level of a script", s)
 	default:
 		return fmt.Errorf("unknown statement type: %T", stmt)
 	}
 }
 
 func (rt *Runtime) executeConnect(ctx context.Context, stmt *ast.ConnectStatement) error {
+	if rt.shared {
+		return nil
+	}
+
 	// Evaluate server expression
 	serverVal, err := rt.evaluateExpression(ctx, stmt.Server)
 	if err != nil {
@@ -134,7 +235,7 @@ func (rt *Runtime) executeCall(ctx context.Context, stmt *ast.CallStatement) err
 			for i, tool := range tools {
 				toolNames[i] = tool.Name
 			}
-			rt.variables[stmt.Variable] = toolNames
+			rt.setVar(stmt.Variable, toolNames)
 		}
 		return nil
 	}
@@ -151,8 +252,21 @@ func (rt *Runtime) executeCall(ctx context.Context, stmt *ast.CallStatement) err
 
 	// Call the tool
 	result, err := rt.client.CallTool(ctx, stmt.Tool, args)
-	if err != nil {
-		return fmt.Errorf("tool call failed: %w", err)
+	var callErr *CallError
+	switch {
+	case err != nil:
+		callErr = &CallError{Tool: stmt.Tool, Message: err.Error(), Err: err}
+	case result.IsError:
+		callErr = &CallError{Tool: stmt.Tool, Message: resultText(result), IsError: true}
+	}
+	if callErr != nil {
+		// try call leaves checking the failure to the script, which finds the
+		// error object in the result variable
+		if stmt.Try {
+			rt.setVar(stmt.Variable, errorValue(withLine(callErr, rt.file, stmt.Line)))
+			return nil
+		}
+		return callErr
 	}
 
 	// Store result if variable specified
@@ -161,48 +275,51 @@ func (rt *Runtime) executeCall(ctx context.Context, stmt *ast.CallStatement) err
 		var resultValue interface{}
 		if len(result.Content) == 1 {
 			// Single content item - process it
-			content := result.Content[0]
-			// Check if it's a TextContent with JSON data
-			if textContent, ok := content.(mcp.TextContent); ok {
-				// Try to parse as JSON
-				var jsonData interface{}
-				if err := json.Unmarshal([]byte(textContent.Text), &jsonData); err == nil {
-					resultValue = jsonData
-				} else {
-					// Not JSON, store as plain text
-					resultValue = textContent.Text
-				}
-			} else {
-				// Not TextContent, store as is
-				resultValue = content
-			}
+			resultValue = contentValue(result.Content[0])
 		} else {
 			// Multiple content items - process each one
 			items := make([]interface{}, len(result.Content))
 			for i, content := range result.Content {
-				if textContent, ok := content.(mcp.TextContent); ok {
-					// Try to parse as JSON
-					var jsonData interface{}
-					if err := json.Unmarshal([]byte(textContent.Text), &jsonData); err == nil {
-						items[i] = jsonData
-					} else {
-						// Not JSON, store as plain text
-						items[i] = textContent.Text
-					}
-				} else {
-					// Not TextContent, store as is
-					items[i] = content
-				}
+				items[i] = contentValue(content)
 			}
 			resultValue = items
 		}
-		rt.variables[stmt.Variable] = resultValue
+		rt.setVar(stmt.Variable, resultValue)
 	}
 
 	return nil
 }
 
+// contentValue converts an item of a tool result to a script value: text
+// holding JSON is parsed, other text is kept as a string and images become
+// {type, mimeType, data} objects
+func contentValue(content mcp.Content) interface{} {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		// Try to parse as JSON
+		var jsonData interface{}
+		if err := json.Unmarshal([]byte(c.Text), &jsonData); err == nil {
+			return jsonData
+		}
+		// Not JSON, store as plain text
+		return c.Text
+	case mcp.ImageContent:
+		return map[string]interface{}{
+			"type":     "image",
+			"mimeType": c.MIMEType,
+			"data":     c.Data,
+		}
+	default:
+		// Store as is
+		return content
+	}
+}
+
 func (rt *Runtime) executeAssert(ctx context.Context, stmt *ast.AssertStatement) error {
+	if stmt.Check != "" {
+		return rt.executeCheck(ctx, stmt)
+	}
+
 	// Evaluate condition
 	result, err := rt.evaluateExpression(ctx, stmt.Expression)
 	if err != nil {
@@ -315,16 +432,16 @@ func (rt *Runtime) executeLoop(ctx context.Context, stmt *ast.LoopStatement) err
 		return fmt.Errorf("collection is not iterable: %w", err)
 	}
 
-	// Execute loop body for each item
+	// Execute loop body for each item, with the iterator in a scope of its own
 	for _, item := range items {
-		// Set iterator variable
-		rt.variables[stmt.Iterator] = item
+		iteration := newScope(rt.scope, false)
+		iteration.vars[stmt.Iterator] = item
 
-		// Execute body
-		for _, bodyStmt := range stmt.Body {
-			if err := rt.executeStatement(ctx, bodyStmt); err != nil {
-				return err
-			}
+		err := rt.withScope(iteration, func() error {
+			return rt.executeBlock(ctx, stmt.Body)
+		})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -364,7 +481,10 @@ func (rt *Runtime) executeSet(ctx context.Context, stmt *ast.SetStatement) error
 	}
 
 	// Set variable
-	rt.variables[stmt.Variable] = value
+	if stmt.Outer {
+		return rt.setOuterVar(stmt.Variable, value)
+	}
+	rt.setVar(stmt.Variable, value)
 	return nil
 }
 
@@ -378,56 +498,27 @@ func (rt *Runtime) executePrint(ctx context.Context, stmt *ast.PrintStatement) e
 	// Format and print value
 	output := rt.formatValue(value)
 	fmt.Fprintln(&rt.output, output)
-	fmt.Println(output)
-	return nil
-}
-
-func (rt *Runtime) executeDefine(ctx context.Context, stmt *ast.DefineStatement) error {
-	// Store automation definition
-	rt.automations[stmt.Name] = stmt
+	fmt.Fprintln(rt.stdout, output)
 	return nil
 }
 
-func (rt *Runtime) executeRun(ctx context.Context, stmt *ast.RunStatement) error {
-	// Find automation
-	automation, ok := rt.automations[stmt.Name]
-	if !ok {
-		return fmt.Errorf("automation '%s' not defined", stmt.Name)
+// executeImport defines the automations of an imported library under its
+// namespace
+func (rt *Runtime) executeImport(ctx context.Context, stmt *ast.ImportStatement) error {
+	if stmt.Library == nil {
+		return fmt.Errorf("import %q was not resolved", stmt.Path)
 	}
 
-	// Check argument count
-	if len(stmt.Arguments) != len(automation.Parameters) {
-		return fmt.Errorf("automation '%s' expects %d arguments, got %d",
-			stmt.Name, len(automation.Parameters), len(stmt.Arguments))
-	}
-
-	// Create new scope with parameters
-	oldVars := rt.variables
-	rt.variables = make(map[string]interface{})
-	for k, v := range oldVars {
-		rt.variables[k] = v
-	}
+	outer, outerFile := rt.namespace, rt.file
+	rt.namespace = outer + stmt.Namespace + "."
+	rt.file = stmt.Library.File
+	defer func() { rt.namespace, rt.file = outer, outerFile }()
 
-	// Bind arguments to parameters
-	for i, param := range automation.Parameters {
-		value, err := rt.evaluateExpression(ctx, stmt.Arguments[i])
-		if err != nil {
-			rt.variables = oldVars
-			return fmt.Errorf("failed to evaluate argument %d: %w", i, err)
+	for _, libStmt := range stmt.Library.Statements {
+		if err := rt.executeStatement(ctx, libStmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt.Path, err)
 		}
-		rt.variables[param] = value
 	}
-
-	// Execute automation body
-	for _, bodyStmt := range automation.Body {
-		if err := rt.executeStatement(ctx, bodyStmt); err != nil {
-			rt.variables = oldVars
-			return err
-		}
-	}
-
-	// Restore scope
-	rt.variables = oldVars
 	return nil
 }
 
@@ -440,8 +531,12 @@ func (rt *Runtime) evaluateExpression(ctx context.Context, expr ast.Expression)
 	case *ast.BooleanLiteral:
 		return e.Value, nil
 	case *ast.Variable:
-		val, ok := rt.variables[e.Name]
+		val, ok := rt.lookupVar(e.Name)
 		if !ok {
+			// Automations are values too, so they can be passed and returned
+			if automation, ok := rt.lookupAutomation(e.Name); ok {
+				return automation, nil
+			}
 			return nil, fmt.Errorf("undefined variable: %s", e.Name)
 		}
 		return val, nil
@@ -624,8 +719,37 @@ func (rt *Runtime) evaluateFunctionCall(ctx context.Context, call *ast.FunctionC
 		}
 		return string(data), nil
 
+	case "read_file":
+		if len(call.Arguments) != 1 {
+			return nil, fmt.Errorf("read_file() expects 1 argument, got %d", len(call.Arguments))
+		}
+		arg, err := rt.evaluateExpression(ctx, call.Arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		path, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("read_file() expects a string path, got %T", arg)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rt.fileDir(), path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read_file(): %w", err)
+		}
+		return string(data), nil
+
 	default:
-		return nil, fmt.Errorf("unknown function: %s", call.Name)
+		// Automations can be called like functions, returning their value
+		automation, err := rt.resolveAutomation(ctx, call.Name)
+		if err != nil {
+			return nil, err
+		}
+		if automation == nil {
+			return nil, fmt.Errorf("unknown function: %s", call.Name)
+		}
+		return rt.callAutomation(ctx, call.Name, call.Arguments)
 	}
 }
 
@@ -659,10 +783,29 @@ func (rt *Runtime) equals(a, b interface{}) bool {
 		return a == b
 	}
 
+	// Numbers are equal by value, whether they are ints, as returned by
+	// len() and int(), or floats
+	if aNum, ok := number(a); ok {
+		if bNum, ok := number(b); ok {
+			return aNum == bNum
+		}
+	}
+
 	// Use deep equality
 	return reflect.DeepEqual(a, b)
 }
 
+// number returns val as a float64 if it is a number
+func number(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
 func (rt *Runtime) toIterable(val interface{}) ([]interface{}, error) {
 	switch v := val.(type) {
 	case []interface{}:
@@ -2,10 +2,18 @@ package runtime
 
 import (
 	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/periplon/bract/internal/dsl/ast"
+	"github.com/periplon/bract/internal/dsl/parser"
+	"github.com/periplon/bract/internal/mcpclient"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -121,6 +129,18 @@ func TestRuntime_Expressions(t *testing.T) {
 			},
 			expected: []interface{}{1.0, 2.0, 3.0},
 		},
+		{
+			name: "int equals float",
+			expr: &ast.BinaryOp{
+				Left: &ast.FunctionCall{
+					Name:      "len",
+					Arguments: []ast.Expression{&ast.StringLiteral{Value: "abc"}},
+				},
+				Operator: "==",
+				Right:    &ast.NumberLiteral{Value: 3},
+			},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -274,6 +294,10 @@ func TestRuntime_FunctionCalls(t *testing.T) {
 	rt := NewRuntime()
 	ctx := context.Background()
 
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.json"), []byte(`{"name":"Ada"}`), 0o644))
+	rt.SetDir(dir)
+
 	tests := []struct {
 		name     string
 		funcCall *ast.FunctionCall
@@ -351,6 +375,26 @@ func TestRuntime_FunctionCalls(t *testing.T) {
 			},
 			expected: `{"key":"value"}`,
 		},
+		{
+			name: "read file relative to the script",
+			funcCall: &ast.FunctionCall{
+				Name: "read_file",
+				Arguments: []ast.Expression{
+					&ast.StringLiteral{Value: "user.json"},
+				},
+			},
+			expected: `{"name":"Ada"}`,
+		},
+		{
+			name: "read missing file",
+			funcCall: &ast.FunctionCall{
+				Name: "read_file",
+				Arguments: []ast.Expression{
+					&ast.StringLiteral{Value: "missing.json"},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "unknown function",
 			funcCall: &ast.FunctionCall{
@@ -457,6 +501,187 @@ func TestRuntime_Assert(t *testing.T) {
 	assert.Contains(t, err.Error(), "This should fail")
 }
 
+func TestRuntime_StepsAndLines(t *testing.T) {
+	rt := NewRuntime()
+	ctx := context.Background()
+
+	var steps []Step
+	rt.OnStep(func(step Step) {
+		steps = append(steps, step)
+	})
+
+	script := &ast.Script{
+		Statements: []ast.Statement{
+			&ast.AssertStatement{
+				Position:   ast.Position{Line: 1},
+				Expression: &ast.BooleanLiteral{Value: true},
+			},
+			&ast.LoopStatement{
+				Position:   ast.Position{Line: 2},
+				Iterator:   "i",
+				Collection: &ast.ArrayLiteral{Elements: []ast.Expression{&ast.NumberLiteral{Value: 1}}},
+				Body: []ast.Statement{
+					&ast.AssertStatement{
+						Position:   ast.Position{Line: 3},
+						Expression: &ast.BooleanLiteral{Value: false},
+						Message:    "inner",
+					},
+				},
+			},
+		},
+	}
+
+	err := rt.Execute(ctx, script)
+	require.Error(t, err)
+
+	// The error points at the innermost failing statement
+	var rtErr *Error
+	require.ErrorAs(t, err, &rtErr)
+	assert.Equal(t, 3, rtErr.Line)
+	assert.Contains(t, err.Error(), "line 3: assertion error: inner")
+
+	require.Len(t, steps, 2)
+	assert.Equal(t, StepAssert, steps[0].Kind)
+	assert.Equal(t, "assert", steps[0].Name)
+	assert.NoError(t, steps[0].Err)
+	assert.Equal(t, "inner", steps[1].Name)
+	assert.Equal(t, 3, steps[1].Line)
+	assert.Error(t, steps[1].Err)
+}
+
+func TestRuntime_Tests(t *testing.T) {
+	assertStmt := func(line int, value bool, message string) *ast.AssertStatement {
+		return &ast.AssertStatement{
+			Position:   ast.Position{Line: line},
+			Expression: &ast.BooleanLiteral{Value: value},
+			Message:    message,
+		}
+	}
+	appendLog := func(entry string) *ast.SetStatement {
+		return &ast.SetStatement{
+			Variable: "log",
+			Value: &ast.BinaryOp{
+				Left:     &ast.Variable{Name: "log"},
+				Operator: "+",
+				Right:    &ast.StringLiteral{Value: entry},
+			},
+		}
+	}
+
+	t.Run("runs every test and guarantees teardown", func(t *testing.T) {
+		rt := NewRuntime()
+		var steps []Step
+		rt.OnStep(func(step Step) {
+			steps = append(steps, step)
+		})
+
+		script := &ast.Script{
+			Statements: []ast.Statement{
+				&ast.SetStatement{Variable: "log", Value: &ast.StringLiteral{Value: ""}},
+				&ast.HookStatement{Kind: ast.HookSetup, Body: []ast.Statement{appendLog("S")}},
+				&ast.HookStatement{Kind: ast.HookTeardown, Body: []ast.Statement{
+					&ast.PrintStatement{Expression: &ast.StringLiteral{Value: "teardown ran"}},
+				}},
+				&ast.HookStatement{Kind: ast.HookAfterEach, Body: []ast.Statement{
+					&ast.PrintStatement{Expression: &ast.StringLiteral{Value: "after_each ran"}},
+				}},
+				&ast.TestStatement{Position: ast.Position{Line: 10}, Name: "failing", Body: []ast.Statement{
+					appendLog("F"),
+					assertStmt(12, false, "boom"),
+				}},
+				&ast.TestStatement{Position: ast.Position{Line: 20}, Name: "passing", Body: []ast.Statement{
+					assertStmt(21, true, ""),
+					// Variables set by an earlier test don't leak into this one
+					&ast.AssertStatement{
+						Position: ast.Position{Line: 22},
+						Expression: &ast.BinaryOp{
+							Left:     &ast.Variable{Name: "log"},
+							Operator: "==",
+							Right:    &ast.StringLiteral{Value: "S"},
+						},
+						Message: "isolated",
+					},
+				}},
+			},
+		}
+
+		err := rt.Execute(context.Background(), script)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrTestsFailed)
+		assert.Contains(t, err.Error(), "1 of 2")
+
+		results := rt.TestResults()
+		require.Len(t, results, 2)
+		assert.Equal(t, "failing", results[0].Name)
+		assert.Contains(t, results[0].Err.Error(), "line 12: assertion error: boom")
+		assert.NoError(t, results[1].Err)
+
+		output := rt.GetOutput()
+		assert.Equal(t, 2, strings.Count(output, "after_each ran"))
+		assert.Contains(t, output, "teardown ran")
+		assert.Contains(t, output, "✗ failing")
+		assert.Contains(t, output, "✓ passing")
+		assert.Contains(t, output, "2 tests: 1 passed, 1 failed")
+
+		var testSteps []Step
+		for _, step := range steps {
+			if step.Kind == StepTest {
+				testSteps = append(testSteps, step)
+			} else if step.Line == 12 {
+				assert.Equal(t, "failing", step.Test)
+			}
+		}
+		require.Len(t, testSteps, 2)
+		assert.Equal(t, 10, testSteps[0].Line)
+	})
+
+	t.Run("failed setup fails every test but still tears down", func(t *testing.T) {
+		rt := NewRuntime()
+
+		script := &ast.Script{
+			Statements: []ast.Statement{
+				&ast.HookStatement{Kind: ast.HookSetup, Body: []ast.Statement{assertStmt(2, false, "no browser")}},
+				&ast.HookStatement{Kind: ast.HookTeardown, Body: []ast.Statement{
+					&ast.PrintStatement{Expression: &ast.StringLiteral{Value: "teardown ran"}},
+				}},
+				&ast.TestStatement{Name: "one", Body: []ast.Statement{assertStmt(5, true, "")}},
+				&ast.TestStatement{Name: "two", Body: []ast.Statement{assertStmt(8, true, "")}},
+			},
+		}
+
+		err := rt.Execute(context.Background(), script)
+		assert.ErrorIs(t, err, ErrTestsFailed)
+
+		for _, result := range rt.TestResults() {
+			require.Error(t, result.Err)
+			assert.Contains(t, result.Err.Error(), "setup failed")
+		}
+		assert.Contains(t, rt.GetOutput(), "teardown ran")
+	})
+
+	t.Run("teardown runs after a cancelled context", func(t *testing.T) {
+		rt := NewRuntime()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		script := &ast.Script{
+			Statements: []ast.Statement{
+				&ast.HookStatement{Kind: ast.HookTeardown, Body: []ast.Statement{
+					&ast.WaitStatement{Condition: &ast.NumberLiteral{Value: 0.01}},
+					&ast.PrintStatement{Expression: &ast.StringLiteral{Value: "teardown ran"}},
+				}},
+				&ast.TestStatement{Name: "waits", Body: []ast.Statement{
+					&ast.WaitStatement{Condition: &ast.NumberLiteral{Value: 1}},
+				}},
+			},
+		}
+
+		err := rt.Execute(ctx, script)
+		assert.ErrorIs(t, err, ErrTestsFailed)
+		assert.Contains(t, rt.GetOutput(), "teardown ran")
+	})
+}
+
 func TestRuntime_Print(t *testing.T) {
 	rt := NewRuntime()
 	ctx := context.Background()
@@ -582,3 +807,336 @@ func TestRuntime_Wait(t *testing.T) {
 		assert.Less(t, elapsed, 100*time.Millisecond)
 	})
 }
+
+// parseScript parses DSL source into a script
+func parseScript(t *testing.T, source string) *ast.Script {
+	t.Helper()
+	tokens, err := parser.NewLexer(source).Tokenize()
+	require.NoError(t, err)
+	script, err := parser.NewParser(tokens).Parse()
+	require.NoError(t, err)
+	return script
+}
+
+// newToolServer starts an MCP server whose "fail" tool returns an error
+// result, whose "ok" tool succeeds and whose "image" tool returns an image,
+// and returns a client connected to it
+func newToolServer(t *testing.T) *mcpclient.Client {
+	t.Helper()
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp.NewTool("ok"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(`{"ok": true}`), nil
+	})
+	s.AddTool(mcp.NewTool("fail"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("Failed to click: tabId is required"), nil
+	})
+	s.AddTool(mcp.NewTool("image"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultImage("1x1 pixel", "iVBORw0KGgo=", "image/png"), nil
+	})
+	ts := httptest.NewServer(server.NewStreamableHTTPServer(s))
+	t.Cleanup(ts.Close)
+
+	client := &mcpclient.Client{}
+	require.NoError(t, client.ConnectURL(context.Background(), ts.URL+"/mcp"))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRuntime_ToolErrors(t *testing.T) {
+	client := newToolServer(t)
+	ctx := context.Background()
+
+	t.Run("error results fail calls without a result variable", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		err := rt.Execute(ctx, parseScript(t, "call ok -> r\ncall fail\nset after = true"))
+		require.Error(t, err)
+
+		var callErr *CallError
+		require.ErrorAs(t, err, &callErr)
+		assert.True(t, callErr.IsError)
+		assert.Equal(t, "fail", callErr.Tool)
+		assert.Equal(t, "Failed to click: tabId is required", callErr.Message)
+		assert.Equal(t, "line 2: fail returned an error: Failed to click: tabId is required", err.Error())
+		assert.Equal(t, map[string]interface{}{"ok": true}, rt.variables["r"])
+		assert.NotContains(t, rt.variables, "after")
+	})
+
+	t.Run("error results fail calls with a result variable", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		err := rt.Execute(ctx, parseScript(t, "call fail -> r\nset after = true"))
+		require.Error(t, err)
+
+		var callErr *CallError
+		require.ErrorAs(t, err, &callErr)
+		assert.True(t, callErr.IsError)
+		assert.NotContains(t, rt.variables, "r")
+		assert.NotContains(t, rt.variables, "after")
+	})
+
+	t.Run("try call stores error results in the result variable", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		err := rt.Execute(ctx, parseScript(t, `
+try call ok -> r
+try call fail -> r2
+assert r.isError != true
+assert r2.isError == true
+assert r2.message == "Failed to click: tabId is required"
+`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"message": "Failed to click: tabId is required",
+			"tool":    "fail",
+			"isError": true,
+			"line":    float64(3),
+		}, rt.variables["r2"])
+	})
+
+	t.Run("expect_error", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		err := rt.Execute(ctx, parseScript(t, `
+expect_error call fail {selector: "#x"} matches "tabId (is )?required" -> e
+assert e.isError == true
+assert e.tool == "fail"
+assert e.message == "Failed to click: tabId is required"
+expect_error call fail
+`))
+		require.NoError(t, err)
+
+		err = rt.Execute(ctx, parseScript(t, `expect_error call ok`))
+		assert.ErrorContains(t, err, "line 1: expected ok to fail, but it succeeded")
+
+		err = rt.Execute(ctx, parseScript(t, `expect_error call fail matches "^timeout"`))
+		assert.ErrorContains(t, err, `fail failed with "Failed to click: tabId is required", which does not match "^timeout"`)
+
+		// Errors of the script itself are not expected failures
+		err = rt.Execute(ctx, parseScript(t, `expect_error call fail {tabId: missing}`))
+		assert.ErrorContains(t, err, "failed to evaluate arguments")
+	})
+
+	t.Run("try catch", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		var steps []Step
+		rt.OnStep(func(step Step) {
+			steps = append(steps, step)
+		})
+
+		err := rt.Execute(ctx, parseScript(t, `
+try {
+  call ok
+  call fail
+  set after = true
+} catch err {
+  set caught = err
+}
+try {
+  assert false, "boom"
+} catch {
+  set anonymous = true
+}
+try {
+  set fine = true
+} catch {
+  set unreachable = true
+}
+`))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"message": "Failed to click: tabId is required",
+			"tool":    "fail",
+			"isError": true,
+			"line":    float64(4),
+		}, rt.variables["caught"])
+		assert.NotContains(t, rt.variables, "after")
+		assert.Equal(t, true, rt.variables["anonymous"])
+		assert.Equal(t, true, rt.variables["fine"])
+		assert.NotContains(t, rt.variables, "unreachable")
+
+		// Caught failures are not reported as failed steps
+		require.Len(t, steps, 1)
+		assert.Equal(t, "ok", steps[0].Name)
+		assert.NoError(t, steps[0].Err)
+	})
+
+	t.Run("errors in catch propagate", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		err := rt.Execute(ctx, parseScript(t, "try {\n  call fail\n} catch err {\n  assert err.isError == false, \"unexpected tool error\"\n}"))
+		assert.ErrorContains(t, err, "line 4: assertion error: unexpected tool error")
+	})
+
+	t.Run("cancellation is not caught", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.UseClient(client)
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err := rt.Execute(ctx, parseScript(t, "try {\n  wait 5\n} catch {\n  set caught = true\n}"))
+		assert.Error(t, err)
+		assert.NotContains(t, rt.variables, "caught")
+	})
+}
+
+func TestRuntime_ContentValues(t *testing.T) {
+	client := newToolServer(t)
+	rt := NewRuntime()
+	rt.UseClient(client)
+
+	err := rt.Execute(context.Background(), parseScript(t, `call image -> shot
+assert shot[0] == "1x1 pixel"
+assert shot[1].type == "image"
+assert shot[1].mimeType == "image/png"
+assert shot[1].data == "iVBORw0KGgo="
+`))
+	require.NoError(t, err)
+}
+
+func TestRuntime_Automations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("return values", func(t *testing.T) {
+		rt := NewRuntime()
+		err := rt.Execute(ctx, parseScript(t, `
+define double(n) {
+  return n * 2
+}
+define nothing() {
+  return
+  set unreachable = true
+}
+define first_over(items, limit) {
+  loop item in items {
+    if item > limit {
+      return item
+    }
+  }
+  return null
+}
+run double(21) -> answer
+run nothing() -> empty
+set found = first_over([1, 5, 9], 4)
+set sum = double(2) + double(3)
+`))
+		require.NoError(t, err)
+		assert.Equal(t, 42.0, rt.variables["answer"])
+		assert.Nil(t, rt.variables["empty"])
+		assert.Contains(t, rt.variables, "empty")
+		assert.Equal(t, 5.0, rt.variables["found"])
+		assert.Equal(t, 10.0, rt.variables["sum"])
+		assert.NotContains(t, rt.variables, "unreachable")
+		assert.NotContains(t, rt.variables, "item")
+	})
+
+	t.Run("recursion", func(t *testing.T) {
+		rt := NewRuntime()
+		err := rt.Execute(ctx, parseScript(t, `
+define fact(n) {
+  if n <= 1 {
+    return 1
+  }
+  return n * fact(n - 1)
+}
+set result = fact(5)
+`))
+		require.NoError(t, err)
+		assert.Equal(t, 120.0, rt.variables["result"])
+
+		err = rt.Execute(ctx, parseScript(t, "define forever() {\n  run forever()\n}\nrun forever()"))
+		assert.ErrorContains(t, err, "maximum call depth of 256 exceeded")
+	})
+
+	t.Run("variables are local to each call", func(t *testing.T) {
+		rt := NewRuntime()
+		err := rt.Execute(ctx, parseScript(t, `
+set x = "global"
+set total = 0
+define shadow() {
+  set x = "local"
+  set seen = x
+  return seen
+}
+define add(n) {
+  set outer total = total + n
+}
+run shadow() -> inner
+run add(2)
+run add(3)
+loop i in [1, 2] {
+  set last = i
+}
+`))
+		require.NoError(t, err)
+		assert.Equal(t, "global", rt.variables["x"])
+		assert.Equal(t, "local", rt.variables["inner"])
+		assert.NotContains(t, rt.variables, "seen")
+		assert.Equal(t, 5.0, rt.variables["total"])
+		assert.Equal(t, 2.0, rt.variables["last"])
+		assert.NotContains(t, rt.variables, "i")
+
+		err = rt.Execute(ctx, parseScript(t, "define bad() {\n  set outer missing = 1\n}\nrun bad()"))
+		assert.ErrorContains(t, err, "line 2: cannot set outer variable missing: not defined outside the current automation")
+	})
+
+	t.Run("closures and page objects", func(t *testing.T) {
+		rt := NewRuntime()
+		err := rt.Execute(ctx, parseScript(t, `
+define counter(start) {
+  set count = start
+  define next() {
+    set outer count = count + 1
+    return count
+  }
+  define current() {
+    return count
+  }
+  return {next: next, current: current}
+}
+run counter(10) -> c
+run c.next()
+run c.next() -> twelve
+set now = c.current()
+print c
+`))
+		require.NoError(t, err)
+		assert.Equal(t, 12.0, rt.variables["twelve"])
+		assert.Equal(t, 12.0, rt.variables["now"])
+
+		// Nested automations are only visible where they are defined
+		err = rt.Execute(ctx, parseScript(t, "run next()"))
+		assert.ErrorContains(t, err, "automation 'next' not defined")
+
+		err = rt.Execute(ctx, parseScript(t, "set obj = {name: 1}\nrun obj.name()"))
+		assert.ErrorContains(t, err, "'obj.name' is not an automation")
+
+		err = rt.Execute(ctx, parseScript(t, "set y = missing(1)"))
+		assert.ErrorContains(t, err, "unknown function: missing")
+	})
+
+	t.Run("return is not caught", func(t *testing.T) {
+		rt := NewRuntime()
+		err := rt.Execute(ctx, parseScript(t, `
+define early() {
+  try {
+    return "from try"
+  } catch {
+    return "from catch"
+  }
+  return "after"
+}
+run early() -> result
+`))
+		require.NoError(t, err)
+		assert.Equal(t, "from try", rt.variables["result"])
+	})
+}
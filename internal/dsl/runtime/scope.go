@@ -0,0 +1,247 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/periplon/bract/internal/dsl/ast"
+)
+
+// maxCallDepth bounds nested automation calls, so runaway recursion fails
+// with an error instead of exhausting the stack
+const maxCallDepth = 256
+
+// scope holds the variables and automations visible to running code.
+//
+// Scopes form a chain: the script itself, each test and each automation call
+// run in a frame, and loop iterations and catch blocks add a block scope
+// holding the loop iterator or caught error. Reading a name searches the
+// chain outwards. set assigns to the name in the current frame (or a block
+// scope within it that binds the name), creating it in the frame if needed,
+// so automations and tests never change their caller's variables by
+// accident; set outer assigns to an existing variable outside the frame.
+//
+// An automation call's frame is enclosed by the scope its define ran in, so
+// nested automations see the variables of the automation defining them.
+type scope struct {
+	vars        map[string]interface{}
+	automations map[string]*automation
+	parent      *scope
+	frame       bool
+}
+
+// newScope creates a scope enclosed by parent
+func newScope(parent *scope, frame bool) *scope {
+	return &scope{
+		vars:        make(map[string]interface{}),
+		automations: make(map[string]*automation),
+		parent:      parent,
+		frame:       frame,
+	}
+}
+
+// automation is a defined automation with the scope, namespace and file it
+// was defined in
+type automation struct {
+	*ast.DefineStatement
+	scope     *scope
+	namespace string
+	file      string
+}
+
+// String describes the automation when printed
+func (a *automation) String() string {
+	return fmt.Sprintf("<automation %s>", a.Name)
+}
+
+// MarshalJSON encodes the automation as its description, so values holding
+// automations can be printed
+func (a *automation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// returnSignal carries the value of a return statement out of an
+// automation body
+type returnSignal struct {
+	value interface{}
+}
+
+func (r *returnSignal) Error() string {
+	return "return outside of an automation"
+}
+
+// withScope runs fn with s as the current scope
+func (rt *Runtime) withScope(s *scope, fn func() error) error {
+	outer := rt.scope
+	rt.scope = s
+	defer func() { rt.scope = outer }()
+	return fn()
+}
+
+// lookupVar returns the value of the named variable
+func (rt *Runtime) lookupVar(name string) (interface{}, bool) {
+	for s := rt.scope; s != nil; s = s.parent {
+		if value, ok := s.vars[name]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// setVar assigns a variable in the current frame
+func (rt *Runtime) setVar(name string, value interface{}) {
+	for s := rt.scope; s != nil; s = s.parent {
+		if _, ok := s.vars[name]; ok || s.frame {
+			s.vars[name] = value
+			return
+		}
+	}
+}
+
+// setOuterVar assigns an existing variable outside the current frame
+func (rt *Runtime) setOuterVar(name string, value interface{}) error {
+	s := rt.scope
+	for !s.frame {
+		s = s.parent
+	}
+	for s = s.parent; s != nil; s = s.parent {
+		if _, ok := s.vars[name]; ok {
+			s.vars[name] = value
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot set outer variable %s: not defined outside the current automation", name)
+}
+
+// lookupAutomation finds an automation by name. Top-level automations are
+// looked up in the namespace of the running code first.
+func (rt *Runtime) lookupAutomation(name string) (*automation, bool) {
+	for s := rt.scope; s != nil; s = s.parent {
+		if s == rt.globals && rt.namespace != "" {
+			if automation, ok := s.automations[rt.namespace+name]; ok {
+				return automation, true
+			}
+		}
+		if automation, ok := s.automations[name]; ok {
+			return automation, true
+		}
+	}
+	return nil, false
+}
+
+func (rt *Runtime) executeDefine(ctx context.Context, stmt *ast.DefineStatement) error {
+	// Store automation definition; top-level ones are qualified by namespace
+	name := stmt.Name
+	if rt.scope == rt.globals {
+		name = rt.namespace + name
+	}
+	rt.scope.automations[name] = &automation{DefineStatement: stmt, scope: rt.scope, namespace: rt.namespace, file: rt.file}
+	return nil
+}
+
+func (rt *Runtime) executeRun(ctx context.Context, stmt *ast.RunStatement) error {
+	value, err := rt.callAutomation(ctx, stmt.Name, stmt.Arguments)
+	if err != nil {
+		return err
+	}
+
+	// Store result if variable specified
+	if stmt.Variable != "" {
+		rt.setVar(stmt.Variable, value)
+	}
+	return nil
+}
+
+func (rt *Runtime) executeReturn(ctx context.Context, stmt *ast.ReturnStatement) error {
+	var value interface{}
+	if stmt.Value != nil {
+		var err error
+		if value, err = rt.evaluateExpression(ctx, stmt.Value); err != nil {
+			return fmt.Errorf("failed to evaluate return value: %w", err)
+		}
+	}
+	return &returnSignal{value: value}
+}
+
+// resolveAutomation finds the automation called by name. Besides defined
+// automations, a qualified name can refer to one held in a variable, such as
+// page.click for an object returned by a page object automation. It returns
+// nil if name refers to no automation.
+func (rt *Runtime) resolveAutomation(ctx context.Context, name string) (*automation, error) {
+	if automation, ok := rt.lookupAutomation(name); ok {
+		return automation, nil
+	}
+
+	parts := strings.Split(name, ".")
+	if _, ok := rt.lookupVar(parts[0]); !ok {
+		return nil, nil
+	}
+
+	var expr ast.Expression = &ast.Variable{Name: parts[0]}
+	for _, field := range parts[1:] {
+		expr = &ast.FieldAccess{Object: expr, Field: field}
+	}
+	value, err := rt.evaluateExpression(ctx, expr)
+	if err != nil {
+		return nil, err
+	}
+	if automation, ok := value.(*automation); ok {
+		return automation, nil
+	}
+	return nil, fmt.Errorf("'%s' is not an automation", name)
+}
+
+// callAutomation runs an automation in a new frame and returns the value of
+// its return statement, or nil if it has none
+func (rt *Runtime) callAutomation(ctx context.Context, name string, argExprs []ast.Expression) (interface{}, error) {
+	// Find automation
+	automation, err := rt.resolveAutomation(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if automation == nil {
+		return nil, fmt.Errorf("automation '%s' not defined", name)
+	}
+
+	// Check argument count
+	if len(argExprs) != len(automation.Parameters) {
+		return nil, fmt.Errorf("automation '%s' expects %d arguments, got %d",
+			name, len(automation.Parameters), len(argExprs))
+	}
+
+	if rt.callDepth >= maxCallDepth {
+		return nil, fmt.Errorf("automation '%s': maximum call depth of %d exceeded", name, maxCallDepth)
+	}
+
+	// Bind arguments to parameters in a frame enclosed by the definition
+	frame := newScope(automation.scope, true)
+	for i, param := range automation.Parameters {
+		value, err := rt.evaluateExpression(ctx, argExprs[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate argument %d: %w", i, err)
+		}
+		frame.vars[param] = value
+	}
+
+	// Execute automation body in the namespace and file it was defined in
+	outerNamespace, outerFile := rt.namespace, rt.file
+	rt.namespace, rt.file = automation.namespace, automation.file
+	rt.callDepth++
+	defer func() {
+		rt.namespace, rt.file = outerNamespace, outerFile
+		rt.callDepth--
+	}()
+
+	err = rt.withScope(frame, func() error {
+		return rt.executeBlock(ctx, automation.Body)
+	})
+
+	var ret *returnSignal
+	if errors.As(err, &ret) {
+		return ret.value, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StepKind identifies the kind of statement reported as a step
+type StepKind string
+
+// Statements reported as steps
+const (
+	StepCall   StepKind = "call"
+	StepAssert StepKind = "assert"
+	StepTest   StepKind = "test"
+)
+
+// Step is the outcome of a call, assert or test statement
+type Step struct {
+	Kind     StepKind
+	Name     string // tool name, assertion message or test name
+	File     string // file of the statement, such as an imported library, if the script was read from one
+	Line     int
+	Test     string // enclosing test, if any
+	Duration time.Duration
+	Err      error
+}
+
+// OnStep registers fn to be called after every call, assert and test statement
+func (rt *Runtime) OnStep(fn func(Step)) {
+	rt.onStep = fn
+}
+
+func (rt *Runtime) reportStep(step Step) {
+	step.File = rt.file
+	if rt.onStep != nil {
+		rt.onStep(step)
+	}
+}
+
+// Error is a runtime error annotated with the DSL source file and line it
+// occurred on
+type Error struct {
+	File string // empty if the script was not read from a file
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// withLine annotates err with file and line, unless it is already annotated
+// by a nested statement, the line is unknown or err is a return
+func withLine(err error, file string, line int) error {
+	if err == nil || line == 0 {
+		return err
+	}
+	var rtErr *Error
+	var ret *returnSignal
+	if errors.As(err, &rtErr) || errors.As(err, &ret) {
+		return err
+	}
+	return &Error{File: file, Line: line, Err: err}
+}
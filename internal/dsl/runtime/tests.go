@@ -0,0 +1,190 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/periplon/bract/internal/dsl/ast"
+)
+
+// ErrTestsFailed is returned by Execute when one or more tests failed
+var ErrTestsFailed = errors.New("tests failed")
+
+// cleanupTimeout bounds after_each and teardown blocks, which still run after
+// the script's context is cancelled
+const cleanupTimeout = 30 * time.Second
+
+// TestResult is the outcome of a test block
+type TestResult struct {
+	Name     string
+	Line     int
+	Duration time.Duration
+	Err      error
+}
+
+// TestResults returns the results of the tests run so far
+func (rt *Runtime) TestResults() []TestResult {
+	return rt.testResults
+}
+
+// hasTests reports whether the script uses test or hook blocks
+func hasTests(script *ast.Script) bool {
+	for _, stmt := range script.Statements {
+		switch stmt.(type) {
+		case *ast.TestStatement, *ast.HookStatement:
+			return true
+		}
+	}
+	return false
+}
+
+// executeTests runs a script containing tests. Top-level statements run in
+// order; setup runs before the first test, and every test runs even if an
+// earlier one failed. Teardown always runs once tests have started.
+func (rt *Runtime) executeTests(ctx context.Context, script *ast.Script) error {
+	hooks := make(map[string][]*ast.HookStatement)
+	for _, stmt := range script.Statements {
+		if hook, ok := stmt.(*ast.HookStatement); ok {
+			hooks[hook.Kind] = append(hooks[hook.Kind], hook)
+		}
+	}
+
+	var (
+		started  bool
+		setupErr error
+		runErr   error
+	)
+
+	for _, stmt := range script.Statements {
+		switch s := stmt.(type) {
+		case *ast.HookStatement:
+			continue
+		case *ast.TestStatement:
+			if !started {
+				started = true
+				setupErr = rt.runHooks(ctx, hooks[ast.HookSetup])
+			}
+			rt.runTest(ctx, s, hooks, setupErr)
+		default:
+			runErr = rt.executeStatement(ctx, stmt)
+		}
+		if runErr != nil {
+			break
+		}
+	}
+
+	var teardownErr error
+	if started {
+		cleanupCtx, cancel := cleanupContext(ctx)
+		teardownErr = rt.runHooks(cleanupCtx, hooks[ast.HookTeardown])
+		cancel()
+	}
+
+	rt.printSummary()
+
+	failed := 0
+	for _, result := range rt.testResults {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	switch {
+	case runErr != nil:
+		return runErr
+	case teardownErr != nil:
+		return fmt.Errorf("teardown failed: %w", teardownErr)
+	case failed > 0:
+		return fmt.Errorf("%w: %d of %d", ErrTestsFailed, failed, len(rt.testResults))
+	}
+	return nil
+}
+
+// runTest runs one test with its before_each and after_each hooks in a frame
+// of its own. after_each runs even if the test fails.
+func (rt *Runtime) runTest(ctx context.Context, test *ast.TestStatement, hooks map[string][]*ast.HookStatement, setupErr error) {
+	start := time.Now()
+	rt.currentTest = test.Name
+	rt.started = start
+
+	var err error
+	if setupErr != nil {
+		err = fmt.Errorf("setup failed: %w", setupErr)
+	} else {
+		err = rt.withScope(newScope(rt.globals, true), func() error {
+			err := rt.runHooks(ctx, hooks[ast.HookBeforeEach])
+			if err == nil {
+				err = rt.executeBlock(ctx, test.Body)
+			}
+
+			cleanupCtx, cancel := cleanupContext(ctx)
+			defer cancel()
+			if afterErr := rt.runHooks(cleanupCtx, hooks[ast.HookAfterEach]); afterErr != nil && err == nil {
+				err = fmt.Errorf("after_each failed: %w", afterErr)
+			}
+			return err
+		})
+	}
+
+	rt.currentTest = ""
+
+	result := TestResult{
+		Name:     test.Name,
+		Line:     test.Line,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	rt.testResults = append(rt.testResults, result)
+	rt.reportStep(Step{Kind: StepTest, Name: test.Name, Line: test.Line, Test: test.Name, Duration: result.Duration, Err: err})
+}
+
+// runHooks runs hook blocks in order, stopping at the first error
+func (rt *Runtime) runHooks(ctx context.Context, hooks []*ast.HookStatement) error {
+	for _, hook := range hooks {
+		if err := rt.executeBlock(ctx, hook.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeBlock runs statements in order, stopping at the first error
+func (rt *Runtime) executeBlock(ctx context.Context, statements []ast.Statement) error {
+	for _, stmt := range statements {
+		if err := rt.executeStatement(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanupContext returns a context for cleanup blocks that outlives
+// cancellation of ctx
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// printSummary prints the outcome of every test
+func (rt *Runtime) printSummary() {
+	passed := 0
+	rt.println("\n=== Test Summary ===")
+	for _, result := range rt.testResults {
+		if result.Err == nil {
+			passed++
+			rt.println(fmt.Sprintf("✓ %s (%.2fs)", result.Name, result.Duration.Seconds()))
+			continue
+		}
+		rt.println(fmt.Sprintf("✗ %s (%.2fs)", result.Name, result.Duration.Seconds()))
+		rt.println(fmt.Sprintf("    %v", result.Err))
+	}
+	rt.println(fmt.Sprintf("%d tests: %d passed, %d failed",
+		len(rt.testResults), passed, len(rt.testResults)-passed))
+}
+
+// println writes a line to the output, like print
+func (rt *Runtime) println(line string) {
+	fmt.Fprintln(&rt.output, line)
+	fmt.Fprintln(rt.stdout, line)
+}
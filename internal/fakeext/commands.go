@@ -0,0 +1,1397 @@
+package fakeext
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/periplon/bract/internal/browser"
+	"golang.org/x/net/html"
+)
+
+// Size of the image returned for screenshots, and of the viewport
+const (
+	screenshotWidth  = 800
+	screenshotHeight = 600
+	screenshotBand   = 60
+)
+
+// params holds the parameters of any command; each command reads the fields
+// it needs
+type params struct {
+	TabID          int                                 `json:"tabId"`
+	URL            string                              `json:"url"`
+	Active         *bool                               `json:"active"`
+	Selector       string                              `json:"selector"`
+	Text           string                              `json:"text"`
+	ClearFirst     bool                                `json:"clearFirst"`
+	ContentType    string                              `json:"contentType"`
+	Attribute      string                              `json:"attribute"`
+	Script         string                              `json:"script"`
+	State          string                              `json:"state"`
+	X              *float64                            `json:"x"`
+	Y              *float64                            `json:"y"`
+	Format         string                              `json:"format"`
+	Key            string                              `json:"key"`
+	Value          string                              `json:"value"`
+	Name           string                              `json:"name"`
+	Domain         string                              `json:"domain"`
+	Path           string                              `json:"path"`
+	Secure         bool                                `json:"secure"`
+	HTTPOnly       bool                                `json:"httpOnly"`
+	ExpirationDate float64                             `json:"expirationDate"`
+	Limit          int                                 `json:"limit"`
+	Action         string                              `json:"action"`
+	MaxDuration    float64                             `json:"maxDuration"`
+	Route          *browser.Route                      `json:"route"`
+	ID             string                              `json:"id"`
+	Columns        []browser.TableColumn               `json:"columns"`
+	Fields         map[string]*browser.StructuredField `json:"fields"`
+	Button         string                              `json:"button"`
+	ClickCount     int                                 `json:"clickCount"`
+	Modifiers      []string                            `json:"modifiers"`
+	Keys           []browser.KeyPress                  `json:"keys"`
+	Source         *browser.DragPoint                  `json:"source"`
+	Target         *browser.DragPoint                  `json:"target"`
+	Selection      browser.OptionSelection             `json:"selection"`
+	Checked        bool                                `json:"checked"`
+	UploadID       string                              `json:"uploadId"`
+	Index          int                                 `json:"index"`
+	Data           string                              `json:"data"`
+	Files          []browser.UploadedFile              `json:"files"`
+	DownloadID     int                                 `json:"downloadId"`
+	Hosts          []string                            `json:"hosts"`
+}
+
+type commandHandler func(p params) (interface{}, error)
+
+// tab is an open tab with its navigation history
+type tab struct {
+	id      int
+	history []*page
+	current int
+	scrollX float64
+	scrollY float64
+	session map[string]map[string]string // sessionStorage by origin
+
+	recording      bool
+	recordingStart time.Time
+	capturing      bool // network capture running
+}
+
+func (t *tab) page() *page {
+	return t.history[t.current]
+}
+
+// commandHandlers maps the commands of the server to their handlers. Commands
+// the server sends without a tabs. prefix are listed under both names.
+func (e *Extension) commandHandlers() map[string]commandHandler {
+	return map[string]commandHandler{
+		"tabs.list":                e.listTabs,
+		"tabs.create":              e.createTab,
+		"tabs.close":               e.closeTab,
+		"tabs.activate":            e.activateTab,
+		"tabs.navigate":            e.navigate,
+		"tabs.reload":              e.reload,
+		"reload":                   e.reload,
+		"tabs.goBack":              e.goBack,
+		"tabs.goForward":           e.goForward,
+		"tabs.executeScript":       e.executeScript,
+		"tabs.extractText":         e.extractText,
+		"tabs.extractRows":         e.extractRows,
+		"tabs.extractStructured":   e.extractStructured,
+		"tabs.click":               e.click,
+		"tabs.type":                e.typeText,
+		"tabs.pressKey":            e.pressKey,
+		"tabs.hover":               e.hover,
+		"tabs.drag":                e.drag,
+		"tabs.selectOption":        e.selectOption,
+		"tabs.setChecked":          e.setChecked,
+		"tabs.uploadChunk":         e.uploadChunk,
+		"tabs.uploadFile":          e.uploadFile,
+		"tabs.waitForElement":      e.waitForElement,
+		"tabs.scroll":              e.scroll,
+		"tabs.captureScreenshot":   e.captureScreenshot,
+		"tabs.captureVideo":        e.captureVideo,
+		"tabs.findElements":        e.findElements,
+		"tabs.getValue":            e.getValue,
+		"tabs.getCookies":          e.getCookies,
+		"tabs.setCookie":           e.setCookie,
+		"tabs.deleteCookie":        e.deleteCookie,
+		"tabs.getLocalStorage":     e.getLocalStorage,
+		"tabs.setLocalStorage":     e.setLocalStorage,
+		"tabs.clearLocalStorage":   e.clearLocalStorage,
+		"tabs.getSessionStorage":   e.getSessionStorage,
+		"tabs.setSessionStorage":   e.setSessionStorage,
+		"tabs.clearSessionStorage": e.clearSessionStorage,
+		"tabs.getActionables":      e.getActionables,
+		"tabs.getPageTitle":        e.getPageTitle,
+		"getPageTitle":             e.getPageTitle,
+		"network.addRoute":         e.addRoute,
+		"network.removeRoute":      e.removeRoute,
+		"network.startCapture":     e.startNetworkCapture,
+		"network.stopCapture":      e.stopNetworkCapture,
+		"downloads.read":           e.readDownload,
+	}
+}
+
+// Tabs returns the open tabs in tab strip order
+func (e *Extension) Tabs() []browser.Tab {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tabs := make([]browser.Tab, len(e.tabs))
+	for i, t := range e.tabs {
+		tabs[i] = e.tabInfo(t)
+	}
+	return tabs
+}
+
+// tabInfo describes t as the extension reports tabs. e.mu must be held.
+func (e *Extension) tabInfo(t *tab) browser.Tab {
+	index := 0
+	for i, other := range e.tabs {
+		if other == t {
+			index = i
+		}
+	}
+	p := t.page()
+	return browser.Tab{
+		ID:     t.id,
+		URL:    p.url,
+		Title:  p.title(),
+		Active: t.id == e.activeID,
+		Index:  index,
+	}
+}
+
+// tab returns the tab with the given ID, or the active tab for IDs below 1
+func (e *Extension) tab(id int) (*tab, error) {
+	if id < 1 {
+		id = e.activeID
+	}
+	for _, t := range e.tabs {
+		if t.id == id {
+			return t, nil
+		}
+	}
+	if id < 1 {
+		return nil, fmt.Errorf("no active tab")
+	}
+	return nil, fmt.Errorf("no tab with id: %d", id)
+}
+
+// load navigates t to rawURL, adding it to the tab's history. The document
+// request goes through the network routes first.
+func (e *Extension) load(t *tab, rawURL string) error {
+	p, err := e.routePage(t, rawURL)
+	if p == nil && err == nil {
+		p, err = loadPage(e.opts.Fixtures, rawURL)
+	}
+	e.captureDocument(t, rawURL, p, err)
+	if err != nil {
+		return err
+	}
+	t.history = append(t.history[:t.current+1], p)
+	t.current = len(t.history) - 1
+	t.scrollX, t.scrollY = 0, 0
+
+	e.emit("navigationCommitted", browser.NavigationEvent{TabID: t.id, URL: p.url, TransitionType: "link"})
+	e.runPageScripts(t)
+	e.emit("pageLoad", browser.PageLoadEvent{TabID: t.id, URL: p.url, Title: p.title()})
+	return nil
+}
+
+// navigation is the result of commands that load a page
+func navigation(t *tab) map[string]interface{} {
+	p := t.page()
+	return map[string]interface{}{
+		"success": true,
+		"tabId":   t.id,
+		"url":     p.url,
+		"title":   p.title(),
+		"status":  p.status,
+	}
+}
+
+var success = map[string]interface{}{"success": true}
+
+// Tab commands
+
+func (e *Extension) listTabs(p params) (interface{}, error) {
+	tabs := make([]browser.Tab, len(e.tabs))
+	for i, t := range e.tabs {
+		tabs[i] = e.tabInfo(t)
+	}
+	return tabs, nil
+}
+
+func (e *Extension) createTab(p params) (interface{}, error) {
+	t := &tab{id: e.nextID, current: -1, session: make(map[string]map[string]string)}
+	if err := e.load(t, p.URL); err != nil {
+		return nil, err
+	}
+	e.nextID++
+	e.tabs = append(e.tabs, t)
+	if p.Active == nil || *p.Active || e.activeID == 0 {
+		e.activeID = t.id
+	}
+	info := e.tabInfo(t)
+	e.emit("tabCreated", browser.TabEvent{TabID: t.id, URL: info.URL, Title: info.Title, Status: "complete", Active: info.Active})
+	return info, nil
+}
+
+func (e *Extension) closeTab(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	for i, other := range e.tabs {
+		if other == t {
+			e.tabs = append(e.tabs[:i], e.tabs[i+1:]...)
+			break
+		}
+	}
+	if e.activeID == t.id {
+		e.activeID = 0
+		if len(e.tabs) > 0 {
+			e.activeID = e.tabs[len(e.tabs)-1].id
+		}
+	}
+	e.emit("tabClosed", browser.TabEvent{TabID: t.id})
+	return success, nil
+}
+
+func (e *Extension) activateTab(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	e.activeID = t.id
+	return success, nil
+}
+
+// Navigation commands
+
+func (e *Extension) navigate(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if p.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	if err := e.load(t, p.URL); err != nil {
+		return nil, err
+	}
+	return navigation(t), nil
+}
+
+func (e *Extension) reload(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	reloaded, err := loadPage(e.opts.Fixtures, t.page().url)
+	if err != nil {
+		return nil, err
+	}
+	t.history[t.current] = reloaded
+	return success, nil
+}
+
+func (e *Extension) goBack(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if t.current == 0 {
+		return nil, fmt.Errorf("no previous page in history")
+	}
+	t.current--
+	return navigation(t), nil
+}
+
+func (e *Extension) goForward(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if t.current == len(t.history)-1 {
+		return nil, fmt.Errorf("no next page in history")
+	}
+	t.current++
+	return navigation(t), nil
+}
+
+// Content commands
+
+// executeScript evaluates the few expressions scripts commonly use to read
+// page state; the fake extension has no JavaScript engine
+func (e *Extension) executeScript(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	page := t.page()
+
+	script := strings.TrimSpace(p.Script)
+	if ok, err := e.runConsoleStatement(t, script, "executeScript"); ok {
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"result": nil}, nil
+	}
+
+	script = strings.TrimSuffix(strings.TrimPrefix(script, "return "), ";")
+	var result interface{}
+	switch strings.TrimSpace(script) {
+	case "document.title":
+		result = page.title()
+	case "document.URL", "document.baseURI", "location.href", "window.location.href", "document.location.href":
+		result = page.url
+	case "document.body.innerText", "document.body.textContent":
+		result = textContent(page.doc)
+	case "document.documentElement.outerHTML":
+		result = outerHTML(page.doc.FirstChild)
+	case "window.scrollX", "window.pageXOffset":
+		result = t.scrollX
+	case "window.scrollY", "window.pageYOffset":
+		result = t.scrollY
+	case "window.innerWidth":
+		result = screenshotWidth
+	case "window.innerHeight":
+		result = screenshotHeight
+	default:
+		return nil, fmt.Errorf("fake extension cannot evaluate script: %s", p.Script)
+	}
+	return map[string]interface{}{"result": result}, nil
+}
+
+func (e *Extension) extractText(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	selector := p.Selector
+	if selector == "" {
+		selector = "body"
+	}
+	nodes, err := t.page().locate(p.Hosts, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		switch p.ContentType {
+		case "html":
+			texts = append(texts, innerHTML(n))
+		case "outerHTML":
+			texts = append(texts, outerHTML(n))
+		case "attribute":
+			value, _ := getAttr(n, p.Attribute)
+			texts = append(texts, value)
+		default:
+			texts = append(texts, textContent(n))
+		}
+	}
+	return map[string]interface{}{"text": texts}, nil
+}
+
+// extractRows reads the columns of each element matching the selector, with
+// null for columns matching nothing in the row
+func (e *Extension) extractRows(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := t.page().locate(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	selectors := make([]selector, len(p.Columns))
+	for i, column := range p.Columns {
+		if column.Selector == "" {
+			continue
+		}
+		if selectors[i], err = parseSelector(column.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([][]*string, 0, len(nodes))
+	for _, n := range nodes {
+		row := make([]*string, len(p.Columns))
+		for i, column := range p.Columns {
+			target := n
+			if selectors[i] != nil {
+				// Like querySelector, the row itself is not a match
+				target = nil
+				for _, m := range querySelectorAll(n, selectors[i]) {
+					if m != n {
+						target = m
+						break
+					}
+				}
+			}
+			if target == nil {
+				continue
+			}
+			value := textContent(target)
+			if column.Attribute != "" {
+				var ok bool
+				if value, ok = getAttr(target, column.Attribute); !ok {
+					continue
+				}
+			}
+			row[i] = &value
+		}
+		rows = append(rows, row)
+	}
+	return map[string]interface{}{"rows": rows}, nil
+}
+
+// extractStructured reads the fields of a schema in the element matching the
+// selector, or the document
+func (e *Extension) extractStructured(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	root := t.page().doc
+	if p.Selector != "" {
+		if root, err = t.page().locateOne(p.Hosts, p.Selector); err != nil {
+			return nil, err
+		}
+	}
+	data, err := readFields(root, p.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"data": data}, nil
+}
+
+// readFields reads the fields of a schema inside n, leaving out those that
+// match nothing
+func readFields(n *html.Node, fields map[string]*browser.StructuredField) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for name, field := range fields {
+		matches := []*html.Node{n}
+		if field.Selector != "" {
+			sel, err := parseSelector(field.Selector)
+			if err != nil {
+				return nil, err
+			}
+			matches = nil
+			for _, m := range querySelectorAll(n, sel) {
+				if m != n {
+					matches = append(matches, m)
+				}
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if !field.Multiple {
+			matches = matches[:1]
+		}
+
+		values := make([]interface{}, len(matches))
+		for i, m := range matches {
+			var err error
+			if values[i], err = readField(m, field); err != nil {
+				return nil, err
+			}
+		}
+		if field.Multiple {
+			data[name] = values
+		} else {
+			data[name] = values[0]
+		}
+	}
+	return data, nil
+}
+
+// readField reads a field of a schema from the element it matches: its
+// fields, its attribute, null without the attribute, or its text
+func readField(n *html.Node, field *browser.StructuredField) (interface{}, error) {
+	if field.Fields != nil {
+		return readFields(n, field.Fields)
+	}
+	if field.Attribute != "" {
+		if value, ok := getAttr(n, field.Attribute); ok {
+			return value, nil
+		}
+		return nil, nil
+	}
+	return textContent(n), nil
+}
+
+func (e *Extension) getPageTitle(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"title": t.page().title()}, nil
+}
+
+// captureScreenshot renders nothing of the page but a band at the top whose
+// color is derived from the page text, so screenshots of different pages
+// differ. Element screenshots only check that the element exists.
+func (e *Extension) captureScreenshot(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Selector != "" {
+		if _, err := t.page().locateOne(p.Hosts, p.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, screenshotWidth, screenshotHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	hash := fnv.New32a()
+	hash.Write([]byte(textContent(t.page().doc)))
+	sum := hash.Sum32()
+	band := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+	draw.Draw(img, image.Rect(0, 0, screenshotWidth, screenshotBand), &image.Uniform{C: band}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	mime := "image/png"
+	if p.Format == "jpeg" || p.Format == "jpg" {
+		mime = "image/jpeg"
+		if err := jpeg.Encode(&buf, img, nil); err != nil {
+			return nil, err
+		}
+	} else if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"dataUrl": "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// captureVideo starts and stops recordings. The fake extension renders
+// nothing, so a stopped recording is an empty WebM file.
+func (e *Extension) captureVideo(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Action {
+	case "start":
+		if t.recording {
+			return nil, fmt.Errorf("tab %d is already being recorded", t.id)
+		}
+		t.recording, t.recordingStart = true, time.Now()
+		return map[string]interface{}{"success": true, "tabId": t.id}, nil
+	case "stop":
+		if !t.recording {
+			return nil, fmt.Errorf("tab %d is not being recorded", t.id)
+		}
+		t.recording = false
+		return map[string]interface{}{
+			"dataUrl":  "data:video/webm;base64,",
+			"duration": time.Since(t.recordingStart).Seconds(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid video action: %q", p.Action)
+	}
+}
+
+func (e *Extension) findElements(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := t.page().locate(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if p.Limit > 0 && len(nodes) > p.Limit {
+		nodes = nodes[:p.Limit]
+	}
+
+	// Fixtures have no layout, so elements have no bounding box
+	elements := make([]browser.Element, 0, len(nodes))
+	for i, n := range nodes {
+		attributes := make(map[string]string, len(n.Attr))
+		for _, a := range n.Attr {
+			attributes[a.Key] = a.Val
+		}
+		_, hidden := getAttr(n, "hidden")
+		elements = append(elements, browser.Element{
+			Index:      i,
+			Tag:        n.Data,
+			Text:       textContent(n),
+			Attributes: attributes,
+			Selector:   cssPath(n),
+			Visible:    !hidden,
+		})
+	}
+	return map[string]interface{}{"elements": elements}, nil
+}
+
+func (e *Extension) getValue(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := t.page().locateOne(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	value := browser.ElementValue{Tag: n.Data}
+	switch n.Data {
+	case "input":
+		value.Type, _ = getAttr(n, "type")
+		if value.Type == "" {
+			value.Type = "text"
+		}
+		value.Value, _ = getAttr(n, "value")
+		if value.Type == "file" {
+			// Browsers hide the path of the files chosen
+			value.Value = ""
+			for i, file := range t.page().files[n] {
+				if i == 0 {
+					value.Value = `C:\fakepath\` + file.Name
+				}
+				value.Files = append(value.Files, file.Name)
+			}
+		}
+		if value.Type == "checkbox" || value.Type == "radio" {
+			_, checked := getAttr(n, "checked")
+			value.Checked = &checked
+			if value.Value == "" {
+				value.Value = "on"
+			}
+		}
+	case "textarea":
+		if v, ok := getAttr(n, "value"); ok {
+			value.Value = v
+		} else {
+			value.Value = textContent(n)
+		}
+	case "select":
+		options := querySelectorAll(n, selector{{{tag: "option"}}})
+		var selected []string
+		for _, option := range options {
+			if _, ok := getAttr(option, "selected"); ok {
+				selected = append(selected, optionValue(option))
+			}
+		}
+		_, multiple := getAttr(n, "multiple")
+		if len(selected) == 0 && len(options) > 0 && !multiple {
+			selected = append(selected, optionValue(options[0]))
+		}
+		if len(selected) > 0 {
+			value.Value = selected[0]
+		}
+		if multiple {
+			value.Values = selected
+		}
+	default:
+		return nil, fmt.Errorf("element is not a form field: %s", p.Selector)
+	}
+	return value, nil
+}
+
+// optionValue returns the value of an option, which defaults to its text
+func optionValue(n *html.Node) string {
+	if value, ok := getAttr(n, "value"); ok {
+		return value
+	}
+	return textContent(n)
+}
+
+// Interaction commands
+
+// click activates the element with the left button: it toggles checkboxes,
+// checks radio buttons and follows links, in a new background tab with
+// Control, Meta or the middle button, or downloads them when they have a
+// download attribute. Right clicks open the context menu,
+// which the fake has none of.
+func (e *Extension) click(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	pg := t.page()
+	n, err := pg.locateOne(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if p.Button == "right" {
+		return success, nil
+	}
+	newTab := p.Button == "middle"
+	for _, modifier := range p.Modifiers {
+		newTab = newTab || modifier == "Control" || modifier == "Meta"
+	}
+
+	if p.Button != "middle" {
+		pg.focused = n
+		for i := 0; i < max(p.ClickCount, 1); i++ {
+			toggle(n)
+		}
+	}
+
+	// Clicking a link follows it, or downloads it
+	for link := n; link != nil; link = link.Parent {
+		if link.Type != html.ElementNode || link.Data != "a" {
+			continue
+		}
+		if href, ok := getAttr(link, "href"); ok && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "javascript:") {
+			if name, ok := getAttr(link, "download"); ok {
+				e.startDownload(t, pg.resolve(href), name)
+			} else if newTab {
+				active := false
+				_, err = e.createTab(params{URL: pg.resolve(href), Active: &active})
+			} else {
+				err = e.load(t, pg.resolve(href))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		break
+	}
+	return success, nil
+}
+
+// toggle clicks a checkbox or radio button: checkboxes switch, and radio
+// buttons are checked
+func toggle(n *html.Node) {
+	if n.Data != "input" {
+		return
+	}
+	switch kind, _ := getAttr(n, "type"); kind {
+	case "checkbox":
+		_, checked := getAttr(n, "checked")
+		checkField(n, !checked)
+	case "radio":
+		checkField(n, true)
+	}
+}
+
+// checkField checks or unchecks a field. Checking a radio button unchecks the
+// others of its group.
+func checkField(n *html.Node, checked bool) {
+	if !checked {
+		removeAttr(n, "checked")
+		return
+	}
+	if kind, _ := getAttr(n, "type"); kind == "radio" {
+		if name, ok := getAttr(n, "name"); ok {
+			root := n
+			for root.Parent != nil && root.Data != "form" {
+				root = root.Parent
+			}
+			for _, other := range querySelectorAll(root, selector{{{tag: "input"}}}) {
+				if otherName, _ := getAttr(other, "name"); otherName == name {
+					removeAttr(other, "checked")
+				}
+			}
+		}
+	}
+	setAttr(n, "checked", "")
+}
+
+func (e *Extension) typeText(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := t.page().locateOne(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if n.Data != "input" && n.Data != "textarea" {
+		return nil, fmt.Errorf("element is not an input: %s", p.Selector)
+	}
+	t.page().focused = n
+
+	value, _ := getAttr(n, "value")
+	if p.ClearFirst {
+		value = ""
+	}
+	setAttr(n, "value", value+p.Text)
+	return success, nil
+}
+
+// pressKey types the characters of the keys pressed without Control, Alt or
+// Meta into the focused input, and deletes with Backspace. Other keys do
+// nothing without scripts.
+func (e *Extension) pressKey(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	pg := t.page()
+	if p.Selector != "" {
+		if pg.focused, err = pg.locateOne(p.Hosts, p.Selector); err != nil {
+			return nil, err
+		}
+	}
+	n := pg.focused
+	if n == nil || (n.Data != "input" && n.Data != "textarea") {
+		return success, nil
+	}
+
+	value, _ := getAttr(n, "value")
+	for _, key := range p.Keys {
+		shortcut := false
+		for _, modifier := range key.Modifiers {
+			shortcut = shortcut || modifier != "Shift"
+		}
+		switch {
+		case shortcut:
+		case key.Key == "Backspace":
+			if runes := []rune(value); len(runes) > 0 {
+				value = string(runes[:len(runes)-1])
+			}
+		case utf8.RuneCountInString(key.Key) == 1:
+			value += key.Key
+		}
+	}
+	setAttr(n, "value", value)
+	return success, nil
+}
+
+// hover checks that the element exists; the fake has no hover styles
+func (e *Extension) hover(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := t.page().locateOne(p.Hosts, p.Selector); err != nil {
+		return nil, err
+	}
+	return success, nil
+}
+
+// drag checks that the elements dragged from and to exist; the fake has no
+// drag and drop handlers
+func (e *Extension) drag(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	for _, point := range []*browser.DragPoint{p.Source, p.Target} {
+		switch {
+		case point == nil:
+			return nil, fmt.Errorf("drag needs a source and a target")
+		case point.Selector != "":
+			if _, err := t.page().locateOne(point.Hosts, point.Selector); err != nil {
+				return nil, err
+			}
+		case point.X == nil || point.Y == nil:
+			return nil, fmt.Errorf("drag points need a selector or coordinates")
+		}
+	}
+	return success, nil
+}
+
+// selectOption replaces the selection of a select element with the options
+// matching the values, labels or indexes
+func (e *Extension) selectOption(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := t.page().locateOne(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if n.Data != "select" {
+		return nil, fmt.Errorf("element is not a select: %s", p.Selector)
+	}
+	options := querySelectorAll(n, selector{{{tag: "option"}}})
+
+	var picked []*html.Node
+	s := p.Selection
+	for _, value := range s.Values {
+		option := findOption(options, func(o *html.Node) bool { return optionValue(o) == value })
+		if option == nil {
+			return nil, fmt.Errorf("no option with value %q", value)
+		}
+		picked = append(picked, option)
+	}
+	for _, label := range s.Labels {
+		option := findOption(options, func(o *html.Node) bool { return textContent(o) == label })
+		if option == nil {
+			return nil, fmt.Errorf("no option with label %q", label)
+		}
+		picked = append(picked, option)
+	}
+	for _, index := range s.Indexes {
+		if index < 0 || index >= len(options) {
+			return nil, fmt.Errorf("no option at index %d, the select has %d", index, len(options))
+		}
+		picked = append(picked, options[index])
+	}
+	if _, multiple := getAttr(n, "multiple"); len(picked) > 1 && !multiple {
+		return nil, fmt.Errorf("element is not a multiple select: %s", p.Selector)
+	}
+
+	for _, option := range options {
+		removeAttr(option, "selected")
+	}
+	values := make([]string, 0, len(picked))
+	for _, option := range picked {
+		setAttr(option, "selected", "")
+		values = append(values, optionValue(option))
+	}
+	return map[string]interface{}{"values": values}, nil
+}
+
+// findOption returns the first option matching
+func findOption(options []*html.Node, match func(*html.Node) bool) *html.Node {
+	for _, option := range options {
+		if match(option) {
+			return option
+		}
+	}
+	return nil
+}
+
+func (e *Extension) setChecked(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := t.page().locateOne(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	kind, _ := getAttr(n, "type")
+	switch {
+	case n.Data != "input" || (kind != "checkbox" && kind != "radio"):
+		return nil, fmt.Errorf("element is not a checkbox or radio button: %s", p.Selector)
+	case kind == "radio" && !p.Checked:
+		return nil, fmt.Errorf("radio buttons cannot be unchecked: %s", p.Selector)
+	}
+	checkField(n, p.Checked)
+	return success, nil
+}
+
+// waitForElement answers at once: fixtures are static, so waiting longer
+// would not change the outcome
+func (e *Extension) waitForElement(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := t.page().locate(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.State {
+	case "hidden", "detached":
+		if len(nodes) > 0 {
+			return nil, fmt.Errorf("timeout waiting for element to be %s: %s", p.State, p.Selector)
+		}
+		return map[string]interface{}{"found": false}, nil
+	default:
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("timeout waiting for element: %s", p.Selector)
+		}
+		return map[string]interface{}{"found": true}, nil
+	}
+}
+
+func (e *Extension) scroll(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Selector != "" {
+		if _, err := t.page().locateOne(p.Hosts, p.Selector); err != nil {
+			return nil, err
+		}
+	}
+	if p.X != nil {
+		t.scrollX = *p.X
+	}
+	if p.Y != nil {
+		t.scrollY = *p.Y
+	}
+	return map[string]interface{}{"success": true, "x": t.scrollX, "y": t.scrollY}, nil
+}
+
+// getActionables lists links, buttons and form fields, labelled in document
+// order
+func (e *Extension) getActionables(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := t.page().query("a[href], button, input, select, textarea, [onclick], [role=button]")
+	if err != nil {
+		return nil, err
+	}
+
+	actionables := make([]browser.Actionable, 0, len(nodes))
+	for _, n := range nodes {
+		kind := n.Data
+		if n.Data == "input" {
+			if inputType, _ := getAttr(n, "type"); inputType == "hidden" {
+				continue
+			} else if inputType != "" {
+				kind = inputType
+			}
+		}
+		actionables = append(actionables, browser.Actionable{
+			LabelNumber: len(actionables) + 1,
+			Description: describe(n),
+			Type:        kind,
+			Selector:    cssPath(n),
+		})
+	}
+	return map[string]interface{}{"actionables": actionables}, nil
+}
+
+// describe returns a short label for an element
+func describe(n *html.Node) string {
+	if text := textContent(n); text != "" {
+		return text
+	}
+	for _, name := range []string{"aria-label", "placeholder", "value", "name", "title"} {
+		if value, ok := getAttr(n, name); ok && value != "" {
+			return value
+		}
+	}
+	return n.Data
+}
+
+// Storage commands
+
+func (e *Extension) getCookies(p params) (interface{}, error) {
+	host := ""
+	if p.URL != "" {
+		if u, err := url.Parse(p.URL); err == nil {
+			host = u.Hostname()
+		}
+	}
+
+	cookies := []browser.Cookie{}
+	for _, cookie := range e.cookies {
+		if p.Name != "" && cookie.Name != p.Name {
+			continue
+		}
+		if host != "" && !domainMatches(host, cookie.Domain) {
+			continue
+		}
+		cookies = append(cookies, cookie)
+	}
+	return map[string]interface{}{"cookies": cookies}, nil
+}
+
+func (e *Extension) setCookie(p params) (interface{}, error) {
+	if p.Name == "" {
+		return nil, fmt.Errorf("cookie name is required")
+	}
+	cookie := browser.Cookie{
+		Name:           p.Name,
+		Value:          p.Value,
+		Domain:         p.Domain,
+		Path:           p.Path,
+		Secure:         p.Secure,
+		HTTPOnly:       p.HTTPOnly,
+		ExpirationDate: p.ExpirationDate,
+	}
+	if cookie.Domain == "" {
+		if u, err := url.Parse(p.URL); err == nil {
+			cookie.Domain = u.Hostname()
+		}
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+
+	for i, existing := range e.cookies {
+		if existing.Name == cookie.Name && existing.Domain == cookie.Domain && existing.Path == cookie.Path {
+			e.cookies[i] = cookie
+			return map[string]interface{}{"success": true, "cookie": cookie}, nil
+		}
+	}
+	e.cookies = append(e.cookies, cookie)
+	return map[string]interface{}{"success": true, "cookie": cookie}, nil
+}
+
+func (e *Extension) deleteCookie(p params) (interface{}, error) {
+	host := ""
+	if p.URL != "" {
+		if u, err := url.Parse(p.URL); err == nil {
+			host = u.Hostname()
+		}
+	}
+
+	kept := e.cookies[:0]
+	removed := 0
+	for _, cookie := range e.cookies {
+		if (p.Name == "" || cookie.Name == p.Name) && (host == "" || domainMatches(host, cookie.Domain)) {
+			removed++
+			continue
+		}
+		kept = append(kept, cookie)
+	}
+	e.cookies = kept
+	return map[string]interface{}{"success": true, "removed": removed}, nil
+}
+
+// domainMatches reports whether a cookie for domain is sent to host
+func domainMatches(host, domain string) bool {
+	domain = strings.TrimPrefix(domain, ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func (e *Extension) getLocalStorage(p params) (interface{}, error) {
+	return e.getStorage(p, e.localStorage)
+}
+
+func (e *Extension) setLocalStorage(p params) (interface{}, error) {
+	return e.setStorage(p, e.localStorage)
+}
+
+func (e *Extension) clearLocalStorage(p params) (interface{}, error) {
+	return e.clearStorage(p, e.localStorage)
+}
+
+func (e *Extension) getSessionStorage(p params) (interface{}, error) {
+	return e.getStorage(p, sessionStorage)
+}
+
+func (e *Extension) setSessionStorage(p params) (interface{}, error) {
+	return e.setStorage(p, sessionStorage)
+}
+
+func (e *Extension) clearSessionStorage(p params) (interface{}, error) {
+	return e.clearStorage(p, sessionStorage)
+}
+
+// localStorage returns the localStorage of the tab's origin, shared by all
+// tabs
+func (e *Extension) localStorage(t *tab) map[string]string {
+	origin := t.page().origin()
+	if e.storage[origin] == nil {
+		e.storage[origin] = make(map[string]string)
+	}
+	return e.storage[origin]
+}
+
+// sessionStorage returns the sessionStorage of the tab's origin
+func sessionStorage(t *tab) map[string]string {
+	origin := t.page().origin()
+	if t.session[origin] == nil {
+		t.session[origin] = make(map[string]string)
+	}
+	return t.session[origin]
+}
+
+func (e *Extension) getStorage(p params, area func(*tab) map[string]string) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	storage := area(t)
+	result := make(map[string]interface{})
+	if p.Key == "" {
+		for key, value := range storage {
+			result[key] = value
+		}
+	} else if value, ok := storage[p.Key]; ok {
+		result[p.Key] = value
+	} else {
+		result[p.Key] = nil
+	}
+	return map[string]interface{}{"storage": result}, nil
+}
+
+func (e *Extension) setStorage(p params, area func(*tab) map[string]string) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	area(t)[p.Key] = p.Value
+	return success, nil
+}
+
+func (e *Extension) clearStorage(p params, area func(*tab) map[string]string) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	storage := area(t)
+	for key := range storage {
+		delete(storage, key)
+	}
+	return success, nil
+}
+
+// Network commands
+
+func (e *Extension) addRoute(p params) (interface{}, error) {
+	if p.Route == nil || p.Route.ID == "" {
+		return nil, fmt.Errorf("route is required")
+	}
+	e.routes = append(e.routes, *p.Route)
+	return success, nil
+}
+
+func (e *Extension) removeRoute(p params) (interface{}, error) {
+	for i, route := range e.routes {
+		if route.ID == p.ID {
+			e.routes = append(e.routes[:i], e.routes[i+1:]...)
+			return success, nil
+		}
+	}
+	return nil, fmt.Errorf("no route with id: %s", p.ID)
+}
+
+// routePage applies the most recently added route matching the document
+// request for rawURL. It returns the page of a fulfilled request, an error
+// for a blocked one, and neither when the request goes to the fixtures. Header
+// changes have no effect on the fake's documents.
+func (e *Extension) routePage(t *tab, rawURL string) (*page, error) {
+	for i := len(e.routes) - 1; i >= 0; i-- {
+		route := e.routes[i]
+		if route.TabID != 0 && route.TabID != t.id || !route.Matches("GET", rawURL) {
+			continue
+		}
+		switch route.Action {
+		case browser.RouteBlock:
+			return nil, fmt.Errorf("net::ERR_BLOCKED_BY_CLIENT: %s", rawURL)
+		case browser.RouteFulfill:
+			return newPage(rawURL, route.Body, route.Status)
+		case browser.RouteDelay:
+			time.Sleep(time.Duration(route.DelayMs) * time.Millisecond)
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+func (e *Extension) startNetworkCapture(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if t.capturing {
+		return nil, fmt.Errorf("network capture already running for tab %d", t.id)
+	}
+	t.capturing = true
+	return success, nil
+}
+
+func (e *Extension) stopNetworkCapture(p params) (interface{}, error) {
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.capturing {
+		return nil, fmt.Errorf("no network capture running for tab %d", t.id)
+	}
+	t.capturing = false
+	return success, nil
+}
+
+// captureDocument raises the network events of a document request while a
+// capture runs on the tab. Only http(s) documents are requested over the
+// network; the fake loads no subresources.
+func (e *Extension) captureDocument(t *tab, rawURL string, p *page, loadErr error) {
+	if !t.capturing || !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return
+	}
+
+	e.requests++
+	requestID := fmt.Sprintf("%d.%d", t.id, e.requests)
+	e.emit("request", browser.RequestEvent{
+		TabID:        t.id,
+		RequestID:    requestID,
+		URL:          rawURL,
+		Method:       "GET",
+		ResourceType: "Document",
+		Headers:      map[string]string{"Accept": "text/html"},
+	})
+
+	if loadErr != nil {
+		e.emit("requestFailed", browser.RequestFailedEvent{TabID: t.id, RequestID: requestID, URL: rawURL, ErrorText: loadErr.Error()})
+		return
+	}
+	e.emit("response", browser.ResponseEvent{
+		TabID:             t.id,
+		RequestID:         requestID,
+		URL:               p.url,
+		Status:            p.status,
+		StatusText:        http.StatusText(p.status),
+		Headers:           map[string]string{"Content-Type": "text/html; charset=utf-8"},
+		MimeType:          "text/html",
+		Protocol:          "http/1.1",
+		EncodedDataLength: p.size,
+	})
+}
+
+// Page scripts
+
+var (
+	consolePattern = regexp.MustCompile(`^console\.(log|info|warn|error|debug)\((["'])(.*)["']\);?$`)
+	throwPattern   = regexp.MustCompile(`^throw new (\w*Error)\((["'])(.*)["']\);?$`)
+)
+
+// runConsoleStatement runs a console.<level>("text") call or a throw new
+// Error("message") statement, the only statements the fake can run. It
+// reports whether script was one of them, and the error a throw raises.
+func (e *Extension) runConsoleStatement(t *tab, script, source string) (bool, error) {
+	if m := consolePattern.FindStringSubmatch(script); m != nil {
+		e.emit("console", browser.ConsoleEvent{TabID: t.id, Level: m[1], Text: m[3], Source: source})
+		return true, nil
+	}
+	if m := throwPattern.FindStringSubmatch(script); m != nil {
+		text := fmt.Sprintf("Uncaught %s: %s", m[1], m[3])
+		e.emit("console", browser.ConsoleEvent{TabID: t.id, Level: "exception", Text: text, Source: source})
+		return true, errors.New(text)
+	}
+	return false, nil
+}
+
+// runPageScripts runs the console statements of the page's inline scripts,
+// one per line; a throw stops the script it is in
+func (e *Extension) runPageScripts(t *tab) {
+	p := t.page()
+	scripts, _ := p.query("script")
+	for _, script := range scripts {
+		var source strings.Builder
+		for c := script.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				source.WriteString(c.Data)
+			}
+		}
+		for _, line := range strings.Split(source.String(), "\n") {
+			if _, err := e.runConsoleStatement(t, strings.TrimSpace(line), p.url); err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package fakeext
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is a parsed group of CSS selectors, matching elements that match
+// any of its chains
+type selector [][]step
+
+// step is one compound selector of a chain, with the combinator relating it
+// to the previous step: ' ' for descendant, '>' for child
+type step struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+	attrs      []attrMatch
+	nthChild   int
+}
+
+// attrMatch is an attribute condition such as [type="text"]
+type attrMatch struct {
+	name  string
+	op    string // "", "=", "~=", "^=", "$=" or "*="
+	value string
+}
+
+// parseSelector parses the subset of CSS selectors the fake extension
+// supports: type, universal, id, class and attribute selectors, :nth-child(n),
+// descendant and child combinators, and selector lists
+func parseSelector(s string) (selector, error) {
+	p := &selectorParser{src: s}
+	sel, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("unsupported selector %q: %w", s, err)
+	}
+	return sel, nil
+}
+
+type selectorParser struct {
+	src string
+	pos int
+}
+
+func (p *selectorParser) parse() (selector, error) {
+	var sel selector
+	for {
+		chain, err := p.parseChain()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, chain)
+
+		if p.pos >= len(p.src) {
+			return sel, nil
+		}
+		p.pos++ // ','
+	}
+}
+
+func (p *selectorParser) parseChain() ([]step, error) {
+	var chain []step
+	combinator := byte(' ')
+	for {
+		p.skipSpace()
+		st, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		st.combinator = combinator
+		chain = append(chain, st)
+
+		spaced := p.skipSpace()
+		switch {
+		case p.pos >= len(p.src) || p.src[p.pos] == ',':
+			return chain, nil
+		case p.src[p.pos] == '>':
+			p.pos++
+			combinator = '>'
+		case spaced:
+			combinator = ' '
+		default:
+			return nil, fmt.Errorf("unexpected %q", p.src[p.pos])
+		}
+	}
+}
+
+func (p *selectorParser) parseCompound() (step, error) {
+	var st step
+	start := p.pos
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '*':
+			p.pos++
+		case isIdentByte(c):
+			st.tag = strings.ToLower(p.ident())
+		case c == '#':
+			p.pos++
+			if st.id = p.ident(); st.id == "" {
+				return st, fmt.Errorf("expected id after '#'")
+			}
+		case c == '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return st, fmt.Errorf("expected class name after '.'")
+			}
+			st.classes = append(st.classes, class)
+		case c == '[':
+			match, err := p.parseAttr()
+			if err != nil {
+				return st, err
+			}
+			st.attrs = append(st.attrs, match)
+		case c == ':':
+			n, err := p.parseNthChild()
+			if err != nil {
+				return st, err
+			}
+			st.nthChild = n
+		default:
+			if p.pos == start {
+				return st, fmt.Errorf("expected a selector at %q", p.src[p.pos:])
+			}
+			return st, nil
+		}
+	}
+	if p.pos == start {
+		return st, fmt.Errorf("expected a selector")
+	}
+	return st, nil
+}
+
+func (p *selectorParser) parseAttr() (attrMatch, error) {
+	var match attrMatch
+	p.pos++ // '['
+	p.skipSpace()
+	if match.name = strings.ToLower(p.ident()); match.name == "" {
+		return match, fmt.Errorf("expected attribute name")
+	}
+	p.skipSpace()
+
+	for _, op := range []string{"=", "~=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			match.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if match.op != "" {
+		p.skipSpace()
+		if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+			quote := p.src[p.pos]
+			end := strings.IndexByte(p.src[p.pos+1:], quote)
+			if end < 0 {
+				return match, fmt.Errorf("unterminated string")
+			}
+			match.value = p.src[p.pos+1 : p.pos+1+end]
+			p.pos += end + 2
+		} else {
+			match.value = p.ident()
+		}
+		p.skipSpace()
+	}
+
+	if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+		return match, fmt.Errorf("expected ']'")
+	}
+	p.pos++
+	return match, nil
+}
+
+func (p *selectorParser) parseNthChild() (int, error) {
+	const prefix = ":nth-child("
+	if !strings.HasPrefix(p.src[p.pos:], prefix) {
+		return 0, fmt.Errorf("pseudo-class at %q", p.src[p.pos:])
+	}
+	p.pos += len(prefix)
+	end := strings.IndexByte(p.src[p.pos:], ')')
+	if end < 0 {
+		return 0, fmt.Errorf("expected ')'")
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(p.src[p.pos : p.pos+end]))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("only :nth-child(n) with a positive number is supported")
+	}
+	p.pos += end + 1
+	return n, nil
+}
+
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// skipSpace skips whitespace and reports whether there was any
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func isIdentByte(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// querySelectorAll returns the elements under root matching sel, in document order
+func querySelectorAll(root *html.Node, sel selector) []*html.Node {
+	var found []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && sel.matches(n) {
+			found = append(found, n)
+		}
+		// The content of templates, such as shadow roots, is not part of
+		// the document
+		if n != root && n.Type == html.ElementNode && n.Data == "template" {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
+
+func (sel selector) matches(n *html.Node) bool {
+	for _, chain := range sel {
+		if matchChain(n, chain, len(chain)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchChain reports whether n matches chain[i] with ancestors matching the
+// steps before it
+func matchChain(n *html.Node, chain []step, i int) bool {
+	if !chain[i].matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if chain[i].combinator == '>' {
+		p := n.Parent
+		return p != nil && p.Type == html.ElementNode && matchChain(p, chain, i-1)
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && matchChain(p, chain, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (st step) matches(n *html.Node) bool {
+	if st.tag != "" && n.Data != st.tag {
+		return false
+	}
+	if st.id != "" {
+		if id, _ := getAttr(n, "id"); id != st.id {
+			return false
+		}
+	}
+	if len(st.classes) > 0 {
+		classes, _ := getAttr(n, "class")
+		fields := strings.Fields(classes)
+		for _, class := range st.classes {
+			if !contains(fields, class) {
+				return false
+			}
+		}
+	}
+	for _, match := range st.attrs {
+		if !match.matches(n) {
+			return false
+		}
+	}
+	if st.nthChild > 0 && childIndex(n) != st.nthChild {
+		return false
+	}
+	return true
+}
+
+func (m attrMatch) matches(n *html.Node) bool {
+	value, ok := getAttr(n, m.name)
+	if !ok {
+		return false
+	}
+	switch m.op {
+	case "=":
+		return value == m.value
+	case "~=":
+		return contains(strings.Fields(value), m.value)
+	case "^=":
+		return strings.HasPrefix(value, m.value)
+	case "$=":
+		return strings.HasSuffix(value, m.value)
+	case "*=":
+		return strings.Contains(value, m.value)
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// childIndex returns the 1-based position of n among its element siblings
+func childIndex(n *html.Node) int {
+	index := 1
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			index++
+		}
+	}
+	return index
+}
+
+// cssPath returns a selector matching only n, using its id when it has one
+func cssPath(n *html.Node) string {
+	var parts []string
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if id, ok := getAttr(n, "id"); ok && id != "" && isIdent(id) {
+			parts = append(parts, "#"+id)
+			break
+		}
+		if n.Data == "html" || n.Data == "body" {
+			parts = append(parts, n.Data)
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s:nth-child(%d)", n.Data, childIndex(n)))
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+func isIdent(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isIdentByte(s[i]) {
+			return false
+		}
+	}
+	return s != "" && (s[0] < '0' || s[0] > '9')
+}
+
+func getAttr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func setAttr(n *html.Node, name, value string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: name, Val: value})
+}
+
+func removeAttr(n *html.Node, name string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
+
+// textContent returns the text of n with whitespace collapsed, leaving out
+// scripts and styles
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style" || n.Data == "head"):
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// innerHTML renders the children of n
+func innerHTML(n *html.Node) string {
+	var buf bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		_ = html.Render(&buf, c)
+	}
+	return buf.String()
+}
+
+// outerHTML renders n itself
+func outerHTML(n *html.Node) string {
+	var buf bytes.Buffer
+	_ = html.Render(&buf, n)
+	return buf.String()
+}
+
+// findElement returns the first element with the given tag name, or nil
+func findElement(root *html.Node, tag string) *html.Node {
+	if root.Type == html.ElementNode && root.Data == tag {
+		return root
+	}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if n := findElement(c, tag); n != nil {
+			return n
+		}
+	}
+	return nil
+}
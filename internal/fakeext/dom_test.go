@@ -0,0 +1,90 @@
+package fakeext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+const selectorFixture = `<html><body>
+<div id="main" class="content wide">
+  <h1>Title</h1>
+  <ul class="items">
+    <li class="item first">One</li>
+    <li class="item">Two <a href="/two" data-kind="link">more</a></li>
+    <li class="item last">Three</li>
+  </ul>
+  <form><input type="text" name="q"><input type="submit" value="Go"></form>
+</div>
+<p>Footer <span>text</span></p>
+</body></html>`
+
+func TestQuerySelectorAll(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorFixture))
+	require.NoError(t, err)
+
+	tests := []struct {
+		selector string
+		want     []string // text of the matched elements
+	}{
+		{"li", []string{"One", "Two more", "Three"}},
+		{"#main h1", []string{"Title"}},
+		{".item.first", []string{"One"}},
+		{"ul > li:nth-child(2)", []string{"Two more"}},
+		{"div > li", nil},
+		{"div li a", []string{"more"}},
+		{"a[href]", []string{"more"}},
+		{`a[data-kind="link"]`, []string{"more"}},
+		{"a[href^='/t']", []string{"more"}},
+		{"[class~=wide] h1, p span", []string{"Title", "text"}},
+		{"input[type=submit]", []string{""}},
+		{"*.last", []string{"Three"}},
+		{"LI.first", []string{"One"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := parseSelector(tt.selector)
+			require.NoError(t, err)
+
+			var got []string
+			for _, n := range querySelectorAll(doc, sel) {
+				got = append(got, textContent(n))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSelector_Errors(t *testing.T) {
+	for _, selector := range []string{"", "div >", "a:hover", "[href", "li:nth-child(odd)", "#", "a + b"} {
+		_, err := parseSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestCSSPath(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorFixture))
+	require.NoError(t, err)
+
+	sel, err := parseSelector("a, input[name=q], p span")
+	require.NoError(t, err)
+	nodes := querySelectorAll(doc, sel)
+	require.Len(t, nodes, 3)
+
+	paths := []string{
+		"#main > ul:nth-child(2) > li:nth-child(2) > a:nth-child(1)",
+		"#main > form:nth-child(3) > input:nth-child(1)",
+		"body > p:nth-child(2) > span:nth-child(1)",
+	}
+	for i, n := range nodes {
+		assert.Equal(t, paths[i], cssPath(n))
+
+		// Every path selects its element alone
+		sel, err := parseSelector(paths[i])
+		require.NoError(t, err)
+		assert.Equal(t, []*html.Node{n}, querySelectorAll(doc, sel))
+	}
+}
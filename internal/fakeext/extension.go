@@ -0,0 +1,207 @@
+// Package fakeext is a stand-in for the Chrome extension. It connects to the
+// server's WebSocket endpoint, announces itself with the connected handshake
+// and answers commands against an in-memory browser whose tabs load local
+// HTML fixtures, so the whole stack can run end-to-end without Chrome.
+package fakeext
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/periplon/bract/internal/browser"
+	wsserver "github.com/periplon/bract/internal/websocket"
+)
+
+// Options configures a fake extension
+type Options struct {
+	BrowserID string // announced in the handshake; a random ID if empty
+	Name      string
+	Profile   string
+	Version   string
+	Fixtures  fs.FS // HTML fixtures served for http(s) URLs, see loadPage
+	Logger    *log.Logger
+}
+
+// Extension is an in-memory browser that answers the commands of the server
+type Extension struct {
+	opts Options
+
+	mu           sync.Mutex
+	tabs         []*tab // in tab strip order
+	nextID       int
+	activeID     int
+	cookies      []browser.Cookie
+	storage      map[string]map[string]string // localStorage by origin
+	routes       []browser.Route              // network routes, oldest first
+	requests     int                          // requests sent, numbering the network events
+	uploads      map[string]map[int][]byte    // chunks of the files of uploads in progress, by upload ID and file index
+	downloads    []*download
+	nextDownload int
+	events       chan *wsserver.Message // events raised while handling a command
+	handlers     map[string]commandHandler
+}
+
+// New creates a fake extension with no open tabs
+func New(opts Options) *Extension {
+	if opts.BrowserID == "" {
+		opts.BrowserID = uuid.New().String()
+	}
+	if opts.Name == "" {
+		opts.Name = "Fake Extension"
+	}
+	if opts.Version == "" {
+		opts.Version = "0.0.0"
+	}
+
+	e := &Extension{
+		opts:         opts,
+		nextID:       1,
+		storage:      make(map[string]map[string]string),
+		uploads:      make(map[string]map[int][]byte),
+		nextDownload: 1,
+		events:       make(chan *wsserver.Message, 64),
+	}
+	e.handlers = e.commandHandlers()
+	return e
+}
+
+// BrowserID returns the ID the extension announces in its handshake
+func (e *Extension) BrowserID() string {
+	return e.opts.BrowserID
+}
+
+// Run connects to the server at url and answers its commands until ctx is
+// done or the connection is closed. The browser state outlives the
+// connection, so Run can be called again to reconnect.
+func (e *Extension) Run(ctx context.Context, url string) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	handshake, err := json.Marshal(map[string]string{
+		"browserId": e.opts.BrowserID,
+		"name":      e.opts.Name,
+		"profile":   e.opts.Profile,
+		"version":   e.opts.Version,
+	})
+	if err != nil {
+		return err
+	}
+	if err := conn.WriteJSON(&wsserver.Message{
+		ID:   uuid.New().String(),
+		Type: "connected",
+		Data: handshake,
+	}); err != nil {
+		return fmt.Errorf("failed to send handshake: %w", err)
+	}
+
+	for {
+		var msg wsserver.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if msg.Type != "command" {
+			continue
+		}
+
+		if err := conn.WriteJSON(e.handle(&msg)); err != nil {
+			return err
+		}
+		if err := e.flushEvents(conn); err != nil {
+			return err
+		}
+	}
+}
+
+// flushEvents sends the events raised by the last command
+func (e *Extension) flushEvents(conn *websocket.Conn) error {
+	for {
+		select {
+		case event := <-e.events:
+			if err := conn.WriteJSON(event); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// handle runs a command and returns the response to send
+func (e *Extension) handle(msg *wsserver.Message) *wsserver.Message {
+	command := msg.Command
+	if command == "" {
+		command = msg.Action
+	}
+	response := &wsserver.Message{ID: msg.ID, Type: "response"}
+
+	raw := msg.Params
+	if raw == nil {
+		raw = msg.Data
+	}
+	var p params
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			response.Error = fmt.Sprintf("invalid params for %s: %v", command, err)
+			return response
+		}
+	}
+
+	handler, ok := e.handlers[command]
+	if !ok {
+		response.Error = fmt.Sprintf("unsupported command: %s", command)
+		e.logf("unsupported command %s", command)
+		return response
+	}
+
+	e.mu.Lock()
+	result, err := handler(p)
+	e.mu.Unlock()
+	if err != nil {
+		response.Error = err.Error()
+		return response
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		response.Error = fmt.Sprintf("failed to encode result: %v", err)
+		return response
+	}
+	response.Result = data
+	return response
+}
+
+// emit queues an event for the server. e.mu must be held.
+func (e *Extension) emit(action string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	select {
+	case e.events <- &wsserver.Message{ID: uuid.New().String(), Type: "event", Action: action, Data: payload}:
+	default:
+		e.logf("dropped %s event", action)
+	}
+}
+
+func (e *Extension) logf(format string, args ...interface{}) {
+	if e.opts.Logger != nil {
+		e.opts.Logger.Printf(format, args...)
+	}
+}
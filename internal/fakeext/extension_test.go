@@ -0,0 +1,792 @@
+package fakeext_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/periplon/bract/internal/browser"
+	"github.com/periplon/bract/internal/config"
+	"github.com/periplon/bract/internal/dsl/runner"
+	"github.com/periplon/bract/internal/fakeext"
+	"github.com/periplon/bract/internal/handler"
+	"github.com/periplon/bract/internal/mcp"
+	"github.com/periplon/bract/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fixtures = fstest.MapFS{
+	"example.com/index.html": {Data: []byte(`<html><head><title>Example Domain</title></head><body>
+<h1>Example Domain</h1>
+<p><a id="more" href="/about">More information...</a></p>
+</body></html>`)},
+	"example.com/about.html": {Data: []byte(`<html><head><title>About</title></head><body><h1>About us</h1></body></html>`)},
+	"example.com/broken.html": {Data: []byte(`<html><head><title>Broken</title><script>
+console.log("booting");
+throw new TypeError("app is undefined");
+console.log("never logged");
+</script></head><body><h1>Broken</h1></body></html>`)},
+	"example.com/blog/post.html": {Data: []byte(`<html><head><title>Post</title></head><body>
+<nav><a href="/">Home</a> <a href="/blog/">Blog</a></nav>
+<article><h1>Release notes</h1><p>Version 2 is out, see the <a href="changes.html">changes</a>.</p>
+<table><tr><th>Version</th><th>Date</th></tr><tr><td>2.0</td><td>2025-03-01</td></tr></table></article>
+<footer>&copy; Example</footer>
+</body></html>`)},
+	"example.com/shop.html": {Data: []byte(`<html><head><title>Shop</title></head><body>
+<table id="prices"><thead><tr><th>Item</th><th>Size</th><th>Price</th></tr></thead>
+<tbody><tr><td rowspan="2">Tea</td><td>Small</td><td>$2.50</td></tr><tr><td>Large</td><td>$3.00</td></tr></tbody></table>
+<div class="card" data-id="1"><h3>Mug</h3><a href="/mug">View</a></div>
+<div class="card" data-id="2"><h3>Pot</h3></div>
+</body></html>`)},
+	"example.com/files.html": {Data: []byte(`<html><head><title>Files</title></head><body>
+<form><input type="file" id="import" accept=".csv"><input type="file" id="photos" multiple></form>
+<a id="report" href="/reports/report.csv" download>Report</a><a id="broken" href="/reports/gone.csv" download="old.csv">Old report</a>
+</body></html>`)},
+	"example.com/reports/report.csv": {Data: []byte("id,name\n1,ada\n")},
+	"example.com/editor.html": {Data: []byte(`<html><head><title>Editor</title></head><body>
+<iframe id="editor" srcdoc="<h1>Draft</h1><input id='title'><input id='public' type='checkbox'><select id='lang'><option value='en'>English</option><option value='fr'>French</option></select><input id='attachment' type='file'><rich-text><template shadowrootmode='open'><button class='bold'>Bold</button></template></rich-text>"></iframe>
+<user-card><template shadowrootmode="open"><span class="name">Ada</span></template></user-card>
+<secret-box><template shadowrootmode="closed"><span>hidden</span></template></secret-box>
+<p class="name">Outside</p>
+</body></html>`)},
+	"login.html": {Data: []byte(`<html><head><title>Login</title></head><body>
+<form><input id="user" name="user" value="x"><input type="checkbox" id="remember"><button id="submit">Sign in</button>
+<select id="lang"><option value="en">English</option><option value="fr" selected>French</option></select></form>
+</body></html>`)},
+}
+
+// startExtension serves the WebSocket endpoint of a browser client and
+// connects a fake extension to it
+func startExtension(t *testing.T) (*browser.Client, *fakeext.Extension) {
+	t.Helper()
+
+	client := browser.NewClient(config.WebSocketConfig{ReconnectMs: 2000})
+	ws := httptest.NewServer(websocket.NewServer(0, client, nil).Handler())
+	t.Cleanup(ws.Close)
+
+	ext := fakeext.New(fakeext.Options{BrowserID: "fake", Fixtures: fixtures})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = ext.Run(ctx, "ws"+strings.TrimPrefix(ws.URL, "http"))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.NoError(t, client.WaitForConnection(context.Background(), 5*time.Second))
+	require.Eventually(t, func() bool { return len(client.ListBrowsers()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	return client, ext
+}
+
+func TestExtension_BrowserClient(t *testing.T) {
+	client, ext := startExtension(t)
+	ctx := context.Background()
+
+	browsers := client.ListBrowsers()
+	assert.Equal(t, "fake", browsers[0].ID)
+	assert.Equal(t, "Fake Extension", browsers[0].Name)
+
+	tab, err := client.CreateTab(ctx, "https://example.com/", true)
+	require.NoError(t, err)
+	assert.Equal(t, "Example Domain", tab.Title)
+	assert.True(t, tab.Active)
+
+	headings, err := client.ExtractContent(ctx, 0, "h1", "text", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Example Domain"}, headings)
+
+	// Clicking a link loads its target, and history can be walked back
+	require.NoError(t, client.Click(ctx, tab.ID, "#more", 0))
+	title, err := client.GetPageTitle(ctx, tab.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "About", title)
+	require.Equal(t, "https://example.com/about", ext.Tabs()[0].URL)
+
+	// Fixtures are found without the host directory, and unknown URLs are 404 pages
+	_, err = client.Navigate(ctx, tab.ID, "http://localhost:8080/login", true)
+	require.NoError(t, err)
+	require.NoError(t, client.Type(ctx, tab.ID, "#user", "alice", true, 0))
+	values, err := client.ExtractContent(ctx, tab.ID, "#user", "attribute", "value")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, values)
+
+	response, err := client.Navigate(ctx, tab.ID, "https://example.com/missing", true)
+	require.NoError(t, err)
+	assert.Contains(t, string(response), `"status":404`)
+
+	err = client.Click(ctx, tab.ID, "#nope", 0)
+	assert.ErrorContains(t, err, "element not found: #nope")
+
+	result, err := client.ExecuteScript(ctx, tab.ID, "return document.title;", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"result":"404 Not Found"}`, string(result))
+
+	// Storage is kept per origin
+	require.NoError(t, client.SetLocalStorage(ctx, tab.ID, "token", "abc"))
+	value, err := client.GetLocalStorage(ctx, tab.ID, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", value)
+
+	_, err = client.SetCookie(ctx, browser.Cookie{Name: "session", Value: "1", Domain: "example.com"})
+	require.NoError(t, err)
+	cookies, err := client.GetCookies(ctx, "https://www.example.com", "")
+	require.NoError(t, err)
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "session", cookies[0].Name)
+
+	dataURL, err := client.Screenshot(ctx, tab.ID, false, "", "png", 90)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(dataURL, "data:image/png;base64,"))
+
+	// Closing a tab raises the tabClosed event
+	second, err := client.CreateTab(ctx, "about:blank", true)
+	require.NoError(t, err)
+	require.NoError(t, client.CloseTab(ctx, second.ID))
+	tabs, err := client.ListTabs(ctx)
+	require.NoError(t, err)
+	require.Len(t, tabs, 1)
+	assert.Equal(t, tab.ID, tabs[0].ID)
+
+	// Events are recorded by the client as the extension raises them
+	require.Eventually(t, func() bool {
+		page := client.GetEvents(ctx, 0, browser.EventFilter{TabID: second.ID}, 0)
+		return len(page.Events) > 0 && page.Events[len(page.Events)-1].Type == browser.EventTabClosed
+	}, 5*time.Second, 10*time.Millisecond)
+	page := client.GetEvents(ctx, 0, browser.EventFilter{TabID: second.ID}, 0)
+	var types []browser.EventType
+	for _, event := range page.Events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []browser.EventType{browser.EventNavigationCommitted, browser.EventPageLoad, browser.EventTabCreated, browser.EventTabClosed}, types)
+
+	_, err = client.ExecuteScript(ctx, tab.ID, "document.querySelector('h1').click()", nil)
+	assert.ErrorContains(t, err, "fake extension cannot evaluate script")
+}
+
+func TestExtension_ElementsHistoryAndVideo(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/login", true)
+	require.NoError(t, err)
+
+	elements, err := client.FindElements(ctx, tab.ID, "input, button", 2)
+	require.NoError(t, err)
+	require.Len(t, elements, 2)
+	assert.Equal(t, "input", elements[0].Tag)
+	assert.Equal(t, map[string]string{"id": "user", "name": "user", "value": "x"}, elements[0].Attributes)
+	assert.Equal(t, "#remember", elements[1].Selector)
+	assert.Nil(t, elements[1].BoundingBox)
+
+	require.NoError(t, client.Click(ctx, tab.ID, "#remember", 0))
+	value, err := client.GetValue(ctx, tab.ID, "#remember")
+	require.NoError(t, err)
+	require.NotNil(t, value.Checked)
+	assert.True(t, *value.Checked)
+
+	value, err = client.GetValue(ctx, tab.ID, "#lang")
+	require.NoError(t, err)
+	assert.Equal(t, "fr", value.Value)
+
+	_, err = client.GetValue(ctx, tab.ID, "#submit")
+	assert.ErrorContains(t, err, "element is not a form field")
+
+	// History can be walked in both directions
+	_, err = client.Navigate(ctx, tab.ID, "https://example.com/about", true)
+	require.NoError(t, err)
+	response, err := client.GoBack(ctx, tab.ID, true, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, string(response), `"title":"Login"`)
+	response, err = client.GoForward(ctx, tab.ID, true, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, string(response), `"title":"About"`)
+	_, err = client.GoForward(ctx, tab.ID, true, 1000)
+	assert.ErrorContains(t, err, "no next page in history")
+
+	// A stopped recording is saved in the directory under the requested name
+	_, err = client.StopVideo(ctx, tab.ID, "", "")
+	assert.ErrorContains(t, err, "not being recorded")
+	_, err = client.StartVideo(ctx, tab.ID, 0)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.webm")
+	recording, err := client.StopVideo(ctx, tab.ID, dir, "run.webm")
+	require.NoError(t, err)
+	assert.Equal(t, path, recording.Path)
+	assert.Equal(t, "video/webm", recording.MimeType)
+	assert.FileExists(t, path)
+}
+
+func TestExtension_Routes(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/login", true)
+	require.NoError(t, err)
+
+	blocked, err := client.AddRoute(ctx, browser.Route{Pattern: "https://example.com/about", Action: browser.RouteBlock})
+	require.NoError(t, err)
+	_, err = client.Navigate(ctx, tab.ID, "https://example.com/about", true)
+	assert.ErrorContains(t, err, "net::ERR_BLOCKED_BY_CLIENT")
+
+	// The most recently added route wins
+	_, err = client.AddRoute(ctx, browser.Route{
+		Pattern: "https://example.com/*",
+		Action:  browser.RouteFulfill,
+		Status:  503,
+		Body:    []byte("<title>Maintenance</title>"),
+	})
+	require.NoError(t, err)
+	response, err := client.Navigate(ctx, tab.ID, "https://example.com/about", true)
+	require.NoError(t, err)
+	assert.Contains(t, string(response), `"title":"Maintenance"`)
+	assert.Contains(t, string(response), `"status":503`)
+
+	routes, err := client.ListRoutes(ctx)
+	require.NoError(t, err)
+	require.Len(t, routes, 2)
+	assert.Equal(t, blocked.ID, routes[0].ID)
+
+	require.NoError(t, client.RemoveRoute(ctx, routes[1].ID))
+	require.NoError(t, client.RemoveRoute(ctx, blocked.ID))
+	response, err = client.Navigate(ctx, tab.ID, "https://example.com/about", true)
+	require.NoError(t, err)
+	assert.Contains(t, string(response), `"title":"About"`)
+}
+
+func TestExtension_NetworkCapture(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/login", true)
+	require.NoError(t, err)
+	require.NoError(t, client.StartNetworkCapture(ctx, tab.ID))
+
+	_, err = client.Navigate(ctx, tab.ID, "https://example.com/about", true)
+	require.NoError(t, err)
+	_, err = client.Navigate(ctx, tab.ID, "https://example.com/missing", true)
+	require.NoError(t, err)
+	_, err = client.AddRoute(ctx, browser.Route{Pattern: "**/blocked", Action: browser.RouteBlock})
+	require.NoError(t, err)
+	_, err = client.Navigate(ctx, tab.ID, "https://example.com/blocked", true)
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.har")
+	recording, err := client.StopNetworkCapture(ctx, tab.ID, dir, "run.har")
+	require.NoError(t, err)
+	assert.Equal(t, 3, recording.Entries)
+	assert.Positive(t, recording.Summary.TotalBytes)
+	require.Len(t, recording.Summary.Failed, 2)
+	assert.Equal(t, 404, recording.Summary.Failed[0].Status)
+	assert.Contains(t, recording.Summary.Failed[1].Error, "net::ERR_BLOCKED_BY_CLIENT")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var har browser.HAR
+	require.NoError(t, json.Unmarshal(data, &har))
+	require.Len(t, har.Log.Entries, 3)
+	assert.Equal(t, "https://example.com/about", har.Log.Entries[0].Request.URL)
+	assert.Equal(t, 200, har.Log.Entries[0].Response.Status)
+	assert.Equal(t, "About", har.Log.Pages[0].Title)
+}
+
+func TestExtension_Script(t *testing.T) {
+	client, _ := startExtension(t)
+
+	// The MCP server the DSL runner talks to, backed by the fake extension
+	server := mcp.NewServer("test", "1.0.0", handler.NewBrowserHandler(client))
+	h, err := server.HTTPHandler(config.TransportStreamableHTTP, "")
+	require.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	script := filepath.Join(t.TempDir(), "e2e.dsl")
+	require.NoError(t, os.WriteFile(script, []byte(`connect "./bin/mcp-browser-server"
+call browser_wait_for_connection {timeout: 5}
+
+call browser_create_tab {url: "https://example.com"} -> tab
+assert tab.title == "Example Domain", "Fixture should be loaded"
+
+call browser_extract_content {tabId: tab.id, selector: "h1"} -> headings
+assert headings[0] == "Example Domain"
+
+call browser_click {tabId: tab.id, selector: "a"}
+call browser_execute_script {tabId: tab.id, script: "document.title"} -> title
+assert title.result == "About"
+
+try {
+  call browser_click {tabId: tab.id, selector: "#missing"}
+} catch err {
+  set failure = err.message
+}
+assert failure != null, "Clicking a missing element should fail"
+
+call browser_close_tab {tabId: tab.id}
+call browser_list_tabs -> tabs
+assert len(tabs) == 0
+`), 0o644))
+
+	var out bytes.Buffer
+	results := runner.Run(context.Background(), []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out})
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err, out.String())
+}
+
+func TestExtension_Console(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/broken", true)
+	require.NoError(t, err)
+	_, err = client.ExecuteScript(ctx, tab.ID, `console.warn("deprecated API")`, nil)
+	require.NoError(t, err)
+	_, err = client.ExecuteScript(ctx, tab.ID, `throw new Error("boom")`, nil)
+	require.Error(t, err)
+
+	messages, err := client.GetConsole(ctx, tab.ID, browser.ConsoleFilter{}, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+	assert.Equal(t, browser.ConsoleMessage{Level: "log", Text: "booting", Source: "https://example.com/broken", Time: messages[0].Time}, messages[0])
+	assert.Equal(t, "Uncaught TypeError: app is undefined", messages[1].Text)
+	assert.Equal(t, "warn", messages[2].Level)
+	assert.Equal(t, "Uncaught Error: boom", messages[3].Text)
+
+	messages, err = client.GetConsole(ctx, tab.ID, browser.ConsoleFilter{Levels: []string{"error", "exception"}}, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Uncaught Error: boom", messages[0].Text)
+
+	// The DSL check fails the script on the page's errors
+	server := mcp.NewServer("test", "1.0.0", handler.NewBrowserHandler(client))
+	h, err := server.HTTPHandler(config.TransportStreamableHTTP, "")
+	require.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	script := filepath.Join(t.TempDir(), "console.dsl")
+	require.NoError(t, os.WriteFile(script, []byte(`connect "./bin/mcp-browser-server"
+call browser_create_tab {url: "https://example.com"} -> tab
+assert no_console_errors {tabId: tab.id}
+
+call browser_navigate {tabId: tab.id, url: "https://example.com/broken"}
+assert no_console_errors {tabId: tab.id}
+`), 0o644))
+
+	var out bytes.Buffer
+	results := runner.Run(ctx, []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out})
+	require.Len(t, results, 1)
+	require.Error(t, results[0].Err)
+	assert.Contains(t, results[0].Err.Error(), "line 6: assertion error: 1 console error: [exception] Uncaught TypeError: app is undefined (https://example.com/broken)")
+}
+
+func TestExtension_VisualRegression(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	baselines := t.TempDir()
+	h := handler.NewBrowserHandler(client)
+	h.SetBaselineDir(baselines)
+	server := mcp.NewServer("test", "1.0.0", h)
+	mcpHandler, err := server.HTTPHandler(config.TransportStreamableHTTP, "")
+	require.NoError(t, err)
+	ts := httptest.NewServer(mcpHandler)
+	defer ts.Close()
+
+	dir := t.TempDir()
+	setup := filepath.Join(dir, "baseline.dsl")
+	require.NoError(t, os.WriteFile(setup, []byte(`connect "./bin/mcp-browser-server"
+call browser_create_tab {url: "https://example.com"} -> tab
+assert screenshot_matches {name: "home", tabId: tab.id}
+`), 0o644))
+	script := filepath.Join(dir, "visual.dsl")
+	require.NoError(t, os.WriteFile(script, []byte(`connect "./bin/mcp-browser-server"
+call browser_create_tab {url: "https://example.com"} -> tab
+assert screenshot_matches {name: "home", tabId: tab.id}
+assert screenshot_matches {name: "home", tabId: tab.id}
+
+call browser_navigate {tabId: tab.id, url: "https://example.com/about"}
+assert screenshot_matches {name: "home", tabId: tab.id, tolerance: 20}
+assert screenshot_matches {name: "home", tabId: tab.id}
+`), 0o644))
+
+	// A missing baseline fails instead of being created
+	var out bytes.Buffer
+	results := runner.Run(ctx, []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out})
+	require.Len(t, results, 1)
+	require.Error(t, results[0].Err)
+	assert.Contains(t, results[0].Err.Error(), "line 3: ")
+	assert.Contains(t, results[0].Err.Error(), "Baseline "+filepath.Join(baselines, "home.png")+" does not exist")
+	assert.NoFileExists(t, filepath.Join(baselines, "home.png"))
+
+	results = runner.Run(ctx, []string{setup}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out, UpdateBaselines: true})
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err, out.String())
+	assert.FileExists(t, filepath.Join(baselines, "home.png"))
+
+	// The fake renders a band colored after the page text, 10% of the image
+	results = runner.Run(ctx, []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out})
+	require.Len(t, results, 1)
+	require.Error(t, results[0].Err)
+	assert.Contains(t, results[0].Err.Error(), "line 8: assertion error: screenshot differs from baseline home by 10% (tolerance 0%), diff saved to "+filepath.Join(baselines, "home.diff.png"))
+	assert.FileExists(t, filepath.Join(baselines, "home.diff.png"))
+
+	// Updating the baselines makes the about page the new reference
+	results = runner.Run(ctx, []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out, UpdateBaselines: true})
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err, out.String())
+	assert.NoFileExists(t, filepath.Join(baselines, "home.diff.png"))
+}
+
+func TestExtension_SetOfMarks(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	server := mcp.NewServer("test", "1.0.0", handler.NewBrowserHandler(client))
+	h, err := server.HTTPHandler(config.TransportStreamableHTTP, "")
+	require.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	// The fake has no layout, so the marks are listed without boxes
+	script := filepath.Join(t.TempDir(), "marks.dsl")
+	require.NoError(t, os.WriteFile(script, []byte(`connect "./bin/mcp-browser-server"
+call browser_create_tab {url: "https://example.com/login"} -> tab
+call browser_screenshot_marked {tabId: tab.id} -> marked
+set remember = marked[0][1]
+assert remember.selector == "#remember", "second actionable is not the checkbox"
+assert remember.marked == false, "marked without a box"
+assert marked[1].type == "image", "no screenshot"
+
+call browser_click_actionable {tabId: tab.id, label: remember.label}
+call browser_get_value {tabId: tab.id, selector: "#remember"} -> value
+assert value.checked == true, "checkbox not clicked"
+
+call browser_navigate {tabId: tab.id, url: "https://example.com"}
+call browser_click_actionable {tabId: tab.id, label: 1}
+`), 0o644))
+
+	var out bytes.Buffer
+	results := runner.Run(ctx, []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out})
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err, out.String())
+
+	// Labels are listed again after navigating, label 1 being the link
+	tabs, err := client.ListTabs(ctx)
+	require.NoError(t, err)
+	require.Len(t, tabs, 1)
+	assert.Equal(t, "https://example.com/about", tabs[0].URL)
+}
+
+func TestExtension_Interactions(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/login", true)
+	require.NoError(t, err)
+	value := func(selector string) *browser.ElementValue {
+		t.Helper()
+		value, err := client.GetValue(ctx, tab.ID, selector)
+		require.NoError(t, err)
+		return value
+	}
+
+	// Characters are typed, shortcuts are not
+	require.NoError(t, client.PressKey(ctx, tab.ID, "#user", []browser.KeyPress{
+		{Key: "Backspace"}, {Key: "a"}, {Key: "B", Modifiers: []string{"Shift"}}, {Key: "a", Modifiers: []string{"Control"}},
+	}))
+	assert.Equal(t, "aB", value("#user").Value)
+	require.NoError(t, client.PressKey(ctx, tab.ID, "", []browser.KeyPress{{Key: "!"}}))
+	assert.Equal(t, "aB!", value("#user").Value)
+
+	require.NoError(t, client.SetChecked(ctx, tab.ID, "#remember", true))
+	require.NoError(t, client.SetChecked(ctx, tab.ID, "#remember", true))
+	assert.True(t, *value("#remember").Checked)
+	require.NoError(t, client.ClickWithOptions(ctx, tab.ID, "#remember", 0, browser.ClickOptions{ClickCount: 2}))
+	assert.True(t, *value("#remember").Checked)
+	require.NoError(t, client.SetChecked(ctx, tab.ID, "#remember", false))
+	assert.False(t, *value("#remember").Checked)
+	assert.ErrorContains(t, client.SetChecked(ctx, tab.ID, "#user", true), "element is not a checkbox or radio button")
+
+	values, err := client.SelectOption(ctx, tab.ID, "#lang", browser.OptionSelection{Labels: []string{"English"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en"}, values)
+	assert.Equal(t, "en", value("#lang").Value)
+	_, err = client.SelectOption(ctx, tab.ID, "#lang", browser.OptionSelection{Indexes: []int{5}})
+	assert.EqualError(t, err, "chrome extension error: no option at index 5, the select has 2")
+	_, err = client.SelectOption(ctx, tab.ID, "#lang", browser.OptionSelection{Values: []string{"en", "fr"}})
+	assert.ErrorContains(t, err, "element is not a multiple select")
+
+	x, y := 10.0, 20.0
+	require.NoError(t, client.Drag(ctx, tab.ID, browser.DragPoint{Selector: "#user"}, browser.DragPoint{X: &x, Y: &y}))
+	assert.ErrorContains(t, client.Hover(ctx, tab.ID, "#nope", 0), "element not found")
+
+	// Right clicks leave links alone, Control+click opens them in a new tab
+	_, err = client.Navigate(ctx, tab.ID, "https://example.com", true)
+	require.NoError(t, err)
+	require.NoError(t, client.Hover(ctx, tab.ID, "#more", 0))
+	require.NoError(t, client.ClickWithOptions(ctx, tab.ID, "#more", 0, browser.ClickOptions{Button: "right"}))
+	require.NoError(t, client.ClickWithOptions(ctx, tab.ID, "#more", 0, browser.ClickOptions{Modifiers: []string{"Control"}}))
+	tabs, err := client.ListTabs(ctx)
+	require.NoError(t, err)
+	require.Len(t, tabs, 2)
+	assert.Equal(t, "https://example.com", tabs[0].URL)
+	assert.True(t, tabs[0].Active)
+	assert.Equal(t, "https://example.com/about", tabs[1].URL)
+}
+
+func TestExtension_Table(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/shop", true)
+	require.NoError(t, err)
+
+	table, err := client.ExtractTable(ctx, tab.ID, "#prices", 0)
+	require.NoError(t, err)
+	assert.Equal(t, &browser.Table{
+		Columns: []string{"Item", "Size", "Price"},
+		Rows:    [][]string{{"Tea", "Small", "$2.50"}, {"Tea", "Large", "$3.00"}},
+	}, table)
+
+	_, err = client.ExtractTable(ctx, tab.ID, "table", 1)
+	assert.EqualError(t, err, "1 element(s) match table, no element at index 1")
+
+	table, err = client.ExtractRows(ctx, tab.ID, ".card", []browser.TableColumn{
+		{Name: "id", Attribute: "data-id"},
+		{Name: "name", Selector: "h3"},
+		{Name: "link", Selector: "a", Attribute: "href"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &browser.Table{
+		Columns: []string{"id", "name", "link"},
+		Rows:    [][]string{{"1", "Mug", "/mug"}, {"2", "Pot", ""}},
+	}, table)
+}
+
+func TestExtension_Structured(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	server := mcp.NewServer("test", "1.0.0", handler.NewBrowserHandler(client))
+	h, err := server.HTTPHandler(config.TransportStreamableHTTP, "")
+	require.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	script := filepath.Join(t.TempDir(), "structured.dsl")
+	require.NoError(t, os.WriteFile(script, []byte(`connect "./bin/mcp-browser-server"
+call browser_create_tab {url: "https://example.com/shop"} -> tab
+
+# The DSL null literal is an empty string, so nulls are compared as text
+call browser_extract_structured {tabId: tab.id, schema: {
+  title: "title",
+  products: {selector: ".card", multiple: true, fields: {
+    id: {attribute: "data-id", type: "integer"},
+    name: "h3",
+    link: "a@href"
+  }},
+  price: {selector: "#prices td", type: "number"},
+  cells: {selector: "#prices tbody td", multiple: true},
+  sale: {selector: ".sale", optional: true}
+}} -> result
+assert result.data.title == "Shop", "title not read"
+assert len(result.data.products) == 2, "products not listed"
+assert result.data.products[0].id == 2 - 1, "id not read as an integer"
+assert result.data.products[0].link == "/mug", "link not read"
+assert result.data.products[1].name == "Pot", "nested fields not read"
+assert str(result.data.products[1].link) == "null", "missing link not null"
+assert result.errors["products[1].link"] == "no element matches a", "missing link not reported"
+assert result.errors["price"] == "cannot read \"Tea\" as a number", "price not reported"
+assert len(result.data.cells) == 5, "cells not listed"
+assert str(result.data.sale) == "null", "optional field not null"
+assert str(result.errors["sale"]) == "null", "optional field reported"
+
+call browser_extract_structured {tabId: tab.id, selector: ".card", schema: {name: "h3"}} -> first
+assert first.data.name == "Mug", "schema not read in the selector"
+assert str(first.errors) == "null", "errors without failures"
+`), 0o644))
+
+	var out bytes.Buffer
+	results := runner.Run(ctx, []string{script}, runner.Options{ServerURL: ts.URL + "/mcp", Stdout: &out})
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err, out.String())
+}
+
+func TestExtension_Markdown(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/blog/post", true)
+	require.NoError(t, err)
+
+	md, err := client.ExtractMarkdown(ctx, tab.ID, "body", browser.MarkdownOptions{AbsoluteLinks: true, MainContent: true})
+	require.NoError(t, err)
+	assert.Equal(t, "# Release notes\n\n"+
+		"Version 2 is out, see the [changes](https://example.com/blog/changes.html).\n\n"+
+		"| Version | Date |\n| --- | --- |\n| 2.0 | 2025-03-01 |", md)
+
+	md, err = client.ExtractMarkdown(ctx, tab.ID, "body", browser.MarkdownOptions{})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(md, "[Home](/) [Blog](/blog/)\n\n# Release notes"), md)
+	assert.True(t, strings.HasSuffix(md, "© Example"), md)
+
+	text, err := client.ExtractText(ctx, tab.ID, "article")
+	require.NoError(t, err)
+	assert.Equal(t, "Release notes\nVersion 2 is out, see the changes.\nVersion Date\n2.0 2025-03-01", text)
+}
+
+func TestExtension_Files(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/files", true)
+	require.NoError(t, err)
+
+	// Uploads are streamed in chunks and put back together by the extension
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.csv")
+	require.NoError(t, os.WriteFile(users, []byte(strings.Repeat("id,name\n", browser.UploadChunkSize/4)), 0o644))
+	photo := filepath.Join(dir, "cat.png")
+	require.NoError(t, os.WriteFile(photo, []byte("not really a png"), 0o644))
+
+	files, err := client.UploadFiles(ctx, tab.ID, "#import", []string{users})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(2*browser.UploadChunkSize), files[0].Size)
+	value, err := client.GetValue(ctx, tab.ID, "#import")
+	require.NoError(t, err)
+	assert.Equal(t, `C:\fakepath\users.csv`, value.Value)
+	assert.Equal(t, []string{"users.csv"}, value.Files)
+
+	_, err = client.UploadFiles(ctx, tab.ID, "#photos", []string{photo, users})
+	require.NoError(t, err)
+	value, err = client.GetValue(ctx, tab.ID, "#photos")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cat.png", "users.csv"}, value.Files)
+
+	_, err = client.UploadFiles(ctx, tab.ID, "#import", []string{photo, users})
+	assert.EqualError(t, err, "chrome extension error: element does not accept multiple files: #import")
+	_, err = client.UploadFiles(ctx, tab.ID, "#report", []string{photo})
+	assert.EqualError(t, err, "chrome extension error: element is not a file input: #report")
+
+	// Links with a download attribute are downloaded
+	require.NoError(t, client.Click(ctx, tab.ID, "#report", 0))
+	download, err := client.WaitForDownload(ctx, browser.DownloadFilter{}, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/reports/report.csv", download.URL)
+	assert.Equal(t, "complete", download.State)
+	assert.Equal(t, int64(14), download.BytesReceived)
+	assert.Equal(t, tab.ID, download.TabID)
+
+	path, err := client.SaveDownload(ctx, download.ID, filepath.Join(dir, "downloads"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "downloads", "report.csv"), path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "id,name\n1,ada\n", string(content))
+
+	require.NoError(t, client.Click(ctx, tab.ID, "#broken", 0))
+	download, err = client.WaitForDownload(ctx, browser.DownloadFilter{URL: "gone"}, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "interrupted", download.State)
+	assert.Equal(t, "SERVER_BAD_CONTENT", download.Error)
+	assert.True(t, strings.HasSuffix(download.Filename, "/old.csv"))
+	_, err = client.SaveDownload(ctx, download.ID, dir)
+	assert.EqualError(t, err, fmt.Sprintf("download %d is interrupted, not complete", download.ID))
+
+	downloads, err := client.ListDownloads(ctx, browser.DownloadFilter{State: "complete"})
+	require.NoError(t, err)
+	require.Len(t, downloads, 1)
+	assert.Equal(t, path, downloads[0].Path)
+}
+
+func TestExtension_Locators(t *testing.T) {
+	client, _ := startExtension(t)
+	ctx := context.Background()
+
+	tab, err := client.CreateTab(ctx, "https://example.com/editor", true)
+	require.NoError(t, err)
+
+	// Selectors are matched in the documents of iframes and the shadow roots
+	// of their hosts, and not in the page
+	text, err := client.ExtractContent(ctx, tab.ID, "user-card >>> .name", "text", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Ada"}, text)
+	text, err = client.ExtractContent(ctx, tab.ID, ".name", "text", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Outside"}, text)
+	text, err = client.ExtractContent(ctx, tab.ID, "#editor >>> h1", "text", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Draft"}, text)
+
+	require.NoError(t, client.Type(ctx, tab.ID, "#editor >>> #title", "Notes", true, 0))
+	value, err := client.ExtractContent(ctx, tab.ID, "#editor >>> #title", "attribute", "value")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Notes"}, value)
+	field, err := client.GetValue(ctx, tab.ID, "#editor >>> #title")
+	require.NoError(t, err)
+	assert.Equal(t, "Notes", field.Value)
+
+	require.NoError(t, client.Hover(ctx, tab.ID, "#editor >>> #public", 0))
+	require.NoError(t, client.SetChecked(ctx, tab.ID, "#editor >>> #public", true))
+	field, err = client.GetValue(ctx, tab.ID, "#editor >>> #public")
+	require.NoError(t, err)
+	require.NotNil(t, field.Checked)
+	assert.True(t, *field.Checked)
+	values, err := client.SelectOption(ctx, tab.ID, "#editor >>> #lang", browser.OptionSelection{Labels: []string{"French"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fr"}, values)
+	elements, err := client.FindElements(ctx, tab.ID, "#editor >>> option", 0)
+	require.NoError(t, err)
+	assert.Len(t, elements, 2)
+
+	require.NoError(t, client.PressKey(ctx, tab.ID, "#editor >>> #title", []browser.KeyPress{{Key: "Backspace"}}))
+	field, err = client.GetValue(ctx, tab.ID, "#editor >>> #title")
+	require.NoError(t, err)
+	assert.Equal(t, "Note", field.Value)
+	require.NoError(t, client.Drag(ctx, tab.ID, browser.DragPoint{Selector: "#editor >>> h1"}, browser.DragPoint{Selector: "#editor >>> #title"}))
+	_, err = client.Scroll(ctx, tab.ID, nil, nil, "#editor >>> h1", "auto")
+	require.NoError(t, err)
+
+	attachment := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(attachment, []byte("notes"), 0o644))
+	files, err := client.UploadFiles(ctx, tab.ID, "#editor >>> #attachment", []string{attachment})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "notes.txt", files[0].Name)
+
+	table, err := client.ExtractRows(ctx, tab.ID, "#editor >>> option", []browser.TableColumn{{Name: "label"}, {Name: "value", Attribute: "value"}})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"English", "en"}, {"French", "fr"}}, table.Rows)
+	data, err := client.ExtractStructured(ctx, tab.ID, "#editor >>> #lang", map[string]*browser.StructuredField{"first": {Selector: "option"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"first": "English"}, data)
+
+	require.NoError(t, client.Click(ctx, tab.ID, "#editor >>> rich-text >>> button.bold", 0))
+	_, err = client.WaitForElement(ctx, tab.ID, "#editor >>> rich-text >>> .bold", 1000, "visible")
+	require.NoError(t, err)
+	_, err = client.Screenshot(ctx, tab.ID, false, "user-card >>> .name", "png", 0)
+	require.NoError(t, err)
+
+	_, err = client.WaitForElement(ctx, tab.ID, "#editor >>> .bold", 1000, "visible")
+	assert.EqualError(t, err, "chrome extension error: timeout waiting for element: .bold")
+	err = client.Click(ctx, tab.ID, "secret-box >>> span", 0)
+	assert.EqualError(t, err, "chrome extension error: shadow root is closed: secret-box")
+	err = client.Click(ctx, tab.ID, "p >>> span", 0)
+	assert.EqualError(t, err, "chrome extension error: element is not an iframe or shadow host: p")
+	err = client.Click(ctx, tab.ID, "#missing >>> span", 0)
+	assert.EqualError(t, err, "chrome extension error: element not found: #missing")
+}
@@ -0,0 +1,154 @@
+package fakeext
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"path"
+
+	"github.com/periplon/bract/internal/browser"
+	"golang.org/x/net/html"
+)
+
+// downloadDir is where the fake browser saves downloads
+const downloadDir = "/home/user/Downloads"
+
+// download is a file the browser downloaded, or failed to
+type download struct {
+	id       int
+	filename string
+	mimeType string
+	data     []byte
+	state    string
+}
+
+// File upload commands
+
+// uploadChunk buffers a chunk of the index-th file of an upload until
+// uploadFile sets the files
+func (e *Extension) uploadChunk(p params) (interface{}, error) {
+	if p.UploadID == "" {
+		return nil, fmt.Errorf("uploadId is required")
+	}
+	data, err := base64.StdEncoding.DecodeString(p.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunk: %w", err)
+	}
+	if e.uploads[p.UploadID] == nil {
+		e.uploads[p.UploadID] = make(map[int][]byte)
+	}
+	e.uploads[p.UploadID][p.Index] = append(e.uploads[p.UploadID][p.Index], data...)
+	return success, nil
+}
+
+// uploadFile sets the files of a file input to the files of an upload
+func (e *Extension) uploadFile(p params) (interface{}, error) {
+	chunks := e.uploads[p.UploadID]
+	delete(e.uploads, p.UploadID)
+
+	t, err := e.tab(p.TabID)
+	if err != nil {
+		return nil, err
+	}
+	pg := t.page()
+	n, err := pg.locateOne(p.Hosts, p.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if kind, _ := getAttr(n, "type"); n.Data != "input" || kind != "file" {
+		return nil, fmt.Errorf("element is not a file input: %s", p.Selector)
+	}
+	if _, multiple := getAttr(n, "multiple"); len(p.Files) > 1 && !multiple {
+		return nil, fmt.Errorf("element does not accept multiple files: %s", p.Selector)
+	}
+	for i, file := range p.Files {
+		if int64(len(chunks[i])) != file.Size {
+			return nil, fmt.Errorf("received %d bytes of %s, expected %d", len(chunks[i]), file.Name, file.Size)
+		}
+	}
+
+	if pg.files == nil {
+		pg.files = make(map[*html.Node][]browser.UploadedFile)
+	}
+	pg.files[n] = p.Files
+	return success, nil
+}
+
+// Download commands
+
+// startDownload downloads rawURL, as clicking a link with the download
+// attribute does, saving it under name, or the last segment of its path
+// when name is empty. Fixtures are downloaded whole at once, and URLs
+// without one are interrupted as a server error would.
+func (e *Extension) startDownload(t *tab, rawURL, name string) {
+	d := &download{id: e.nextDownload, state: browser.DownloadComplete}
+	e.nextDownload++
+	e.downloads = append(e.downloads, d)
+
+	u, err := url.Parse(rawURL)
+	if err == nil {
+		switch u.Scheme {
+		case "data":
+			d.data, err = decodeDataURL(rawURL)
+		case "http", "https":
+			d.data, err = readFixture(e.opts.Fixtures, u)
+		default:
+			err = fmt.Errorf("unsupported URL scheme: %s", rawURL)
+		}
+	}
+
+	if name == "" && u != nil {
+		name = path.Base(u.Path)
+	}
+	if name == "" || name == "/" || name == "." {
+		name = "download"
+	}
+	d.filename = path.Join(downloadDir, name)
+	d.mimeType = mime.TypeByExtension(path.Ext(name))
+	if d.mimeType == "" {
+		d.mimeType = "application/octet-stream"
+	}
+
+	e.emit("download", browser.DownloadEvent{
+		TabID:    t.id,
+		ID:       d.id,
+		URL:      rawURL,
+		Filename: d.filename,
+		MimeType: d.mimeType,
+		State:    browser.DownloadInProgress,
+	})
+	if err != nil {
+		d.state = browser.DownloadInterrupted
+		reason := "NETWORK_FAILED"
+		if errors.Is(err, fs.ErrNotExist) {
+			reason = "SERVER_BAD_CONTENT"
+		}
+		e.emit("download", browser.DownloadEvent{ID: d.id, State: d.state, Error: reason})
+		return
+	}
+	e.emit("download", browser.DownloadEvent{
+		ID:            d.id,
+		State:         d.state,
+		BytesReceived: int64(len(d.data)),
+		TotalBytes:    int64(len(d.data)),
+	})
+}
+
+// readDownload returns the file of a completed download as a data URL
+func (e *Extension) readDownload(p params) (interface{}, error) {
+	for _, d := range e.downloads {
+		if d.id != p.DownloadID {
+			continue
+		}
+		if d.state != browser.DownloadComplete {
+			return nil, fmt.Errorf("download %d is not complete", d.id)
+		}
+		return map[string]interface{}{
+			"dataUrl": "data:" + d.mimeType + ";base64," + base64.StdEncoding.EncodeToString(d.data),
+		}, nil
+	}
+	return nil, fmt.Errorf("no download with id: %d", p.DownloadID)
+}
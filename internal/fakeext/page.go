@@ -0,0 +1,276 @@
+package fakeext
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/periplon/bract/internal/browser"
+	"golang.org/x/net/html"
+)
+
+// notFoundPage is loaded for URLs without a fixture
+const notFoundPage = `<!DOCTYPE html>
+<html><head><title>404 Not Found</title></head>
+<body><h1>Not Found</h1><p>No fixture for this URL.</p></body></html>`
+
+// page is a document loaded in a tab
+type page struct {
+	url    string
+	status int
+	size   int64 // bytes of the document
+	doc    *html.Node
+
+	focused *html.Node                            // element keys are pressed in
+	files   map[*html.Node][]browser.UploadedFile // files chosen in file inputs
+	frames  map[*html.Node]*html.Node             // documents of iframes, parsed when first reached
+}
+
+// title returns the text of the page's title element
+func (p *page) title() string {
+	if title := findElement(p.doc, "title"); title != nil {
+		return textContent(title)
+	}
+	return ""
+}
+
+// origin returns the scheme and host of the page's URL
+func (p *page) origin() string {
+	u, err := url.Parse(p.url)
+	if err != nil || u.Host == "" {
+		return p.url
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// query returns the elements matching the CSS selector
+func (p *page) query(css string) ([]*html.Node, error) {
+	sel, err := parseSelector(css)
+	if err != nil {
+		return nil, err
+	}
+	return querySelectorAll(p.doc, sel), nil
+}
+
+// queryOne returns the first element matching the CSS selector
+func (p *page) queryOne(css string) (*html.Node, error) {
+	nodes, err := p.query(css)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("element not found: %s", css)
+	}
+	return nodes[0], nil
+}
+
+// locate returns the elements matching the CSS selector inside hosts: the
+// document of each iframe host, or the shadow root of each shadow host,
+// outermost first
+func (p *page) locate(hosts []string, css string) ([]*html.Node, error) {
+	root := p.doc
+	for _, host := range hosts {
+		sel, err := parseSelector(host)
+		if err != nil {
+			return nil, err
+		}
+		nodes := querySelectorAll(root, sel)
+		if len(nodes) == 0 {
+			return nil, fmt.Errorf("element not found: %s", host)
+		}
+		if root, err = p.hostRoot(nodes[0], host); err != nil {
+			return nil, err
+		}
+	}
+
+	sel, err := parseSelector(css)
+	if err != nil {
+		return nil, err
+	}
+	return querySelectorAll(root, sel), nil
+}
+
+// locateOne returns the first element matching the CSS selector inside hosts
+func (p *page) locateOne(hosts []string, css string) (*html.Node, error) {
+	nodes, err := p.locate(hosts, css)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("element not found: %s", css)
+	}
+	return nodes[0], nil
+}
+
+// hostRoot returns the node the selectors inside a host are matched in: the
+// document of an iframe, loaded from its srcdoc or a data URL, or the shadow
+// root of a shadow host, declared with a template element
+func (p *page) hostRoot(n *html.Node, host string) (*html.Node, error) {
+	if n.Data == "iframe" || n.Data == "frame" {
+		if doc, ok := p.frames[n]; ok {
+			return doc, nil
+		}
+		data, ok := getAttr(n, "srcdoc")
+		if !ok {
+			src, _ := getAttr(n, "src")
+			if !strings.HasPrefix(src, "data:") {
+				return nil, fmt.Errorf("frame has no document the fake extension can load: %s", host)
+			}
+			raw, err := decodeDataURL(src)
+			if err != nil {
+				return nil, err
+			}
+			data = string(raw)
+		}
+		doc, err := html.Parse(strings.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the document of %s: %w", host, err)
+		}
+		if p.frames == nil {
+			p.frames = make(map[*html.Node]*html.Node)
+		}
+		p.frames[n] = doc
+		return doc, nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "template" {
+			continue
+		}
+		switch mode, _ := getAttr(c, "shadowrootmode"); mode {
+		case "open":
+			return c, nil
+		case "closed":
+			return nil, fmt.Errorf("shadow root is closed: %s", host)
+		}
+	}
+	return nil, fmt.Errorf("element is not an iframe or shadow host: %s", host)
+}
+
+// resolve resolves a possibly relative URL against the page's URL
+func (p *page) resolve(ref string) string {
+	base, err := url.Parse(p.url)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// loadPage loads the document for rawURL. about:blank is an empty page, data
+// URLs hold the document, file URLs are read from disk and http(s) URLs are
+// served from fixtures: https://example.com/docs/ is looked up as
+// example.com/docs/index.html, then docs/index.html, and a path without an
+// extension also as .html. URLs without a fixture load a 404 page, as a
+// browser would.
+func loadPage(fixtures fs.FS, rawURL string) (*page, error) {
+	if rawURL == "" {
+		rawURL = "about:blank"
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+
+	var (
+		data   []byte
+		status = 200
+	)
+	switch u.Scheme {
+	case "about":
+		data = []byte("<html><head></head><body></body></html>")
+	case "data":
+		if data, err = decodeDataURL(rawURL); err != nil {
+			return nil, err
+		}
+	case "file":
+		if data, err = os.ReadFile(u.Path); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", rawURL, err)
+		}
+	case "http", "https":
+		if data, err = readFixture(fixtures, u); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to load %s: %w", rawURL, err)
+			}
+			data, status = []byte(notFoundPage), 404
+		}
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme: %s", rawURL)
+	}
+
+	return newPage(u.String(), data, status)
+}
+
+// newPage parses the document served for rawURL
+func newPage(rawURL string, data []byte, status int) (*page, error) {
+	doc, err := html.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", rawURL, err)
+	}
+	return &page{url: rawURL, status: status, size: int64(len(data)), doc: doc}, nil
+}
+
+// decodeDataURL returns the content of a data: URL
+func decodeDataURL(rawURL string) ([]byte, error) {
+	header, content, ok := strings.Cut(strings.TrimPrefix(rawURL, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid data URL: missing ','")
+	}
+	if strings.HasSuffix(header, ";base64") {
+		data, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URL: %w", err)
+		}
+		return data, nil
+	}
+	data, err := url.PathUnescape(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data URL: %w", err)
+	}
+	return []byte(data), nil
+}
+
+// readFixture reads the fixture file for an http(s) URL
+func readFixture(fixtures fs.FS, u *url.URL) ([]byte, error) {
+	if fixtures == nil {
+		return nil, fs.ErrNotExist
+	}
+
+	p := strings.TrimPrefix(path.Clean("/"+u.Path), "/")
+	var names []string
+	switch {
+	case p == "" || strings.HasSuffix(u.Path, "/"):
+		names = append(names, path.Join(p, "index.html"))
+	case path.Ext(p) == "":
+		names = append(names, p, p+".html", path.Join(p, "index.html"))
+	default:
+		names = append(names, p)
+	}
+
+	for _, prefix := range []string{u.Hostname(), ""} {
+		for _, name := range names {
+			data, err := fs.ReadFile(fixtures, path.Join(prefix, name))
+			if err == nil {
+				return data, nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) && !isDirError(fixtures, path.Join(prefix, name)) {
+				return nil, err
+			}
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// isDirError reports whether name is a directory, which fs.ReadFile fails on
+func isDirError(fixtures fs.FS, name string) bool {
+	info, err := fs.Stat(fixtures, name)
+	return err == nil && info.IsDir()
+}
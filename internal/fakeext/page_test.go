@@ -0,0 +1,44 @@
+package fakeext
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPage(t *testing.T) {
+	fixtures := fstest.MapFS{
+		"example.com/index.html": {Data: []byte("<title>Home</title>")},
+		"example.com/docs.html":  {Data: []byte("<title>Docs</title>")},
+		"shared/index.html":      {Data: []byte("<title>Shared</title>")},
+	}
+
+	tests := []struct {
+		url    string
+		title  string
+		status int
+	}{
+		{"https://example.com", "Home", 200},
+		{"https://example.com/", "Home", 200},
+		{"https://example.com/docs", "Docs", 200},
+		{"http://localhost:8080/shared/", "Shared", 200},
+		{"https://example.com/missing", "404 Not Found", 404},
+		{"about:blank", "", 200},
+		{"", "", 200},
+		{"data:text/html,<title>Inline%20page</title>", "Inline page", 200},
+		{"data:text/html;base64,PHRpdGxlPkVuY29kZWQ8L3RpdGxlPg==", "Encoded", 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			p, err := loadPage(fixtures, tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.title, p.title())
+			assert.Equal(t, tt.status, p.status)
+		})
+	}
+
+	_, err := loadPage(fixtures, "ftp://example.com/")
+	assert.ErrorContains(t, err, "unsupported URL scheme")
+}
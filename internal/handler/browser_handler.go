@@ -2,8 +2,18 @@ package handler
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"io/fs"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -12,16 +22,69 @@ import (
 
 // BrowserHandler handles browser automation tool requests
 type BrowserHandler struct {
-	client BrowserClient
+	client             BrowserClient
+	screenshotDir      string // directory screenshots are saved in, if set
+	screenshotMaxBytes int    // size screenshots returned inline are downscaled to fit in
+	baselineDir        string // directory baseline screenshots are kept in
+	downloadDir        string // directory finished downloads are copied into, if set
+	videoDir           string // directory recordings are saved in, if set
+	harDir             string // directory network captures are saved in, if set
+	routesDir          string // directory route body files are read from, if set
 }
 
 // NewBrowserHandler creates a new browser handler
 func NewBrowserHandler(client BrowserClient) *BrowserHandler {
 	return &BrowserHandler{
-		client: client,
+		client:             client,
+		screenshotMaxBytes: DefaultScreenshotMaxBytes,
+		baselineDir:        DefaultBaselineDir,
 	}
 }
 
+// SetScreenshotOptions sets the directory screenshots are saved in and the
+// size screenshots returned inline are downscaled to fit in. With a
+// directory, screenshots are saved there and only their path is returned.
+func (h *BrowserHandler) SetScreenshotOptions(dir string, maxBytes int) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultScreenshotMaxBytes
+	}
+	h.screenshotDir = dir
+	h.screenshotMaxBytes = maxBytes
+}
+
+// SetBaselineDir sets the directory browser_screenshot_compare keeps the
+// baseline screenshots in
+func (h *BrowserHandler) SetBaselineDir(dir string) {
+	if dir == "" {
+		dir = DefaultBaselineDir
+	}
+	h.baselineDir = dir
+}
+
+// SetDownloadDir sets the directory browser_wait_for_download copies the
+// files of finished downloads into
+func (h *BrowserHandler) SetDownloadDir(dir string) {
+	h.downloadDir = dir
+}
+
+// SetVideoDir sets the directory browser_stop_video saves recordings in.
+// Without one, recordings are saved in the temporary directory.
+func (h *BrowserHandler) SetVideoDir(dir string) {
+	h.videoDir = dir
+}
+
+// SetHARDir sets the directory browser_network_stop saves HAR files in.
+// Without one, HAR files are saved in the temporary directory.
+func (h *BrowserHandler) SetHARDir(dir string) {
+	h.harDir = dir
+}
+
+// SetRoutesDir sets the directory browser_route_add reads bodyFile from.
+// Without one, bodyFile is refused.
+func (h *BrowserHandler) SetRoutesDir(dir string) {
+	h.routesDir = dir
+}
+
 // Connection Handlers
 
 // WaitForConnection waits for the browser extension to connect
@@ -36,6 +99,37 @@ func (h *BrowserHandler) WaitForConnection(ctx context.Context, request mcp.Call
 	return mcp.NewToolResultText("Successfully connected to browser extension"), nil
 }
 
+// ListBrowsers lists the connected browser extensions
+func (h *BrowserHandler) ListBrowsers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	browsers := h.client.ListBrowsers()
+
+	// Return browsers as JSON
+	browsersJSON, err := json.Marshal(browsers)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize browsers: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(browsersJSON)), nil
+}
+
+// ReleaseSession releases, or closes when closeTabs is set, the tabs owned by
+// a disconnected MCP session
+func (h *BrowserHandler) ReleaseSession(ctx context.Context, sessionID string, closeTabs bool) error {
+	return h.client.ReleaseSession(ctx, sessionID, closeTabs)
+}
+
+// Events returns the browser events after the since cursor, for resources
+// reading the event stream
+func (h *BrowserHandler) Events(ctx context.Context, since uint64, filter browser.EventFilter, limit int) *browser.EventPage {
+	return h.client.GetEvents(ctx, since, filter, limit)
+}
+
+// SubscribeEvents calls fn with every browser event until the returned
+// function is called
+func (h *BrowserHandler) SubscribeEvents(fn func(browser.Event)) (unsubscribe func()) {
+	return h.client.SubscribeEvents(fn)
+}
+
 // Tab Management Handlers
 
 // ListTabs lists all open browser tabs
@@ -139,6 +233,40 @@ func (h *BrowserHandler) Reload(ctx context.Context, request mcp.CallToolRequest
 	return mcp.NewToolResultText(fmt.Sprintf("%s page", reloadType)), nil
 }
 
+// GoBack navigates back in the tab's history
+func (h *BrowserHandler) GoBack(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	waitUntilLoad := request.GetBool("waitUntilLoad", true)
+	timeout := request.GetInt("timeout", 30000)
+	tabID := request.GetInt("tabId", 0)
+
+	response, err := h.client.GoBack(ctx, tabID, waitUntilLoad, timeout)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to go back: %v", err)), nil
+	}
+
+	if len(response) > 0 {
+		return mcp.NewToolResultText(string(response)), nil
+	}
+	return mcp.NewToolResultText("Navigated back"), nil
+}
+
+// GoForward navigates forward in the tab's history
+func (h *BrowserHandler) GoForward(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	waitUntilLoad := request.GetBool("waitUntilLoad", true)
+	timeout := request.GetInt("timeout", 30000)
+	tabID := request.GetInt("tabId", 0)
+
+	response, err := h.client.GoForward(ctx, tabID, waitUntilLoad, timeout)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to go forward: %v", err)), nil
+	}
+
+	if len(response) > 0 {
+		return mcp.NewToolResultText(string(response)), nil
+	}
+	return mcp.NewToolResultText("Navigated forward"), nil
+}
+
 // Interaction Handlers
 
 // Click clicks on an element
@@ -147,15 +275,29 @@ func (h *BrowserHandler) Click(ctx context.Context, request mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	timeout := request.GetInt("timeout", 30000)
 	tabID := request.GetInt("tabId", 0)
 
-	if err := h.client.Click(ctx, tabID, selector, timeout); err != nil {
+	options, err := parseClickOptions(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if options.Button == "left" && options.ClickCount == 1 && len(options.Modifiers) == 0 {
+		err = h.client.Click(ctx, tabID, selector, timeout)
+	} else {
+		err = h.client.ClickWithOptions(ctx, tabID, selector, timeout, options)
+	}
+	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to click: %v", err)), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Clicked on element: %s", selector)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("%s on element: %s", clickVerb(options), selector)), nil
 }
 
 // Type types text into an input field
@@ -164,6 +306,10 @@ func (h *BrowserHandler) Type(ctx context.Context, request mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
 	text, err := request.RequireString("text")
 	if err != nil {
@@ -196,130 +342,822 @@ func (h *BrowserHandler) Scroll(ctx context.Context, request mcp.CallToolRequest
 		y = &yVal
 	}
 
-	selector := request.GetString("selector", "")
-	behavior := request.GetString("behavior", "auto")
+	selector, err := parseLocator(request, request.GetString("selector", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	behavior := request.GetString("behavior", "auto")
+	tabID := request.GetInt("tabId", 0)
+
+	response, err := h.client.Scroll(ctx, tabID, x, y, selector, behavior)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to scroll: %v", err)), nil
+	}
+
+	// If response contains scroll position data, return it
+	if len(response) > 0 {
+		return mcp.NewToolResultText(string(response)), nil
+	}
+
+	// Otherwise, return a descriptive message
+	var scrollDesc string
+	if selector != "" {
+		scrollDesc = fmt.Sprintf("to element %s", selector)
+	} else if x != nil && y != nil {
+		scrollDesc = fmt.Sprintf("to position (%g, %g)", *x, *y)
+	} else if x != nil {
+		scrollDesc = fmt.Sprintf("horizontally to %g", *x)
+	} else if y != nil {
+		scrollDesc = fmt.Sprintf("vertically to %g", *y)
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Scrolled %s", scrollDesc)), nil
+}
+
+// PressKey presses keys and key combinations such as Control+Shift+K
+func (h *BrowserHandler) PressKey(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	keys, err := request.RequireString("keys")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	selector, err := parseLocator(request, request.GetString("selector", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	presses, err := parseKeys(keys)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := h.client.PressKey(ctx, tabID, selector, presses); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to press keys: %v", err)), nil
+	}
+
+	if selector != "" {
+		return mcp.NewToolResultText(fmt.Sprintf("Pressed %s in %s", strings.Join(strings.Fields(keys), " "), selector)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Pressed %s", strings.Join(strings.Fields(keys), " "))), nil
+}
+
+// Hover moves the mouse over an element
+func (h *BrowserHandler) Hover(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	timeout := request.GetInt("timeout", 30000)
+	tabID := request.GetInt("tabId", 0)
+
+	if err := h.client.Hover(ctx, tabID, selector, timeout); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to hover: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Hovered over element: %s", selector)), nil
+}
+
+// Drag drags from an element or point to another
+func (h *BrowserHandler) Drag(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	source, err := parseDragPoint(request, "source")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	target, err := parseDragPoint(request, "target")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if request.GetString("frame", "") != "" && source.Selector == "" && target.Selector == "" {
+		return mcp.NewToolResultError("frame needs a source or target selector"), nil
+	}
+
+	tabID := request.GetInt("tabId", 0)
+
+	if err := h.client.Drag(ctx, tabID, source, target); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to drag: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Dragged %s to %s", describeDragPoint(source), describeDragPoint(target))), nil
+}
+
+// SelectOption selects options of a select element by value, label or index
+func (h *BrowserHandler) SelectOption(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	selection, err := parseOptionSelection(request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tabID := request.GetInt("tabId", 0)
+
+	values, err := h.client.SelectOption(ctx, tabID, selector, selection)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to select option: %v", err)), nil
+	}
+
+	result, err := json.Marshal(map[string]interface{}{"selector": selector, "values": values})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize selection: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// Check checks a checkbox or radio button
+func (h *BrowserHandler) Check(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return h.setChecked(ctx, request, true)
+}
+
+// Uncheck unchecks a checkbox
+func (h *BrowserHandler) Uncheck(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return h.setChecked(ctx, request, false)
+}
+
+// setChecked checks or unchecks the field of a request
+func (h *BrowserHandler) setChecked(ctx context.Context, request mcp.CallToolRequest, checked bool) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tabID := request.GetInt("tabId", 0)
+
+	action, done := "check", "Checked"
+	if !checked {
+		action, done = "uncheck", "Unchecked"
+	}
+	if err := h.client.SetChecked(ctx, tabID, selector, checked); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to %s: %v", action, err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("%s %s", done, selector)), nil
+}
+
+// WaitForElement waits for an element to appear
+func (h *BrowserHandler) WaitForElement(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	timeout := request.GetInt("timeout", 30000)
+	state := request.GetString("state", "visible")
+	tabID := request.GetInt("tabId", 0)
+
+	response, err := h.client.WaitForElement(ctx, tabID, selector, timeout, state)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to wait for element: %v", err)), nil
+	}
+
+	// If response contains element data, return it
+	if len(response) > 0 {
+		return mcp.NewToolResultText(string(response)), nil
+	}
+
+	// Otherwise, return a descriptive message
+	return mcp.NewToolResultText(fmt.Sprintf("Element %s is now %s", selector, state)), nil
+}
+
+// Content Handlers
+
+// ExecuteScript executes JavaScript in page context
+func (h *BrowserHandler) ExecuteScript(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	script, err := request.RequireString("script")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Get args as raw arguments and convert to slice
+	var args []interface{}
+	if rawArgs := request.GetArguments()["args"]; rawArgs != nil {
+		if argsSlice, ok := rawArgs.([]interface{}); ok {
+			args = argsSlice
+		}
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	result, err := h.client.ExecuteScript(ctx, tabID, script, args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute script: %v", err)), nil
+	}
+
+	// The result from ExecuteScript is already JSON-encoded
+	// Return it as-is for proper parsing by the DSL runtime
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// ExtractContent extracts content from the page
+func (h *BrowserHandler) ExtractContent(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := parseLocator(request, request.GetString("selector", "body"))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	contentType := request.GetString("type", "text")
+	attribute := request.GetString("attribute", "")
+	tabID := request.GetInt("tabId", 0)
+
+	results, err := h.client.ExtractContent(ctx, tabID, selector, contentType, attribute)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to extract content: %v", err)), nil
+	}
+
+	// Return results as JSON array
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultsJSON)), nil
+}
+
+// FindElements finds the elements matching a selector
+func (h *BrowserHandler) FindElements(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	limit := request.GetInt("limit", 0)
+	if limit < 0 {
+		return mcp.NewToolResultError("limit must not be negative"), nil
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	elements, err := h.client.FindElements(ctx, tabID, selector, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find elements: %v", err)), nil
+	}
+	if elements == nil {
+		elements = []browser.Element{}
+	}
+
+	// Return elements as JSON array
+	elementsJSON, err := json.Marshal(elements)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize elements: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(elementsJSON)), nil
+}
+
+// GetValue reads the value of a form field
+func (h *BrowserHandler) GetValue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tabID := request.GetInt("tabId", 0)
+
+	value, err := h.client.GetValue(ctx, tabID, selector)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get value: %v", err)), nil
+	}
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize value: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(valueJSON)), nil
+}
+
+// Screenshot takes a screenshot. It is returned as image content,
+// downscaled to fit in maxBytes, or saved in the screenshot directory, under
+// savePath if given, in which case only its path is returned.
+func (h *BrowserHandler) Screenshot(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	fullPage := request.GetBool("fullPage", false)
+	selector, err := parseLocator(request, request.GetString("selector", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	quality := request.GetInt("quality", 90)
+	tabID := request.GetInt("tabId", 0)
+	savePath := request.GetString("savePath", "")
+	if savePath != "" {
+		if h.screenshotDir == "" {
+			return mcp.NewToolResultError("savePath needs a screenshot directory; set browser.screenshot_dir in the configuration"), nil
+		}
+		if savePath, err = screenshotPath(h.screenshotDir, savePath); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	maxBytes := request.GetInt("maxBytes", h.screenshotMaxBytes)
+	if maxBytes <= 0 {
+		return mcp.NewToolResultError("maxBytes must be positive"), nil
+	}
+
+	// Without a format, saved screenshots take the one of their file extension
+	format := request.GetString("format", "")
+	if format == "" {
+		format = "png"
+		if ext := strings.ToLower(filepath.Ext(savePath)); ext == ".jpg" || ext == ".jpeg" {
+			format = "jpeg"
+		}
+	}
+	mimeType, ok := screenshotFormats[format]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("unsupported format: %s", format)), nil
+	}
+
+	dataURL, err := h.client.Screenshot(ctx, tabID, fullPage, selector, format, quality)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to take screenshot: %v", err)), nil
+	}
+	_, data, err := browser.DecodeDataURL(dataURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode screenshot: %v", err)), nil
+	}
+
+	if savePath == "" && h.screenshotDir == "" {
+		shot, err := fitScreenshot(data, mimeType, quality, maxBytes)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to process screenshot: %v", err)), nil
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.NewImageContent(base64.StdEncoding.EncodeToString(shot.Data), shot.MIMEType)},
+		}, nil
+	}
+
+	// Saved screenshots keep their full size
+	shot, err := fitScreenshot(data, mimeType, quality, 0)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process screenshot: %v", err)), nil
+	}
+	if savePath == "" {
+		savePath = filepath.Join(h.screenshotDir, fmt.Sprintf("screenshot-%s%s", time.Now().Format("20060102-150405.000"), screenshotExtensions[mimeType]))
+	}
+	if err := os.MkdirAll(filepath.Dir(savePath), 0o755); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create screenshot directory: %v", err)), nil
+	}
+	if err := os.WriteFile(savePath, shot.Data, 0o644); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save screenshot: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"path":     savePath,
+		"mimeType": shot.MIMEType,
+		"width":    shot.Width,
+		"height":   shot.Height,
+		"size":     len(shot.Data),
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize screenshot: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(resultJSON)), nil
+}
+
+// ScreenshotCompare compares a screenshot with a named baseline screenshot.
+// A missing baseline is saved from the screenshot, as is any baseline in
+// update mode. When pixels differ, a diff image is saved next to the baseline
+// and returned with the result.
+func (h *BrowserHandler) ScreenshotCompare(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := request.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	path, err := baselinePath(h.baselineDir, name)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	fullPage := request.GetBool("fullPage", false)
+	selector := request.GetString("selector", "")
+	tabID := request.GetInt("tabId", 0)
+	update := request.GetBool("update", false)
+	tolerance := request.GetFloat("tolerance", 0)
+	if tolerance < 0 || tolerance > 100 {
+		return mcp.NewToolResultError("tolerance must be between 0 and 100"), nil
+	}
+	threshold := request.GetFloat("threshold", DefaultPixelThreshold)
+	if threshold < 0 || threshold > 1 {
+		return mcp.NewToolResultError("threshold must be between 0 and 1"), nil
+	}
+	ignoreSelectors, ignoreRects, err := parseIgnoreRegions(request.GetArguments()["ignore"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	dataURL, err := h.client.Screenshot(ctx, tabID, fullPage, selector, "png", 100)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to take screenshot: %v", err)), nil
+	}
+	_, data, err := browser.DecodeDataURL(dataURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode screenshot: %v", err)), nil
+	}
+	actual, err := decodeImage(data)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode screenshot: %v", err)), nil
+	}
+
+	result := &screenshotComparison{
+		Name:      name,
+		Baseline:  path,
+		Width:     actual.Bounds().Dx(),
+		Height:    actual.Bounds().Dy(),
+		Tolerance: tolerance,
+		Passed:    true,
+	}
+
+	if update {
+		if err := writePNG(path, actual); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to save baseline: %v", err)), nil
+		}
+		_ = os.Remove(diffPath(path))
+		result.Updated = true
+		return h.comparisonResult(result, nil)
+	}
+	baselineData, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return mcp.NewToolResultError(fmt.Sprintf("Baseline %s does not exist; compare with update: true to create it", path)), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to read baseline: %v", err)), nil
+	}
+	baseline, err := decodeImage(baselineData)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode baseline %s: %v", path, err)), nil
+	}
+
+	regions, err := h.selectorRegions(ctx, tabID, ignoreSelectors, fullPage, selector, result.Width)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to locate ignore regions: %v", err)), nil
+	}
+
+	diff, diffPixels, totalPixels := compareImages(baseline, actual, threshold, append(ignoreRects, regions...))
+	result.SizeChanged = baseline.Bounds() != actual.Bounds()
+	result.DiffPixels = diffPixels
+	result.TotalPixels = totalPixels
+	if totalPixels > 0 {
+		result.Mismatch = math.Round(float64(diffPixels)*100/float64(totalPixels)*1000) / 1000
+	}
+	result.Passed = result.Mismatch <= tolerance
+
+	if diffPixels == 0 {
+		_ = os.Remove(diffPath(path))
+		return h.comparisonResult(result, nil)
+	}
+	result.DiffPath = diffPath(path)
+	if err := writePNG(result.DiffPath, diff); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save diff image: %v", err)), nil
+	}
+	return h.comparisonResult(result, diff)
+}
+
+// comparisonResult returns the result of a screenshot comparison, with the
+// diff image, if any, downscaled to fit in the screenshot size limit
+func (h *BrowserHandler) comparisonResult(result *screenshotComparison, diff image.Image) (*mcp.CallToolResult, error) {
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize comparison: %v", err)), nil
+	}
+	if diff == nil {
+		return mcp.NewToolResultText(string(resultJSON)), nil
+	}
+
+	data, err := encodeImage(diff, "image/png", 0)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode diff image: %v", err)), nil
+	}
+	shot, err := fitScreenshot(data, "image/png", 0, h.screenshotMaxBytes)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process diff image: %v", err)), nil
+	}
+	return mcp.NewToolResultImage(string(resultJSON), base64.StdEncoding.EncodeToString(shot.Data), shot.MIMEType), nil
+}
+
+// ScreenshotMarked takes a screenshot of the viewport with a numbered box over
+// each actionable element, and returns it with the table of labels, which
+// browser_click_actionable accepts
+func (h *BrowserHandler) ScreenshotMarked(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tabID := request.GetInt("tabId", 0)
+	maxBytes := request.GetInt("maxBytes", h.screenshotMaxBytes)
+	if maxBytes <= 0 {
+		return mcp.NewToolResultError("maxBytes must be positive"), nil
+	}
+
+	actionables, err := h.client.GetActionables(ctx, tabID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get actionables: %v", err)), nil
+	}
+	dataURL, err := h.client.Screenshot(ctx, tabID, false, "", "png", 100)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to take screenshot: %v", err)), nil
+	}
+	_, data, err := browser.DecodeDataURL(dataURL)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode screenshot: %v", err)), nil
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode screenshot: %v", err)), nil
+	}
+
+	// Screenshots are in device pixels, bounding boxes in CSS pixels
+	cssWidth, err := h.scriptNumber(ctx, tabID, "window.innerWidth")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get viewport width: %v", err)), nil
+	}
+	scale := 1.0
+	if cssWidth > 0 {
+		scale = float64(img.Bounds().Dx()) / cssWidth
+	}
+
+	table := make([]markedActionable, len(actionables))
+	for i, a := range actionables {
+		table[i] = markedActionable{Label: a.LabelNumber, Description: a.Description, Type: a.Type, Selector: a.Selector}
+
+		// Fall back on the element's box when the extension reports none
+		box := a.BoundingBox
+		if box == nil && a.Selector != "" {
+			if elements, err := h.client.FindElements(ctx, tabID, a.Selector, 1); err == nil && len(elements) > 0 {
+				box = elements[0].BoundingBox
+			}
+		}
+		if r, ok := markBox(box, scale, img.Bounds()); ok {
+			drawMark(img, r, a.LabelNumber, markColors[i%len(markColors)], scale)
+			table[i].Marked = true
+		}
+	}
+
+	tableJSON, err := json.Marshal(table)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize actionables: %v", err)), nil
+	}
+	marked, err := encodeImage(img, "image/png", 0)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode screenshot: %v", err)), nil
+	}
+	shot, err := fitScreenshot(marked, "image/png", 0, maxBytes)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to process screenshot: %v", err)), nil
+	}
+	return mcp.NewToolResultImage(string(tableJSON), base64.StdEncoding.EncodeToString(shot.Data), shot.MIMEType), nil
+}
+
+// Video Handlers
+
+// StartVideo starts recording a video of a tab
+func (h *BrowserHandler) StartVideo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	maxDuration := request.GetInt("maxDuration", 0)
+	if maxDuration < 0 {
+		return mcp.NewToolResultError("maxDuration must not be negative"), nil
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	response, err := h.client.StartVideo(ctx, tabID, maxDuration)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to start video recording: %v", err)), nil
+	}
+
+	if len(response) > 0 {
+		return mcp.NewToolResultText(string(response)), nil
+	}
+	return mcp.NewToolResultText("Started video recording"), nil
+}
+
+// StopVideo stops recording a tab and saves the video
+func (h *BrowserHandler) StopVideo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := request.GetString("name", "")
 	tabID := request.GetInt("tabId", 0)
 
-	response, err := h.client.Scroll(ctx, tabID, x, y, selector, behavior)
+	recording, err := h.client.StopVideo(ctx, tabID, h.videoDir, name)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to scroll: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to stop video recording: %v", err)), nil
 	}
 
-	// If response contains scroll position data, return it
-	if len(response) > 0 {
-		return mcp.NewToolResultText(string(response)), nil
+	recordingJSON, err := json.Marshal(recording)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize recording: %v", err)), nil
 	}
 
-	// Otherwise, return a descriptive message
-	var scrollDesc string
-	if selector != "" {
-		scrollDesc = fmt.Sprintf("to element %s", selector)
-	} else if x != nil && y != nil {
-		scrollDesc = fmt.Sprintf("to position (%g, %g)", *x, *y)
-	} else if x != nil {
-		scrollDesc = fmt.Sprintf("horizontally to %g", *x)
-	} else if y != nil {
-		scrollDesc = fmt.Sprintf("vertically to %g", *y)
-	}
+	return mcp.NewToolResultText(string(recordingJSON)), nil
+}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Scrolled %s", scrollDesc)), nil
+// Network Handlers
+
+// routeBodyPath returns the file a route body named name is read from,
+// refusing names that leave the routes directory
+func (h *BrowserHandler) routeBodyPath(name string) (string, error) {
+	if h.routesDir == "" {
+		return "", errors.New("bodyFile needs a routes directory; set browser.routes_dir in the configuration")
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("bodyFile must be a relative path inside the routes directory: %q", name)
+	}
+	return filepath.Join(h.routesDir, name), nil
 }
 
-// WaitForElement waits for an element to appear
-func (h *BrowserHandler) WaitForElement(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	selector, err := request.RequireString("selector")
+// RouteAdd registers a rule intercepting the network requests matching a URL pattern
+func (h *BrowserHandler) RouteAdd(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pattern, err := request.RequireString("pattern")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	action, err := request.RequireString("action")
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	timeout := request.GetInt("timeout", 30000)
-	state := request.GetString("state", "visible")
-	tabID := request.GetInt("tabId", 0)
+	route := browser.Route{
+		Pattern:     pattern,
+		Action:      browser.RouteAction(action),
+		Methods:     request.GetStringSlice("methods", nil),
+		TabID:       request.GetInt("tabId", 0),
+		DelayMs:     request.GetInt("delayMs", 0),
+		Status:      request.GetInt("status", 0),
+		ContentType: request.GetString("contentType", ""),
+	}
+	if route.RequestHeaders, err = stringMapArgument(request, "requestHeaders"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if route.ResponseHeaders, err = stringMapArgument(request, "responseHeaders"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	response, err := h.client.WaitForElement(ctx, tabID, selector, timeout, state)
+	body := request.GetString("body", "")
+	bodyFile := request.GetString("bodyFile", "")
+	switch {
+	case body != "" && bodyFile != "":
+		return mcp.NewToolResultError("body and bodyFile cannot be used together"), nil
+	case bodyFile != "":
+		path, err := h.routeBodyPath(bodyFile)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if route.Body, err = os.ReadFile(path); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to read body file: %v", err)), nil
+		}
+		if route.ContentType == "" {
+			route.ContentType = mime.TypeByExtension(filepath.Ext(bodyFile))
+		}
+	case request.GetBool("bodyBase64", false):
+		if route.Body, err = base64.StdEncoding.DecodeString(body); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid base64 body: %v", err)), nil
+		}
+	case body != "":
+		route.Body = []byte(body)
+	}
+
+	added, err := h.client.AddRoute(ctx, route)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to wait for element: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to add route: %v", err)), nil
 	}
 
-	// If response contains element data, return it
-	if len(response) > 0 {
-		return mcp.NewToolResultText(string(response)), nil
+	routeJSON, err := json.Marshal(added)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize route: %v", err)), nil
 	}
 
-	// Otherwise, return a descriptive message
-	return mcp.NewToolResultText(fmt.Sprintf("Element %s is now %s", selector, state)), nil
+	return mcp.NewToolResultText(string(routeJSON)), nil
 }
 
-// Content Handlers
+// RouteRemove removes one or all network routes
+func (h *BrowserHandler) RouteRemove(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id := request.GetString("id", "")
+	all := request.GetBool("all", false)
+	if id == "" && !all {
+		return mcp.NewToolResultError("id or all is required"), nil
+	}
 
-// ExecuteScript executes JavaScript in page context
-func (h *BrowserHandler) ExecuteScript(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	script, err := request.RequireString("script")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	if !all {
+		if err := h.client.RemoveRoute(ctx, id); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to remove route: %v", err)), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Removed route %s", id)), nil
 	}
 
-	// Get args as raw arguments and convert to slice
-	var args []interface{}
-	if rawArgs := request.GetArguments()["args"]; rawArgs != nil {
-		if argsSlice, ok := rawArgs.([]interface{}); ok {
-			args = argsSlice
+	routes, err := h.client.ListRoutes(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list routes: %v", err)), nil
+	}
+	for _, route := range routes {
+		if err := h.client.RemoveRoute(ctx, route.ID); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to remove route: %v", err)), nil
 		}
 	}
-	tabID := request.GetInt("tabId", 0)
+	return mcp.NewToolResultText(fmt.Sprintf("Removed %d routes", len(routes))), nil
+}
 
-	result, err := h.client.ExecuteScript(ctx, tabID, script, args)
+// RouteList lists the network routes
+func (h *BrowserHandler) RouteList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	routes, err := h.client.ListRoutes(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to execute script: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list routes: %v", err)), nil
+	}
+	if routes == nil {
+		routes = []browser.Route{}
 	}
 
-	// The result from ExecuteScript is already JSON-encoded
-	// Return it as-is for proper parsing by the DSL runtime
-	return mcp.NewToolResultText(string(result)), nil
+	routesJSON, err := json.Marshal(routes)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize routes: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(routesJSON)), nil
 }
 
-// ExtractContent extracts content from the page
-func (h *BrowserHandler) ExtractContent(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	selector := request.GetString("selector", "body")
-	contentType := request.GetString("type", "text")
-	attribute := request.GetString("attribute", "")
+// NetworkStart starts capturing the network traffic of a tab
+func (h *BrowserHandler) NetworkStart(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	tabID := request.GetInt("tabId", 0)
 
-	results, err := h.client.ExtractContent(ctx, tabID, selector, contentType, attribute)
+	if err := h.client.StartNetworkCapture(ctx, tabID); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to start network capture: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText("Started network capture"), nil
+}
+
+// NetworkStop stops capturing the network traffic of a tab and saves it as a HAR file
+func (h *BrowserHandler) NetworkStop(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := request.GetString("name", "")
+	tabID := request.GetInt("tabId", 0)
+
+	recording, err := h.client.StopNetworkCapture(ctx, tabID, h.harDir, name)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to extract content: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to stop network capture: %v", err)), nil
 	}
 
-	// Return results as JSON array
-	resultsJSON, err := json.Marshal(results)
+	recordingJSON, err := json.Marshal(recording)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize network capture: %v", err)), nil
 	}
 
-	return mcp.NewToolResultText(string(resultsJSON)), nil
+	return mcp.NewToolResultText(string(recordingJSON)), nil
 }
 
-// Screenshot takes a screenshot
-func (h *BrowserHandler) Screenshot(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	fullPage := request.GetBool("fullPage", false)
-	selector := request.GetString("selector", "")
-	format := request.GetString("format", "png")
-	quality := request.GetInt("quality", 90)
+// NetworkSummary lists the failed and slow requests of a tab's network capture
+func (h *BrowserHandler) NetworkSummary(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	slowMs := request.GetInt("slowMs", browser.DefaultSlowRequestMs)
+	if slowMs <= 0 {
+		return mcp.NewToolResultError("slowMs must be positive"), nil
+	}
 	tabID := request.GetInt("tabId", 0)
 
-	dataURL, err := h.client.Screenshot(ctx, tabID, fullPage, selector, format, quality)
+	summary, err := h.client.NetworkSummary(ctx, tabID, slowMs)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to take screenshot: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to summarize network capture: %v", err)), nil
 	}
 
-	// Return screenshot data as JSON
-	result := map[string]string{"dataUrl": dataURL}
-	resultJSON, err := json.Marshal(result)
+	summaryJSON, err := json.Marshal(summary)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize screenshot: %v", err)), nil
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize network summary: %v", err)), nil
 	}
 
-	return mcp.NewToolResultText(string(resultJSON)), nil
+	return mcp.NewToolResultText(string(summaryJSON)), nil
+}
+
+// stringMapArgument returns an object argument whose values are all strings
+func stringMapArgument(request mcp.CallToolRequest, name string) (map[string]string, error) {
+	raw, ok := request.GetArguments()[name]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", name)
+	}
+	result := make(map[string]string, len(object))
+	for key, value := range object {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a string", name, key)
+		}
+		result[key] = s
+	}
+	return result, nil
 }
 
 // Storage Handlers
@@ -542,6 +1380,27 @@ func (h *BrowserHandler) GetActionables(ctx context.Context, request mcp.CallToo
 	return mcp.NewToolResultText(string(actionablesJSON)), nil
 }
 
+// ClickActionable clicks the actionable element with a label, as listed by
+// browser_get_actionables or browser_screenshot_marked
+func (h *BrowserHandler) ClickActionable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	label, err := request.RequireInt("label")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	timeout := request.GetInt("timeout", 30000)
+	tabID := request.GetInt("tabId", 0)
+
+	actionable, err := h.client.ResolveActionable(ctx, tabID, label)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve actionable: %v", err)), nil
+	}
+	if err := h.client.Click(ctx, tabID, actionable.Selector, timeout); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to click: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Clicked on actionable %d (%s): %s", label, actionable.Description, actionable.Selector)), nil
+}
+
 // ExtractText extracts content from the page and returns it as plain text
 func (h *BrowserHandler) ExtractText(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	selector := request.GetString("selector", "body")
@@ -556,6 +1415,119 @@ func (h *BrowserHandler) ExtractText(ctx context.Context, request mcp.CallToolRe
 	return mcp.NewToolResultText(text), nil
 }
 
+// ExtractMarkdown extracts content from the page and returns it as Markdown
+func (h *BrowserHandler) ExtractMarkdown(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector := request.GetString("selector", "body")
+	tabID := request.GetInt("tabId", 0)
+	options := browser.MarkdownOptions{
+		AbsoluteLinks: request.GetBool("absoluteLinks", true),
+		MainContent:   request.GetBool("mainContent", false),
+		MaxChars:      request.GetInt("maxChars", 0),
+	}
+	if options.MaxChars < 0 {
+		return mcp.NewToolResultError("maxChars must not be negative"), nil
+	}
+
+	md, err := h.client.ExtractMarkdown(ctx, tabID, selector, options)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to extract markdown: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(md), nil
+}
+
+// ExtractTable extracts an HTML table, or the rows of a repeated element
+// pattern, as JSON records or CSV
+func (h *BrowserHandler) ExtractTable(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector := request.GetString("selector", "")
+	rowSelector := request.GetString("rowSelector", "")
+	index := request.GetInt("index", 0)
+	format := request.GetString("format", "json")
+	coerce := request.GetBool("coerce", false)
+	tabID := request.GetInt("tabId", 0)
+
+	if !tableFormats[format] {
+		return mcp.NewToolResultError(fmt.Sprintf("unsupported format: %s", format)), nil
+	}
+	if (selector == "") == (rowSelector == "") {
+		return mcp.NewToolResultError("either selector or rowSelector is required"), nil
+	}
+
+	var table *browser.Table
+	var err error
+	if rowSelector != "" {
+		rowSelector, err = parseLocator(request, rowSelector)
+	} else {
+		selector, err = parseLocator(request, selector)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if rowSelector != "" {
+		value, ok := request.GetArguments()["columns"]
+		if !ok {
+			return mcp.NewToolResultError("columns are required with rowSelector"), nil
+		}
+		columns, err := parseTableColumns(value)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		table, err = h.client.ExtractRows(ctx, tabID, rowSelector, columns)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to extract table: %v", err)), nil
+		}
+	} else {
+		if table, err = h.client.ExtractTable(ctx, tabID, selector, index); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to extract table: %v", err)), nil
+		}
+	}
+
+	rows := coerceTable(table, coerce)
+	if format == "csv" {
+		text, err := tableCSV(table, rows)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize table: %v", err)), nil
+		}
+		return mcp.NewToolResultText(text), nil
+	}
+
+	tableJSON, err := tableJSON(table, rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize table: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(tableJSON)), nil
+}
+
+// ExtractStructured extracts a JSON document described by a schema of fields
+func (h *BrowserHandler) ExtractStructured(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := parseLocator(request, request.GetString("selector", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	value, ok := request.GetArguments()["schema"]
+	if !ok {
+		return mcp.NewToolResultError("schema is required"), nil
+	}
+	fields, err := parseStructuredSchema(value)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := h.client.ExtractStructured(ctx, tabID, selector, fields)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to extract data: %v", err)), nil
+	}
+
+	// Fields that could not be read are reported with the document
+	result, err := json.Marshal(readStructured(fields, data))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize data: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
+
 // GetAccessibilitySnapshot gets the accessibility tree of the page
 func (h *BrowserHandler) GetAccessibilitySnapshot(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	tabID := request.GetInt("tabId", 0)
@@ -740,3 +1712,170 @@ func (h *BrowserHandler) GetPageTitle(ctx context.Context, request mcp.CallToolR
 
 	return mcp.NewToolResultText(string(resultJSON)), nil
 }
+
+// Event Handlers
+
+// GetEvents returns the browser events recorded after a cursor
+func (h *BrowserHandler) GetEvents(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	since := request.GetInt("since", 0)
+	if since < 0 {
+		return mcp.NewToolResultError("since must not be negative"), nil
+	}
+	limit := request.GetInt("limit", 100)
+	if limit < 0 {
+		return mcp.NewToolResultError("limit must not be negative"), nil
+	}
+
+	filter := browser.EventFilter{TabID: request.GetInt("tabId", 0)}
+	for _, name := range request.GetStringSlice("types", nil) {
+		eventType, err := browser.ParseEventType(name)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filter.Types = append(filter.Types, eventType)
+	}
+
+	page := h.client.GetEvents(ctx, uint64(since), filter, limit)
+
+	pageJSON, err := json.Marshal(page)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize events: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(pageJSON)), nil
+}
+
+// GetConsole returns the console messages and uncaught exceptions of a tab
+func (h *BrowserHandler) GetConsole(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	limit := request.GetInt("limit", 100)
+	if limit < 0 {
+		return mcp.NewToolResultError("limit must not be negative"), nil
+	}
+
+	var filter browser.ConsoleFilter
+	for _, level := range request.GetStringSlice("levels", nil) {
+		if !slices.Contains(browser.ConsoleLevels, level) {
+			return mcp.NewToolResultError(fmt.Sprintf("unknown console level: %s", level)), nil
+		}
+		filter.Levels = append(filter.Levels, level)
+	}
+	if since := request.GetString("since", ""); since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return mcp.NewToolResultError("since must be an RFC 3339 timestamp"), nil
+		}
+		filter.Since = t
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	messages, err := h.client.GetConsole(ctx, tabID, filter, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get console messages: %v", err)), nil
+	}
+
+	messagesJSON, err := json.Marshal(messages)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize console messages: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(messagesJSON)), nil
+}
+
+// File Handlers
+
+// downloadStates are the states browser_list_downloads filters by
+var downloadStates = []string{browser.DownloadInProgress, browser.DownloadComplete, browser.DownloadInterrupted}
+
+// UploadFile sets the files of a file input to local files
+func (h *BrowserHandler) UploadFile(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	selector, err := request.RequireString("selector")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	selector, err = parseLocator(request, selector)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	value, ok := request.GetArguments()["paths"]
+	if !ok {
+		return mcp.NewToolResultError("paths is required"), nil
+	}
+	paths, err := stringList(value, "paths")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	tabID := request.GetInt("tabId", 0)
+
+	files, err := h.client.UploadFiles(ctx, tabID, selector, paths)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to upload files: %v", err)), nil
+	}
+
+	result, err := json.Marshal(map[string]interface{}{"selector": selector, "files": files})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize files: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// ListDownloads lists the downloads of the browser
+func (h *BrowserHandler) ListDownloads(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter := browser.DownloadFilter{
+		URL:   request.GetString("url", ""),
+		State: request.GetString("state", ""),
+	}
+	if filter.State != "" && !slices.Contains(downloadStates, filter.State) {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown download state: %s", filter.State)), nil
+	}
+
+	downloads, err := h.client.ListDownloads(ctx, filter)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list downloads: %v", err)), nil
+	}
+	if downloads == nil {
+		downloads = []browser.Download{}
+	}
+
+	downloadsJSON, err := json.Marshal(downloads)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize downloads: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(downloadsJSON)), nil
+}
+
+// WaitForDownload waits for a download to finish, and copies its file into
+// the download directory when asked to
+func (h *BrowserHandler) WaitForDownload(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter := browser.DownloadFilter{
+		ID:  request.GetInt("id", 0),
+		URL: request.GetString("url", ""),
+	}
+	timeout := request.GetInt("timeout", 30000)
+	copyFile := request.GetBool("copy", false)
+	if copyFile && h.downloadDir == "" {
+		return mcp.NewToolResultError("copy needs a download directory; set browser.download_dir in the configuration"), nil
+	}
+
+	download, err := h.client.WaitForDownload(ctx, filter, time.Duration(timeout)*time.Millisecond)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to wait for download: %v", err)), nil
+	}
+	if download.State == browser.DownloadInterrupted {
+		return mcp.NewToolResultError(fmt.Sprintf("Download %d of %s was interrupted: %s", download.ID, download.URL, download.Error)), nil
+	}
+
+	if copyFile {
+		path, err := h.client.SaveDownload(ctx, download.ID, h.downloadDir)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to copy download: %v", err)), nil
+		}
+		download.Path = path
+	}
+
+	downloadJSON, err := json.Marshal(download)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize download: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(downloadJSON)), nil
+}
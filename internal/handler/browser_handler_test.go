@@ -1,9 +1,18 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -40,6 +49,20 @@ func (m *MockBrowserClient) WaitForConnection(ctx context.Context, timeout time.
 	return args.Error(0)
 }
 
+func (m *MockBrowserClient) RegisterBrowser(info browser.BrowserInfo, conn browser.Connection) {
+	m.Called(info, conn)
+}
+
+func (m *MockBrowserClient) ListBrowsers() []browser.BrowserInfo {
+	args := m.Called()
+	return args.Get(0).([]browser.BrowserInfo)
+}
+
+func (m *MockBrowserClient) ReleaseSession(ctx context.Context, sessionID string, closeTabs bool) error {
+	args := m.Called(ctx, sessionID, closeTabs)
+	return args.Error(0)
+}
+
 func (m *MockBrowserClient) ListTabs(ctx context.Context) ([]browser.Tab, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]browser.Tab), args.Error(1)
@@ -81,6 +104,39 @@ func (m *MockBrowserClient) Click(ctx context.Context, tabID int, selector strin
 	return args.Error(0)
 }
 
+func (m *MockBrowserClient) ClickWithOptions(ctx context.Context, tabID int, selector string, timeout int, options browser.ClickOptions) error {
+	args := m.Called(ctx, tabID, selector, timeout, options)
+	return args.Error(0)
+}
+
+func (m *MockBrowserClient) PressKey(ctx context.Context, tabID int, selector string, keys []browser.KeyPress) error {
+	args := m.Called(ctx, tabID, selector, keys)
+	return args.Error(0)
+}
+
+func (m *MockBrowserClient) Hover(ctx context.Context, tabID int, selector string, timeout int) error {
+	args := m.Called(ctx, tabID, selector, timeout)
+	return args.Error(0)
+}
+
+func (m *MockBrowserClient) Drag(ctx context.Context, tabID int, source, target browser.DragPoint) error {
+	args := m.Called(ctx, tabID, source, target)
+	return args.Error(0)
+}
+
+func (m *MockBrowserClient) SelectOption(ctx context.Context, tabID int, selector string, selection browser.OptionSelection) ([]string, error) {
+	args := m.Called(ctx, tabID, selector, selection)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockBrowserClient) SetChecked(ctx context.Context, tabID int, selector string, checked bool) error {
+	args := m.Called(ctx, tabID, selector, checked)
+	return args.Error(0)
+}
+
 func (m *MockBrowserClient) Type(ctx context.Context, tabID int, selector, text string, clearFirst bool, delay int) error {
 	args := m.Called(ctx, tabID, selector, text, clearFirst, delay)
 	return args.Error(0)
@@ -102,6 +158,22 @@ func (m *MockBrowserClient) WaitForElement(ctx context.Context, tabID int, selec
 	return args.Get(0).(json.RawMessage), args.Error(1)
 }
 
+func (m *MockBrowserClient) GoBack(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	args := m.Called(ctx, tabID, waitUntilLoad, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(json.RawMessage), args.Error(1)
+}
+
+func (m *MockBrowserClient) GoForward(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	args := m.Called(ctx, tabID, waitUntilLoad, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(json.RawMessage), args.Error(1)
+}
+
 func (m *MockBrowserClient) ExecuteScript(ctx context.Context, tabID int, script string, args []interface{}) (json.RawMessage, error) {
 	callArgs := m.Called(ctx, tabID, script, args)
 	if callArgs.Get(0) == nil {
@@ -123,11 +195,151 @@ func (m *MockBrowserClient) ExtractText(ctx context.Context, tabID int, selector
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockBrowserClient) ExtractMarkdown(ctx context.Context, tabID int, selector string, options browser.MarkdownOptions) (string, error) {
+	args := m.Called(ctx, tabID, selector, options)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockBrowserClient) ExtractTable(ctx context.Context, tabID int, selector string, index int) (*browser.Table, error) {
+	args := m.Called(ctx, tabID, selector, index)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.Table), args.Error(1)
+}
+
+func (m *MockBrowserClient) ExtractRows(ctx context.Context, tabID int, rowSelector string, columns []browser.TableColumn) (*browser.Table, error) {
+	args := m.Called(ctx, tabID, rowSelector, columns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.Table), args.Error(1)
+}
+
+func (m *MockBrowserClient) ExtractStructured(ctx context.Context, tabID int, selector string, fields map[string]*browser.StructuredField) (map[string]interface{}, error) {
+	args := m.Called(ctx, tabID, selector, fields)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]interface{}), args.Error(1)
+}
+
+func (m *MockBrowserClient) FindElements(ctx context.Context, tabID int, selector string, limit int) ([]browser.Element, error) {
+	args := m.Called(ctx, tabID, selector, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]browser.Element), args.Error(1)
+}
+
+func (m *MockBrowserClient) GetValue(ctx context.Context, tabID int, selector string) (*browser.ElementValue, error) {
+	args := m.Called(ctx, tabID, selector)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.ElementValue), args.Error(1)
+}
+
+func (m *MockBrowserClient) StartVideo(ctx context.Context, tabID int, maxDuration int) (json.RawMessage, error) {
+	args := m.Called(ctx, tabID, maxDuration)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(json.RawMessage), args.Error(1)
+}
+
+func (m *MockBrowserClient) StopVideo(ctx context.Context, tabID int, dir, name string) (*browser.VideoRecording, error) {
+	args := m.Called(ctx, tabID, dir, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.VideoRecording), args.Error(1)
+}
+
 func (m *MockBrowserClient) Screenshot(ctx context.Context, tabID int, fullPage bool, selector, format string, quality int) (string, error) {
 	args := m.Called(ctx, tabID, fullPage, selector, format, quality)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockBrowserClient) AddRoute(ctx context.Context, route browser.Route) (*browser.Route, error) {
+	args := m.Called(ctx, route)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.Route), args.Error(1)
+}
+
+func (m *MockBrowserClient) RemoveRoute(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockBrowserClient) ListRoutes(ctx context.Context) ([]browser.Route, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]browser.Route), args.Error(1)
+}
+
+func (m *MockBrowserClient) StartNetworkCapture(ctx context.Context, tabID int) error {
+	args := m.Called(ctx, tabID)
+	return args.Error(0)
+}
+
+func (m *MockBrowserClient) StopNetworkCapture(ctx context.Context, tabID int, dir, name string) (*browser.NetworkRecording, error) {
+	args := m.Called(ctx, tabID, dir, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.NetworkRecording), args.Error(1)
+}
+
+func (m *MockBrowserClient) NetworkSummary(ctx context.Context, tabID int, slowMs int) (*browser.NetworkSummary, error) {
+	args := m.Called(ctx, tabID, slowMs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.NetworkSummary), args.Error(1)
+}
+
+func (m *MockBrowserClient) GetConsole(ctx context.Context, tabID int, filter browser.ConsoleFilter, limit int) ([]browser.ConsoleMessage, error) {
+	args := m.Called(ctx, tabID, filter, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]browser.ConsoleMessage), args.Error(1)
+}
+
+func (m *MockBrowserClient) UploadFiles(ctx context.Context, tabID int, selector string, paths []string) ([]browser.UploadedFile, error) {
+	args := m.Called(ctx, tabID, selector, paths)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]browser.UploadedFile), args.Error(1)
+}
+
+func (m *MockBrowserClient) ListDownloads(ctx context.Context, filter browser.DownloadFilter) ([]browser.Download, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]browser.Download), args.Error(1)
+}
+
+func (m *MockBrowserClient) WaitForDownload(ctx context.Context, filter browser.DownloadFilter, timeout time.Duration) (*browser.Download, error) {
+	args := m.Called(ctx, filter, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.Download), args.Error(1)
+}
+
+func (m *MockBrowserClient) SaveDownload(ctx context.Context, id int, dir string) (string, error) {
+	args := m.Called(ctx, id, dir)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockBrowserClient) GetCookies(ctx context.Context, url, name string) ([]browser.Cookie, error) {
 	args := m.Called(ctx, url, name)
 	if args.Get(0) == nil {
@@ -187,6 +399,14 @@ func (m *MockBrowserClient) GetActionables(ctx context.Context, tabID int) ([]br
 	return args.Get(0).([]browser.Actionable), args.Error(1)
 }
 
+func (m *MockBrowserClient) ResolveActionable(ctx context.Context, tabID int, label int) (*browser.Actionable, error) {
+	args := m.Called(ctx, tabID, label)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*browser.Actionable), args.Error(1)
+}
+
 func (m *MockBrowserClient) GetAccessibilitySnapshot(ctx context.Context, tabID int, interestingOnly bool, root string) (json.RawMessage, error) {
 	args := m.Called(ctx, tabID, interestingOnly, root)
 	if args.Get(0) == nil {
@@ -274,6 +494,16 @@ func getTextFromContent(t *testing.T, content mcp.Content) string {
 	}
 }
 
+func (m *MockBrowserClient) GetEvents(ctx context.Context, since uint64, filter browser.EventFilter, limit int) *browser.EventPage {
+	args := m.Called(ctx, since, filter, limit)
+	return args.Get(0).(*browser.EventPage)
+}
+
+func (m *MockBrowserClient) SubscribeEvents(fn func(browser.Event)) func() {
+	m.Called(fn)
+	return func() {}
+}
+
 func TestNewBrowserHandler(t *testing.T) {
 	mockClient := &MockBrowserClient{}
 	handler := NewBrowserHandler(mockClient)
@@ -377,6 +607,35 @@ func TestBrowserHandler_WaitForConnection(t *testing.T) {
 	}
 }
 
+func TestBrowserHandler_ListBrowsers(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	mockClient.On("ListBrowsers").Return([]browser.BrowserInfo{
+		{ID: "work", Profile: "Work", Default: true},
+		{ID: "personal", Profile: "Personal"},
+	})
+
+	request := mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      "browser_list_browsers",
+			Arguments: map[string]interface{}{},
+		},
+	}
+
+	result, err := handler.ListBrowsers(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, result.Content, 1)
+
+	var browsers []browser.BrowserInfo
+	require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &browsers))
+	require.Len(t, browsers, 2)
+	assert.Equal(t, "work", browsers[0].ID)
+	assert.True(t, browsers[0].Default)
+
+	mockClient.AssertExpectations(t)
+}
+
 func TestBrowserHandler_ListTabs(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -789,6 +1048,81 @@ func TestBrowserHandler_Navigate(t *testing.T) {
 	}
 }
 
+func TestBrowserHandler_GoBack(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     mcp.CallToolRequest
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name: "go back with defaults",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name:      "browser_go_back",
+					Arguments: map[string]interface{}{},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("GoBack", mock.Anything, 0, true, 30000).Return(json.RawMessage(`{"success":true}`), nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.Equal(t, `{"success":true}`, getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name: "go back without waiting",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "browser_go_back",
+					Arguments: map[string]interface{}{
+						"waitUntilLoad": false,
+						"tabId":         3,
+					},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("GoBack", mock.Anything, 3, false, 30000).Return(json.RawMessage(nil), nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.Equal(t, "Navigated back", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name: "go back with error",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name:      "browser_go_back",
+					Arguments: map[string]interface{}{},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("GoBack", mock.Anything, 0, true, 30000).Return(json.RawMessage(nil), errors.New("no previous page in history"))
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "Failed to go back: no previous page in history")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			tt.setupMock(mockClient)
+
+			result, err := handler.GoBack(context.Background(), tt.request)
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
 func TestBrowserHandler_Click(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -857,6 +1191,65 @@ func TestBrowserHandler_Click(t *testing.T) {
 				assert.Contains(t, text, "required argument \"selector\" not found")
 			},
 		},
+		{
+			name: "double click",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "browser_click",
+					Arguments: map[string]interface{}{
+						"selector":   ".row",
+						"clickCount": 2,
+					},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("ClickWithOptions", mock.Anything, 0, ".row", 30000, browser.ClickOptions{Button: "left", ClickCount: 2}).Return(nil)
+			},
+			wantErr: false,
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.Equal(t, "Double-clicked on element: .row", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name: "right click with modifiers",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "browser_click",
+					Arguments: map[string]interface{}{
+						"selector":  ".row",
+						"button":    "right",
+						"modifiers": []interface{}{"ctrl", "Shift"},
+					},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("ClickWithOptions", mock.Anything, 0, ".row", 30000, browser.ClickOptions{Button: "right", ClickCount: 1, Modifiers: []string{"Control", "Shift"}}).Return(nil)
+			},
+			wantErr: false,
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.Equal(t, "Clicked with the right button holding Control+Shift on element: .row", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name: "click with invalid options",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "browser_click",
+					Arguments: map[string]interface{}{
+						"selector":   ".row",
+						"clickCount": 4,
+					},
+				},
+			},
+			setupMock: nil,
+			wantErr:   false,
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "clickCount must be between 1 and 3", getTextFromContent(t, result.Content[0]))
+			},
+		},
 		{
 			name: "click element not found",
 			request: mcp.CallToolRequest{
@@ -1230,3 +1623,1389 @@ func TestBrowserHandler_GetAccessibilitySnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestBrowserHandler_FindElements(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     mcp.CallToolRequest
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name: "find elements",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "browser_find_elements",
+					Arguments: map[string]interface{}{
+						"selector": "li",
+						"limit":    2,
+					},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("FindElements", mock.Anything, 0, "li", 2).Return([]browser.Element{
+					{Index: 0, Tag: "li", Text: "One", Selector: "ul > li:nth-child(1)", Visible: true},
+				}, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				var elements []browser.Element
+				require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &elements))
+				require.Len(t, elements, 1)
+				assert.Equal(t, "One", elements[0].Text)
+			},
+		},
+		{
+			name: "no elements found",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name:      "browser_find_elements",
+					Arguments: map[string]interface{}{"selector": ".missing"},
+				},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("FindElements", mock.Anything, 0, ".missing", 0).Return(nil, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.Equal(t, "[]", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name: "missing selector",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name:      "browser_find_elements",
+					Arguments: map[string]interface{}{},
+				},
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "required argument \"selector\" not found")
+			},
+		},
+		{
+			name: "negative limit",
+			request: mcp.CallToolRequest{
+				Params: mcp.CallToolParams{
+					Name: "browser_find_elements",
+					Arguments: map[string]interface{}{
+						"selector": "li",
+						"limit":    -1,
+					},
+				},
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "limit must not be negative")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			if tt.setupMock != nil {
+				tt.setupMock(mockClient)
+			}
+
+			result, err := handler.FindElements(context.Background(), tt.request)
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_GetValue(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	checked := false
+	mockClient.On("GetValue", mock.Anything, 0, "#agree").Return(&browser.ElementValue{
+		Tag: "input", Type: "checkbox", Value: "on", Checked: &checked,
+	}, nil)
+
+	result, err := handler.GetValue(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{
+			Name:      "browser_get_value",
+			Arguments: map[string]interface{}{"selector": "#agree"},
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.JSONEq(t, `{"tag":"input","type":"checkbox","value":"on","checked":false}`, getTextFromContent(t, result.Content[0]))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_ExtractMarkdown(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	extract := func(arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler.ExtractMarkdown(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_extract_markdown", Arguments: arguments},
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	mockClient.On("ExtractMarkdown", mock.Anything, 0, "body", browser.MarkdownOptions{AbsoluteLinks: true}).Return("# Title", nil).Once()
+	result := extract(map[string]interface{}{})
+	assert.False(t, result.IsError)
+	assert.Equal(t, "# Title", getTextFromContent(t, result.Content[0]))
+
+	mockClient.On("ExtractMarkdown", mock.Anything, 3, "#post", browser.MarkdownOptions{MainContent: true, MaxChars: 500}).Return("Post", nil).Once()
+	result = extract(map[string]interface{}{"tabId": 3, "selector": "#post", "mainContent": true, "absoluteLinks": false, "maxChars": 500})
+	assert.False(t, result.IsError)
+	assert.Equal(t, "Post", getTextFromContent(t, result.Content[0]))
+
+	mockClient.On("ExtractMarkdown", mock.Anything, 0, "#missing", browser.MarkdownOptions{AbsoluteLinks: true}).Return("", errors.New("element not found")).Once()
+	result = extract(map[string]interface{}{"selector": "#missing"})
+	assert.True(t, result.IsError)
+	assert.Equal(t, "Failed to extract markdown: element not found", getTextFromContent(t, result.Content[0]))
+
+	result = extract(map[string]interface{}{"maxChars": -1})
+	assert.True(t, result.IsError)
+	assert.Equal(t, "maxChars must not be negative", getTextFromContent(t, result.Content[0]))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_ExtractTable(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	extract := func(arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler.ExtractTable(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_extract_table", Arguments: arguments},
+		})
+		require.NoError(t, err)
+		return result
+	}
+	prices := &browser.Table{Columns: []string{"Item", "Price"}, Rows: [][]string{{"Tea", "2.50"}, {"Coffee", "3"}}}
+
+	t.Run("table as JSON records", func(t *testing.T) {
+		mockClient.On("ExtractTable", mock.Anything, 0, "#prices", 0).Return(prices, nil).Once()
+		result := extract(map[string]interface{}{"selector": "#prices", "coerce": true})
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"columns":["Item","Price"],"rows":[{"Item":"Tea","Price":2.5},{"Item":"Coffee","Price":3}]}`, getTextFromContent(t, result.Content[0]))
+	})
+
+	t.Run("rows as CSV", func(t *testing.T) {
+		columns := []browser.TableColumn{{Name: "Item", Selector: "h3"}, {Name: "Price", Selector: ".price", Attribute: "data-value"}}
+		mockClient.On("ExtractRows", mock.Anything, 2, ".card", columns).Return(prices, nil).Once()
+		result := extract(map[string]interface{}{
+			"rowSelector": ".card",
+			"columns":     map[string]interface{}{"Item": "h3", "Price": ".price@data-value"},
+			"format":      "csv",
+			"tabId":       2,
+		})
+		assert.False(t, result.IsError)
+		assert.Equal(t, "Item,Price\nTea,2.50\nCoffee,3\n", getTextFromContent(t, result.Content[0]))
+	})
+
+	t.Run("extraction error", func(t *testing.T) {
+		mockClient.On("ExtractTable", mock.Anything, 0, "table", 1).Return(nil, errors.New("1 element(s) match table, no element at index 1")).Once()
+		result := extract(map[string]interface{}{"selector": "table", "index": 1})
+		assert.True(t, result.IsError)
+		assert.Equal(t, "Failed to extract table: 1 element(s) match table, no element at index 1", getTextFromContent(t, result.Content[0]))
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		for _, tt := range []struct {
+			arguments map[string]interface{}
+			err       string
+		}{
+			{map[string]interface{}{}, "either selector or rowSelector is required"},
+			{map[string]interface{}{"selector": "table", "rowSelector": "tr"}, "either selector or rowSelector is required"},
+			{map[string]interface{}{"selector": "table", "format": "xml"}, "unsupported format: xml"},
+			{map[string]interface{}{"rowSelector": ".card"}, "columns are required with rowSelector"},
+			{map[string]interface{}{"rowSelector": ".card", "columns": "h3"}, "columns must be an object of selectors or an array of {name, selector, attribute} objects"},
+		} {
+			result := extract(tt.arguments)
+			assert.True(t, result.IsError)
+			assert.Equal(t, tt.err, getTextFromContent(t, result.Content[0]))
+		}
+	})
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_ExtractStructured(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	extract := func(arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler.ExtractStructured(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_extract_structured", Arguments: arguments},
+		})
+		require.NoError(t, err)
+		return result
+	}
+	schema := map[string]interface{}{
+		"name":  "h1",
+		"price": map[string]interface{}{"selector": ".price", "type": "number"},
+	}
+	fields := map[string]*browser.StructuredField{
+		"name":  {Selector: "h1", Type: "string"},
+		"price": {Selector: ".price", Type: "number"},
+	}
+
+	t.Run("document with field errors", func(t *testing.T) {
+		mockClient.On("ExtractStructured", mock.Anything, 4, ".product", fields).Return(map[string]interface{}{"name": "Teapot"}, nil).Once()
+		result := extract(map[string]interface{}{"schema": schema, "selector": ".product", "tabId": 4})
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"data":{"name":"Teapot","price":null},"errors":{"price":"no element matches .price"}}`, getTextFromContent(t, result.Content[0]))
+	})
+
+	t.Run("extraction error", func(t *testing.T) {
+		mockClient.On("ExtractStructured", mock.Anything, 0, "", fields).Return(nil, errors.New("element not found: .product")).Once()
+		result := extract(map[string]interface{}{"schema": schema})
+		assert.True(t, result.IsError)
+		assert.Equal(t, "Failed to extract data: element not found: .product", getTextFromContent(t, result.Content[0]))
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		result := extract(map[string]interface{}{})
+		assert.True(t, result.IsError)
+		assert.Equal(t, "schema is required", getTextFromContent(t, result.Content[0]))
+
+		result = extract(map[string]interface{}{"schema": map[string]interface{}{"price": map[string]interface{}{"type": "money"}}})
+		assert.True(t, result.IsError)
+		assert.Equal(t, "field price: unknown type money", getTextFromContent(t, result.Content[0]))
+	})
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_Interactions(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	call := func(tool func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := tool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}})
+		require.NoError(t, err)
+		return result
+	}
+	assertText := func(t *testing.T, result *mcp.CallToolResult, isError bool, expected string) {
+		t.Helper()
+		assert.Equal(t, isError, result.IsError)
+		assert.Equal(t, expected, getTextFromContent(t, result.Content[0]))
+	}
+
+	t.Run("press key", func(t *testing.T) {
+		keys := []browser.KeyPress{{Key: "K", Code: "KeyK", Modifiers: []string{"Control", "Shift"}}, {Key: "Enter", Code: "Enter"}}
+		mockClient.On("PressKey", mock.Anything, 3, "#search", keys).Return(nil).Once()
+		assertText(t, call(handler.PressKey, map[string]interface{}{"keys": "Control+Shift+K  Enter", "selector": "#search", "tabId": 3}), false, "Pressed Control+Shift+K Enter in #search")
+
+		mockClient.On("PressKey", mock.Anything, 0, "", []browser.KeyPress{{Key: "Escape", Code: "Escape"}}).Return(errors.New("no focused element")).Once()
+		assertText(t, call(handler.PressKey, map[string]interface{}{"keys": "Esc"}), true, "Failed to press keys: no focused element")
+
+		assertText(t, call(handler.PressKey, map[string]interface{}{"keys": "Hyper+K"}), true, "invalid key combination Hyper+K: unknown modifier: Hyper")
+		assertText(t, call(handler.PressKey, map[string]interface{}{}), true, `required argument "keys" not found`)
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		mockClient.On("Hover", mock.Anything, 0, ".menu", 30000).Return(nil).Once()
+		assertText(t, call(handler.Hover, map[string]interface{}{"selector": ".menu"}), false, "Hovered over element: .menu")
+
+		mockClient.On("Hover", mock.Anything, 0, "#gone", 1000).Return(errors.New("element not found")).Once()
+		assertText(t, call(handler.Hover, map[string]interface{}{"selector": "#gone", "timeout": 1000}), true, "Failed to hover: element not found")
+	})
+
+	t.Run("drag", func(t *testing.T) {
+		x, y := 300.0, 40.5
+		mockClient.On("Drag", mock.Anything, 0, browser.DragPoint{Selector: "#card"}, browser.DragPoint{X: &x, Y: &y}).Return(nil).Once()
+		assertText(t, call(handler.Drag, map[string]interface{}{"source": "#card", "targetX": 300, "targetY": 40.5}), false, "Dragged #card to (300, 40.5)")
+
+		assertText(t, call(handler.Drag, map[string]interface{}{"target": "#done"}), true, "source needs a selector, or sourceX and sourceY")
+		assertText(t, call(handler.Drag, map[string]interface{}{"source": "#card", "target": "#done", "targetX": 1}), true, "target takes a selector or coordinates, not both")
+		assertText(t, call(handler.Drag, map[string]interface{}{"sourceX": 1, "sourceY": 2, "targetY": 3}), true, "target needs a selector, or targetX and targetY")
+	})
+
+	t.Run("select option", func(t *testing.T) {
+		mockClient.On("SelectOption", mock.Anything, 0, "#size", browser.OptionSelection{Labels: []string{"Large"}}).Return([]string{"l"}, nil).Once()
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#size", "label": "Large"}), false, `{"selector":"#size","values":["l"]}`)
+
+		mockClient.On("SelectOption", mock.Anything, 0, "#tags", browser.OptionSelection{Indexes: []int{0, 2}}).Return([]string{"a", "c"}, nil).Once()
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#tags", "index": []interface{}{0.0, 2.0}}), false, `{"selector":"#tags","values":["a","c"]}`)
+
+		mockClient.On("SelectOption", mock.Anything, 0, "#size", browser.OptionSelection{Values: []string{"xl"}}).Return(nil, errors.New(`no option with value "xl"`)).Once()
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#size", "value": "xl"}), true, `Failed to select option: no option with value "xl"`)
+
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#size"}), true, "exactly one of value, label or index is required")
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#size", "value": "s", "index": 1}), true, "exactly one of value, label or index is required")
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#size", "index": -1}), true, "index must be a non-negative integer or an array of them")
+		assertText(t, call(handler.SelectOption, map[string]interface{}{"selector": "#size", "label": []interface{}{1}}), true, "label must be a string or an array of strings")
+	})
+
+	t.Run("check and uncheck", func(t *testing.T) {
+		mockClient.On("SetChecked", mock.Anything, 0, "#terms", true).Return(nil).Once()
+		assertText(t, call(handler.Check, map[string]interface{}{"selector": "#terms"}), false, "Checked #terms")
+
+		mockClient.On("SetChecked", mock.Anything, 2, "#news", false).Return(errors.New("element not found")).Once()
+		assertText(t, call(handler.Uncheck, map[string]interface{}{"selector": "#news", "tabId": 2}), true, "Failed to uncheck: element not found")
+	})
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_StopVideo(t *testing.T) {
+	tests := []struct {
+		name        string
+		videoDir    string
+		arguments   map[string]interface{}
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name:      "stop and save video",
+			videoDir:  "videos",
+			arguments: map[string]interface{}{"name": "run.webm"},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("StopVideo", mock.Anything, 0, "videos", "run.webm").Return(&browser.VideoRecording{
+					TabID: 1, Path: "videos/run.webm", MimeType: "video/webm", Size: 1024, Duration: 2.5,
+				}, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.JSONEq(t, `{"tabId":1,"path":"videos/run.webm","mimeType":"video/webm","size":1024,"duration":2.5}`,
+					getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "stop without recording",
+			arguments: map[string]interface{}{},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("StopVideo", mock.Anything, 0, "", "").Return(nil, errors.New("tab 1 is not being recorded"))
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "Failed to stop video recording: tab 1 is not being recorded")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			handler.SetVideoDir(tt.videoDir)
+			tt.setupMock(mockClient)
+
+			result, err := handler.StopVideo(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_stop_video", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_GetEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name: "get events since cursor",
+			arguments: map[string]interface{}{
+				"since": 4,
+				"types": []interface{}{"console", "dialog"},
+				"tabId": 7,
+			},
+			setupMock: func(m *MockBrowserClient) {
+				filter := browser.EventFilter{TabID: 7, Types: []browser.EventType{browser.EventConsole, browser.EventDialog}}
+				m.On("GetEvents", mock.Anything, uint64(4), filter, 100).Return(&browser.EventPage{
+					Events: []browser.Event{{Seq: 5, Type: browser.EventConsole, TabID: 7}},
+					Cursor: 6,
+				})
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				var page browser.EventPage
+				require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &page))
+				assert.Equal(t, uint64(6), page.Cursor)
+				require.Len(t, page.Events, 1)
+				assert.Equal(t, browser.EventConsole, page.Events[0].Type)
+			},
+		},
+		{
+			name:      "unknown event type",
+			arguments: map[string]interface{}{"types": []interface{}{"keypress"}},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "unknown event type: keypress")
+			},
+		},
+		{
+			name:      "negative cursor",
+			arguments: map[string]interface{}{"since": -1},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "since must not be negative")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			if tt.setupMock != nil {
+				tt.setupMock(mockClient)
+			}
+
+			result, err := handler.GetEvents(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_get_events", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_Screenshot(t *testing.T) {
+	data := noisePNG(t, 400, 300)
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		dir         string
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name:      "returned as image content",
+			arguments: map[string]interface{}{"tabId": 1},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("Screenshot", mock.Anything, 1, false, "", "png", 90).Return(dataURL, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				require.Len(t, result.Content, 1)
+				image, ok := result.Content[0].(mcp.ImageContent)
+				require.True(t, ok)
+				assert.Equal(t, "image/png", image.MIMEType)
+				assert.Equal(t, base64.StdEncoding.EncodeToString(data), image.Data)
+			},
+		},
+		{
+			name:      "downscaled to maxBytes",
+			arguments: map[string]interface{}{"maxBytes": len(data) / 4, "format": "png"},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("Screenshot", mock.Anything, 0, false, "", "png", 90).Return(dataURL, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				image, ok := result.Content[0].(mcp.ImageContent)
+				require.True(t, ok)
+				decoded, err := base64.StdEncoding.DecodeString(image.Data)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(decoded), len(data)/4)
+			},
+		},
+		{
+			name:      "saved with the format of its extension",
+			arguments: map[string]interface{}{"savePath": "shots/home.jpg", "fullPage": true},
+			dir:       dir,
+			setupMock: func(m *MockBrowserClient) {
+				m.On("Screenshot", mock.Anything, 0, true, "", "jpeg", 90).Return(dataURL, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				var saved struct {
+					Path     string `json:"path"`
+					MIMEType string `json:"mimeType"`
+					Width    int    `json:"width"`
+					Size     int    `json:"size"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &saved))
+				assert.Equal(t, filepath.Join(dir, "shots", "home.jpg"), saved.Path)
+				assert.Equal(t, "image/jpeg", saved.MIMEType)
+				assert.Equal(t, 400, saved.Width, "saved screenshots are not downscaled")
+				info, err := os.Stat(saved.Path)
+				require.NoError(t, err)
+				assert.Equal(t, int64(saved.Size), info.Size())
+			},
+		},
+		{
+			name:      "saved in the screenshot directory",
+			arguments: map[string]interface{}{"selector": "#hero", "savePath": "hero.png"},
+			dir:       dir,
+			setupMock: func(m *MockBrowserClient) {
+				m.On("Screenshot", mock.Anything, 0, false, "#hero", "png", 90).Return(dataURL, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), `"path":"`+filepath.Join(dir, "hero.png")+`"`)
+				saved, err := os.ReadFile(filepath.Join(dir, "hero.png"))
+				require.NoError(t, err)
+				assert.Equal(t, data, saved)
+			},
+		},
+		{
+			name:      "savePath outside the screenshot directory",
+			arguments: map[string]interface{}{"savePath": "../home.png"},
+			dir:       dir,
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, `savePath must be a relative path inside the screenshot directory: "../home.png"`, getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "absolute savePath",
+			arguments: map[string]interface{}{"savePath": filepath.Join(dir, "home.png")},
+			dir:       dir,
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Contains(t, getTextFromContent(t, result.Content[0]), "savePath must be a relative path inside the screenshot directory")
+			},
+		},
+		{
+			name:      "savePath without a screenshot directory",
+			arguments: map[string]interface{}{"savePath": "home.png"},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "savePath needs a screenshot directory; set browser.screenshot_dir in the configuration", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "unsupported format",
+			arguments: map[string]interface{}{"format": "webp"},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "unsupported format: webp", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "capture fails",
+			arguments: map[string]interface{}{},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("Screenshot", mock.Anything, 0, false, "", "png", 90).Return("", errors.New("no active tab"))
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "Failed to take screenshot: no active tab", getTextFromContent(t, result.Content[0]))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			handler.SetScreenshotOptions(tt.dir, 0)
+			tt.setupMock(mockClient)
+
+			result, err := handler.Screenshot(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_screenshot", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_ScreenshotCompare(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	pngDataURL := func(img image.Image) string {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, img))
+		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	page := solidImage(100, 50, white)
+	changed := solidImage(100, 50, white)
+	draw.Draw(changed, image.Rect(0, 0, 10, 5), &image.Uniform{C: color.RGBA{R: 255, A: 255}}, image.Point{}, draw.Src)
+
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+	handler.SetBaselineDir(t.TempDir())
+
+	compare := func(t *testing.T, arguments map[string]interface{}) (*mcp.CallToolResult, screenshotComparison) {
+		t.Helper()
+		result, err := handler.ScreenshotCompare(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_screenshot_compare", Arguments: arguments},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, getTextFromContent(t, result.Content[0]))
+		var comparison screenshotComparison
+		require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &comparison))
+		return result, comparison
+	}
+
+	t.Run("missing baseline fails", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(pngDataURL(page), nil).Once()
+		result, err := handler.ScreenshotCompare(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_screenshot_compare", Arguments: map[string]interface{}{"name": "home", "tabId": 1}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		path := filepath.Join(handler.baselineDir, "home.png")
+		assert.Equal(t, "Baseline "+path+" does not exist; compare with update: true to create it", getTextFromContent(t, result.Content[0]))
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("update creates the baseline", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(pngDataURL(page), nil).Once()
+		_, comparison := compare(t, map[string]interface{}{"name": "home", "tabId": 1, "update": true})
+		assert.True(t, comparison.Updated)
+		assert.True(t, comparison.Passed)
+		assert.FileExists(t, comparison.Baseline)
+	})
+
+	t.Run("identical screenshot passes", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(pngDataURL(page), nil).Once()
+		result, comparison := compare(t, map[string]interface{}{"name": "home", "tabId": 1})
+		assert.True(t, comparison.Passed)
+		assert.False(t, comparison.Updated)
+		assert.Equal(t, 0, comparison.DiffPixels)
+		assert.Equal(t, 5000, comparison.TotalPixels)
+		assert.Len(t, result.Content, 1, "no diff image")
+	})
+
+	t.Run("changed pixels fail with a diff image", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(pngDataURL(changed), nil).Once()
+		result, comparison := compare(t, map[string]interface{}{"name": "home", "tabId": 1})
+		assert.False(t, comparison.Passed)
+		assert.Equal(t, 50, comparison.DiffPixels)
+		assert.Equal(t, 1.0, comparison.Mismatch)
+		assert.FileExists(t, comparison.DiffPath)
+		require.Len(t, result.Content, 2)
+		diff, ok := result.Content[1].(mcp.ImageContent)
+		require.True(t, ok)
+		assert.Equal(t, "image/png", diff.MIMEType)
+	})
+
+	t.Run("within tolerance", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(pngDataURL(changed), nil).Once()
+		_, comparison := compare(t, map[string]interface{}{"name": "home", "tabId": 1, "tolerance": 1.5})
+		assert.True(t, comparison.Passed)
+		assert.Equal(t, 1.5, comparison.Tolerance)
+	})
+
+	t.Run("ignored rectangle and selector", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, true, "", "png", 100).Return(pngDataURL(changed), nil).Once()
+		mockClient.On("ExecuteScript", mock.Anything, 1, "window.innerWidth", []interface{}(nil)).Return(json.RawMessage(`{"result":50}`), nil).Once()
+		mockClient.On("ExecuteScript", mock.Anything, 1, "window.scrollX", []interface{}(nil)).Return(json.RawMessage(`{"result":0}`), nil).Once()
+		mockClient.On("ExecuteScript", mock.Anything, 1, "window.scrollY", []interface{}(nil)).Return(json.RawMessage(`{"result":0}`), nil).Once()
+		// 2x display: the 3x2.5 CSS pixel box covers the top left 6x5 pixels
+		mockClient.On("FindElements", mock.Anything, 1, "#badge", 0).Return([]browser.Element{
+			{Tag: "span", BoundingBox: &browser.Rect{X: 0, Y: 0, Width: 3, Height: 2.5}},
+			{Tag: "span"},
+		}, nil).Once()
+
+		_, comparison := compare(t, map[string]interface{}{
+			"name":     "home",
+			"tabId":    1,
+			"fullPage": true,
+			"ignore":   []interface{}{"#badge", map[string]interface{}{"x": 6, "y": 0, "width": 4, "height": 5}},
+		})
+		assert.True(t, comparison.Passed)
+		assert.Equal(t, 0, comparison.DiffPixels)
+		assert.Equal(t, 4950, comparison.TotalPixels)
+		assert.Empty(t, comparison.DiffPath)
+	})
+
+	t.Run("update replaces the baseline", func(t *testing.T) {
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(pngDataURL(changed), nil).Twice()
+		_, comparison := compare(t, map[string]interface{}{"name": "home", "tabId": 1, "update": true})
+		assert.True(t, comparison.Updated)
+		assert.NoFileExists(t, diffPath(comparison.Baseline))
+
+		_, comparison = compare(t, map[string]interface{}{"name": "home", "tabId": 1})
+		assert.True(t, comparison.Passed)
+		assert.Equal(t, 0, comparison.DiffPixels)
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		for _, tt := range []struct {
+			arguments map[string]interface{}
+			err       string
+		}{
+			{map[string]interface{}{}, `required argument "name" not found`},
+			{map[string]interface{}{"name": "../home"}, `baseline name must be a relative path inside the baseline directory: "../home"`},
+			{map[string]interface{}{"name": "home", "tolerance": 101}, "tolerance must be between 0 and 100"},
+			{map[string]interface{}{"name": "home", "threshold": -1}, "threshold must be between 0 and 1"},
+			{map[string]interface{}{"name": "home", "ignore": []interface{}{true}}, "ignore regions must be selectors or {x, y, width, height} objects"},
+		} {
+			result, err := handler.ScreenshotCompare(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_screenshot_compare", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			assert.True(t, result.IsError)
+			assert.Equal(t, tt.err, getTextFromContent(t, result.Content[0]))
+		}
+	})
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_ScreenshotMarked(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, solidImage(200, 100, white)))
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	t.Run("marks actionables with boxes", func(t *testing.T) {
+		mockClient := &MockBrowserClient{}
+		handler := NewBrowserHandler(mockClient)
+
+		mockClient.On("GetActionables", mock.Anything, 1).Return([]browser.Actionable{
+			{LabelNumber: 0, Description: "Submit", Type: "button", Selector: "#submit", BoundingBox: &browser.Rect{X: 10, Y: 20, Width: 30, Height: 10}},
+			{LabelNumber: 1, Description: "Home", Type: "link", Selector: "#home"},
+			{LabelNumber: 2, Description: "Footer", Type: "link", Selector: "#footer"},
+		}, nil)
+		mockClient.On("Screenshot", mock.Anything, 1, false, "", "png", 100).Return(dataURL, nil)
+		// 2x display: 100 CSS pixels over 200 image pixels
+		mockClient.On("ExecuteScript", mock.Anything, 1, "window.innerWidth", []interface{}(nil)).Return(json.RawMessage(`{"result":100}`), nil)
+		mockClient.On("FindElements", mock.Anything, 1, "#home", 1).Return([]browser.Element{
+			{Tag: "a", BoundingBox: &browser.Rect{X: 60, Y: 20, Width: 20, Height: 10}},
+		}, nil)
+		mockClient.On("FindElements", mock.Anything, 1, "#footer", 1).Return([]browser.Element{
+			{Tag: "a", BoundingBox: &browser.Rect{X: 0, Y: 300, Width: 20, Height: 10}},
+		}, nil)
+
+		result, err := handler.ScreenshotMarked(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_screenshot_marked", Arguments: map[string]interface{}{"tabId": 1}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, getTextFromContent(t, result.Content[0]))
+		require.Len(t, result.Content, 2)
+
+		var table []markedActionable
+		require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &table))
+		assert.Equal(t, []markedActionable{
+			{Label: 0, Description: "Submit", Type: "button", Selector: "#submit", Marked: true},
+			{Label: 1, Description: "Home", Type: "link", Selector: "#home", Marked: true},
+			{Label: 2, Description: "Footer", Type: "link", Selector: "#footer"},
+		}, table)
+
+		shot, ok := result.Content[1].(mcp.ImageContent)
+		require.True(t, ok)
+		assert.Equal(t, "image/png", shot.MIMEType)
+		data, err := base64.StdEncoding.DecodeString(shot.Data)
+		require.NoError(t, err)
+		img, err := decodeImage(data)
+		require.NoError(t, err)
+		assert.Equal(t, markColors[0], img.RGBAAt(20, 50), "left edge of the first box")
+		assert.Equal(t, markColors[1], img.RGBAAt(120, 50), "left edge of the second box")
+		assert.Equal(t, white, img.RGBAAt(50, 50), "inside the first box")
+
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("actionables error", func(t *testing.T) {
+		mockClient := &MockBrowserClient{}
+		handler := NewBrowserHandler(mockClient)
+		mockClient.On("GetActionables", mock.Anything, 0).Return(nil, errors.New("page not loaded"))
+
+		result, err := handler.ScreenshotMarked(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_screenshot_marked", Arguments: map[string]interface{}{}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "Failed to get actionables: page not loaded", getTextFromContent(t, result.Content[0]))
+	})
+
+	t.Run("invalid maxBytes", func(t *testing.T) {
+		handler := NewBrowserHandler(&MockBrowserClient{})
+		result, err := handler.ScreenshotMarked(context.Background(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: "browser_screenshot_marked", Arguments: map[string]interface{}{"maxBytes": 0}},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "maxBytes must be positive", getTextFromContent(t, result.Content[0]))
+	})
+}
+
+func TestBrowserHandler_ClickActionable(t *testing.T) {
+	tests := []struct {
+		name      string
+		setupMock func(*MockBrowserClient)
+		arguments map[string]interface{}
+		wantText  string
+		wantError bool
+	}{
+		{
+			name: "clicks the selector of the label",
+			setupMock: func(m *MockBrowserClient) {
+				m.On("ResolveActionable", mock.Anything, 0, 3).Return(&browser.Actionable{LabelNumber: 3, Description: "Submit", Selector: "#submit"}, nil)
+				m.On("Click", mock.Anything, 0, "#submit", 30000).Return(nil)
+			},
+			arguments: map[string]interface{}{"label": 3},
+			wantText:  "Clicked on actionable 3 (Submit): #submit",
+		},
+		{
+			name: "with tab and timeout",
+			setupMock: func(m *MockBrowserClient) {
+				m.On("ResolveActionable", mock.Anything, 7, 0).Return(&browser.Actionable{Description: "Home", Selector: "#home"}, nil)
+				m.On("Click", mock.Anything, 7, "#home", 5000).Return(nil)
+			},
+			arguments: map[string]interface{}{"label": 0, "tabId": 7, "timeout": 5000},
+			wantText:  "Clicked on actionable 0 (Home): #home",
+		},
+		{
+			name: "unknown label",
+			setupMock: func(m *MockBrowserClient) {
+				m.On("ResolveActionable", mock.Anything, 0, 42).Return(nil, errors.New("no actionable with label 42"))
+			},
+			arguments: map[string]interface{}{"label": 42},
+			wantText:  "Failed to resolve actionable: no actionable with label 42",
+			wantError: true,
+		},
+		{
+			name: "click error",
+			setupMock: func(m *MockBrowserClient) {
+				m.On("ResolveActionable", mock.Anything, 0, 1).Return(&browser.Actionable{LabelNumber: 1, Selector: "#gone"}, nil)
+				m.On("Click", mock.Anything, 0, "#gone", 30000).Return(errors.New("element not found"))
+			},
+			arguments: map[string]interface{}{"label": 1},
+			wantText:  "Failed to click: element not found",
+			wantError: true,
+		},
+		{
+			name:      "missing label",
+			arguments: map[string]interface{}{},
+			wantText:  `required argument "label" not found`,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			if tt.setupMock != nil {
+				tt.setupMock(mockClient)
+			}
+
+			result, err := handler.ClickActionable(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_click_actionable", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError)
+			assert.Equal(t, tt.wantText, getTextFromContent(t, result.Content[0]))
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_RouteAdd(t *testing.T) {
+	routesDir := t.TempDir()
+	fixture := "user.json"
+	require.NoError(t, os.WriteFile(filepath.Join(routesDir, fixture), []byte(`{"name":"Ada"}`), 0644))
+
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name: "fulfill with a body file",
+			arguments: map[string]interface{}{
+				"pattern":         "**/api/user",
+				"action":          "fulfill",
+				"bodyFile":        fixture,
+				"responseHeaders": map[string]interface{}{"X-Fixture": "user"},
+			},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("AddRoute", mock.Anything, browser.Route{
+					Pattern:         "**/api/user",
+					Action:          browser.RouteFulfill,
+					ContentType:     "application/json",
+					Body:            []byte(`{"name":"Ada"}`),
+					ResponseHeaders: map[string]string{"X-Fixture": "user"},
+				}).Return(&browser.Route{ID: "route-1", Pattern: "**/api/user", Action: browser.RouteFulfill, Status: 200, BodySize: 14}, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.JSONEq(t, `{"id":"route-1","pattern":"**/api/user","action":"fulfill","status":200,"bodySize":14}`,
+					getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "base64 body",
+			arguments: map[string]interface{}{"pattern": "**/logo.png", "action": "fulfill", "body": "iVBORw==", "bodyBase64": true},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("AddRoute", mock.Anything, browser.Route{
+					Pattern: "**/logo.png",
+					Action:  browser.RouteFulfill,
+					Body:    []byte{0x89, 'P', 'N', 'G'},
+				}).Return(&browser.Route{ID: "route-2"}, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+			},
+		},
+		{
+			name:      "body and body file",
+			arguments: map[string]interface{}{"pattern": "**", "action": "fulfill", "body": "x", "bodyFile": fixture},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "body and bodyFile cannot be used together", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "header values must be strings",
+			arguments: map[string]interface{}{"pattern": "**", "action": "modifyHeaders", "requestHeaders": map[string]interface{}{"X-Retry": 3}},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "requestHeaders.X-Retry must be a string", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "missing action",
+			arguments: map[string]interface{}{"pattern": "**"},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+			},
+		},
+		{
+			name:      "invalid route",
+			arguments: map[string]interface{}{"pattern": "**", "action": "delay"},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("AddRoute", mock.Anything, browser.Route{Pattern: "**", Action: browser.RouteDelay}).
+					Return(nil, errors.New("delay routes need a delayMs greater than 0"))
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "Failed to add route: delay routes need a delayMs greater than 0", getTextFromContent(t, result.Content[0]))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			handler.SetRoutesDir(routesDir)
+			tt.setupMock(mockClient)
+
+			result, err := handler.RouteAdd(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_route_add", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_RouteAddBodyFile(t *testing.T) {
+	routesDir := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0600))
+	escape, err := filepath.Rel(routesDir, secret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		routesDir string
+		bodyFile  string
+		wantText  string
+	}{
+		{
+			name:     "no routes directory",
+			bodyFile: "user.json",
+			wantText: "bodyFile needs a routes directory; set browser.routes_dir in the configuration",
+		},
+		{
+			name:      "absolute path",
+			routesDir: routesDir,
+			bodyFile:  secret,
+			wantText:  fmt.Sprintf("bodyFile must be a relative path inside the routes directory: %q", secret),
+		},
+		{
+			name:      "path leaving the routes directory",
+			routesDir: routesDir,
+			bodyFile:  escape,
+			wantText:  fmt.Sprintf("bodyFile must be a relative path inside the routes directory: %q", escape),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			handler.SetRoutesDir(tt.routesDir)
+
+			result, err := handler.RouteAdd(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_route_add", Arguments: map[string]interface{}{
+					"pattern":  "**",
+					"action":   "fulfill",
+					"bodyFile": tt.bodyFile,
+				}},
+			})
+			require.NoError(t, err)
+			assert.True(t, result.IsError)
+			assert.Equal(t, tt.wantText, getTextFromContent(t, result.Content[0]))
+
+			mockClient.AssertNotCalled(t, "AddRoute", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBrowserHandler_RouteRemove(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	mockClient.On("ListRoutes", mock.Anything).Return([]browser.Route{{ID: "route-1"}, {ID: "route-3"}}, nil)
+	mockClient.On("RemoveRoute", mock.Anything, "route-1").Return(nil)
+	mockClient.On("RemoveRoute", mock.Anything, "route-3").Return(nil)
+
+	result, err := handler.RouteRemove(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "browser_route_remove", Arguments: map[string]interface{}{"all": true}},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, "Removed 2 routes", getTextFromContent(t, result.Content[0]))
+
+	result, err = handler.RouteRemove(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "browser_route_remove", Arguments: map[string]interface{}{}},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "id or all is required", getTextFromContent(t, result.Content[0]))
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_NetworkStop(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+	handler.SetHARDir("har")
+	mockClient.On("StopNetworkCapture", mock.Anything, 2, "har", "run.har").Return(&browser.NetworkRecording{
+		TabID: 2, Path: "har/run.har", Entries: 3,
+	}, nil)
+
+	result, err := handler.NetworkStop(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "browser_network_stop", Arguments: map[string]interface{}{"tabId": 2, "name": "run.har"}},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, getTextFromContent(t, result.Content[0]), `"path":"har/run.har"`)
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_NetworkSummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name:      "default threshold",
+			arguments: map[string]interface{}{"tabId": 2},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("NetworkSummary", mock.Anything, 2, 1000).Return(&browser.NetworkSummary{
+					TabID: 2, Requests: 3, TotalBytes: 2048, SlowMs: 1000,
+					Failed: []browser.RequestSummary{{URL: "https://example.com/app.js", Method: "GET", Status: 404, Duration: 12}},
+					Slow:   []browser.RequestSummary{},
+				}, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.JSONEq(t, `{"tabId":2,"capturing":false,"requests":3,"pending":0,"totalBytes":2048,"slowMs":1000,
+					"failed":[{"url":"https://example.com/app.js","method":"GET","status":404,"duration":12,"size":0}],"slow":[]}`,
+					getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "invalid threshold",
+			arguments: map[string]interface{}{"slowMs": 0},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "slowMs must be positive", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "no capture",
+			arguments: map[string]interface{}{"slowMs": 500},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("NetworkSummary", mock.Anything, 0, 500).Return(nil, errors.New("no network capture for tab 1"))
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "Failed to summarize network capture: no network capture for tab 1", getTextFromContent(t, result.Content[0]))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			tt.setupMock(mockClient)
+
+			result, err := handler.NetworkSummary(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_network_summary", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_GetConsole(t *testing.T) {
+	logged := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		arguments   map[string]interface{}
+		setupMock   func(*MockBrowserClient)
+		checkResult func(*testing.T, *mcp.CallToolResult)
+	}{
+		{
+			name:      "errors since a time",
+			arguments: map[string]interface{}{"tabId": 3, "levels": []interface{}{"error", "exception"}, "since": "2025-03-01T11:59:59.5Z"},
+			setupMock: func(m *MockBrowserClient) {
+				filter := browser.ConsoleFilter{
+					Levels: []string{"error", "exception"},
+					Since:  time.Date(2025, 3, 1, 11, 59, 59, 500000000, time.UTC),
+				}
+				m.On("GetConsole", mock.Anything, 3, filter, 100).Return([]browser.ConsoleMessage{
+					{Level: "exception", Text: "Uncaught TypeError: x is undefined", Source: "https://example.com/app.js", Line: 12, Column: 4, Time: logged},
+				}, nil)
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.False(t, result.IsError)
+				assert.JSONEq(t, `[{"level":"exception","text":"Uncaught TypeError: x is undefined","source":"https://example.com/app.js",
+					"line":12,"column":4,"time":"2025-03-01T12:00:00Z"}]`, getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "unknown level",
+			arguments: map[string]interface{}{"levels": []interface{}{"fatal"}},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "unknown console level: fatal", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "invalid since",
+			arguments: map[string]interface{}{"since": "yesterday"},
+			setupMock: func(m *MockBrowserClient) {},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "since must be an RFC 3339 timestamp", getTextFromContent(t, result.Content[0]))
+			},
+		},
+		{
+			name:      "no active tab",
+			arguments: map[string]interface{}{"limit": 10},
+			setupMock: func(m *MockBrowserClient) {
+				m.On("GetConsole", mock.Anything, 0, browser.ConsoleFilter{}, 10).Return(nil, errors.New("no active tab"))
+			},
+			checkResult: func(t *testing.T, result *mcp.CallToolResult) {
+				assert.True(t, result.IsError)
+				assert.Equal(t, "Failed to get console messages: no active tab", getTextFromContent(t, result.Content[0]))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &MockBrowserClient{}
+			handler := NewBrowserHandler(mockClient)
+			tt.setupMock(mockClient)
+
+			result, err := handler.GetConsole(context.Background(), mcp.CallToolRequest{
+				Params: mcp.CallToolParams{Name: "browser_get_console", Arguments: tt.arguments},
+			})
+			require.NoError(t, err)
+			tt.checkResult(t, result)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBrowserHandler_Files(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	call := func(tool func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := tool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}})
+		require.NoError(t, err)
+		return result
+	}
+	assertText := func(t *testing.T, result *mcp.CallToolResult, isError bool, expected string) {
+		t.Helper()
+		assert.Equal(t, isError, result.IsError)
+		assert.Equal(t, expected, getTextFromContent(t, result.Content[0]))
+	}
+	started := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	report := browser.Download{ID: 4, URL: "https://example.com/report.csv", Filename: "/home/me/Downloads/report.csv", State: "complete", Started: started, Updated: started}
+
+	t.Run("upload file", func(t *testing.T) {
+		files := []browser.UploadedFile{{Name: "users.csv", MimeType: "text/csv; charset=utf-8", Size: 42}}
+		mockClient.On("UploadFiles", mock.Anything, 0, "#import", []string{"testdata/users.csv"}).Return(files, nil).Once()
+		result := call(handler.UploadFile, map[string]interface{}{"selector": "#import", "paths": []interface{}{"testdata/users.csv"}})
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"selector":"#import","files":[{"name":"users.csv","mimeType":"text/csv; charset=utf-8","size":42}]}`, getTextFromContent(t, result.Content[0]))
+
+		mockClient.On("UploadFiles", mock.Anything, 2, "#import", []string{"missing.csv"}).Return(nil, errors.New("failed to open missing.csv: no such file or directory")).Once()
+		assertText(t, call(handler.UploadFile, map[string]interface{}{"selector": "#import", "paths": "missing.csv", "tabId": 2}), true,
+			"Failed to upload files: failed to open missing.csv: no such file or directory")
+
+		assertText(t, call(handler.UploadFile, map[string]interface{}{"selector": "#import"}), true, "paths is required")
+		assertText(t, call(handler.UploadFile, map[string]interface{}{"selector": "#import", "paths": []interface{}{}}), true, "paths must not be empty")
+	})
+
+	t.Run("list downloads", func(t *testing.T) {
+		mockClient.On("ListDownloads", mock.Anything, browser.DownloadFilter{State: "complete", URL: "report"}).Return([]browser.Download{report}, nil).Once()
+		result := call(handler.ListDownloads, map[string]interface{}{"state": "complete", "url": "report"})
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `[{"id":4,"url":"https://example.com/report.csv","filename":"/home/me/Downloads/report.csv","state":"complete",
+			"started":"2025-03-01T12:00:00Z","updated":"2025-03-01T12:00:00Z"}]`, getTextFromContent(t, result.Content[0]))
+
+		mockClient.On("ListDownloads", mock.Anything, browser.DownloadFilter{}).Return(nil, nil).Once()
+		assertText(t, call(handler.ListDownloads, map[string]interface{}{}), false, "[]")
+
+		assertText(t, call(handler.ListDownloads, map[string]interface{}{"state": "paused"}), true, "unknown download state: paused")
+	})
+
+	t.Run("wait for download", func(t *testing.T) {
+		download := report
+		mockClient.On("WaitForDownload", mock.Anything, browser.DownloadFilter{URL: "report"}, 5*time.Second).Return(&download, nil).Once()
+		result := call(handler.WaitForDownload, map[string]interface{}{"url": "report", "timeout": 5000})
+		assert.False(t, result.IsError)
+		assert.Contains(t, getTextFromContent(t, result.Content[0]), `"state":"complete"`)
+
+		interrupted := browser.Download{ID: 5, URL: "https://example.com/big.zip", State: "interrupted", Error: "NETWORK_FAILED"}
+		mockClient.On("WaitForDownload", mock.Anything, browser.DownloadFilter{ID: 5}, 30*time.Second).Return(&interrupted, nil).Once()
+		assertText(t, call(handler.WaitForDownload, map[string]interface{}{"id": 5}), true, "Download 5 of https://example.com/big.zip was interrupted: NETWORK_FAILED")
+
+		mockClient.On("WaitForDownload", mock.Anything, browser.DownloadFilter{}, 30*time.Second).Return(nil, errors.New("timeout waiting for download")).Once()
+		assertText(t, call(handler.WaitForDownload, map[string]interface{}{}), true, "Failed to wait for download: timeout waiting for download")
+
+		assertText(t, call(handler.WaitForDownload, map[string]interface{}{"copy": true}), true,
+			"copy needs a download directory; set browser.download_dir in the configuration")
+	})
+
+	t.Run("copy download", func(t *testing.T) {
+		dir := t.TempDir()
+		handler.SetDownloadDir(dir)
+		defer handler.SetDownloadDir("")
+
+		download := report
+		mockClient.On("WaitForDownload", mock.Anything, browser.DownloadFilter{}, 30*time.Second).Return(&download, nil).Once()
+		mockClient.On("SaveDownload", mock.Anything, 4, dir).Return(filepath.Join(dir, "report.csv"), nil).Once()
+		result := call(handler.WaitForDownload, map[string]interface{}{"copy": true})
+		assert.False(t, result.IsError)
+
+		var saved browser.Download
+		require.NoError(t, json.Unmarshal([]byte(getTextFromContent(t, result.Content[0])), &saved))
+		assert.Equal(t, filepath.Join(dir, "report.csv"), saved.Path)
+	})
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestBrowserHandler_Locators(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	handler := NewBrowserHandler(mockClient)
+
+	call := func(tool func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), arguments map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		result, err := tool(context.Background(), mcp.CallToolRequest{Params: mcp.CallToolParams{Arguments: arguments}})
+		require.NoError(t, err)
+		return result
+	}
+	assertText := func(t *testing.T, result *mcp.CallToolResult, isError bool, expected string) {
+		t.Helper()
+		assert.Equal(t, isError, result.IsError)
+		assert.Equal(t, expected, getTextFromContent(t, result.Content[0]))
+	}
+
+	// The frame is prefixed to the selector, which may pierce on its own
+	mockClient.On("Click", mock.Anything, 0, "iframe#app >>> chat-box >>> button.send", 30000).Return(nil).Once()
+	assertText(t, call(handler.Click, map[string]interface{}{"selector": "chat-box>>>button.send", "frame": "iframe#app"}), false,
+		"Clicked on element: iframe#app >>> chat-box >>> button.send")
+
+	mockClient.On("Type", mock.Anything, 0, "iframe >>> textarea", "hi", false, 0).Return(nil).Once()
+	assertText(t, call(handler.Type, map[string]interface{}{"selector": "textarea", "frame": "iframe", "text": "hi"}), false, "Typed 'hi' into iframe >>> textarea")
+
+	mockClient.On("WaitForElement", mock.Anything, 0, "my-app >>> .ready", 30000, "visible").Return(json.RawMessage(nil), nil).Once()
+	assertText(t, call(handler.WaitForElement, map[string]interface{}{"selector": "my-app >>> .ready"}), false, "Element my-app >>> .ready is now visible")
+
+	mockClient.On("Hover", mock.Anything, 0, "iframe >>> .menu", 30000).Return(nil).Once()
+	assertText(t, call(handler.Hover, map[string]interface{}{"selector": ".menu", "frame": "iframe"}), false, "Hovered over element: iframe >>> .menu")
+
+	mockClient.On("SetChecked", mock.Anything, 0, "iframe >>> #terms", true).Return(nil).Once()
+	assert.False(t, call(handler.Check, map[string]interface{}{"selector": "#terms", "frame": "iframe"}).IsError)
+
+	mockClient.On("PressKey", mock.Anything, 0, "search-box >>> input", mock.Anything).Return(nil).Once()
+	assertText(t, call(handler.PressKey, map[string]interface{}{"keys": "Enter", "selector": "input", "frame": "search-box"}), false, "Pressed Enter in search-box >>> input")
+
+	mockClient.On("Scroll", mock.Anything, 0, (*float64)(nil), (*float64)(nil), "iframe >>> #footer", "auto").Return(json.RawMessage(nil), nil).Once()
+	assertText(t, call(handler.Scroll, map[string]interface{}{"selector": "#footer", "frame": "iframe"}), false, "Scrolled to element iframe >>> #footer")
+
+	// The frame applies to both ends of a drag
+	mockClient.On("Drag", mock.Anything, 0, browser.DragPoint{Selector: "iframe#board >>> li.card"}, browser.DragPoint{Selector: "iframe#board >>> ul.done"}).Return(nil).Once()
+	assertText(t, call(handler.Drag, map[string]interface{}{"source": "li.card", "target": "ul.done", "frame": "iframe#board"}), false,
+		"Dragged iframe#board >>> li.card to iframe#board >>> ul.done")
+
+	mockClient.On("UploadFiles", mock.Anything, 0, "iframe >>> input[type=file]", []string{"a.txt"}).Return([]browser.UploadedFile{}, nil).Once()
+	assert.False(t, call(handler.UploadFile, map[string]interface{}{"selector": "input[type=file]", "frame": "iframe", "paths": []interface{}{"a.txt"}}).IsError)
+
+	mockClient.On("ExtractRows", mock.Anything, 0, "iframe >>> .card", mock.Anything).Return(&browser.Table{Columns: []string{"name"}}, nil).Once()
+	assert.False(t, call(handler.ExtractTable, map[string]interface{}{"rowSelector": ".card", "frame": "iframe", "columns": map[string]interface{}{"name": "h2"}}).IsError)
+
+	mockClient.On("ExtractStructured", mock.Anything, 0, "iframe >>> main", mock.Anything).Return(map[string]interface{}{}, nil).Once()
+	assert.False(t, call(handler.ExtractStructured, map[string]interface{}{"selector": "main", "frame": "iframe", "schema": map[string]interface{}{"title": "h1"}}).IsError)
+
+	// Content is read from the body of the frame by default
+	mockClient.On("ExtractContent", mock.Anything, 0, "iframe.preview >>> body", "text", "").Return([]string{"Hello"}, nil).Once()
+	assertText(t, call(handler.ExtractContent, map[string]interface{}{"frame": "iframe.preview"}), false, `["Hello"]`)
+
+	// Locators are validated before they are sent
+	assertText(t, call(handler.Click, map[string]interface{}{"selector": "button", "frame": "iframe >>>"}), true, `invalid locator "iframe >>> >>> button": empty selector`)
+	assertText(t, call(handler.Type, map[string]interface{}{"selector": "input[name=q", "text": "x"}), true, `invalid locator "input[name=q": missing ]`)
+	assertText(t, call(handler.Screenshot, map[string]interface{}{"frame": "iframe"}), true, "frame needs a selector")
+	assertText(t, call(handler.GetValue, map[string]interface{}{"selector": "#a\\\\>"}), true, `invalid locator "#a\\\\>": selector "#a\\\\>" ends with >`)
+	assertText(t, call(handler.FindElements, map[string]interface{}{"selector": "li", "frame": "> ul"}), true, `invalid locator "> ul >>> li": selector "> ul" starts with >`)
+	assertText(t, call(handler.Scroll, map[string]interface{}{"y": 100, "frame": "iframe"}), true, "frame needs a selector")
+	assertText(t, call(handler.Drag, map[string]interface{}{"sourceX": 1, "sourceY": 2, "targetX": 3, "targetY": 4, "frame": "iframe"}), true, "frame needs a source or target selector")
+	assertText(t, call(handler.Drag, map[string]interface{}{"source": "li,", "target": "ul"}), true, `invalid locator "li,": selector "li," ends with ,`)
+	assertText(t, call(handler.ExtractTable, map[string]interface{}{"selector": "table", "frame": "iframe >>>"}), true, `invalid locator "iframe >>> >>> table": empty selector`)
+	assertText(t, call(handler.UploadFile, map[string]interface{}{"selector": "input[type=file", "paths": []interface{}{"a.txt"}}), true, `invalid locator "input[type=file": missing ]`)
+
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/periplon/bract/internal/browser"
+)
+
+// DefaultBaselineDir is the directory baseline screenshots are kept in unless
+// configured otherwise
+const DefaultBaselineDir = "baselines"
+
+// DefaultPixelThreshold is the color difference, from 0 to 1, from which two
+// pixels are counted as different unless told otherwise
+const DefaultPixelThreshold = 0.1
+
+// screenshotComparison is the result of comparing a screenshot with its
+// baseline
+type screenshotComparison struct {
+	Name        string  `json:"name"`
+	Baseline    string  `json:"baseline"`          // path of the baseline file
+	Updated     bool    `json:"updated,omitempty"` // the screenshot was saved as the baseline
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	SizeChanged bool    `json:"sizeChanged,omitempty"` // the screenshot and the baseline have different sizes
+	DiffPixels  int     `json:"diffPixels"`
+	TotalPixels int     `json:"totalPixels"` // compared pixels, ignored regions excluded
+	Mismatch    float64 `json:"mismatch"`    // percentage of differing pixels
+	Tolerance   float64 `json:"tolerance"`   // percentage of differing pixels allowed
+	Passed      bool    `json:"passed"`
+	DiffPath    string  `json:"diffPath,omitempty"` // diff image, saved when pixels differ
+}
+
+// baselinePath returns the file of a named baseline in dir. Names are
+// relative paths inside the directory; .png is added when they have no
+// extension.
+func baselinePath(dir, name string) (string, error) {
+	if name == "" || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("baseline name must be a relative path inside the baseline directory: %q", name)
+	}
+	if filepath.Ext(name) == "" {
+		name += ".png"
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// diffPath returns the file the diff image of a baseline is saved to
+func diffPath(baseline string) string {
+	return strings.TrimSuffix(baseline, filepath.Ext(baseline)) + ".diff.png"
+}
+
+// parseIgnoreRegions splits the ignore argument into selectors and
+// rectangles in screenshot pixels
+func parseIgnoreRegions(value interface{}) ([]string, []image.Rectangle, error) {
+	if value == nil {
+		return nil, nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("ignore must be an array")
+	}
+
+	var selectors []string
+	var rects []image.Rectangle
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			selectors = append(selectors, v)
+		case map[string]interface{}:
+			var r browser.Rect
+			data, _ := json.Marshal(v)
+			if err := json.Unmarshal(data, &r); err != nil || r.Width <= 0 || r.Height <= 0 {
+				return nil, nil, fmt.Errorf("ignore rectangles need a positive width and height: %v", v)
+			}
+			rects = append(rects, pixelRect(r, 0, 0, 1))
+		default:
+			return nil, nil, fmt.Errorf("ignore regions must be selectors or {x, y, width, height} objects")
+		}
+	}
+	return selectors, rects, nil
+}
+
+// pixelRect converts a rectangle to whole pixels, moved by -originX,
+// -originY and scaled, covering every pixel it touches
+func pixelRect(r browser.Rect, originX, originY, scale float64) image.Rectangle {
+	return image.Rect(
+		int(math.Floor((r.X-originX)*scale)),
+		int(math.Floor((r.Y-originY)*scale)),
+		int(math.Ceil((r.X-originX+r.Width)*scale)),
+		int(math.Ceil((r.Y-originY+r.Height)*scale)),
+	)
+}
+
+// selectorRegions returns the rectangles, in pixels of a screenshot imgWidth
+// wide, of the elements matching the selectors. Bounding boxes are relative
+// to the viewport, so they are moved to the captured element for element
+// screenshots and by the scroll offset for full page ones. Elements without
+// a bounding box are left out.
+func (h *BrowserHandler) selectorRegions(ctx context.Context, tabID int, selectors []string, fullPage bool, selector string, imgWidth int) ([]image.Rectangle, error) {
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+
+	var originX, originY, cssWidth float64
+	if selector != "" {
+		targets, err := h.client.FindElements(ctx, tabID, selector, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) == 0 || targets[0].BoundingBox == nil {
+			return nil, nil
+		}
+		box := targets[0].BoundingBox
+		originX, originY, cssWidth = box.X, box.Y, box.Width
+	} else {
+		var err error
+		if cssWidth, err = h.scriptNumber(ctx, tabID, "window.innerWidth"); err != nil {
+			return nil, err
+		}
+		if fullPage {
+			scrollX, err := h.scriptNumber(ctx, tabID, "window.scrollX")
+			if err != nil {
+				return nil, err
+			}
+			scrollY, err := h.scriptNumber(ctx, tabID, "window.scrollY")
+			if err != nil {
+				return nil, err
+			}
+			originX, originY = -scrollX, -scrollY
+		}
+	}
+
+	// Screenshots are in device pixels, bounding boxes in CSS pixels
+	scale := 1.0
+	if cssWidth > 0 {
+		scale = float64(imgWidth) / cssWidth
+	}
+
+	var rects []image.Rectangle
+	for _, s := range selectors {
+		elements, err := h.client.FindElements(ctx, tabID, s, 0)
+		if err != nil {
+			return nil, fmt.Errorf("ignore selector %s: %w", s, err)
+		}
+		for _, e := range elements {
+			if e.BoundingBox != nil {
+				rects = append(rects, pixelRect(*e.BoundingBox, originX, originY, scale))
+			}
+		}
+	}
+	return rects, nil
+}
+
+// scriptNumber evaluates a script returning a number in a tab
+func (h *BrowserHandler) scriptNumber(ctx context.Context, tabID int, script string) (float64, error) {
+	data, err := h.client.ExecuteScript(ctx, tabID, script, nil)
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		Result float64 `json:"result"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, fmt.Errorf("%s did not return a number", script)
+	}
+	return result.Result, nil
+}
+
+// compareImages compares a screenshot with its baseline pixel by pixel. Pixels
+// whose color differs by more than threshold, from 0 to 1, count as
+// different, as do the pixels only one of the images covers. Pixels in the
+// ignored rectangles are skipped. The diff image shows the screenshot faded,
+// with the differing pixels in red and the ignored ones in blue.
+func compareImages(baseline, actual *image.RGBA, threshold float64, ignore []image.Rectangle) (diff *image.RGBA, diffPixels, totalPixels int) {
+	bounds := baseline.Bounds().Union(actual.Bounds())
+	diff = image.NewRGBA(bounds)
+	limit := int(threshold * 255)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+	pixels:
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Point{X: x, Y: y}
+			for _, r := range ignore {
+				if p.In(r) {
+					diff.SetRGBA(x, y, color.RGBA{R: 200, G: 220, B: 255, A: 255})
+					continue pixels
+				}
+			}
+			totalPixels++
+
+			if !p.In(baseline.Bounds()) || !p.In(actual.Bounds()) {
+				diffPixels++
+				diff.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+			a, b := actual.RGBAAt(x, y), baseline.RGBAAt(x, y)
+			if channelDelta(a.R, b.R) > limit || channelDelta(a.G, b.G) > limit ||
+				channelDelta(a.B, b.B) > limit || channelDelta(a.A, b.A) > limit {
+				diffPixels++
+				diff.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+
+			// Unchanged pixels are shown as faded gray
+			gray := uint8((int(a.R)*299+int(a.G)*587+int(a.B)*114)/1000/4 + 191)
+			diff.SetRGBA(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 255})
+		}
+	}
+	return diff, diffPixels, totalPixels
+}
+
+// channelDelta returns the absolute difference of two color channels
+func channelDelta(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// decodeImage decodes a PNG or JPEG image as an RGBA image
+func decodeImage(data []byte) (*image.RGBA, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return toRGBA(img), nil
+}
+
+// writePNG saves an image as a PNG file, creating its directory
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// writeFile saves data to a file, creating its directory
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
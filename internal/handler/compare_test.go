@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+
+	"github.com/periplon/bract/internal/browser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// solidImage returns a width x height image of one color
+func solidImage(width, height int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestBaselinePath(t *testing.T) {
+	path, err := baselinePath("baselines", "home")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("baselines", "home.png"), path)
+
+	path, err = baselinePath("baselines", "checkout/cart.png")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("baselines", "checkout", "cart.png"), path)
+	assert.Equal(t, filepath.Join("baselines", "checkout", "cart.diff.png"), diffPath(path))
+
+	for _, name := range []string{"", "../home", "/tmp/home"} {
+		_, err := baselinePath("baselines", name)
+		assert.ErrorContains(t, err, "baseline name must be a relative path inside the baseline directory", name)
+	}
+}
+
+func TestParseIgnoreRegions(t *testing.T) {
+	selectors, rects, err := parseIgnoreRegions([]interface{}{
+		"#clock",
+		map[string]interface{}{"x": 10.5, "y": 0, "width": 20, "height": 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#clock"}, selectors)
+	assert.Equal(t, []image.Rectangle{image.Rect(10, 0, 31, 5)}, rects)
+
+	_, _, err = parseIgnoreRegions([]interface{}{map[string]interface{}{"x": 1, "y": 1}})
+	assert.ErrorContains(t, err, "ignore rectangles need a positive width and height")
+	_, _, err = parseIgnoreRegions([]interface{}{42})
+	assert.EqualError(t, err, "ignore regions must be selectors or {x, y, width, height} objects")
+	_, _, err = parseIgnoreRegions("#clock")
+	assert.EqualError(t, err, "ignore must be an array")
+}
+
+func TestPixelRect(t *testing.T) {
+	// A box 100 CSS pixels down a page scrolled by 40, on a 2x display
+	r := pixelRect(browser.Rect{X: 10, Y: 100, Width: 50.5, Height: 20}, 0, -40, 2)
+	assert.Equal(t, image.Rect(20, 280, 121, 320), r)
+}
+
+func TestCompareImages(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	baseline := solidImage(10, 10, white)
+
+	t.Run("identical", func(t *testing.T) {
+		_, diffPixels, total := compareImages(baseline, solidImage(10, 10, white), DefaultPixelThreshold, nil)
+		assert.Equal(t, 0, diffPixels)
+		assert.Equal(t, 100, total)
+	})
+
+	t.Run("changed pixels", func(t *testing.T) {
+		actual := solidImage(10, 10, white)
+		draw.Draw(actual, image.Rect(0, 0, 5, 2), &image.Uniform{C: color.RGBA{A: 255}}, image.Point{}, draw.Src)
+		// Below the threshold
+		actual.SetRGBA(9, 9, color.RGBA{R: 250, G: 250, B: 250, A: 255})
+
+		diff, diffPixels, total := compareImages(baseline, actual, DefaultPixelThreshold, nil)
+		assert.Equal(t, 10, diffPixels)
+		assert.Equal(t, 100, total)
+		assert.Equal(t, color.RGBA{R: 255, A: 255}, diff.RGBAAt(0, 0))
+		assert.NotEqual(t, color.RGBA{R: 255, A: 255}, diff.RGBAAt(9, 9))
+
+		_, diffPixels, _ = compareImages(baseline, actual, 0, nil)
+		assert.Equal(t, 11, diffPixels, "a threshold of 0 counts any change")
+	})
+
+	t.Run("ignored regions", func(t *testing.T) {
+		actual := solidImage(10, 10, white)
+		draw.Draw(actual, image.Rect(0, 0, 5, 2), &image.Uniform{C: color.RGBA{A: 255}}, image.Point{}, draw.Src)
+
+		_, diffPixels, total := compareImages(baseline, actual, DefaultPixelThreshold, []image.Rectangle{image.Rect(0, 0, 4, 2)})
+		assert.Equal(t, 2, diffPixels)
+		assert.Equal(t, 92, total)
+	})
+
+	t.Run("size changed", func(t *testing.T) {
+		diff, diffPixels, total := compareImages(baseline, solidImage(10, 12, white), DefaultPixelThreshold, nil)
+		assert.Equal(t, 20, diffPixels)
+		assert.Equal(t, 120, total)
+		assert.Equal(t, image.Rect(0, 0, 10, 12), diff.Bounds())
+	})
+}
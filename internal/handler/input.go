@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/periplon/bract/internal/browser"
+)
+
+// mouseButtons are the buttons browser_click clicks with
+var mouseButtons = map[string]bool{"left": true, "middle": true, "right": true}
+
+// parseClickOptions reads the button, clickCount and modifiers arguments of a
+// click
+func parseClickOptions(request mcp.CallToolRequest) (browser.ClickOptions, error) {
+	options := browser.ClickOptions{
+		Button:     request.GetString("button", "left"),
+		ClickCount: request.GetInt("clickCount", 1),
+	}
+	if !mouseButtons[options.Button] {
+		return options, fmt.Errorf("unsupported button: %s", options.Button)
+	}
+	if options.ClickCount < 1 || options.ClickCount > 3 {
+		return options, fmt.Errorf("clickCount must be between 1 and 3")
+	}
+	for _, name := range request.GetStringSlice("modifiers", nil) {
+		modifier, err := parseModifier(name)
+		if err != nil {
+			return options, err
+		}
+		options.Modifiers = append(options.Modifiers, modifier)
+	}
+	return options, nil
+}
+
+// clickVerb describes a click in the past tense, as in "Double-clicked" or
+// "Clicked with the right button"
+func clickVerb(options browser.ClickOptions) string {
+	verb := "Clicked"
+	switch options.ClickCount {
+	case 2:
+		verb = "Double-clicked"
+	case 3:
+		verb = "Triple-clicked"
+	}
+	if options.Button != "left" {
+		verb += fmt.Sprintf(" with the %s button", options.Button)
+	}
+	if len(options.Modifiers) > 0 {
+		verb += " holding " + strings.Join(options.Modifiers, "+")
+	}
+	return verb
+}
+
+// parseDragPoint reads an end of a drag from its selector argument, a locator
+// inside the frame argument if any, or from its x and y arguments, as sourceX
+// and sourceY
+func parseDragPoint(request mcp.CallToolRequest, name string) (browser.DragPoint, error) {
+	point := browser.DragPoint{Selector: request.GetString(name, "")}
+	args := request.GetArguments()
+	for _, c := range []struct {
+		name   string
+		target **float64
+	}{{name + "X", &point.X}, {name + "Y", &point.Y}} {
+		if _, ok := args[c.name]; !ok {
+			continue
+		}
+		v := request.GetFloat(c.name, 0)
+		*c.target = &v
+	}
+
+	coordinates := point.X != nil || point.Y != nil
+	switch {
+	case point.Selector != "" && coordinates:
+		return point, fmt.Errorf("%s takes a selector or coordinates, not both", name)
+	case point.Selector == "" && (point.X == nil || point.Y == nil):
+		return point, fmt.Errorf("%s needs a selector, or %sX and %sY", name, name, name)
+	case point.Selector != "":
+		var err error
+		point.Selector, err = parseLocator(request, point.Selector)
+		return point, err
+	}
+	return point, nil
+}
+
+// describeDragPoint returns the selector or coordinates of an end of a drag
+func describeDragPoint(point browser.DragPoint) string {
+	if point.Selector != "" {
+		return point.Selector
+	}
+	return fmt.Sprintf("(%g, %g)", *point.X, *point.Y)
+}
+
+// parseOptionSelection reads the value, label or index argument of
+// browser_select_option, each a single option or an array of options
+func parseOptionSelection(request mcp.CallToolRequest) (browser.OptionSelection, error) {
+	var selection browser.OptionSelection
+	args := request.GetArguments()
+
+	var given []string
+	for _, name := range []string{"value", "label", "index"} {
+		if _, ok := args[name]; ok {
+			given = append(given, name)
+		}
+	}
+	if len(given) != 1 {
+		return selection, fmt.Errorf("exactly one of value, label or index is required")
+	}
+
+	var err error
+	switch given[0] {
+	case "value":
+		selection.Values, err = stringList(args["value"], "value")
+	case "label":
+		selection.Labels, err = stringList(args["label"], "label")
+	default:
+		selection.Indexes, err = indexList(args["index"])
+	}
+	return selection, err
+}
+
+// stringList reads an argument given as a string or an array of strings
+func stringList(value interface{}, name string) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("%s must not be empty", name)
+		}
+		list := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string or an array of strings", name)
+			}
+			list[i] = s
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%s must be a string or an array of strings", name)
+}
+
+// indexList reads the index argument, a number or an array of numbers
+func indexList(value interface{}) ([]int, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("index must not be empty")
+	}
+	indexes := make([]int, len(items))
+	for i, item := range items {
+		var n float64
+		switch v := item.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		default:
+			n = -1
+		}
+		if n < 0 || n != math.Trunc(n) {
+			return nil, fmt.Errorf("index must be a non-negative integer or an array of them")
+		}
+		indexes[i] = int(n)
+	}
+	return indexes, nil
+}
@@ -16,6 +16,9 @@ type BrowserClient interface {
 	HandleResponse(id string, data json.RawMessage, errMsg string)
 	HandleEvent(action string, data json.RawMessage)
 	WaitForConnection(ctx context.Context, timeout time.Duration) error
+	RegisterBrowser(info browser.BrowserInfo, conn browser.Connection)
+	ListBrowsers() []browser.BrowserInfo
+	ReleaseSession(ctx context.Context, sessionID string, closeTabs bool) error
 
 	// Tab management
 	ListTabs(ctx context.Context) ([]browser.Tab, error)
@@ -26,19 +29,45 @@ type BrowserClient interface {
 	// Navigation
 	Navigate(ctx context.Context, tabID int, url string, waitUntilLoad bool) (json.RawMessage, error)
 	Reload(ctx context.Context, tabID int, hardReload bool) error
+	GoBack(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error)
+	GoForward(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error)
 
 	// Interaction
 	Click(ctx context.Context, tabID int, selector string, timeout int) error
 	Type(ctx context.Context, tabID int, selector, text string, clearFirst bool, delay int) error
 	Scroll(ctx context.Context, tabID int, x, y *float64, selector, behavior string) (json.RawMessage, error)
 	WaitForElement(ctx context.Context, tabID int, selector string, timeout int, state string) (json.RawMessage, error)
+	ClickWithOptions(ctx context.Context, tabID int, selector string, timeout int, options browser.ClickOptions) error
+	PressKey(ctx context.Context, tabID int, selector string, keys []browser.KeyPress) error
+	Hover(ctx context.Context, tabID int, selector string, timeout int) error
+	Drag(ctx context.Context, tabID int, source, target browser.DragPoint) error
+	SelectOption(ctx context.Context, tabID int, selector string, selection browser.OptionSelection) ([]string, error)
+	SetChecked(ctx context.Context, tabID int, selector string, checked bool) error
 
 	// Content
 	ExecuteScript(ctx context.Context, tabID int, script string, args []interface{}) (json.RawMessage, error)
 	ExtractContent(ctx context.Context, tabID int, selector, contentType, attribute string) ([]string, error)
 	ExtractText(ctx context.Context, tabID int, selector string) (string, error)
+	ExtractMarkdown(ctx context.Context, tabID int, selector string, options browser.MarkdownOptions) (string, error)
+	ExtractTable(ctx context.Context, tabID int, selector string, index int) (*browser.Table, error)
+	ExtractRows(ctx context.Context, tabID int, rowSelector string, columns []browser.TableColumn) (*browser.Table, error)
+	ExtractStructured(ctx context.Context, tabID int, selector string, fields map[string]*browser.StructuredField) (map[string]interface{}, error)
+	FindElements(ctx context.Context, tabID int, selector string, limit int) ([]browser.Element, error)
+	GetValue(ctx context.Context, tabID int, selector string) (*browser.ElementValue, error)
 	Screenshot(ctx context.Context, tabID int, fullPage bool, selector, format string, quality int) (string, error)
 
+	// Video
+	StartVideo(ctx context.Context, tabID int, maxDuration int) (json.RawMessage, error)
+	StopVideo(ctx context.Context, tabID int, dir, name string) (*browser.VideoRecording, error)
+
+	// Network
+	AddRoute(ctx context.Context, route browser.Route) (*browser.Route, error)
+	RemoveRoute(ctx context.Context, id string) error
+	ListRoutes(ctx context.Context) ([]browser.Route, error)
+	StartNetworkCapture(ctx context.Context, tabID int) error
+	StopNetworkCapture(ctx context.Context, tabID int, dir, name string) (*browser.NetworkRecording, error)
+	NetworkSummary(ctx context.Context, tabID int, slowMs int) (*browser.NetworkSummary, error)
+
 	// Storage
 	GetCookies(ctx context.Context, url, name string) ([]browser.Cookie, error)
 	SetCookie(ctx context.Context, cookie browser.Cookie) (json.RawMessage, error)
@@ -52,6 +81,7 @@ type BrowserClient interface {
 
 	// Actionables
 	GetActionables(ctx context.Context, tabID int) ([]browser.Actionable, error)
+	ResolveActionable(ctx context.Context, tabID int, label int) (*browser.Actionable, error)
 
 	// Accessibility
 	GetAccessibilitySnapshot(ctx context.Context, tabID int, interestingOnly bool, root string) (json.RawMessage, error)
@@ -66,4 +96,15 @@ type BrowserClient interface {
 	ShowOmnibar(ctx context.Context, tabID int, barType, query string) (json.RawMessage, error)
 	StartVisualMode(ctx context.Context, tabID int, selectElement bool) (json.RawMessage, error)
 	GetPageTitle(ctx context.Context, tabID int) (string, error)
+
+	// Events
+	GetEvents(ctx context.Context, since uint64, filter browser.EventFilter, limit int) *browser.EventPage
+	SubscribeEvents(fn func(browser.Event)) (unsubscribe func())
+	GetConsole(ctx context.Context, tabID int, filter browser.ConsoleFilter, limit int) ([]browser.ConsoleMessage, error)
+
+	// Files
+	UploadFiles(ctx context.Context, tabID int, selector string, paths []string) ([]browser.UploadedFile, error)
+	ListDownloads(ctx context.Context, filter browser.DownloadFilter) ([]browser.Download, error)
+	WaitForDownload(ctx context.Context, filter browser.DownloadFilter, timeout time.Duration) (*browser.Download, error)
+	SaveDownload(ctx context.Context, id int, dir string) (string, error)
 }
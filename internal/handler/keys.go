@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/periplon/bract/internal/browser"
+)
+
+// modifierKeys are the modifier keys by lower case name, aliases included
+var modifierKeys = map[string]string{
+	"alt":     "Alt",
+	"option":  "Alt",
+	"control": "Control",
+	"ctrl":    "Control",
+	"meta":    "Meta",
+	"cmd":     "Meta",
+	"command": "Meta",
+	"super":   "Meta",
+	"win":     "Meta",
+	"shift":   "Shift",
+}
+
+// namedKeys are the keys other than characters, by lower case name, aliases
+// included, with their DOM key values
+var namedKeys = map[string]string{
+	"enter":       "Enter",
+	"return":      "Enter",
+	"tab":         "Tab",
+	"escape":      "Escape",
+	"esc":         "Escape",
+	"backspace":   "Backspace",
+	"delete":      "Delete",
+	"del":         "Delete",
+	"insert":      "Insert",
+	"ins":         "Insert",
+	"home":        "Home",
+	"end":         "End",
+	"pageup":      "PageUp",
+	"pgup":        "PageUp",
+	"pagedown":    "PageDown",
+	"pgdn":        "PageDown",
+	"arrowup":     "ArrowUp",
+	"up":          "ArrowUp",
+	"arrowdown":   "ArrowDown",
+	"down":        "ArrowDown",
+	"arrowleft":   "ArrowLeft",
+	"left":        "ArrowLeft",
+	"arrowright":  "ArrowRight",
+	"right":       "ArrowRight",
+	"space":       " ",
+	"plus":        "+",
+	"capslock":    "CapsLock",
+	"contextmenu": "ContextMenu",
+	"f1":          "F1",
+	"f2":          "F2",
+	"f3":          "F3",
+	"f4":          "F4",
+	"f5":          "F5",
+	"f6":          "F6",
+	"f7":          "F7",
+	"f8":          "F8",
+	"f9":          "F9",
+	"f10":         "F10",
+	"f11":         "F11",
+	"f12":         "F12",
+}
+
+// punctuationCodes are the physical keys of punctuation characters on a US
+// keyboard
+var punctuationCodes = map[string]string{
+	"-":  "Minus",
+	"=":  "Equal",
+	"[":  "BracketLeft",
+	"]":  "BracketRight",
+	";":  "Semicolon",
+	"'":  "Quote",
+	",":  "Comma",
+	".":  "Period",
+	"/":  "Slash",
+	"\\": "Backslash",
+	"`":  "Backquote",
+}
+
+// parseKeys reads a key sequence: key combinations separated by spaces, as
+// in "Control+K Control+C", each a key after the modifier keys held down, as
+// in "Control+Shift+K". Keys are characters or names such as Enter, Escape,
+// ArrowDown or F5; Space and Plus name the space and plus characters.
+func parseKeys(s string) ([]browser.KeyPress, error) {
+	combinations := strings.Fields(s)
+	if len(combinations) == 0 {
+		return nil, fmt.Errorf("keys must not be empty")
+	}
+
+	keys := make([]browser.KeyPress, 0, len(combinations))
+	for _, combination := range combinations {
+		key, err := parseKeyCombination(combination)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseKeyCombination reads a key after its modifiers, as in Control+Shift+K
+func parseKeyCombination(combination string) (browser.KeyPress, error) {
+	// The plus key is the last + of the combination, as in Control++
+	rest, name := combination, ""
+	if i := strings.LastIndex(combination, "+"); i >= 0 {
+		rest, name = combination[:i], combination[i+1:]
+		if name == "" && (i == 0 || combination[i-1] == '+') {
+			rest, name = strings.TrimSuffix(rest, "+"), "+"
+		} else if rest == "" {
+			return browser.KeyPress{}, fmt.Errorf("invalid key combination: %s", combination)
+		}
+	} else {
+		rest, name = "", combination
+	}
+
+	var press browser.KeyPress
+	if rest != "" {
+		for _, part := range strings.Split(rest, "+") {
+			modifier, err := parseModifier(part)
+			if err != nil {
+				return browser.KeyPress{}, fmt.Errorf("invalid key combination %s: %v", combination, err)
+			}
+			press.Modifiers = append(press.Modifiers, modifier)
+		}
+	}
+
+	switch {
+	case name == "":
+		return browser.KeyPress{}, fmt.Errorf("invalid key combination: %s", combination)
+	case utf8.RuneCountInString(name) == 1:
+		press.Key = name
+	case namedKeys[strings.ToLower(name)] != "":
+		press.Key = namedKeys[strings.ToLower(name)]
+	case modifierKeys[strings.ToLower(name)] != "":
+		press.Key = modifierKeys[strings.ToLower(name)]
+	default:
+		return browser.KeyPress{}, fmt.Errorf("unknown key: %s", name)
+	}
+
+	// Shift turns letters to upper case, as keyboards do
+	if len(press.Key) == 1 && press.Key >= "a" && press.Key <= "z" {
+		for _, modifier := range press.Modifiers {
+			if modifier == "Shift" {
+				press.Key = strings.ToUpper(press.Key)
+			}
+		}
+	}
+	press.Code = keyCode(press.Key)
+	return press, nil
+}
+
+// parseModifier returns the DOM name of a modifier key
+func parseModifier(name string) (string, error) {
+	if modifier, ok := modifierKeys[strings.ToLower(name)]; ok {
+		return modifier, nil
+	}
+	return "", fmt.Errorf("unknown modifier: %s", name)
+}
+
+// keyCode returns the physical key of a key on a US keyboard, or an empty
+// string when it has none
+func keyCode(key string) string {
+	switch {
+	case len(key) == 1 && (key >= "a" && key <= "z" || key >= "A" && key <= "Z"):
+		return "Key" + strings.ToUpper(key)
+	case len(key) == 1 && key >= "0" && key <= "9":
+		return "Digit" + key
+	case key == " ":
+		return "Space"
+	case key == "Alt" || key == "Control" || key == "Meta" || key == "Shift":
+		return key + "Left"
+	case punctuationCodes[key] != "":
+		return punctuationCodes[key]
+	case utf8.RuneCountInString(key) > 1:
+		// Named keys, such as Enter or F5, are their own code
+		return key
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/periplon/bract/internal/browser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	for s, expected := range map[string][]browser.KeyPress{
+		"Enter":           {{Key: "Enter", Code: "Enter"}},
+		"a":               {{Key: "a", Code: "KeyA"}},
+		"Control+Shift+k": {{Key: "K", Code: "KeyK", Modifiers: []string{"Control", "Shift"}}},
+		"ctrl+K ctrl+c":   {{Key: "K", Code: "KeyK", Modifiers: []string{"Control"}}, {Key: "c", Code: "KeyC", Modifiers: []string{"Control"}}},
+		"Cmd+Option+esc":  {{Key: "Escape", Code: "Escape", Modifiers: []string{"Meta", "Alt"}}},
+		"Shift+Tab":       {{Key: "Tab", Code: "Tab", Modifiers: []string{"Shift"}}},
+		"space Plus":      {{Key: " ", Code: "Space"}, {Key: "+"}},
+		"Control++":       {{Key: "+", Modifiers: []string{"Control"}}},
+		"+":               {{Key: "+"}},
+		"Alt+1 /":         {{Key: "1", Code: "Digit1", Modifiers: []string{"Alt"}}, {Key: "/", Code: "Slash"}},
+		"Shift":           {{Key: "Shift", Code: "ShiftLeft"}},
+		"up F5 é":         {{Key: "ArrowUp", Code: "ArrowUp"}, {Key: "F5", Code: "F5"}, {Key: "é"}},
+	} {
+		keys, err := parseKeys(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, keys, s)
+	}
+
+	for s, expected := range map[string]string{
+		"":            "keys must not be empty",
+		"Control+":    "invalid key combination: Control+",
+		"+K":          "invalid key combination: +K",
+		"Hyper+K":     "invalid key combination Hyper+K: unknown modifier: Hyper",
+		"Control+Foo": "unknown key: Foo",
+	} {
+		_, err := parseKeys(s)
+		assert.EqualError(t, err, expected, s)
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/periplon/bract/internal/browser"
+)
+
+// parseLocator validates the selector of a request, which may pierce iframes
+// and shadow roots with >>>, and prefixes it with the frame argument, the
+// iframe or shadow host the selector is in, itself a selector or a chain of
+// them. It returns the locator as a selector chain; an empty selector is
+// returned as is.
+func parseLocator(request mcp.CallToolRequest, selector string) (string, error) {
+	frame := request.GetString("frame", "")
+	switch {
+	case selector == "" && frame != "":
+		return "", fmt.Errorf("frame needs a selector")
+	case selector == "":
+		return "", nil
+	case frame != "":
+		selector = frame + " " + browser.LocatorSeparator + " " + selector
+	}
+
+	locator, err := browser.ParseLocator(selector)
+	if err != nil {
+		return "", err
+	}
+	return locator.String(), nil
+}
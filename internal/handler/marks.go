@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+
+	"github.com/periplon/bract/internal/browser"
+)
+
+// markedActionable is an actionable listed with a marked screenshot
+type markedActionable struct {
+	Label       int    `json:"label"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Selector    string `json:"selector"`
+	Marked      bool   `json:"marked"` // whether its box is drawn on the screenshot
+}
+
+// markColors are the colors of the marks, used in turn
+var markColors = []color.RGBA{
+	{R: 230, G: 25, B: 75, A: 255},
+	{R: 0, G: 130, B: 200, A: 255},
+	{R: 60, G: 150, B: 40, A: 255},
+	{R: 245, G: 130, B: 48, A: 255},
+	{R: 145, G: 30, B: 180, A: 255},
+	{R: 0, G: 128, B: 128, A: 255},
+}
+
+// digitGlyphs are 3x5 pixel bitmaps of the digits, one row per string
+var digitGlyphs = [10][5]string{
+	{"###", "#.#", "#.#", "#.#", "###"},
+	{".#.", "##.", ".#.", ".#.", "###"},
+	{"###", "..#", "###", "#..", "###"},
+	{"###", "..#", "###", "..#", "###"},
+	{"#.#", "#.#", "###", "..#", "..#"},
+	{"###", "#..", "###", "..#", "###"},
+	{"###", "#..", "###", "#.#", "###"},
+	{"###", "..#", "..#", "..#", "..#"},
+	{"###", "#.#", "###", "#.#", "###"},
+	{"###", "#.#", "###", "..#", "###"},
+}
+
+// drawMark outlines a box on the image and tags it with its label, above the
+// box or inside it when there is no room above. Negative labels, which have
+// no glyphs, leave the box untagged. Scale is the number of image pixels per
+// CSS pixel.
+func drawMark(img *image.RGBA, box image.Rectangle, label int, c color.RGBA, scale float64) {
+	box = box.Intersect(img.Bounds())
+	if box.Empty() {
+		return
+	}
+
+	// Outline
+	border := max(1, int(math.Round(2*scale)))
+	uniform := &image.Uniform{C: c}
+	for _, edge := range []image.Rectangle{
+		image.Rect(box.Min.X, box.Min.Y, box.Max.X, box.Min.Y+border),
+		image.Rect(box.Min.X, box.Max.Y-border, box.Max.X, box.Max.Y),
+		image.Rect(box.Min.X, box.Min.Y, box.Min.X+border, box.Max.Y),
+		image.Rect(box.Max.X-border, box.Min.Y, box.Max.X, box.Max.Y),
+	} {
+		draw.Draw(img, edge.Intersect(box), uniform, image.Point{}, draw.Src)
+	}
+
+	// Label tag: white digits on the mark color
+	if label < 0 {
+		return
+	}
+	digits := strconv.Itoa(label)
+	dot := max(2, int(math.Round(2*scale)))
+	width := len(digits)*4*dot + dot
+	height := 7 * dot
+	tag := image.Rect(box.Min.X, box.Min.Y-height, box.Min.X+width, box.Min.Y)
+	if tag.Min.Y < img.Bounds().Min.Y {
+		tag = tag.Add(image.Pt(0, height))
+	}
+	if tag.Max.X > img.Bounds().Max.X {
+		tag = tag.Sub(image.Pt(tag.Max.X-img.Bounds().Max.X, 0))
+	}
+	draw.Draw(img, tag, uniform, image.Point{}, draw.Src)
+
+	white := &image.Uniform{C: color.White}
+	for i, d := range digits {
+		glyph := digitGlyphs[d-'0']
+		x0 := tag.Min.X + dot + i*4*dot
+		for row, line := range glyph {
+			for col, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+				x, y := x0+col*dot, tag.Min.Y+dot+row*dot
+				draw.Draw(img, image.Rect(x, y, x+dot, y+dot), white, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
+
+// markBox returns the box of an actionable in screenshot pixels, or false
+// when it has none or is out of the image
+func markBox(box *browser.Rect, scale float64, bounds image.Rectangle) (image.Rectangle, bool) {
+	if box == nil || box.Width <= 0 || box.Height <= 0 {
+		return image.Rectangle{}, false
+	}
+	r := pixelRect(*box, 0, 0, scale)
+	return r, r.Overlaps(bounds)
+}
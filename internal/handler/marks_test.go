@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/periplon/bract/internal/browser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrawMark(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	red := markColors[0]
+
+	img := solidImage(100, 100, white)
+	drawMark(img, image.Rect(20, 40, 60, 60), 7, red, 1)
+
+	// The outline is 2 pixels wide with the inside left alone
+	assert.Equal(t, red, img.RGBAAt(20, 50))
+	assert.Equal(t, red, img.RGBAAt(59, 50))
+	assert.Equal(t, red, img.RGBAAt(40, 59))
+	assert.Equal(t, white, img.RGBAAt(40, 50))
+
+	// The tag sits above the box, with white digits on the mark color
+	assert.Equal(t, red, img.RGBAAt(20, 26))
+	assert.Equal(t, white, img.RGBAAt(22, 28), "top of the 7")
+	assert.Equal(t, white, img.RGBAAt(40, 30), "right of the tag")
+}
+
+func TestDrawMark_TagInsideAtEdges(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	blue := markColors[1]
+
+	// No room above the box nor right of it: the tag moves inside and left
+	img := solidImage(100, 100, white)
+	drawMark(img, image.Rect(95, 0, 100, 30), 12, blue, 1)
+
+	assert.Equal(t, blue, img.RGBAAt(99, 13), "tag bottom edge")
+	assert.Equal(t, blue, img.RGBAAt(82, 0), "tag shifted left of the box")
+	assert.Equal(t, white, img.RGBAAt(82, 14))
+}
+
+func TestDrawMark_NegativeLabel(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	red := markColors[0]
+
+	// The box is outlined but left without a tag
+	img := solidImage(100, 100, white)
+	assert.NotPanics(t, func() { drawMark(img, image.Rect(20, 40, 60, 60), -1, red, 1) })
+	assert.Equal(t, red, img.RGBAAt(20, 50))
+	assert.Equal(t, white, img.RGBAAt(20, 26), "no tag above the box")
+}
+
+func TestMarkBox(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 100)
+
+	r, ok := markBox(&browser.Rect{X: 10, Y: 5, Width: 20, Height: 10}, 2, bounds)
+	assert.True(t, ok)
+	assert.Equal(t, image.Rect(20, 10, 60, 30), r)
+
+	_, ok = markBox(nil, 1, bounds)
+	assert.False(t, ok, "no box")
+	_, ok = markBox(&browser.Rect{X: 10, Y: 5}, 1, bounds)
+	assert.False(t, ok, "empty box")
+	_, ok = markBox(&browser.Rect{X: 10, Y: 150, Width: 20, Height: 10}, 1, bounds)
+	assert.False(t, ok, "below the viewport")
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"path/filepath"
+)
+
+// DefaultScreenshotMaxBytes is the size screenshots returned inline are
+// downscaled to fit in unless configured otherwise
+const DefaultScreenshotMaxBytes = 1 << 20
+
+// screenshotFormats maps the screenshot formats to their MIME types
+var screenshotFormats = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+}
+
+// screenshotExtensions maps MIME types to file extensions
+var screenshotExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+// screenshotImage is an encoded screenshot
+type screenshotImage struct {
+	MIMEType string
+	Data     []byte
+	Width    int
+	Height   int
+}
+
+// screenshotPath returns the file a screenshot saved under name is written
+// to, refusing names that leave the screenshot directory
+func screenshotPath(dir, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("savePath must be a relative path inside the screenshot directory: %q", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// fitScreenshot converts a screenshot to mimeType and downscales it until it
+// encodes to at most maxBytes. A maxBytes of 0 keeps the original size. Quality
+// applies to JPEG images.
+func fitScreenshot(data []byte, mimeType string, quality, maxBytes int) (*screenshotImage, error) {
+	cfg, srcFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if screenshotFormats[srcFormat] == mimeType && (maxBytes <= 0 || len(data) <= maxBytes) {
+		return &screenshotImage{MIMEType: mimeType, Data: data, Width: cfg.Width, Height: cfg.Height}, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	img := toRGBA(src)
+
+	shot := &screenshotImage{MIMEType: mimeType, Width: cfg.Width, Height: cfg.Height}
+	for {
+		scaled := img
+		if shot.Width != cfg.Width || shot.Height != cfg.Height {
+			scaled = scaleImage(img, shot.Width, shot.Height)
+		}
+		if shot.Data, err = encodeImage(scaled, mimeType, quality); err != nil {
+			return nil, err
+		}
+		if maxBytes <= 0 || len(shot.Data) <= maxBytes || (shot.Width == 1 && shot.Height == 1) {
+			return shot, nil
+		}
+
+		// The encoded size grows with the area, so shrink both sides by the
+		// square root of the overshoot, with some margin
+		factor := math.Min(0.9, math.Sqrt(float64(maxBytes)/float64(len(shot.Data)))*0.95)
+		shot.Width = max(1, int(float64(shot.Width)*factor))
+		shot.Height = max(1, int(float64(shot.Height)*factor))
+	}
+}
+
+// encodeImage encodes an image as PNG or JPEG
+func encodeImage(img image.Image, mimeType string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	switch mimeType {
+	case "image/png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode PNG: %w", err)
+		}
+	case "image/jpeg":
+		if quality <= 0 || quality > 100 {
+			quality = jpeg.DefaultQuality
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image type: %s", mimeType)
+	}
+	return buf.Bytes(), nil
+}
+
+// toRGBA returns the image as an RGBA image with bounds starting at 0,0
+func toRGBA(src image.Image) *image.RGBA {
+	if img, ok := src.(*image.RGBA); ok && img.Bounds().Min == (image.Point{}) {
+		return img
+	}
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	return img
+}
+
+// scaleImage resizes an image to width x height, averaging the source pixels
+// each destination pixel covers
+func scaleImage(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			o := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[o+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
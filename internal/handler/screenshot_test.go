@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noisePNG encodes a PNG of random pixels, which compresses badly
+func noisePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rng.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestScreenshotPath(t *testing.T) {
+	path, err := screenshotPath("shots", "home/../checkout/cart.png")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("shots", "checkout", "cart.png"), path)
+
+	for _, name := range []string{"/tmp/cart.png", "../cart.png", "checkout/../../cart.png"} {
+		_, err := screenshotPath("shots", name)
+		assert.ErrorContains(t, err, "savePath must be a relative path inside the screenshot directory", name)
+	}
+}
+
+func TestFitScreenshot(t *testing.T) {
+	data := noisePNG(t, 400, 300)
+
+	t.Run("kept as is when small enough", func(t *testing.T) {
+		shot, err := fitScreenshot(data, "image/png", 90, len(data))
+		require.NoError(t, err)
+		assert.Equal(t, data, shot.Data)
+		assert.Equal(t, 400, shot.Width)
+		assert.Equal(t, 300, shot.Height)
+	})
+
+	t.Run("downscaled to fit", func(t *testing.T) {
+		shot, err := fitScreenshot(data, "image/png", 90, len(data)/4)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(shot.Data), len(data)/4)
+		assert.Less(t, shot.Width, 400)
+		assert.InDelta(t, 4.0/3, float64(shot.Width)/float64(shot.Height), 0.05, "the aspect ratio is kept")
+
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(shot.Data))
+		require.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, shot.Width, cfg.Width)
+	})
+
+	t.Run("converted to JPEG", func(t *testing.T) {
+		shot, err := fitScreenshot(data, "image/jpeg", 80, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", shot.MIMEType)
+		_, format, err := image.DecodeConfig(bytes.NewReader(shot.Data))
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, 400, shot.Width)
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		_, err := fitScreenshot([]byte("png"), "image/png", 90, 0)
+		assert.ErrorContains(t, err, "failed to decode image")
+	})
+}
+
+func TestScaleImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x%2 == 0 {
+				src.Set(x, y, color.RGBA{R: 200, A: 255})
+			} else {
+				src.Set(x, y, color.RGBA{B: 100, A: 255})
+			}
+		}
+	}
+
+	dst := scaleImage(src, 2, 1)
+	assert.Equal(t, color.RGBA{R: 100, B: 50, A: 255}, dst.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{R: 100, B: 50, A: 255}, dst.RGBAAt(1, 0))
+}
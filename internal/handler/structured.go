@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/periplon/bract/internal/browser"
+)
+
+// structuredTypes are the value types of browser_extract_structured fields
+var structuredTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"date":    true,
+	"object":  true,
+}
+
+// structuredProperties are the properties of a field given as an object
+var structuredProperties = map[string]bool{
+	"selector":  true,
+	"attribute": true,
+	"type":      true,
+	"multiple":  true,
+	"optional":  true,
+	"fields":    true,
+}
+
+// parseStructuredSchema reads the schema argument: an object of field names
+// to fields. A field is a selector, which may end with @attribute as table
+// columns do, or an object of selector, attribute, type, multiple, optional
+// and fields. Fields with fields are objects.
+func parseStructuredSchema(value interface{}) (map[string]*browser.StructuredField, error) {
+	schema, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema must be an object of fields")
+	}
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("schema must not be empty")
+	}
+	return parseStructuredFields(schema, "")
+}
+
+// parseStructuredFields reads the fields of an object at path
+func parseStructuredFields(schema map[string]interface{}, path string) (map[string]*browser.StructuredField, error) {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make(map[string]*browser.StructuredField, len(schema))
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("fields need a name")
+		}
+		field, err := parseStructuredField(schema[name], joinFieldPath(path, name))
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = field
+	}
+	return fields, nil
+}
+
+// parseStructuredField reads the field at path
+func parseStructuredField(value interface{}, path string) (*browser.StructuredField, error) {
+	field := &browser.StructuredField{}
+	switch v := value.(type) {
+	case string:
+		field.Selector, field.Attribute = splitAttribute(v)
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !structuredProperties[name] {
+				return nil, fmt.Errorf("field %s: unknown property %s", path, name)
+			}
+		}
+
+		var ok bool
+		for _, s := range []struct {
+			name   string
+			target *string
+		}{{"selector", &field.Selector}, {"attribute", &field.Attribute}, {"type", &field.Type}} {
+			if v[s.name] == nil {
+				continue
+			}
+			if *s.target, ok = v[s.name].(string); !ok {
+				return nil, fmt.Errorf("field %s: %s must be a string", path, s.name)
+			}
+		}
+		for _, b := range []struct {
+			name   string
+			target *bool
+		}{{"multiple", &field.Multiple}, {"optional", &field.Optional}} {
+			if v[b.name] == nil {
+				continue
+			}
+			if *b.target, ok = v[b.name].(bool); !ok {
+				return nil, fmt.Errorf("field %s: %s must be a boolean", path, b.name)
+			}
+		}
+		if field.Attribute == "" {
+			field.Selector, field.Attribute = splitAttribute(field.Selector)
+		}
+
+		if v["fields"] != nil {
+			nested, ok := v["fields"].(map[string]interface{})
+			if !ok || len(nested) == 0 {
+				return nil, fmt.Errorf("field %s: fields must be an object of fields", path)
+			}
+			fields, err := parseStructuredFields(nested, path)
+			if err != nil {
+				return nil, err
+			}
+			field.Fields = fields
+		}
+	default:
+		return nil, fmt.Errorf("field %s must be a selector or an object", path)
+	}
+
+	if field.Type == "" {
+		field.Type = "string"
+		if field.Fields != nil {
+			field.Type = "object"
+		}
+	}
+	switch {
+	case !structuredTypes[field.Type]:
+		return nil, fmt.Errorf("field %s: unknown type %s", path, field.Type)
+	case field.Type == "object" && field.Fields == nil:
+		return nil, fmt.Errorf("field %s: objects need fields", path)
+	case field.Type != "object" && field.Fields != nil:
+		return nil, fmt.Errorf("field %s: only objects have fields", path)
+	case field.Type == "object" && field.Attribute != "":
+		return nil, fmt.Errorf("field %s: objects have no attribute", path)
+	}
+	return field, nil
+}
+
+// joinFieldPath returns the path of a field of the object at path
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// structuredResult is the document read with a schema, and the errors of the
+// fields that could not be read, by path
+type structuredResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors map[string]string      `json:"errors,omitempty"`
+}
+
+// readStructured converts the values read from the page to the types of the
+// schema. Fields that match nothing, or whose values do not read as their
+// type, are null, or empty lists for multiple fields, and have an error
+// unless they are optional.
+func readStructured(fields map[string]*browser.StructuredField, data map[string]interface{}) *structuredResult {
+	result := &structuredResult{Errors: map[string]string{}}
+	result.Data = result.object("", fields, data)
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result
+}
+
+// object reads the fields of the object at path
+func (r *structuredResult) object(path string, fields map[string]*browser.StructuredField, data map[string]interface{}) map[string]interface{} {
+	doc := make(map[string]interface{}, len(fields))
+	for name, field := range fields {
+		fieldPath := joinFieldPath(path, name)
+		value, ok := data[name]
+		if !field.Multiple {
+			if !ok {
+				doc[name] = nil
+				r.fail(fieldPath, field, fmt.Sprintf("no element matches %s", field.Selector))
+				continue
+			}
+			doc[name] = r.value(fieldPath, field, value)
+			continue
+		}
+
+		items, _ := value.([]interface{})
+		if len(items) == 0 {
+			r.fail(fieldPath, field, fmt.Sprintf("no element matches %s", field.Selector))
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = r.value(fmt.Sprintf("%s[%d]", fieldPath, i), field, item)
+		}
+		doc[name] = list
+	}
+	return doc
+}
+
+// value converts a value read from the page to the type of its field
+func (r *structuredResult) value(path string, field *browser.StructuredField, value interface{}) interface{} {
+	if field.Type == "object" {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			r.fail(path, field, "expected an element, got a value")
+			return nil
+		}
+		return r.object(path, field.Fields, data)
+	}
+
+	if value == nil {
+		// The element has no such attribute, which is false for boolean
+		// attributes such as checked or disabled
+		if field.Type == "boolean" {
+			return false
+		}
+		r.fail(path, field, fmt.Sprintf("the element has no %s attribute", field.Attribute))
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		r.fail(path, field, fmt.Sprintf("expected a value, got %v", value))
+		return nil
+	}
+	s = strings.TrimSpace(s)
+
+	switch field.Type {
+	case "number":
+		if n, ok := parseNumber(s); ok {
+			return n
+		}
+	case "integer":
+		if n, ok := parseNumber(s); ok && n == math.Trunc(n) {
+			return int64(n)
+		}
+	case "boolean":
+		if b, ok := parseBoolean(s, field.Attribute); ok {
+			return b
+		}
+	case "date":
+		if date, ok := parseDate(s); ok {
+			return date
+		}
+	default:
+		return s
+	}
+	article := "a"
+	if field.Type == "integer" {
+		article = "an"
+	}
+	r.fail(path, field, fmt.Sprintf("cannot read %q as %s %s", s, article, field.Type))
+	return nil
+}
+
+// fail records the error of the field at path, unless it is optional
+func (r *structuredResult) fail(path string, field *browser.StructuredField, err string) {
+	if !field.Optional {
+		r.Errors[path] = err
+	}
+}
+
+// parseBoolean reads a value as a boolean. Attributes present without a value,
+// or with their own name as in checked="checked", are true.
+func parseBoolean(s, attribute string) (bool, bool) {
+	switch s = strings.ToLower(s); {
+	case attribute != "" && (s == "" || s == strings.ToLower(attribute)):
+		return true, true
+	case s == "true" || s == "yes" || s == "on" || s == "1":
+		return true, true
+	case s == "false" || s == "no" || s == "off" || s == "0":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/periplon/bract/internal/browser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStructuredSchema(t *testing.T) {
+	fields, err := parseStructuredSchema(map[string]interface{}{
+		"title": "h1",
+		"url":   "link[rel=canonical]@href",
+		"price": map[string]interface{}{"selector": ".price", "type": "number", "optional": true},
+		"reviews": map[string]interface{}{
+			"selector": ".review",
+			"multiple": true,
+			"fields": map[string]interface{}{
+				"stars": map[string]interface{}{"selector": ".stars@data-value", "type": "integer"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*browser.StructuredField{
+		"title": {Selector: "h1", Type: "string"},
+		"url":   {Selector: "link[rel=canonical]", Attribute: "href", Type: "string"},
+		"price": {Selector: ".price", Type: "number", Optional: true},
+		"reviews": {Selector: ".review", Type: "object", Multiple: true, Fields: map[string]*browser.StructuredField{
+			"stars": {Selector: ".stars", Attribute: "data-value", Type: "integer"},
+		}},
+	}, fields)
+
+	for _, tt := range []struct {
+		value interface{}
+		err   string
+	}{
+		{"h1", "schema must be an object of fields"},
+		{map[string]interface{}{}, "schema must not be empty"},
+		{map[string]interface{}{"a": 1}, "field a must be a selector or an object"},
+		{map[string]interface{}{"a": map[string]interface{}{"selecor": "h1"}}, "field a: unknown property selecor"},
+		{map[string]interface{}{"a": map[string]interface{}{"selector": 1}}, "field a: selector must be a string"},
+		{map[string]interface{}{"a": map[string]interface{}{"multiple": "yes"}}, "field a: multiple must be a boolean"},
+		{map[string]interface{}{"a": map[string]interface{}{"type": "money"}}, "field a: unknown type money"},
+		{map[string]interface{}{"a": map[string]interface{}{"type": "object"}}, "field a: objects need fields"},
+		{map[string]interface{}{"a": map[string]interface{}{"type": "number", "fields": map[string]interface{}{"b": "p"}}}, "field a: only objects have fields"},
+		{map[string]interface{}{"a": map[string]interface{}{"selector": "a@href", "fields": map[string]interface{}{"b": "p"}}}, "field a: objects have no attribute"},
+		{map[string]interface{}{"a": map[string]interface{}{"fields": map[string]interface{}{}}}, "field a: fields must be an object of fields"},
+		{map[string]interface{}{"a": map[string]interface{}{"fields": map[string]interface{}{"b": map[string]interface{}{"type": "money"}}}}, "field a.b: unknown type money"},
+	} {
+		_, err := parseStructuredSchema(tt.value)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestReadStructured(t *testing.T) {
+	fields, err := parseStructuredSchema(map[string]interface{}{
+		"title":     "h1",
+		"subtitle":  "h2",
+		"price":     map[string]interface{}{"selector": ".price", "type": "number"},
+		"stock":     map[string]interface{}{"selector": ".stock", "type": "integer"},
+		"inStock":   map[string]interface{}{"selector": "#buy@disabled", "type": "boolean"},
+		"published": map[string]interface{}{"selector": "time", "type": "date"},
+		"image":     map[string]interface{}{"selector": "img@src"},
+		"badge":     map[string]interface{}{"selector": ".badge", "optional": true},
+		"tags":      map[string]interface{}{"selector": ".tag", "multiple": true},
+		"related":   map[string]interface{}{"selector": ".related", "multiple": true, "optional": true, "fields": map[string]interface{}{"name": "a"}},
+		"reviews": map[string]interface{}{
+			"selector": ".review",
+			"multiple": true,
+			"fields": map[string]interface{}{
+				"stars":  map[string]interface{}{"selector": "@data-stars", "type": "integer"},
+				"author": ".author",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	result := readStructured(fields, map[string]interface{}{
+		"title":     " Teapot ",
+		"price":     "$24.50",
+		"stock":     "3.5",
+		"inStock":   nil,
+		"published": "Mar 1, 2025",
+		"image":     nil,
+		"reviews": []interface{}{
+			map[string]interface{}{"stars": "5", "author": "Ann"},
+			map[string]interface{}{"stars": "great"},
+		},
+	})
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"data": {
+			"title": "Teapot",
+			"subtitle": null,
+			"price": 24.5,
+			"stock": null,
+			"inStock": false,
+			"published": "2025-03-01",
+			"image": null,
+			"badge": null,
+			"tags": [],
+			"related": [],
+			"reviews": [{"stars": 5, "author": "Ann"}, {"stars": null, "author": null}]
+		},
+		"errors": {
+			"subtitle": "no element matches h2",
+			"stock": "cannot read \"3.5\" as an integer",
+			"image": "the element has no src attribute",
+			"tags": "no element matches .tag",
+			"reviews[1].stars": "cannot read \"great\" as an integer",
+			"reviews[1].author": "no element matches .author"
+		}
+	}`, string(data))
+
+	for s, expected := range map[string]bool{"": true, "disabled": true, "true": true, "No": false, "0": false} {
+		b, ok := parseBoolean(s, "disabled")
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, b, s)
+	}
+	_, ok := parseBoolean("", "")
+	assert.False(t, ok)
+}
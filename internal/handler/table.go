@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/periplon/bract/internal/browser"
+)
+
+// tableFormats are the output formats of browser_extract_table
+var tableFormats = map[string]bool{"json": true, "csv": true}
+
+// parseTableColumns reads the columns argument: an object of column names to
+// selectors, in name order, or an array of {name, selector, attribute}
+// objects, in their order. Selectors may end with @attribute to read an
+// attribute instead of the text, as in "a@href".
+func parseTableColumns(value interface{}) ([]browser.TableColumn, error) {
+	var columns []browser.TableColumn
+	switch v := value.(type) {
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			selector, ok := v[name].(string)
+			if !ok {
+				return nil, fmt.Errorf("the selector of column %s must be a string", name)
+			}
+			selector, attribute := splitAttribute(selector)
+			columns = append(columns, browser.TableColumn{Name: name, Selector: selector, Attribute: attribute})
+		}
+	case []interface{}:
+		for _, item := range v {
+			var column browser.TableColumn
+			data, _ := json.Marshal(item)
+			if err := json.Unmarshal(data, &column); err != nil {
+				return nil, fmt.Errorf("columns must be {name, selector, attribute} objects: %v", item)
+			}
+			if column.Attribute == "" {
+				column.Selector, column.Attribute = splitAttribute(column.Selector)
+			}
+			columns = append(columns, column)
+		}
+	default:
+		return nil, fmt.Errorf("columns must be an object of selectors or an array of {name, selector, attribute} objects")
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("columns must not be empty")
+	}
+	seen := map[string]bool{}
+	for _, column := range columns {
+		if column.Name == "" {
+			return nil, fmt.Errorf("columns need a name")
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("duplicate column: %s", column.Name)
+		}
+		seen[column.Name] = true
+	}
+	return columns, nil
+}
+
+// attributeSuffix matches the @attribute ending of a column selector
+var attributeSuffix = regexp.MustCompile(`@([A-Za-z_:][-A-Za-z0-9_:.]*)$`)
+
+// splitAttribute splits "selector@attribute" into its parts. An @ inside an
+// attribute selector value, as in a[href^="mailto:a@"], is left alone.
+func splitAttribute(selector string) (string, string) {
+	m := attributeSuffix.FindStringSubmatchIndex(selector)
+	if m == nil || strings.Contains(selector[m[0]:], "]") {
+		return strings.TrimSpace(selector), ""
+	}
+	return strings.TrimSpace(selector[:m[0]]), selector[m[2]:m[3]]
+}
+
+// Values coerced to numbers, after dropping a currency symbol, thousands
+// separators and a percent sign. Numbers with leading zeros, such as ZIP
+// codes, are kept as text.
+var (
+	numberPattern = regexp.MustCompile(`^[-+]?[$€£¥]?(\d{1,3}(,\d{3})+|\d+)(\.\d+)?%?$`)
+	leadingZero   = regexp.MustCompile(`^[-+]?[$€£¥]?0\d`)
+)
+
+// dateLayouts are the date formats coerced to dates. Formats without a time
+// are coerced to YYYY-MM-DD, the others to RFC 3339.
+var dateLayouts = []struct {
+	layout string
+	time   bool
+}{
+	{"2006-01-02", false},
+	{time.RFC3339, true},
+	{"2006-01-02T15:04:05", true},
+	{"2006-01-02 15:04:05", true},
+	{"2006-01-02 15:04", true},
+	{"Jan 2, 2006", false},
+	{"January 2, 2006", false},
+	{"2 Jan 2006", false},
+	{"2 January 2006", false},
+	{"Mon, 2 Jan 2006", false},
+	{"Monday, January 2, 2006", false},
+}
+
+// parseNumber parses a table value as a number
+func parseNumber(s string) (float64, bool) {
+	s = strings.ReplaceAll(s, "−", "-")
+	if !numberPattern.MatchString(s) || leadingZero.MatchString(s) {
+		return 0, false
+	}
+	s = strings.NewReplacer("$", "", "€", "", "£", "", "¥", "", ",", "", "%", "").Replace(s)
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// parseDate parses a table value as a date, returned in ISO 8601
+func parseDate(s string) (string, bool) {
+	for _, l := range dateLayouts {
+		t, err := time.Parse(l.layout, s)
+		if err != nil {
+			continue
+		}
+		if l.time {
+			return t.Format(time.RFC3339), true
+		}
+		return t.Format("2006-01-02"), true
+	}
+	return "", false
+}
+
+// coerceTable converts the values of a table to JSON values. With coerce, the
+// columns whose values all read as numbers become numbers, and those whose
+// values all read as dates become ISO 8601 dates; their empty values become
+// null. Other values stay strings.
+func coerceTable(table *browser.Table, coerce bool) [][]interface{} {
+	rows := make([][]interface{}, len(table.Rows))
+	for r, row := range table.Rows {
+		rows[r] = make([]interface{}, len(row))
+		for c, value := range row {
+			rows[r][c] = value
+		}
+	}
+	if !coerce {
+		return rows
+	}
+
+	for c := range table.Columns {
+		numbers, dates, values := true, true, 0
+		for _, row := range table.Rows {
+			if row[c] == "" {
+				continue
+			}
+			values++
+			if _, ok := parseNumber(row[c]); !ok {
+				numbers = false
+			}
+			if _, ok := parseDate(row[c]); !ok {
+				dates = false
+			}
+		}
+		if values == 0 || (!numbers && !dates) {
+			continue
+		}
+		for r, row := range table.Rows {
+			switch {
+			case row[c] == "":
+				rows[r][c] = nil
+			case numbers:
+				rows[r][c], _ = parseNumber(row[c])
+			default:
+				rows[r][c], _ = parseDate(row[c])
+			}
+		}
+	}
+	return rows
+}
+
+// tableRecord is a row of a table as a JSON object, keeping the order of the
+// columns
+type tableRecord struct {
+	columns []string
+	values  []interface{}
+}
+
+// MarshalJSON writes the record's fields in column order
+func (r tableRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// tableJSON returns a table as its columns and one record per row
+func tableJSON(table *browser.Table, rows [][]interface{}) ([]byte, error) {
+	records := make([]tableRecord, len(rows))
+	for i, values := range rows {
+		records[i] = tableRecord{columns: table.Columns, values: values}
+	}
+	return json.Marshal(struct {
+		Columns []string      `json:"columns"`
+		Rows    []tableRecord `json:"rows"`
+	}{table.Columns, records})
+}
+
+// tableCSV returns a table as CSV with a header line. Null values are empty.
+func tableCSV(table *browser.Table, rows [][]interface{}) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(table.Columns); err != nil {
+		return "", err
+	}
+	for _, values := range rows {
+		record := make([]string, len(values))
+		for i, value := range values {
+			switch v := value.(type) {
+			case string:
+				record[i] = v
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/periplon/bract/internal/browser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTableColumns(t *testing.T) {
+	columns, err := parseTableColumns(map[string]interface{}{"title": "h3", "link": "a@href", "row": ""})
+	require.NoError(t, err)
+	assert.Equal(t, []browser.TableColumn{
+		{Name: "link", Selector: "a", Attribute: "href"},
+		{Name: "row"},
+		{Name: "title", Selector: "h3"},
+	}, columns)
+
+	columns, err = parseTableColumns([]interface{}{
+		map[string]interface{}{"name": "title", "selector": "h3"},
+		map[string]interface{}{"name": "mail", "selector": `a[href^="mailto:a@b"]@href`},
+		map[string]interface{}{"name": "id", "attribute": "data-id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []browser.TableColumn{
+		{Name: "title", Selector: "h3"},
+		{Name: "mail", Selector: `a[href^="mailto:a@b"]`, Attribute: "href"},
+		{Name: "id", Attribute: "data-id"},
+	}, columns)
+
+	for _, tt := range []struct {
+		value interface{}
+		err   string
+	}{
+		{"h3", "columns must be an object of selectors or an array of {name, selector, attribute} objects"},
+		{map[string]interface{}{}, "columns must not be empty"},
+		{map[string]interface{}{"title": 3}, "the selector of column title must be a string"},
+		{[]interface{}{map[string]interface{}{"selector": "h3"}}, "columns need a name"},
+		{[]interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "a"}}, "duplicate column: a"},
+	} {
+		_, err := parseTableColumns(tt.value)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestSplitAttribute(t *testing.T) {
+	for _, tt := range []struct {
+		selector, expected, attribute string
+	}{
+		{"a@href", "a", "href"},
+		{".price @data-value", ".price", "data-value"},
+		{"@title", "", "title"},
+		{"h3", "h3", ""},
+		{`a[href="mailto:me@example.com"]`, `a[href="mailto:me@example.com"]`, ""},
+	} {
+		selector, attribute := splitAttribute(tt.selector)
+		assert.Equal(t, tt.expected, selector, tt.selector)
+		assert.Equal(t, tt.attribute, attribute, tt.selector)
+	}
+}
+
+func TestParseNumberAndDate(t *testing.T) {
+	for s, expected := range map[string]float64{"42": 42, "-3.5": -3.5, "$1,234.50": 1234.5, "45%": 45, "€0.99": 0.99, "−7": -7} {
+		n, ok := parseNumber(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, n, s)
+	}
+	for _, s := range []string{"02134", "1,23", "12 apples", "", "1.2.3"} {
+		_, ok := parseNumber(s)
+		assert.False(t, ok, s)
+	}
+
+	for s, expected := range map[string]string{
+		"2025-03-01":           "2025-03-01",
+		"Mar 1, 2025":          "2025-03-01",
+		"1 March 2025":         "2025-03-01",
+		"2025-03-01 09:30":     "2025-03-01T09:30:00Z",
+		"2025-03-01T09:30:00Z": "2025-03-01T09:30:00Z",
+	} {
+		date, ok := parseDate(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, expected, date, s)
+	}
+	_, ok := parseDate("yesterday")
+	assert.False(t, ok)
+}
+
+func TestCoerceTable(t *testing.T) {
+	table := &browser.Table{
+		Columns: []string{"item", "price", "date", "zip"},
+		Rows: [][]string{
+			{"Tea", "$2.50", "2025-03-01", "02134"},
+			{"Coffee", "", "Mar 2, 2025", "10001"},
+		},
+	}
+
+	assert.Equal(t, [][]interface{}{
+		{"Tea", "$2.50", "2025-03-01", "02134"},
+		{"Coffee", "", "Mar 2, 2025", "10001"},
+	}, coerceTable(table, false))
+
+	rows := coerceTable(table, true)
+	assert.Equal(t, [][]interface{}{
+		{"Tea", 2.5, "2025-03-01", "02134"},
+		{"Coffee", nil, "2025-03-02", "10001"},
+	}, rows)
+
+	data, err := tableJSON(table, rows)
+	require.NoError(t, err)
+	assert.Equal(t, `{"columns":["item","price","date","zip"],"rows":[`+
+		`{"item":"Tea","price":2.5,"date":"2025-03-01","zip":"02134"},`+
+		`{"item":"Coffee","price":null,"date":"2025-03-02","zip":"10001"}]}`, string(data))
+
+	csv, err := tableCSV(table, rows)
+	require.NoError(t, err)
+	assert.Equal(t, "item,price,date,zip\nTea,2.5,2025-03-01,02134\nCoffee,,2025-03-02,10001\n", csv)
+}
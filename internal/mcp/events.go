@@ -0,0 +1,220 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/periplon/bract/internal/browser"
+)
+
+// URIs of the event stream resources
+const (
+	eventsURI          = "browser://events"
+	eventsByTypeURI    = "browser://events/{type}"
+	eventsByTabURI     = "browser://tabs/{tabId}/events"
+	eventsResourceSize = 100 // events returned when reading an event resource
+)
+
+// subscriptions tracks the event resources each MCP session subscribed to
+type subscriptions struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]browser.EventFilter // session ID -> URI -> filter
+}
+
+// eventFilterForURI returns the filter selecting the events of an event resource
+func eventFilterForURI(uri string) (browser.EventFilter, error) {
+	var filter browser.EventFilter
+	switch {
+	case uri == eventsURI:
+	case strings.HasPrefix(uri, eventsURI+"/"):
+		eventType, err := browser.ParseEventType(strings.TrimPrefix(uri, eventsURI+"/"))
+		if err != nil {
+			return filter, err
+		}
+		filter.Types = []browser.EventType{eventType}
+	case strings.HasPrefix(uri, "browser://tabs/") && strings.HasSuffix(uri, "/events"):
+		tabID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(uri, "browser://tabs/"), "/events"))
+		if err != nil || tabID <= 0 {
+			return filter, fmt.Errorf("invalid tab in event resource URI: %s", uri)
+		}
+		filter.TabID = tabID
+	default:
+		return filter, fmt.Errorf("not an event resource: %s", uri)
+	}
+	return filter, nil
+}
+
+// registerEventResources registers the resources exposing the browser event
+// stream. Clients can read them, or subscribe to them with the
+// browser_subscribe_events tool to be notified of new events.
+func (s *Server) registerEventResources() {
+	s.mcpServer.AddResource(
+		mcp.NewResource(eventsURI, "Browser events",
+			mcp.WithResourceDescription("Recent events of all tabs: tabs created, updated and closed, navigations, page loads, console messages, dialogs, downloads and captured network requests"),
+			mcp.WithMIMEType("application/json"),
+		),
+		s.readEventResource,
+	)
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(eventsByTypeURI, "Browser events by type",
+			mcp.WithTemplateDescription("Recent events of one type, such as console or download"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		s.readEventResource,
+	)
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(eventsByTabURI, "Tab events",
+			mcp.WithTemplateDescription("Recent events of one tab"),
+			mcp.WithTemplateMIMEType("application/json"),
+		),
+		s.readEventResource,
+	)
+
+	s.handler.SubscribeEvents(s.notifyEvent)
+}
+
+// readEventResource returns the most recent events of an event resource
+func (s *Server) readEventResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	filter, err := eventFilterForURI(request.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		ctx = browser.WithSessionID(ctx, session.SessionID())
+	}
+
+	// Read everything kept, then keep the tail
+	page := s.handler.Events(ctx, 0, filter, 0)
+	if len(page.Events) > eventsResourceSize {
+		page.Events = page.Events[len(page.Events)-eventsResourceSize:]
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
+
+// notifyEvent sends a resource updated notification, carrying the event, to
+// every session subscribed to a resource the event belongs to
+func (s *Server) notifyEvent(event browser.Event) {
+	type notification struct {
+		sessionID string
+		uri       string
+	}
+
+	s.subscriptions.mu.Lock()
+	var notifications []notification
+	for sessionID, uris := range s.subscriptions.sessions {
+		for uri, filter := range uris {
+			filter.SessionID = sessionID
+			if filter.Matches(event) {
+				notifications = append(notifications, notification{sessionID, uri})
+			}
+		}
+	}
+	s.subscriptions.mu.Unlock()
+
+	for _, n := range notifications {
+		err := s.mcpServer.SendNotificationToSpecificClient(n.sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{
+			"uri":   n.uri,
+			"event": event,
+		})
+		// Streamable HTTP sessions are only registered while a stream is open
+		if err != nil && !errors.Is(err, server.ErrSessionNotFound) {
+			log.Printf("Failed to notify session %s of %s: %v", n.sessionID, n.uri, err)
+		}
+	}
+}
+
+// subscribe records a session's subscription to an event resource
+func (s *Server) subscribe(sessionID, uri string, filter browser.EventFilter) {
+	s.subscriptions.mu.Lock()
+	defer s.subscriptions.mu.Unlock()
+
+	if s.subscriptions.sessions == nil {
+		s.subscriptions.sessions = make(map[string]map[string]browser.EventFilter)
+	}
+	uris, ok := s.subscriptions.sessions[sessionID]
+	if !ok {
+		uris = make(map[string]browser.EventFilter)
+		s.subscriptions.sessions[sessionID] = uris
+	}
+	uris[uri] = filter
+}
+
+// unsubscribe removes a session's subscription to uri, or all of its
+// subscriptions when uri is empty
+func (s *Server) unsubscribe(sessionID, uri string) {
+	s.subscriptions.mu.Lock()
+	defer s.subscriptions.mu.Unlock()
+
+	if uri == "" {
+		delete(s.subscriptions.sessions, sessionID)
+		return
+	}
+	delete(s.subscriptions.sessions[sessionID], uri)
+}
+
+// registerSubscriptionTools registers the tools subscribing to the event
+// resources. mcp-go does not dispatch resources/subscribe, so clients
+// subscribe with a tool call, which works the same on every transport.
+func (s *Server) registerSubscriptionTools() {
+	subscribeTool := mcp.NewTool("browser_subscribe_events",
+		mcp.WithDescription("Subscribe to an event resource. Every new event of the resource is pushed as a notifications/resources/updated notification carrying the resource uri and the event."),
+		mcp.WithString("uri",
+			mcp.Required(),
+			mcp.Description("Event resource: browser://events, browser://events/{type} or browser://tabs/{tabId}/events"),
+		),
+	)
+	s.addTool(subscribeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return mcp.NewToolResultError("subscribing needs an MCP session"), nil
+		}
+		uri, err := request.RequireString("uri")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filter, err := eventFilterForURI(uri)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		s.subscribe(session.SessionID(), uri, filter)
+		return mcp.NewToolResultText(fmt.Sprintf("Subscribed to %s", uri)), nil
+	})
+
+	unsubscribeTool := mcp.NewTool("browser_unsubscribe_events",
+		mcp.WithDescription("Stop the notifications of an event resource subscribed to with browser_subscribe_events"),
+		mcp.WithString("uri",
+			mcp.Description("Event resource to unsubscribe from (defaults to all of them)"),
+		),
+	)
+	s.addTool(unsubscribeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return mcp.NewToolResultError("unsubscribing needs an MCP session"), nil
+		}
+		uri := request.GetString("uri", "")
+		s.unsubscribe(session.SessionID(), uri)
+		if uri == "" {
+			return mcp.NewToolResultText("Unsubscribed from all event resources"), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Unsubscribed from %s", uri)), nil
+	})
+}
@@ -2,48 +2,209 @@ package mcp
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/periplon/bract/internal/browser"
+	"github.com/periplon/bract/internal/config"
 	"github.com/periplon/bract/internal/handler"
+	"github.com/periplon/bract/internal/recorder"
 )
 
 // Server wraps the MCP server with browser automation capabilities
 type Server struct {
-	mcpServer *server.MCPServer
-	handler   *handler.BrowserHandler
+	mcpServer        *server.MCPServer
+	handler          *handler.BrowserHandler
+	closeSessionTabs bool
+	// releaseOnUnregister is false for streamable HTTP, whose sessions are
+	// registered per GET stream and only end with an explicit DELETE
+	releaseOnUnregister bool
+	// sessionIdleTimeout releases streamable HTTP sessions that stop sending
+	// requests without sending DELETE
+	sessionIdleTimeout time.Duration
+	// trackSessions is true for streamable HTTP, whose sessions are tracked in
+	// httpSessions once they are initialized
+	trackSessions  bool
+	httpSessionsMu sync.Mutex
+	httpSessions   map[string]*httpSession
+	recorder       *recorder.Recorder
+	subscriptions  subscriptions
 }
 
 // NewServer creates a new MCP server with browser automation tools
 func NewServer(name, version string, h *handler.BrowserHandler) *Server {
-	// Create MCP server with tool capabilities
-	mcpServer := server.NewMCPServer(
+	s := &Server{
+		handler:             h,
+		releaseOnUnregister: true,
+		httpSessions:        make(map[string]*httpSession),
+	}
+
+	// Release the tabs of sessions that disconnect, and track the streamable
+	// HTTP sessions the server issued
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		if s.releaseOnUnregister {
+			s.releaseSession(ctx, session.SessionID())
+		}
+	})
+	hooks.AddAfterInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest, result *mcp.InitializeResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil && s.trackSessions {
+			s.acceptHTTPSession(session.SessionID())
+		}
+	})
+
+	// Create MCP server with tool and resource capabilities
+	s.mcpServer = server.NewMCPServer(
 		name,
 		version,
 		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(false, false),
 		server.WithRecovery(),
+		server.WithHooks(hooks),
 	)
 
-	s := &Server{
-		mcpServer: mcpServer,
-		handler:   h,
-	}
-
 	// Register all browser automation tools
 	s.registerTools()
+	s.registerEventResources()
 
 	return s
 }
 
-// Start starts the MCP server using stdio transport
-func (s *Server) Start() error {
-	return server.ServeStdio(s.mcpServer)
+// SetCloseSessionTabs sets whether a disconnecting session's tabs are closed
+// rather than released for other sessions
+func (s *Server) SetCloseSessionTabs(closeTabs bool) {
+	s.closeSessionTabs = closeTabs
+}
+
+// SetRecorder records every tool call into a replayable DSL script
+func (s *Server) SetRecorder(r *recorder.Recorder) {
+	s.recorder = r
+}
+
+// releaseSession releases the tabs owned by a disconnected session and drops
+// its subscriptions
+func (s *Server) releaseSession(ctx context.Context, sessionID string) {
+	s.unsubscribe(sessionID, "")
+	if err := s.handler.ReleaseSession(ctx, sessionID, s.closeSessionTabs); err != nil {
+		log.Printf("Failed to release tabs of session %s: %v", sessionID, err)
+	}
+}
+
+// Start starts the MCP server using the transport selected in the config
+func (s *Server) Start(ctx context.Context, cfg config.ServerConfig) error {
+	switch cfg.Transport {
+	case "", config.TransportStdio:
+		return server.ServeStdio(s.mcpServer)
+	case config.TransportSSE, config.TransportStreamableHTTP:
+		return s.serveHTTP(ctx, cfg)
+	default:
+		return fmt.Errorf("unknown MCP transport: %s", cfg.Transport)
+	}
+}
+
+// serveHTTP serves the MCP server over SSE or streamable HTTP
+func (s *Server) serveHTTP(ctx context.Context, cfg config.ServerConfig) error {
+	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+
+	h, err := s.HTTPHandler(cfg.Transport, cfg.PublicURL)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: h,
+	}
+
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	if cfg.Transport == config.TransportStreamableHTTP {
+		go s.expireIdleSessions(ctx)
+	}
+
+	log.Printf("MCP server listening on http://%s (%s transport)", addr, cfg.Transport)
+	err = httpServer.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
 }
 
+// HTTPHandler returns the HTTP handler for the sse or http transport. The SSE
+// transport serves /sse and /message, the streamable HTTP transport serves /mcp.
+// SSE clients are told to post their messages to baseURL, the URL they reach
+// the server at, or to a path relative to /sse when it is empty.
+func (s *Server) HTTPHandler(transport, baseURL string) (http.Handler, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", handleHealth)
+
+	switch transport {
+	case config.TransportSSE:
+		sseServer := server.NewSSEServer(s.mcpServer, server.WithBaseURL(baseURL))
+		mux.Handle("/sse", sseServer.SSEHandler())
+		mux.Handle("/message", sseServer.MessageHandler())
+	case config.TransportStreamableHTTP:
+		s.releaseOnUnregister = false
+		s.trackSessions = true
+		streamableServer := server.NewStreamableHTTPServer(s.mcpServer)
+		mux.Handle("/mcp", s.trackHTTPSessions(streamableServer))
+	default:
+		return nil, fmt.Errorf("transport %s is not served over HTTP", transport)
+	}
+
+	return mux, nil
+}
+
+// handleHealth provides a health check endpoint
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+// addTool registers a tool with the optional browserId argument, which routes
+// the call to a specific connected browser
+func (s *Server) addTool(tool mcp.Tool, h server.ToolHandlerFunc) {
+	mcp.WithString("browserId",
+		mcp.Description("ID of the browser to target (defaults to the most recently connected browser)"),
+	)(&tool)
+
+	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if browserID := request.GetString("browserId", ""); browserID != "" {
+			ctx = browser.WithBrowserID(ctx, browserID)
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			ctx = browser.WithSessionID(ctx, session.SessionID())
+		}
+
+		result, err := h(ctx, request)
+		if s.recorder != nil {
+			if recErr := s.recorder.Record(tool.Name, request.GetArguments(), result, err); recErr != nil {
+				log.Printf("Failed to record %s: %v", tool.Name, recErr)
+			}
+		}
+		return result, err
+	})
+}
+
+// Tools taking a selector for an element that may be inside iframes or shadow
+// roots document the locator syntax with these
+const (
+	locatorHint      = "; pierce iframes and open shadow roots with >>>, as in iframe#editor >>> .toolbar button"
+	frameDescription = "Selector of the iframe or open shadow host the selector is in, or a >>> chain of them for nested ones (defaults to the page)"
+)
+
 // registerTools registers all browser automation tools
 func (s *Server) registerTools() {
 	// Connection Tools
 	s.registerWaitForConnectionTool()
+	s.registerListBrowsersTool()
 
 	// Tab Management Tools
 	s.registerTabListTool()
@@ -54,20 +215,51 @@ func (s *Server) registerTools() {
 	// Navigation Tools
 	s.registerNavigateTool()
 	s.registerReloadTool()
+	s.registerGoBackTool()
+	s.registerGoForwardTool()
 
 	// Interaction Tools
 	s.registerClickTool()
 	s.registerTypeTool()
 	s.registerScrollTool()
 	s.registerWaitForElementTool()
+	s.registerPressKeyTool()
+	s.registerHoverTool()
+	s.registerDragTool()
+	s.registerSelectOptionTool()
+	s.registerCheckTools()
 
 	// Content Tools
 	s.registerExecuteScriptTool()
 	s.registerExtractContentTool()
 	s.registerExtractTextTool()
+	s.registerExtractMarkdownTool()
+	s.registerExtractTableTool()
+	s.registerExtractStructuredTool()
 	s.registerScreenshotTool()
+	s.registerScreenshotCompareTool()
+	s.registerScreenshotMarkedTool()
 	s.registerGetActionablesTool()
+	s.registerClickActionableTool()
 	s.registerGetAccessibilitySnapshotTool()
+	s.registerFindElementsTool()
+	s.registerGetValueTool()
+
+	// Video Tools
+	s.registerStartVideoTool()
+	s.registerStopVideoTool()
+
+	// Event Tools
+	s.registerGetEventsTool()
+	s.registerGetConsoleTool()
+	s.registerSubscriptionTools()
+
+	// File Tools
+	s.registerFileTools()
+
+	// Network Tools
+	s.registerRouteTools()
+	s.registerNetworkCaptureTools()
 
 	// Storage Tools
 	s.registerCookieTools()
@@ -87,11 +279,21 @@ func (s *Server) registerWaitForConnectionTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.WaitForConnection(ctx, request)
 	})
 }
 
+func (s *Server) registerListBrowsersTool() {
+	tool := mcp.NewTool("browser_list_browsers",
+		mcp.WithDescription("List the connected browser extensions and their IDs"),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ListBrowsers(ctx, request)
+	})
+}
+
 // Tab Management Tools
 
 func (s *Server) registerTabListTool() {
@@ -99,7 +301,7 @@ func (s *Server) registerTabListTool() {
 		mcp.WithDescription("List all open browser tabs"),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ListTabs(ctx, request)
 	})
 }
@@ -115,7 +317,7 @@ func (s *Server) registerTabCreateTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.CreateTab(ctx, request)
 	})
 }
@@ -129,7 +331,7 @@ func (s *Server) registerTabCloseTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.CloseTab(ctx, request)
 	})
 }
@@ -143,7 +345,7 @@ func (s *Server) registerTabActivateTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ActivateTab(ctx, request)
 	})
 }
@@ -165,7 +367,7 @@ func (s *Server) registerNavigateTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Navigate(ctx, request)
 	})
 }
@@ -181,19 +383,73 @@ func (s *Server) registerReloadTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Reload(ctx, request)
 	})
 }
 
+func (s *Server) registerGoBackTool() {
+	tool := mcp.NewTool("browser_go_back",
+		mcp.WithDescription("Go back to the previous page in the tab's history"),
+		mcp.WithBoolean("waitUntilLoad",
+			mcp.Description("Wait for the page to fully load (default: true)"),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Maximum time to wait for the page to load in milliseconds (default: 30000)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to navigate in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.GoBack(ctx, request)
+	})
+}
+
+func (s *Server) registerGoForwardTool() {
+	tool := mcp.NewTool("browser_go_forward",
+		mcp.WithDescription("Go forward to the next page in the tab's history"),
+		mcp.WithBoolean("waitUntilLoad",
+			mcp.Description("Wait for the page to fully load (default: true)"),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Maximum time to wait for the page to load in milliseconds (default: 30000)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to navigate in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.GoForward(ctx, request)
+	})
+}
+
 // Interaction Tools
 
 func (s *Server) registerClickTool() {
 	tool := mcp.NewTool("browser_click",
-		mcp.WithDescription("Click on an element"),
+		mcp.WithDescription("Click on an element. Double-click with clickCount 2, open the context menu with button right, and hold modifier keys with modifiers, as for Control+click."),
 		mcp.WithString("selector",
 			mcp.Required(),
-			mcp.Description("CSS selector for the element to click"),
+			mcp.Description("CSS selector for the element to click"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithString("button",
+			mcp.Description("Mouse button (default: left)"),
+			mcp.Enum("left", "middle", "right"),
+		),
+		mcp.WithNumber("clickCount",
+			mcp.Description("Number of clicks, 2 for a double click (default: 1)"),
+			mcp.Min(1),
+			mcp.Max(3),
+		),
+		mcp.WithArray("modifiers",
+			mcp.Description("Modifier keys held during the click: Alt, Control, Meta or Shift"),
+			mcp.Items(map[string]any{"type": "string", "enum": []string{"Alt", "Control", "Meta", "Shift"}}),
 		),
 		mcp.WithNumber("timeout",
 			mcp.Description("Timeout in milliseconds (default: 30000)"),
@@ -203,7 +459,7 @@ func (s *Server) registerClickTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Click(ctx, request)
 	})
 }
@@ -213,7 +469,10 @@ func (s *Server) registerTypeTool() {
 		mcp.WithDescription("Type text into an input field"),
 		mcp.WithString("selector",
 			mcp.Required(),
-			mcp.Description("CSS selector for the input field"),
+			mcp.Description("CSS selector for the input field"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
 		),
 		mcp.WithString("text",
 			mcp.Required(),
@@ -230,7 +489,7 @@ func (s *Server) registerTypeTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Type(ctx, request)
 	})
 }
@@ -245,7 +504,10 @@ func (s *Server) registerScrollTool() {
 			mcp.Description("Vertical scroll position"),
 		),
 		mcp.WithString("selector",
-			mcp.Description("Element to scroll to"),
+			mcp.Description("Element to scroll to"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
 		),
 		mcp.WithString("behavior",
 			mcp.Description("Scroll behavior: auto, smooth, instant"),
@@ -256,17 +518,182 @@ func (s *Server) registerScrollTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Scroll(ctx, request)
 	})
 }
 
+func (s *Server) registerPressKeyTool() {
+	tool := mcp.NewTool("browser_press_key",
+		mcp.WithDescription("Press keys, such as Enter, or key combinations, such as Control+Shift+K, in the focused element or the element matching selector"),
+		mcp.WithString("keys",
+			mcp.Required(),
+			mcp.Description(`Key combinations separated by spaces and pressed one after the other, as in "Control+K Control+C". A combination is a key after the modifiers held down (Alt, Control, Meta, Shift, or Ctrl, Cmd and Option). Keys are characters or names: Enter, Tab, Escape, Backspace, Delete, Home, End, PageUp, PageDown, ArrowUp, ArrowDown, ArrowLeft, ArrowRight, Space, Plus, F1 to F12`),
+		),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the element to focus first (defaults to the focused element)"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to press keys in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.PressKey(ctx, request)
+	})
+}
+
+func (s *Server) registerHoverTool() {
+	tool := mcp.NewTool("browser_hover",
+		mcp.WithDescription("Move the mouse over an element, to open menus or tooltips shown on hover"),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the element to hover"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Timeout in milliseconds (default: 30000)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to hover in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.Hover(ctx, request)
+	})
+}
+
+func (s *Server) registerDragTool() {
+	tool := mcp.NewTool("browser_drag",
+		mcp.WithDescription("Drag from an element or point to another, as for drag and drop lists, sliders or canvases. Each end is a selector, whose element's center is used, or viewport coordinates."),
+		mcp.WithString("source",
+			mcp.Description("CSS selector of the element to drag"+locatorHint),
+		),
+		mcp.WithNumber("sourceX",
+			mcp.Description("Horizontal viewport coordinate to drag from, instead of source"),
+		),
+		mcp.WithNumber("sourceY",
+			mcp.Description("Vertical viewport coordinate to drag from, instead of source"),
+		),
+		mcp.WithString("target",
+			mcp.Description("CSS selector of the element to drop on"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description("Selector of the iframe or open shadow host the source and target selectors are in, or a >>> chain of them for nested ones (defaults to the page)"),
+		),
+		mcp.WithNumber("targetX",
+			mcp.Description("Horizontal viewport coordinate to drop at, instead of target"),
+		),
+		mcp.WithNumber("targetY",
+			mcp.Description("Vertical viewport coordinate to drop at, instead of target"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to drag in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.Drag(ctx, request)
+	})
+}
+
+func (s *Server) registerSelectOptionTool() {
+	// The options to select are one or more values, labels or indexes
+	oneOrMore := func(itemType string) func(map[string]any) {
+		return func(schema map[string]any) {
+			delete(schema, "type")
+			delete(schema, "items")
+			schema["anyOf"] = []map[string]any{
+				{"type": itemType},
+				{"type": "array", "items": map[string]any{"type": itemType}},
+			}
+		}
+	}
+
+	tool := mcp.NewTool("browser_select_option",
+		mcp.WithDescription("Select options of a select element by value, label or index, replacing its selection. Returns the values of the selected options."),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the select element"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithArray("value",
+			mcp.Description("Value of the option to select, or values for a multiple select"),
+			oneOrMore("string"),
+		),
+		mcp.WithArray("label",
+			mcp.Description("Visible text of the option to select, or texts for a multiple select"),
+			oneOrMore("string"),
+		),
+		mcp.WithArray("index",
+			mcp.Description("Index of the option to select, from 0, or indexes for a multiple select"),
+			oneOrMore("integer"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to select in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.SelectOption(ctx, request)
+	})
+}
+
+func (s *Server) registerCheckTools() {
+	check := mcp.NewTool("browser_check",
+		mcp.WithDescription("Check a checkbox or radio button. Fields already checked are left alone."),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the checkbox or radio button"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to check in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(check, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.Check(ctx, request)
+	})
+
+	uncheck := mcp.NewTool("browser_uncheck",
+		mcp.WithDescription("Uncheck a checkbox. Fields already unchecked are left alone."),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the checkbox"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to uncheck in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(uncheck, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.Uncheck(ctx, request)
+	})
+}
+
 func (s *Server) registerWaitForElementTool() {
 	tool := mcp.NewTool("browser_wait_for_element",
 		mcp.WithDescription("Wait for an element to appear on the page"),
 		mcp.WithString("selector",
 			mcp.Required(),
-			mcp.Description("CSS selector for the element"),
+			mcp.Description("CSS selector for the element"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
 		),
 		mcp.WithNumber("timeout",
 			mcp.Description("Timeout in milliseconds (default: 30000)"),
@@ -280,7 +707,7 @@ func (s *Server) registerWaitForElementTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.WaitForElement(ctx, request)
 	})
 }
@@ -302,7 +729,7 @@ func (s *Server) registerExecuteScriptTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ExecuteScript(ctx, request)
 	})
 }
@@ -311,7 +738,10 @@ func (s *Server) registerExtractContentTool() {
 	tool := mcp.NewTool("browser_extract_content",
 		mcp.WithDescription("Extract content from the page"),
 		mcp.WithString("selector",
-			mcp.Description("CSS selector for element(s) to extract"),
+			mcp.Description("CSS selector for element(s) to extract"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
 		),
 		mcp.WithString("type",
 			mcp.Description("Type of content to extract: text, html, attribute"),
@@ -325,7 +755,7 @@ func (s *Server) registerExtractContentTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ExtractContent(ctx, request)
 	})
 }
@@ -341,22 +771,149 @@ func (s *Server) registerExtractTextTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ExtractText(ctx, request)
 	})
 }
 
+func (s *Server) registerExtractMarkdownTool() {
+	tool := mcp.NewTool("browser_extract_markdown",
+		mcp.WithDescription("Extract content from the page as Markdown, keeping headings, links, lists, tables and code blocks. Images are replaced by their alt text."),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector for element(s) to extract (defaults to 'body')"),
+		),
+		mcp.WithBoolean("mainContent",
+			mcp.Description("Keep only the main content of the page, leaving out navigation, sidebars, footers and forms (default: false)"),
+		),
+		mcp.WithBoolean("absoluteLinks",
+			mcp.Description("Make relative links absolute against the page URL (default: true)"),
+		),
+		mcp.WithNumber("maxChars",
+			mcp.Description("Truncate the Markdown to this many characters, at a block boundary when possible (default: no limit)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to extract from (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ExtractMarkdown(ctx, request)
+	})
+}
+
+func (s *Server) registerExtractTableTool() {
+	tool := mcp.NewTool("browser_extract_table",
+		mcp.WithDescription("Extract tabular data as JSON records or CSV, from an HTML table or from repeated elements such as the cards of a list. Table headers name the columns; cells spanning several columns or rows repeat their value."),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the table, or of an element containing it"+locatorHint),
+		),
+		mcp.WithNumber("index",
+			mcp.Description("Index of the table among the elements matching selector (default: 0)"),
+		),
+		mcp.WithString("rowSelector",
+			mcp.Description("CSS selector of the repeated elements, one row each, instead of a table"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description("Selector of the iframe or open shadow host the selector or rowSelector is in, or a >>> chain of them for nested ones (defaults to the page)"),
+		),
+		mcp.WithObject("columns",
+			mcp.Description(`Columns of rowSelector rows: an object of column names to CSS selectors relative to the row, in name order, or an array of {name, selector, attribute} objects, in their order. End a selector with @attribute to read an attribute instead of the text, as in "a@href"; an empty selector is the row itself.`),
+			func(schema map[string]any) {
+				// Either an object or an array
+				delete(schema, "type")
+				delete(schema, "properties")
+				schema["anyOf"] = []map[string]any{
+					{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+					{
+						"type": "array",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"name":      map[string]any{"type": "string"},
+								"selector":  map[string]any{"type": "string"},
+								"attribute": map[string]any{"type": "string"},
+							},
+							"required": []string{"name"},
+						},
+					},
+				}
+			},
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format (default: json, records keyed by column)"),
+			mcp.Enum("json", "csv"),
+		),
+		mcp.WithBoolean("coerce",
+			mcp.Description("Convert columns whose values are all numbers, such as $1,200.50 or 45%, to numbers, and columns of dates to ISO 8601 dates (default: false)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to extract from (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ExtractTable(ctx, request)
+	})
+}
+
+func (s *Server) registerExtractStructuredTool() {
+	tool := mcp.NewTool("browser_extract_structured",
+		mcp.WithDescription(`Extract a JSON document described by a schema, in one call. Returns {"data": {...}, "errors": {...}}: the data has every field of the schema, converted to its type, and errors lists the fields that matched nothing or whose values did not read as their type, by path such as "items[2].price"; those fields are null, or empty lists.`),
+		mcp.WithObject("schema",
+			mcp.Required(),
+			mcp.Description(`Fields by name. A field is a CSS selector, ending with @attribute to read an attribute instead of the text as in "a@href", or an object of selector, attribute, type, multiple, optional and fields, as in {"title": "h1", "price": {"selector": ".price", "type": "number"}, "reviews": {"selector": ".review", "multiple": true, "fields": {"author": ".author", "stars": {"selector": ".stars@data-value", "type": "integer"}}}}`),
+			func(schema map[string]any) {
+				delete(schema, "properties")
+				field := map[string]any{
+					"anyOf": []map[string]any{
+						{"type": "string"},
+						{
+							"type": "object",
+							"properties": map[string]any{
+								"selector":  map[string]any{"type": "string", "description": "CSS selector relative to the enclosing element; the element itself when empty"},
+								"attribute": map[string]any{"type": "string", "description": "Attribute to read instead of the text"},
+								"type":      map[string]any{"type": "string", "enum": []string{"string", "number", "integer", "boolean", "date", "object"}, "description": "Value type (default: string, or object with fields)"},
+								"multiple":  map[string]any{"type": "boolean", "description": "Read every matching element as a list (default: the first)"},
+								"optional":  map[string]any{"type": "boolean", "description": "Matching nothing is not an error (default: false)"},
+								"fields":    map[string]any{"type": "object", "description": "Fields of an object, read inside the element matching selector"},
+							},
+							"additionalProperties": false,
+						},
+					},
+				}
+				schema["additionalProperties"] = field
+			},
+		),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the element the schema is read in (default: the document)"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to extract from (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ExtractStructured(ctx, request)
+	})
+}
+
 func (s *Server) registerScreenshotTool() {
 	tool := mcp.NewTool("browser_screenshot",
-		mcp.WithDescription("Take a screenshot"),
+		mcp.WithDescription("Take a screenshot. It is returned as an image, downscaled to fit in maxBytes, unless the server saves screenshots to a directory; then only the saved file's path, type and size are returned."),
 		mcp.WithBoolean("fullPage",
 			mcp.Description("Capture full page or just viewport"),
 		),
 		mcp.WithString("selector",
-			mcp.Description("CSS selector for specific element"),
+			mcp.Description("CSS selector for specific element"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
 		),
 		mcp.WithString("format",
-			mcp.Description("Image format: png, jpeg"),
+			mcp.Description("Image format: png, jpeg (defaults to png, or the savePath extension)"),
 			mcp.Enum("png", "jpeg"),
 		),
 		mcp.WithNumber("quality",
@@ -365,13 +922,85 @@ func (s *Server) registerScreenshotTool() {
 		mcp.WithNumber("tabId",
 			mcp.Description("Tab ID to capture (defaults to active tab)"),
 		),
+		mcp.WithString("savePath",
+			mcp.Description("File to save the screenshot to, relative to the server's screenshot directory (browser.screenshot_dir); refused when no directory is configured"),
+		),
+		mcp.WithNumber("maxBytes",
+			mcp.Description("Maximum size of an image returned inline; larger screenshots are downscaled (defaults to the server's screenshot_max_bytes)"),
+		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Screenshot(ctx, request)
 	})
 }
 
+func (s *Server) registerScreenshotCompareTool() {
+	tool := mcp.NewTool("browser_screenshot_compare",
+		mcp.WithDescription("Compare a screenshot with a named baseline PNG in the server's baseline directory. Returns the percentage of differing pixels and, when pixels differ, a diff image with them in red. A missing baseline is an error; create it with update."),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Baseline name, a path relative to the baseline directory (.png is added without an extension)"),
+		),
+		mcp.WithBoolean("fullPage",
+			mcp.Description("Capture full page or just viewport"),
+		),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector of the element to capture"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to capture (defaults to active tab)"),
+		),
+		mcp.WithNumber("tolerance",
+			mcp.Description("Percentage of differing pixels allowed for the comparison to pass (default: 0)"),
+		),
+		mcp.WithNumber("threshold",
+			mcp.Description("Color difference from 0 to 1 from which a pixel counts as different (default: 0.1)"),
+		),
+		mcp.WithArray("ignore",
+			mcp.Description("Regions left out of the comparison: CSS selectors, or {x, y, width, height} rectangles in screenshot pixels"),
+			mcp.Items(map[string]any{
+				"anyOf": []map[string]any{
+					{"type": "string"},
+					{
+						"type": "object",
+						"properties": map[string]any{
+							"x":      map[string]any{"type": "number"},
+							"y":      map[string]any{"type": "number"},
+							"width":  map[string]any{"type": "number"},
+							"height": map[string]any{"type": "number"},
+						},
+						"required": []string{"x", "y", "width", "height"},
+					},
+				},
+			}),
+		),
+		mcp.WithBoolean("update",
+			mcp.Description("Save the screenshot as the baseline, creating or replacing it, instead of comparing"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ScreenshotCompare(ctx, request)
+	})
+}
+
+func (s *Server) registerScreenshotMarkedTool() {
+	tool := mcp.NewTool("browser_screenshot_marked",
+		mcp.WithDescription("Take a screenshot of the viewport with a numbered box drawn over each actionable element (set-of-marks). Returns the label table as JSON, with the selector of each label, and the annotated image. Pass a label to browser_click_actionable to click its element."),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to capture (defaults to active tab)"),
+		),
+		mcp.WithNumber("maxBytes",
+			mcp.Description("Maximum size of the returned image in bytes; larger screenshots are downscaled (defaults to the server's screenshot_max_bytes)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ScreenshotMarked(ctx, request)
+	})
+}
+
 func (s *Server) registerGetActionablesTool() {
 	tool := mcp.NewTool("browser_get_actionables",
 		mcp.WithDescription("Get all actionable elements on the page (buttons, links, inputs, etc.)"),
@@ -380,11 +1009,31 @@ func (s *Server) registerGetActionablesTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.GetActionables(ctx, request)
 	})
 }
 
+func (s *Server) registerClickActionableTool() {
+	tool := mcp.NewTool("browser_click_actionable",
+		mcp.WithDescription("Click an actionable element by the label browser_get_actionables or browser_screenshot_marked listed it with. Labels apply until the page navigates."),
+		mcp.WithNumber("label",
+			mcp.Required(),
+			mcp.Description("Label number of the actionable"),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Timeout in milliseconds (default: 30000)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to click in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ClickActionable(ctx, request)
+	})
+}
+
 func (s *Server) registerGetAccessibilitySnapshotTool() {
 	tool := mcp.NewTool("browser_get_accessibility_snapshot",
 		mcp.WithDescription("Get the accessibility tree of the page for understanding page structure and elements"),
@@ -399,11 +1048,326 @@ func (s *Server) registerGetAccessibilitySnapshotTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.GetAccessibilitySnapshot(ctx, request)
 	})
 }
 
+func (s *Server) registerFindElementsTool() {
+	tool := mcp.NewTool("browser_find_elements",
+		mcp.WithDescription("Find the elements matching a selector, with their tag, text, attributes, a unique selector and bounding box"),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the elements to find"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of elements to return (default: all)"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to search in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.FindElements(ctx, request)
+	})
+}
+
+func (s *Server) registerGetValueTool() {
+	tool := mcp.NewTool("browser_get_value",
+		mcp.WithDescription("Get the current value of a form field (input, textarea, select, checkbox or radio button)"),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the form field"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to read from (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.GetValue(ctx, request)
+	})
+}
+
+// Video Tools
+
+func (s *Server) registerStartVideoTool() {
+	tool := mcp.NewTool("browser_start_video",
+		mcp.WithDescription("Start recording a video of a tab"),
+		mcp.WithNumber("maxDuration",
+			mcp.Description("Stop recording automatically after this many seconds"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to record (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.StartVideo(ctx, request)
+	})
+}
+
+func (s *Server) registerStopVideoTool() {
+	tool := mcp.NewTool("browser_stop_video",
+		mcp.WithDescription("Stop recording a tab and save the video, returning the path of the saved file"),
+		mcp.WithString("name",
+			mcp.Description("File name to save the video under in the configured video directory (browser.video_dir, or the temporary directory); defaults to a timestamped name"),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID being recorded (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.StopVideo(ctx, request)
+	})
+}
+
+// Event Tools
+
+func (s *Server) registerGetEventsTool() {
+	types := make([]string, len(browser.EventTypes))
+	for i, t := range browser.EventTypes {
+		types[i] = string(t)
+	}
+
+	tool := mcp.NewTool("browser_get_events",
+		mcp.WithDescription("Get the browser events recorded after a cursor: tabs created, updated and closed, navigations, page loads, console messages, dialogs, downloads and captured network requests. Pass the returned cursor as since to get only newer events."),
+		mcp.WithNumber("since",
+			mcp.Description("Cursor returned by a previous call; only later events are returned (default: 0, all recorded events)"),
+		),
+		mcp.WithArray("types",
+			mcp.Description("Event types to return (defaults to all types)"),
+			mcp.Items(map[string]any{"type": "string", "enum": types}),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Only return events of this tab (defaults to all tabs)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of events to return (default: 100, 0 for no limit)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.GetEvents(ctx, request)
+	})
+}
+
+func (s *Server) registerGetConsoleTool() {
+	tool := mcp.NewTool("browser_get_console",
+		mcp.WithDescription("Get the console messages and uncaught exceptions of a tab, oldest first. Use it to find out why a page or a script broke."),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID (uses active tab if not specified)"),
+		),
+		mcp.WithArray("levels",
+			mcp.Description("Levels to return (defaults to all levels); uncaught exceptions have the exception level"),
+			mcp.Items(map[string]any{"type": "string", "enum": browser.ConsoleLevels}),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only return messages logged after this RFC 3339 timestamp, such as the time of the last message returned"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of messages to return, the most recent ones (default: 100, 0 for no limit)"),
+		),
+	)
+
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.GetConsole(ctx, request)
+	})
+}
+
+// File Tools
+
+func (s *Server) registerFileTools() {
+	upload := mcp.NewTool("browser_upload_file",
+		mcp.WithDescription("Set the files of a file input (input[type=file]) to local files, read by the server and streamed to the browser. Replaces the files already chosen."),
+		mcp.WithString("selector",
+			mcp.Required(),
+			mcp.Description("CSS selector for the file input"+locatorHint),
+		),
+		mcp.WithString("frame",
+			mcp.Description(frameDescription),
+		),
+		mcp.WithArray("paths",
+			mcp.Required(),
+			mcp.Description("Paths of the files to upload, on the machine of the server; several files need an input with the multiple attribute"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID to upload in (defaults to active tab)"),
+		),
+	)
+
+	s.addTool(upload, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.UploadFile(ctx, request)
+	})
+
+	list := mcp.NewTool("browser_list_downloads",
+		mcp.WithDescription("List the downloads of the browser since it connected, oldest first, with their state, file and progress"),
+		mcp.WithString("state",
+			mcp.Description("Only list downloads in this state"),
+			mcp.Enum(browser.DownloadInProgress, browser.DownloadComplete, browser.DownloadInterrupted),
+		),
+		mcp.WithString("url",
+			mcp.Description("Only list downloads whose URL contains this text"),
+		),
+	)
+
+	s.addTool(list, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.ListDownloads(ctx, request)
+	})
+
+	wait := mcp.NewTool("browser_wait_for_download",
+		mcp.WithDescription("Wait for a download to finish and return it. Without an id, returns the oldest finished download no earlier wait returned, so call it after the click that starts the download. Fails if the download is interrupted."),
+		mcp.WithNumber("id",
+			mcp.Description("ID of the download to wait for, as listed by browser_list_downloads"),
+		),
+		mcp.WithString("url",
+			mcp.Description("Only wait for downloads whose URL contains this text"),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("Timeout in milliseconds (default: 30000)"),
+		),
+		mcp.WithBoolean("copy",
+			mcp.Description("Copy the downloaded file into the configured download directory (browser.download_dir) and return its path"),
+		),
+	)
+
+	s.addTool(wait, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.WaitForDownload(ctx, request)
+	})
+}
+
+// Network Tools
+
+func (s *Server) registerRouteTools() {
+	actions := make([]string, len(browser.RouteActions))
+	for i, a := range browser.RouteActions {
+		actions[i] = string(a)
+	}
+	headers := map[string]any{"type": "string"}
+
+	addTool := mcp.NewTool("browser_route_add",
+		mcp.WithDescription("Intercept the network requests matching a URL pattern: block them, delay them, modify their headers or fulfill them with a fixture body. When several routes match a request, the most recently added one applies."),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("Glob matched against the whole request URL: * matches any characters but /, ** matches any characters (e.g. **/api/users/*)"),
+		),
+		mcp.WithString("action",
+			mcp.Required(),
+			mcp.Description("What to do with matching requests"),
+			mcp.Enum(actions...),
+		),
+		mcp.WithArray("methods",
+			mcp.Description("HTTP methods to match (defaults to all methods)"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("tabId",
+			mcp.Description("Only intercept requests of this tab (defaults to all tabs)"),
+		),
+		mcp.WithNumber("delayMs",
+			mcp.Description("Milliseconds to hold requests before letting them through (delay)"),
+		),
+		mcp.WithObject("requestHeaders",
+			mcp.Description("Headers to set on requests; an empty value removes the header (modifyHeaders)"),
+			mcp.AdditionalProperties(headers),
+		),
+		mcp.WithObject("responseHeaders",
+			mcp.Description("Headers to set on responses; an empty value removes the header (modifyHeaders, fulfill)"),
+			mcp.AdditionalProperties(headers),
+		),
+		mcp.WithNumber("status",
+			mcp.Description("Response status (fulfill, default: 200)"),
+		),
+		mcp.WithString("contentType",
+			mcp.Description("Response content type (fulfill, defaults to the type of bodyFile's extension)"),
+		),
+		mcp.WithString("body",
+			mcp.Description("Response body (fulfill)"),
+		),
+		mcp.WithBoolean("bodyBase64",
+			mcp.Description("Whether body is base64 encoded, for binary responses (default: false)"),
+		),
+		mcp.WithString("bodyFile",
+			mcp.Description("File in the configured routes directory (browser.routes_dir) to use as response body (fulfill)"),
+		),
+	)
+
+	s.addTool(addTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.RouteAdd(ctx, request)
+	})
+
+	removeTool := mcp.NewTool("browser_route_remove",
+		mcp.WithDescription("Remove a network route added with browser_route_add"),
+		mcp.WithString("id",
+			mcp.Description("ID of the route to remove"),
+		),
+		mcp.WithBoolean("all",
+			mcp.Description("Remove all routes instead (default: false)"),
+		),
+	)
+
+	s.addTool(removeTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.RouteRemove(ctx, request)
+	})
+
+	listTool := mcp.NewTool("browser_route_list",
+		mcp.WithDescription("List the network routes of the browser, oldest first"),
+	)
+
+	s.addTool(listTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.RouteList(ctx, request)
+	})
+}
+
+func (s *Server) registerNetworkCaptureTools() {
+	startTool := mcp.NewTool("browser_network_start",
+		mcp.WithDescription("Start capturing the network requests of a tab"),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID (uses active tab if not specified)"),
+		),
+	)
+
+	s.addTool(startTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.NetworkStart(ctx, request)
+	})
+
+	stopTool := mcp.NewTool("browser_network_stop",
+		mcp.WithDescription("Stop capturing the network requests of a tab and save them as a HAR 1.2 file, returning its path and a summary of the capture"),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID (uses active tab if not specified)"),
+		),
+		mcp.WithString("name",
+			mcp.Description("File name to save the HAR under in the configured HAR directory (browser.har_dir, or the temporary directory); defaults to a timestamped name"),
+		),
+	)
+
+	s.addTool(stopTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.NetworkStop(ctx, request)
+	})
+
+	summaryTool := mcp.NewTool("browser_network_summary",
+		mcp.WithDescription("Summarize the running or last network capture of a tab: request count, total bytes, failed requests and slow requests"),
+		mcp.WithNumber("tabId",
+			mcp.Description("Tab ID (uses active tab if not specified)"),
+		),
+		mcp.WithNumber("slowMs",
+			mcp.Description("Duration in milliseconds from which a request is slow (default: 1000)"),
+		),
+	)
+
+	s.addTool(summaryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return s.handler.NetworkSummary(ctx, request)
+	})
+}
+
 // Storage Tools
 
 func (s *Server) registerCookieTools() {
@@ -418,7 +1382,7 @@ func (s *Server) registerCookieTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(getCookiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(getCookiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.GetCookies(ctx, request)
 	})
 
@@ -450,7 +1414,7 @@ func (s *Server) registerCookieTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(setCookieTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(setCookieTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.SetCookie(ctx, request)
 	})
 
@@ -465,7 +1429,7 @@ func (s *Server) registerCookieTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(deleteCookiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(deleteCookiesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.DeleteCookies(ctx, request)
 	})
 }
@@ -483,7 +1447,7 @@ func (s *Server) registerStorageTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(getLocalStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(getLocalStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.GetLocalStorage(ctx, request)
 	})
 
@@ -503,7 +1467,7 @@ func (s *Server) registerStorageTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(setLocalStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(setLocalStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.SetLocalStorage(ctx, request)
 	})
 
@@ -515,7 +1479,7 @@ func (s *Server) registerStorageTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(clearLocalStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(clearLocalStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ClearLocalStorage(ctx, request)
 	})
 
@@ -531,7 +1495,7 @@ func (s *Server) registerStorageTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(getSessionStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(getSessionStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.GetSessionStorage(ctx, request)
 	})
 
@@ -551,7 +1515,7 @@ func (s *Server) registerStorageTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(setSessionStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(setSessionStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.SetSessionStorage(ctx, request)
 	})
 
@@ -563,7 +1527,7 @@ func (s *Server) registerStorageTools() {
 		),
 	)
 
-	s.mcpServer.AddTool(clearSessionStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(clearSessionStorageTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ClearSessionStorage(ctx, request)
 	})
 }
@@ -603,7 +1567,7 @@ func (s *Server) registerShowHintsTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ShowHints(ctx, request)
 	})
 }
@@ -625,7 +1589,7 @@ func (s *Server) registerClickHintTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ClickHint(ctx, request)
 	})
 }
@@ -645,7 +1609,7 @@ func (s *Server) registerSearchTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Search(ctx, request)
 	})
 }
@@ -668,7 +1632,7 @@ func (s *Server) registerFindTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.Find(ctx, request)
 	})
 }
@@ -678,7 +1642,7 @@ func (s *Server) registerClipboardReadTool() {
 		mcp.WithDescription("Read text from the system clipboard"),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ReadClipboard(ctx, request)
 	})
 }
@@ -695,7 +1659,7 @@ func (s *Server) registerClipboardWriteTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.WriteClipboard(ctx, request)
 	})
 }
@@ -716,7 +1680,7 @@ func (s *Server) registerOmnibarTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.ShowOmnibar(ctx, request)
 	})
 }
@@ -732,7 +1696,7 @@ func (s *Server) registerVisualModeTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.StartVisualMode(ctx, request)
 	})
 }
@@ -745,7 +1709,7 @@ func (s *Server) registerGetPageTitleTool() {
 		),
 	)
 
-	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return s.handler.GetPageTitle(ctx, request)
 	})
 }
@@ -1,19 +1,30 @@
 package mcp
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/periplon/bract/internal/browser"
+	"github.com/periplon/bract/internal/config"
 	"github.com/periplon/bract/internal/handler"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 // MockBrowserClient is a mock implementation of handler.BrowserClient
-type MockBrowserClient struct{}
+type MockBrowserClient struct {
+	events   *browser.EventBus
+	released chan string // sessions released, when set
+}
 
 func (m *MockBrowserClient) SetConnection(conn browser.Connection)                         {}
 func (m *MockBrowserClient) RemoveConnection(conn browser.Connection)                      {}
@@ -22,6 +33,14 @@ func (m *MockBrowserClient) HandleEvent(action string, data json.RawMessage)
 func (m *MockBrowserClient) WaitForConnection(ctx context.Context, timeout time.Duration) error {
 	return nil
 }
+func (m *MockBrowserClient) RegisterBrowser(info browser.BrowserInfo, conn browser.Connection) {}
+func (m *MockBrowserClient) ListBrowsers() []browser.BrowserInfo                               { return nil }
+func (m *MockBrowserClient) ReleaseSession(ctx context.Context, sessionID string, closeTabs bool) error {
+	if m.released != nil {
+		m.released <- sessionID
+	}
+	return nil
+}
 func (m *MockBrowserClient) ListTabs(ctx context.Context) ([]browser.Tab, error) { return nil, nil }
 func (m *MockBrowserClient) CreateTab(ctx context.Context, url string, active bool) (*browser.Tab, error) {
 	return nil, nil
@@ -36,6 +55,30 @@ func (m *MockBrowserClient) Click(ctx context.Context, tabID int, selector strin
 	return nil
 }
 
+func (m *MockBrowserClient) ClickWithOptions(ctx context.Context, tabID int, selector string, timeout int, options browser.ClickOptions) error {
+	return nil
+}
+
+func (m *MockBrowserClient) PressKey(ctx context.Context, tabID int, selector string, keys []browser.KeyPress) error {
+	return nil
+}
+
+func (m *MockBrowserClient) Hover(ctx context.Context, tabID int, selector string, timeout int) error {
+	return nil
+}
+
+func (m *MockBrowserClient) Drag(ctx context.Context, tabID int, source, target browser.DragPoint) error {
+	return nil
+}
+
+func (m *MockBrowserClient) SelectOption(ctx context.Context, tabID int, selector string, selection browser.OptionSelection) ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) SetChecked(ctx context.Context, tabID int, selector string, checked bool) error {
+	return nil
+}
+
 func (m *MockBrowserClient) Type(ctx context.Context, tabID int, selector, text string, clearFirst bool, delay int) error {
 	return nil
 }
@@ -48,6 +91,14 @@ func (m *MockBrowserClient) WaitForElement(ctx context.Context, tabID int, selec
 	return nil, nil
 }
 
+func (m *MockBrowserClient) GoBack(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) GoForward(ctx context.Context, tabID int, waitUntilLoad bool, timeout int) (json.RawMessage, error) {
+	return nil, nil
+}
+
 func (m *MockBrowserClient) ExecuteScript(ctx context.Context, tabID int, script string, args []interface{}) (json.RawMessage, error) {
 	return nil, nil
 }
@@ -60,10 +111,86 @@ func (m *MockBrowserClient) ExtractText(ctx context.Context, tabID int, selector
 	return "", nil
 }
 
+func (m *MockBrowserClient) ExtractMarkdown(ctx context.Context, tabID int, selector string, options browser.MarkdownOptions) (string, error) {
+	return "", nil
+}
+
+func (m *MockBrowserClient) ExtractTable(ctx context.Context, tabID int, selector string, index int) (*browser.Table, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) ExtractRows(ctx context.Context, tabID int, rowSelector string, columns []browser.TableColumn) (*browser.Table, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) ExtractStructured(ctx context.Context, tabID int, selector string, fields map[string]*browser.StructuredField) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) FindElements(ctx context.Context, tabID int, selector string, limit int) ([]browser.Element, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) GetValue(ctx context.Context, tabID int, selector string) (*browser.ElementValue, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) StartVideo(ctx context.Context, tabID int, maxDuration int) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) StopVideo(ctx context.Context, tabID int, dir, name string) (*browser.VideoRecording, error) {
+	return nil, nil
+}
+
 func (m *MockBrowserClient) Screenshot(ctx context.Context, tabID int, fullPage bool, selector, format string, quality int) (string, error) {
 	return "", nil
 }
 
+func (m *MockBrowserClient) AddRoute(ctx context.Context, route browser.Route) (*browser.Route, error) {
+	return &route, nil
+}
+
+func (m *MockBrowserClient) RemoveRoute(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *MockBrowserClient) ListRoutes(ctx context.Context) ([]browser.Route, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) StartNetworkCapture(ctx context.Context, tabID int) error {
+	return nil
+}
+
+func (m *MockBrowserClient) StopNetworkCapture(ctx context.Context, tabID int, dir, name string) (*browser.NetworkRecording, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) NetworkSummary(ctx context.Context, tabID int, slowMs int) (*browser.NetworkSummary, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) GetConsole(ctx context.Context, tabID int, filter browser.ConsoleFilter, limit int) ([]browser.ConsoleMessage, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) UploadFiles(ctx context.Context, tabID int, selector string, paths []string) ([]browser.UploadedFile, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) ListDownloads(ctx context.Context, filter browser.DownloadFilter) ([]browser.Download, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) WaitForDownload(ctx context.Context, filter browser.DownloadFilter, timeout time.Duration) (*browser.Download, error) {
+	return nil, nil
+}
+
+func (m *MockBrowserClient) SaveDownload(ctx context.Context, id int, dir string) (string, error) {
+	return "", nil
+}
+
 func (m *MockBrowserClient) GetCookies(ctx context.Context, url, name string) ([]browser.Cookie, error) {
 	return nil, nil
 }
@@ -100,6 +227,10 @@ func (m *MockBrowserClient) GetActionables(ctx context.Context, tabID int) ([]br
 	return nil, nil
 }
 
+func (m *MockBrowserClient) ResolveActionable(ctx context.Context, tabID int, label int) (*browser.Actionable, error) {
+	return nil, nil
+}
+
 func (m *MockBrowserClient) GetAccessibilitySnapshot(ctx context.Context, tabID int, interestingOnly bool, root string) (json.RawMessage, error) {
 	return nil, nil
 }
@@ -142,6 +273,22 @@ func (m *MockBrowserClient) GetPageTitle(ctx context.Context, tabID int) (string
 	return "", nil
 }
 
+func (m *MockBrowserClient) GetEvents(ctx context.Context, since uint64, filter browser.EventFilter, limit int) *browser.EventPage {
+	page := m.eventBus().Since(since, filter, limit)
+	return &page
+}
+
+func (m *MockBrowserClient) SubscribeEvents(fn func(browser.Event)) func() {
+	return m.eventBus().Subscribe(fn)
+}
+
+func (m *MockBrowserClient) eventBus() *browser.EventBus {
+	if m.events == nil {
+		m.events = browser.NewEventBus(10)
+	}
+	return m.events
+}
+
 func TestNewServer(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -182,11 +329,121 @@ func TestServer_RegisterTools(t *testing.T) {
 }
 
 func TestServer_Start(t *testing.T) {
-	// Note: Start() uses stdio transport which is difficult to test
+	// Note: Start() with the stdio transport is difficult to test
 	// in unit tests. This would be better tested in integration tests.
 	t.Skip("Start() uses stdio transport - better tested in integration tests")
 }
 
+func TestServer_StartUnknownTransport(t *testing.T) {
+	server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(&MockBrowserClient{}))
+
+	err := server.Start(context.Background(), config.ServerConfig{Transport: "carrier-pigeon"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown MCP transport")
+}
+
+func TestServer_HTTPTransports(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		newClient func(baseURL string) (*client.Client, error)
+	}{
+		{
+			name:      "sse",
+			transport: config.TransportSSE,
+			newClient: func(baseURL string) (*client.Client, error) {
+				return client.NewSSEMCPClient(baseURL + "/sse")
+			},
+		},
+		{
+			name:      "streamable http",
+			transport: config.TransportStreamableHTTP,
+			newClient: func(baseURL string) (*client.Client, error) {
+				return client.NewStreamableHttpClient(baseURL + "/mcp")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(&MockBrowserClient{}))
+
+			var h http.Handler
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			var err error
+			h, err = server.HTTPHandler(tt.transport, ts.URL)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			c, err := tt.newClient(ts.URL)
+			require.NoError(t, err)
+			defer c.Close()
+
+			require.NoError(t, c.Start(ctx))
+
+			initRequest := mcp.InitializeRequest{}
+			initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+			initRequest.Params.ClientInfo = mcp.Implementation{Name: "test-client", Version: "1.0.0"}
+			_, err = c.Initialize(ctx, initRequest)
+			require.NoError(t, err)
+
+			tools, err := c.ListTools(ctx, mcp.ListToolsRequest{})
+			require.NoError(t, err)
+			assert.NotEmpty(t, tools.Tools)
+
+			subscribe := mcp.CallToolRequest{}
+			subscribe.Params.Name = "browser_subscribe_events"
+			subscribe.Params.Arguments = map[string]any{"uri": "browser://events"}
+			result, err := c.CallTool(ctx, subscribe)
+			require.NoError(t, err)
+			assert.False(t, result.IsError)
+
+			resp, err := http.Get(ts.URL + "/health")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+
+	t.Run("sse message endpoint is relative without a base URL", func(t *testing.T) {
+		server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(&MockBrowserClient{}))
+		h, err := server.HTTPHandler(config.TransportSSE, "")
+		require.NoError(t, err)
+		ts := httptest.NewServer(h)
+		defer ts.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/sse", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		var endpoint string
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				endpoint = data
+				break
+			}
+		}
+		assert.True(t, strings.HasPrefix(endpoint, "/message?sessionId="), endpoint)
+	})
+
+	t.Run("stdio is not served over HTTP", func(t *testing.T) {
+		server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(&MockBrowserClient{}))
+		_, err := server.HTTPHandler(config.TransportStdio, "")
+		assert.Error(t, err)
+	})
+}
+
 func TestServer_ToolRegistration(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -195,6 +452,9 @@ func TestServer_ToolRegistration(t *testing.T) {
 		{
 			name: "all browser automation tools registered",
 			expectedTools: []string{
+				// Connection
+				"browser_wait_for_connection",
+				"browser_list_browsers",
 				// Tab management
 				"browser_list_tabs",
 				"browser_create_tab",
@@ -203,15 +463,34 @@ func TestServer_ToolRegistration(t *testing.T) {
 				// Navigation
 				"browser_navigate",
 				"browser_reload",
+				"browser_go_back",
+				"browser_go_forward",
 				// Interaction
 				"browser_click",
 				"browser_type",
 				"browser_scroll",
 				"browser_wait_for_element",
+				"browser_press_key",
+				"browser_hover",
+				"browser_drag",
+				"browser_select_option",
+				"browser_check",
+				"browser_uncheck",
 				// Content
 				"browser_execute_script",
 				"browser_extract_content",
+				"browser_extract_markdown",
+				"browser_extract_table",
+				"browser_extract_structured",
 				"browser_screenshot",
+				"browser_screenshot_compare",
+				"browser_screenshot_marked",
+				"browser_click_actionable",
+				"browser_find_elements",
+				"browser_get_value",
+				// Video
+				"browser_start_video",
+				"browser_stop_video",
 				// Storage
 				"browser_get_cookies",
 				"browser_set_cookie",
@@ -220,6 +499,22 @@ func TestServer_ToolRegistration(t *testing.T) {
 				"browser_set_local_storage",
 				"browser_get_session_storage",
 				"browser_set_session_storage",
+				// Events
+				"browser_get_events",
+				"browser_get_console",
+				"browser_subscribe_events",
+				"browser_unsubscribe_events",
+				// Files
+				"browser_upload_file",
+				"browser_list_downloads",
+				"browser_wait_for_download",
+				// Network
+				"browser_route_add",
+				"browser_route_remove",
+				"browser_route_list",
+				"browser_network_start",
+				"browser_network_stop",
+				"browser_network_summary",
 			},
 		},
 	}
@@ -234,9 +529,231 @@ func TestServer_ToolRegistration(t *testing.T) {
 			require.NotNil(t, server)
 			require.NotNil(t, server.mcpServer)
 
-			// Note: The actual tool registration happens inside the MCP server
-			// which is a third-party library. We're mainly testing that our
-			// registration methods are called without errors.
+			tools := listTools(t, server)
+			for _, name := range tt.expectedTools {
+				assert.Contains(t, tools, name)
+			}
 		})
 	}
 }
+
+func TestServer_EventSubscriptions(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(mockClient))
+
+	var h http.Handler
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	var err error
+	h, err = server.HTTPHandler(config.TransportSSE, ts.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := client.NewSSEMCPClient(ts.URL + "/sse")
+	require.NoError(t, err)
+	defer c.Close()
+	require.NoError(t, c.Start(ctx))
+
+	notifications := make(chan mcp.JSONRPCNotification, 10)
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		if n.Method == mcp.MethodNotificationResourceUpdated {
+			notifications <- n
+		}
+	})
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: "test-client", Version: "1.0.0"}
+	initResult, err := c.Initialize(ctx, initRequest)
+	require.NoError(t, err)
+	require.NotNil(t, initResult.Capabilities.Resources)
+	assert.False(t, initResult.Capabilities.Resources.Subscribe)
+
+	callTool := func(name, uri string) *mcp.CallToolResult {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = map[string]any{"uri": uri}
+		result, err := c.CallTool(ctx, request)
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.False(t, callTool("browser_subscribe_events", "browser://events/console").IsError)
+	assert.True(t, callTool("browser_subscribe_events", "browser://nothing").IsError)
+
+	// Only events of the subscribed type are pushed
+	mockClient.events.Publish(browser.Event{Type: browser.EventTabCreated, TabID: 1})
+	mockClient.events.Publish(browser.Event{Type: browser.EventConsole, TabID: 1, Data: &browser.ConsoleEvent{TabID: 1, Level: "error", Text: "boom"}})
+
+	select {
+	case n := <-notifications:
+		assert.Equal(t, "browser://events/console", n.Params.AdditionalFields["uri"])
+		event, ok := n.Params.AdditionalFields["event"].(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, "console", event["type"])
+	case <-ctx.Done():
+		t.Fatal("no resource updated notification")
+	}
+
+	// Reading the resource returns the recorded events
+	read := mcp.ReadResourceRequest{}
+	read.Params.URI = "browser://events"
+	contents, err := c.ReadResource(ctx, read)
+	require.NoError(t, err)
+	require.Len(t, contents.Contents, 1)
+	var page browser.EventPage
+	require.NoError(t, json.Unmarshal([]byte(contents.Contents[0].(mcp.TextResourceContents).Text), &page))
+	assert.Len(t, page.Events, 2)
+
+	assert.False(t, callTool("browser_unsubscribe_events", "browser://events/console").IsError)
+	mockClient.events.Publish(browser.Event{Type: browser.EventConsole, TabID: 1})
+
+	select {
+	case n := <-notifications:
+		t.Fatalf("unexpected notification after unsubscribing: %v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServer_ToolsAcceptBrowserID(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(mockClient))
+
+	for name, tool := range listTools(t, server) {
+		assert.Contains(t, tool.InputSchema.Properties, "browserId", "tool %s should accept browserId", name)
+	}
+}
+
+func TestServer_ToolsAcceptLocators(t *testing.T) {
+	mockClient := &MockBrowserClient{}
+	server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(mockClient))
+	tools := listTools(t, server)
+
+	// Tools by their arguments taking a locator
+	for name, arguments := range map[string][]string{
+		"browser_click":              {"selector"},
+		"browser_type":               {"selector"},
+		"browser_scroll":             {"selector"},
+		"browser_press_key":          {"selector"},
+		"browser_hover":              {"selector"},
+		"browser_drag":               {"source", "target"},
+		"browser_select_option":      {"selector"},
+		"browser_check":              {"selector"},
+		"browser_uncheck":            {"selector"},
+		"browser_wait_for_element":   {"selector"},
+		"browser_extract_content":    {"selector"},
+		"browser_extract_table":      {"selector", "rowSelector"},
+		"browser_extract_structured": {"selector"},
+		"browser_screenshot":         {"selector"},
+		"browser_find_elements":      {"selector"},
+		"browser_get_value":          {"selector"},
+		"browser_upload_file":        {"selector"},
+	} {
+		tool, ok := tools[name]
+		require.True(t, ok, "tool %s should be registered", name)
+		assert.Contains(t, tool.InputSchema.Properties, "frame", "tool %s should accept frame", name)
+		for _, argument := range arguments {
+			property, _ := tool.InputSchema.Properties[argument].(map[string]any)
+			assert.Contains(t, property["description"], ">>>", "tool %s should document the locator syntax of %s", name, argument)
+		}
+	}
+}
+
+// listTools lists the tools registered on the server through the MCP protocol
+func listTools(t *testing.T, s *Server) map[string]mcp.Tool {
+	t.Helper()
+
+	response := s.mcpServer.HandleMessage(context.Background(),
+		json.RawMessage(`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}`))
+
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	tools := make(map[string]mcp.Tool)
+	for _, tool := range decoded.Result.Tools {
+		tools[tool.Name] = tool
+	}
+	return tools
+}
+
+func TestServer_TracksInitializedSessions(t *testing.T) {
+	server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(&MockBrowserClient{}))
+	h, err := server.HTTPHandler(config.TransportStreamableHTTP, "")
+	require.NoError(t, err)
+
+	post := func(sessionID, body string) *http.Response {
+		r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			r.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test","version":"1.0.0"}}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+
+	// Only the session the server issued is tracked, not one a client made up
+	forged := "mcp-session-" + uuid.New().String()
+	post(forged, `{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	post(sessionID, `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+
+	server.httpSessionsMu.Lock()
+	defer server.httpSessionsMu.Unlock()
+	assert.Contains(t, server.httpSessions, sessionID)
+	assert.NotContains(t, server.httpSessions, forged)
+}
+
+func TestServer_ReleaseIdleSessions(t *testing.T) {
+	client := &MockBrowserClient{released: make(chan string, 10)}
+	server := NewServer("test-server", "1.0.0", handler.NewBrowserHandler(client))
+	server.SetSessionIdleTimeout(time.Minute)
+	h := server.trackHTTPSessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(method, sessionID string) {
+		r := httptest.NewRequest(method, "/mcp", nil)
+		r.Header.Set("Mcp-Session-Id", sessionID)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	for _, sessionID := range []string{"idle", "streaming", "deleted"} {
+		server.acceptHTTPSession(sessionID)
+	}
+	request(http.MethodPost, "idle")
+	request(http.MethodPost, "streaming")
+	server.touchHTTPSession("streaming", 1) // an open GET stream
+
+	// Sessions the server did not issue are not tracked
+	request(http.MethodPost, "forged")
+	assert.NotContains(t, server.httpSessions, "forged")
+
+	// Sessions ending with DELETE are released at once
+	request(http.MethodPost, "deleted")
+	request(http.MethodDelete, "deleted")
+	assert.Equal(t, "deleted", <-client.released)
+
+	server.releaseIdleSessions(context.Background(), time.Now())
+	assert.Empty(t, client.released, "no session is idle yet")
+
+	server.releaseIdleSessions(context.Background(), time.Now().Add(2*time.Minute))
+	assert.Equal(t, "idle", <-client.released)
+	assert.Empty(t, client.released, "sessions with open requests are kept")
+
+	server.SetSessionIdleTimeout(0)
+	server.touchHTTPSession("streaming", -1)
+	server.releaseIdleSessions(context.Background(), time.Now().Add(time.Hour))
+	assert.Empty(t, client.released, "a zero timeout keeps sessions")
+}
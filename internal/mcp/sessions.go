@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// httpSession is the activity of a streamable HTTP session
+type httpSession struct {
+	lastSeen time.Time
+	active   int // requests in progress, such as an open GET stream
+}
+
+// SetSessionIdleTimeout sets how long a streamable HTTP session can go
+// without requests before its tabs are released as if it had disconnected.
+// Zero keeps sessions until they send DELETE.
+func (s *Server) SetSessionIdleTimeout(timeout time.Duration) {
+	s.sessionIdleTimeout = timeout
+}
+
+// trackHTTPSessions records the activity of the tracked streamable HTTP
+// sessions of the requests to next, and releases the sessions that end with
+// DELETE
+func (s *Server) trackHTTPSessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID := r.Header.Get("Mcp-Session-Id")
+		if sessionID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s.touchHTTPSession(sessionID, 1)
+		next.ServeHTTP(w, r)
+		s.touchHTTPSession(sessionID, -1)
+
+		if r.Method == http.MethodDelete {
+			s.forgetHTTPSession(sessionID)
+			s.releaseSession(r.Context(), sessionID)
+		}
+	})
+}
+
+// acceptHTTPSession starts tracking a session the server initialized
+func (s *Server) acceptHTTPSession(sessionID string) {
+	s.httpSessionsMu.Lock()
+	defer s.httpSessionsMu.Unlock()
+	s.httpSessions[sessionID] = &httpSession{lastSeen: time.Now()}
+}
+
+// touchHTTPSession records a request of a tracked session starting, with
+// delta 1, or ending, with delta -1. Sessions the server did not initialize
+// are ignored.
+func (s *Server) touchHTTPSession(sessionID string, delta int) {
+	s.httpSessionsMu.Lock()
+	defer s.httpSessionsMu.Unlock()
+
+	session, ok := s.httpSessions[sessionID]
+	if !ok {
+		return
+	}
+	session.lastSeen = time.Now()
+	session.active += delta
+}
+
+// forgetHTTPSession stops tracking a session
+func (s *Server) forgetHTTPSession(sessionID string) {
+	s.httpSessionsMu.Lock()
+	defer s.httpSessionsMu.Unlock()
+	delete(s.httpSessions, sessionID)
+}
+
+// releaseIdleSessions releases the streamable HTTP sessions without requests
+// in progress whose last request ended before the idle timeout
+func (s *Server) releaseIdleSessions(ctx context.Context, now time.Time) {
+	if s.sessionIdleTimeout <= 0 {
+		return
+	}
+
+	var idle []string
+	s.httpSessionsMu.Lock()
+	for sessionID, session := range s.httpSessions {
+		if session.active == 0 && now.Sub(session.lastSeen) > s.sessionIdleTimeout {
+			idle = append(idle, sessionID)
+			delete(s.httpSessions, sessionID)
+		}
+	}
+	s.httpSessionsMu.Unlock()
+
+	for _, sessionID := range idle {
+		s.releaseSession(ctx, sessionID)
+	}
+}
+
+// expireIdleSessions releases idle streamable HTTP sessions until ctx is done
+func (s *Server) expireIdleSessions(ctx context.Context) {
+	if s.sessionIdleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.sessionIdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.releaseIdleSessions(ctx, now)
+		}
+	}
+}
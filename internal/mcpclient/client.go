@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -34,8 +35,36 @@ func (c *Client) Connect(ctx context.Context, serverCmd string, args ...string)
 	}
 
 	c.mcpClient = mcpClient
+	return c.initialize(ctx)
+}
+
+// ConnectURL establishes a connection to an MCP server already listening over
+// HTTP. URLs ending in /sse use the SSE transport, anything else the
+// streamable HTTP transport. Every connection is a separate server session.
+func (c *Client) ConnectURL(ctx context.Context, serverURL string) error {
+	var (
+		mcpClient *client.Client
+		err       error
+	)
+	if strings.HasSuffix(strings.TrimRight(serverURL, "/"), "/sse") {
+		mcpClient, err = client.NewSSEMCPClient(serverURL)
+	} else {
+		mcpClient, err = client.NewStreamableHttpClient(serverURL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create MCP client: %w", err)
+	}
 
-	// Initialize the client
+	if err := mcpClient.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start MCP client: %w", err)
+	}
+
+	c.mcpClient = mcpClient
+	return c.initialize(ctx)
+}
+
+// initialize performs the MCP initialization handshake
+func (c *Client) initialize(ctx context.Context) error {
 	initRequest := mcp.InitializeRequest{}
 	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
 	initRequest.Params.ClientInfo = mcp.Implementation{
@@ -43,8 +72,7 @@ func (c *Client) Connect(ctx context.Context, serverCmd string, args ...string)
 		Version: "1.0.0",
 	}
 
-	_, err = c.mcpClient.Initialize(ctx, initRequest)
-	if err != nil {
+	if _, err := c.mcpClient.Initialize(ctx, initRequest); err != nil {
 		return fmt.Errorf("failed to initialize client: %w", err)
 	}
 
@@ -0,0 +1,160 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/periplon/bract/internal/dsl"
+	"github.com/periplon/bract/internal/dsl/ast"
+)
+
+// Recorder captures MCP tool calls and writes them out as a DSL script that
+// replays them under mcp-test
+type Recorder struct {
+	mu         sync.Mutex
+	path       string
+	statements []ast.Statement
+	counts     map[string]int     // result variables issued per tool
+	tabs       map[float64]string // tab ID -> variable holding the tab
+}
+
+// New creates a recorder that writes to path. The script connects to
+// serverCommand when replayed.
+func New(path, serverCommand string) *Recorder {
+	return &Recorder{
+		path: path,
+		statements: []ast.Statement{
+			&ast.ConnectStatement{
+				Server: &ast.StringLiteral{Value: serverCommand},
+			},
+		},
+		counts: make(map[string]int),
+		tabs:   make(map[float64]string),
+	}
+}
+
+// Record appends a tool call to the script and rewrites the script file.
+// Failed calls are not recorded.
+func (r *Recorder) Record(tool string, args map[string]interface{}, result *mcp.CallToolResult, err error) error {
+	if err != nil || result == nil || result.IsError {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts[tool]++
+	variable := fmt.Sprintf("%s_%d", strings.TrimPrefix(tool, "browser_"), r.counts[tool])
+
+	stmt := &ast.CallStatement{
+		Tool:     tool,
+		Variable: variable,
+	}
+	if len(args) > 0 {
+		stmt.Arguments = r.objectLiteral(args)
+	}
+	r.statements = append(r.statements, stmt)
+
+	// Later calls on a created tab refer to it through the result variable,
+	// since tab IDs differ between runs
+	if tool == "browser_create_tab" {
+		if id, ok := resultTabID(result); ok {
+			r.tabs[id] = variable
+		}
+	}
+
+	return r.flush()
+}
+
+// Script returns the recorded script
+func (r *Recorder) Script() *ast.Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &ast.Script{Statements: append([]ast.Statement(nil), r.statements...)}
+}
+
+// flush writes the script to disk. r.mu must be held.
+func (r *Recorder) flush() error {
+	script := dsl.FormatAST(&ast.Script{Statements: r.statements})
+	if err := os.WriteFile(r.path, []byte(script), 0o644); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// objectLiteral converts tool arguments to an object literal
+func (r *Recorder) objectLiteral(args map[string]interface{}) *ast.ObjectLiteral {
+	fields := make(map[string]ast.Expression, len(args))
+	for key, value := range args {
+		if value == nil {
+			continue
+		}
+		if key == "tabId" {
+			if id, ok := value.(float64); ok {
+				if variable, ok := r.tabs[id]; ok {
+					fields[key] = &ast.FieldAccess{Object: &ast.Variable{Name: variable}, Field: "id"}
+					continue
+				}
+			}
+		}
+		fields[key] = r.expression(value)
+	}
+	return &ast.ObjectLiteral{Fields: fields}
+}
+
+// expression converts a JSON-decoded argument value to a literal
+func (r *Recorder) expression(value interface{}) ast.Expression {
+	switch v := value.(type) {
+	case string:
+		return &ast.StringLiteral{Value: v}
+	case float64:
+		return &ast.NumberLiteral{Value: v}
+	case int:
+		return &ast.NumberLiteral{Value: float64(v)}
+	case bool:
+		return &ast.BooleanLiteral{Value: v}
+	case map[string]interface{}:
+		return r.objectLiteral(v)
+	case []interface{}:
+		elements := make([]ast.Expression, 0, len(v))
+		for _, elem := range v {
+			if elem != nil {
+				elements = append(elements, r.expression(elem))
+			}
+		}
+		return &ast.ArrayLiteral{Elements: elements}
+	default:
+		// Round-trip anything else through JSON
+		data, err := json.Marshal(v)
+		if err != nil {
+			return &ast.StringLiteral{Value: fmt.Sprintf("%v", v)}
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded == nil {
+			return &ast.StringLiteral{Value: string(data)}
+		}
+		return r.expression(decoded)
+	}
+}
+
+// resultTabID extracts the tab ID from a browser_create_tab result
+func resultTabID(result *mcp.CallToolResult) (float64, bool) {
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var tab struct {
+			ID *float64 `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(text.Text), &tab); err == nil && tab.ID != nil {
+			return *tab.ID, true
+		}
+	}
+	return 0, false
+}
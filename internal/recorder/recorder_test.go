@@ -0,0 +1,73 @@
+package recorder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/periplon/bract/internal/dsl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.dsl")
+	r := New(path, "./bin/mcp-browser-server")
+
+	require.NoError(t, r.Record("browser_create_tab", map[string]interface{}{
+		"url":    "https://example.com",
+		"active": true,
+	}, mcp.NewToolResultText(`{"id": 42, "url": "https://example.com"}`), nil))
+
+	require.NoError(t, r.Record("browser_type", map[string]interface{}{
+		"tabId":    float64(42),
+		"selector": "#search",
+		"text":     "hello \"world\"",
+		"delay":    float64(50),
+	}, mcp.NewToolResultText("Text typed successfully"), nil))
+
+	// Failed calls are skipped
+	require.NoError(t, r.Record("browser_click", map[string]interface{}{"selector": "#missing"},
+		mcp.NewToolResultError("Failed to click: not found"), nil))
+	require.NoError(t, r.Record("browser_click", nil, nil, errors.New("boom")))
+
+	require.NoError(t, r.Record("browser_list_tabs", nil, mcp.NewToolResultText(`[]`), nil))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	expected := `connect "./bin/mcp-browser-server"
+
+call browser_create_tab {active: true, url: "https://example.com"} -> create_tab_1
+
+call browser_type {delay: 50, selector: "#search", tabId: create_tab_1.id, text: "hello \"world\""} -> type_1
+
+call browser_list_tabs -> list_tabs_1
+`
+	assert.Equal(t, expected, string(data))
+
+	// The recording is a valid, canonically formatted script
+	formatted, err := dsl.FormatScript(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, expected, formatted)
+}
+
+func TestRecorder_NestedArguments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recorded.dsl")
+	r := New(path, "./bin/mcp-browser-server")
+
+	require.NoError(t, r.Record("browser_execute_script", map[string]interface{}{
+		"script": "return arguments[0]",
+		"args":   []interface{}{"a", float64(1.5), map[string]interface{}{"content-type": "json"}},
+	}, mcp.NewToolResultText(`"a"`), nil))
+
+	script := r.Script()
+	require.Len(t, script.Statements, 2)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `args: ["a", 1.5, {"content-type": "json"}]`)
+	assert.NoError(t, dsl.ValidateString(string(data)))
+}
@@ -27,6 +27,7 @@ type Server struct {
 // Connection represents a WebSocket connection to a Chrome extension
 type Connection struct {
 	ID        string
+	BrowserID string // set from the "connected" handshake
 	conn      *websocket.Conn
 	send      chan []byte
 	server    *Server
@@ -95,15 +96,20 @@ func (s *Server) checkOrigin(r *http.Request) bool {
 	return false
 }
 
-// Start starts the WebSocket server
-func (s *Server) Start(ctx context.Context) error {
+// Handler returns the HTTP handler serving extension connections on / and
+// the health check on /health
+func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleWebSocket)
 	mux.HandleFunc("/health", s.handleHealth)
+	return mux
+}
 
+// Start starts the WebSocket server
+func (s *Server) Start(ctx context.Context) error {
 	server := &http.Server{
 		Addr:    fmt.Sprintf("localhost:%d", s.port),
-		Handler: mux,
+		Handler: s.Handler(),
 	}
 
 	// Start server in goroutine
@@ -193,7 +199,7 @@ func (c *Connection) readPump() {
 			c.server.browserClient.HandleResponse(msg.ID, responseData, msg.Error)
 		case "event":
 			// Event from the extension (e.g., tab closed)
-			c.server.browserClient.HandleEvent(msg.Action, msg.Data)
+			c.server.browserClient.HandleConnectionEvent(c, msg.Action, msg.Data)
 		case "ping":
 			// Respond to ping
 			if err := c.SendMessage(&Message{
@@ -204,7 +210,8 @@ func (c *Connection) readPump() {
 			}
 		case "connected":
 			// Handle connection confirmation from Chrome extension
-			log.Printf("Chrome extension connected successfully: %s", c.ID)
+			c.registerBrowser(msg)
+			log.Printf("Chrome extension connected successfully: %s (browser: %s)", c.ID, c.BrowserID)
 			// Optionally send acknowledgment back
 			if err := c.SendMessage(&Message{
 				ID:   msg.ID,
@@ -233,6 +240,41 @@ func (c *Connection) readPump() {
 	}
 }
 
+// handshake is the identity a Chrome extension announces in its "connected" message
+type handshake struct {
+	BrowserID string `json:"browserId"`
+	Name      string `json:"name"`
+	Profile   string `json:"profile"`
+	Version   string `json:"version"`
+}
+
+// registerBrowser registers the connection with the browser client under the
+// browser ID from the handshake, falling back to the connection ID
+func (c *Connection) registerBrowser(msg Message) {
+	var hs handshake
+	payload := msg.Data
+	if payload == nil {
+		payload = msg.Params
+	}
+	if payload != nil {
+		if err := json.Unmarshal(payload, &hs); err != nil {
+			log.Printf("Invalid handshake data from %s: %v", c.ID, err)
+		}
+	}
+
+	c.BrowserID = hs.BrowserID
+	if c.BrowserID == "" {
+		c.BrowserID = c.ID
+	}
+
+	c.server.browserClient.RegisterBrowser(browser.BrowserInfo{
+		ID:      c.BrowserID,
+		Name:    hs.Name,
+		Profile: hs.Profile,
+		Version: hs.Version,
+	}, c)
+}
+
 // writePump handles outgoing messages to the WebSocket connection
 func (c *Connection) writePump() {
 	ticker := time.NewTicker(30 * time.Second)
@@ -331,8 +373,17 @@ func mapActionToCommand(action string) string {
 		"captureVideo":        "tabs.captureVideo",
 		"extractText":         "tabs.extractText",
 		"extractContent":      "tabs.extractText",
+		"extractRows":         "tabs.extractRows",
+		"extractStructured":   "tabs.extractStructured",
 		"findElements":        "tabs.findElements",
 		"click":               "tabs.click",
+		"pressKey":            "tabs.pressKey",
+		"hover":               "tabs.hover",
+		"drag":                "tabs.drag",
+		"selectOption":        "tabs.selectOption",
+		"setChecked":          "tabs.setChecked",
+		"uploadChunk":         "tabs.uploadChunk",
+		"uploadFile":          "tabs.uploadFile",
 		"type":                "tabs.type",
 		"getValue":            "tabs.getValue",
 		"waitForElement":      "tabs.waitForElement",
@@ -347,6 +398,11 @@ func mapActionToCommand(action string) string {
 		"getSessionStorage":   "tabs.getSessionStorage",
 		"setSessionStorage":   "tabs.setSessionStorage",
 		"clearSessionStorage": "tabs.clearSessionStorage",
+		"addRoute":            "network.addRoute",
+		"removeRoute":         "network.removeRoute",
+		"startNetworkCapture": "network.startCapture",
+		"stopNetworkCapture":  "network.stopCapture",
+		"readDownload":        "downloads.read",
 	}
 
 	if cmd, ok := commandMap[action]; ok {
@@ -422,3 +422,38 @@ func TestServer_CheckOrigin(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_ConnectedHandshakeRegistersBrowser(t *testing.T) {
+	cfg := config.WebSocketConfig{
+		Port:        8765,
+		ReconnectMs: 5000,
+	}
+	browserClient := browser.NewClient(cfg)
+	server := NewServer(8765, browserClient, []string{"http://localhost"})
+
+	testServer := httptest.NewServer(http.HandlerFunc(server.handleWebSocket))
+	defer testServer.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(testServer.URL, "http")
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	err = ws.WriteJSON(Message{
+		ID:   "hello",
+		Type: "connected",
+		Data: json.RawMessage(`{"browserId": "chrome-work", "profile": "Work", "version": "1.2.0"}`),
+	})
+	require.NoError(t, err)
+
+	var response Message
+	require.NoError(t, ws.ReadJSON(&response))
+	assert.Equal(t, "ack", response.Type)
+
+	browsers := browserClient.ListBrowsers()
+	require.Len(t, browsers, 1)
+	assert.Equal(t, "chrome-work", browsers[0].ID)
+	assert.Equal(t, "Work", browsers[0].Profile)
+	assert.Equal(t, "1.2.0", browsers[0].Version)
+	assert.True(t, browsers[0].Default)
+}